@@ -18,6 +18,8 @@ package logstats
 
 import (
 	"io"
+	"maps"
+	"regexp"
 	"slices"
 	"strconv"
 	"strings"
@@ -54,7 +56,7 @@ func sortBVars(sorted []logbv, bvars map[string]*querypb.BindVariable) []logbv {
 	return sorted
 }
 
-func (log *Logger) appendBVarsJSON(b []byte, bvars map[string]*querypb.BindVariable, full bool) []byte {
+func (log *Logger) appendBVarsJSON(b []byte, bvars map[string]*querypb.BindVariable, full bool, redact *regexp.Regexp) []byte {
 	log.bvars = sortBVars(log.bvars[:0], bvars)
 
 	b = append(b, '{')
@@ -67,7 +69,9 @@ func (log *Logger) appendBVarsJSON(b []byte, bvars map[string]*querypb.BindVaria
 		b = strconv.AppendQuote(b, querypb.Type_name[int32(bv.BVar.Type)])
 		b = append(b, `, "value": `...)
 
-		if sqltypes.IsIntegral(bv.BVar.Type) || sqltypes.IsFloat(bv.BVar.Type) {
+		if redact != nil && redact.MatchString(bv.Name) {
+			b = append(b, `"[REDACTED]"`...)
+		} else if sqltypes.IsIntegral(bv.BVar.Type) || sqltypes.IsFloat(bv.BVar.Type) {
 			b = append(b, bv.BVar.Value...)
 		} else if bv.BVar.Type == sqltypes.Tuple {
 			b = append(b, '"')
@@ -162,7 +166,13 @@ func (log *Logger) BindVariables(bvars map[string]*querypb.BindVariable, full bo
 	// the bind variables are printed as JSON in text mode because the original
 	// printing syntax, which was simply `fmt.Sprintf("%v")`, is not stable or
 	// safe to parse
-	log.b = log.appendBVarsJSON(log.b, bvars, full)
+	log.b = log.appendBVarsJSON(log.b, bvars, full, nil)
+}
+
+// RedactedBindVariables works like BindVariables, but masks the values of
+// the bind variables whose names match redact. A nil redact masks nothing.
+func (log *Logger) RedactedBindVariables(bvars map[string]*querypb.BindVariable, full bool, redact *regexp.Regexp) {
+	log.b = log.appendBVarsJSON(log.b, bvars, full, redact)
 }
 
 func (log *Logger) Int(i int64) {
@@ -188,6 +198,56 @@ func (log *Logger) Strings(strs []string) {
 	log.b = append(log.b, ']')
 }
 
+// StringMap writes m as an object, sorted by key.
+func (log *Logger) StringMap(m map[string]string) {
+	log.b = append(log.b, '{')
+	for i, k := range slices.Sorted(maps.Keys(m)) {
+		if i > 0 {
+			log.b = append(log.b, ',')
+		}
+		log.b = strconv.AppendQuote(log.b, k)
+		log.b = append(log.b, ':')
+		log.b = strconv.AppendQuote(log.b, m[k])
+	}
+	log.b = append(log.b, '}')
+}
+
+func (log *Logger) UintMap(m map[string]uint64) {
+	log.b = append(log.b, '{')
+	for i, k := range slices.Sorted(maps.Keys(m)) {
+		if i > 0 {
+			log.b = append(log.b, ',')
+		}
+		log.b = strconv.AppendQuote(log.b, k)
+		log.b = append(log.b, ':')
+		log.b = strconv.AppendUint(log.b, m[k], 10)
+	}
+	log.b = append(log.b, '}')
+}
+
+// ShardDuration is the time a query spent on a shard.
+type ShardDuration struct {
+	Shard    string
+	Duration time.Duration
+}
+
+// ShardDurations writes shards as an array of objects with the shard and
+// its duration, in seconds.
+func (log *Logger) ShardDurations(shards []ShardDuration) {
+	log.b = append(log.b, '[')
+	for i, shard := range shards {
+		if i > 0 {
+			log.b = append(log.b, ',')
+		}
+		log.b = append(log.b, `{"Shard":`...)
+		log.b = strconv.AppendQuote(log.b, shard.Shard)
+		log.b = append(log.b, `,"Duration":`...)
+		log.Duration(shard.Duration)
+		log.b = append(log.b, '}')
+	}
+	log.b = append(log.b, ']')
+}
+
 func (log *Logger) Flush(w io.Writer) (err error) {
 	if log.json {
 		log.b = append(log.b, '}')
@@ -17,6 +17,7 @@ limitations under the License.
 package logstats
 
 import (
+	"regexp"
 	"testing"
 	"time"
 
@@ -221,6 +222,34 @@ func TestStrings(t *testing.T) {
 	assert.Equal(t, []byte("{[\"testValue1\"]"), tl.b)
 }
 
+func TestStringMap(t *testing.T) {
+	tl := Logger{}
+	tl.Init(false)
+
+	tl.StringMap(map[string]string{"b": "testValue2", "a": "testValue1"})
+	assert.Equal(t, []byte("{\"a\":\"testValue1\",\"b\":\"testValue2\"}"), tl.b)
+
+	tl.b = []byte{}
+	tl.Init(false)
+
+	tl.StringMap(nil)
+	assert.Equal(t, []byte("{}"), tl.b)
+}
+
+func TestUintMap(t *testing.T) {
+	tl := Logger{}
+	tl.Init(false)
+
+	tl.UintMap(map[string]uint64{"ks/80-": 12, "ks/-80": 7})
+	assert.Equal(t, []byte("{\"ks/-80\":7,\"ks/80-\":12}"), tl.b)
+
+	tl.b = []byte{}
+	tl.Init(false)
+
+	tl.UintMap(nil)
+	assert.Equal(t, []byte("{}"), tl.b)
+}
+
 var calledValue []byte
 
 type mockWriter struct{}
@@ -318,3 +347,21 @@ func TestBindVariables(t *testing.T) {
 		})
 	}
 }
+
+func TestRedactedBindVariables(t *testing.T) {
+	bVars := map[string]*querypb.BindVariable{
+		"id":       sqltypes.Int64BindVariable(1),
+		"password": sqltypes.StringBindVariable("hunter2"),
+		"token":    sqltypes.Int64BindVariable(42),
+	}
+
+	tl := Logger{}
+	tl.Init(true)
+	tl.RedactedBindVariables(bVars, true, regexp.MustCompile("password|token"))
+	assert.Equal(t, `{{"id": {"type": "INT64", "value": 1}, "password": {"type": "VARCHAR", "value": "[REDACTED]"}, "token": {"type": "INT64", "value": "[REDACTED]"}}`, string(tl.b))
+
+	tl = Logger{}
+	tl.Init(true)
+	tl.RedactedBindVariables(bVars, true, nil)
+	assert.Equal(t, `{{"id": {"type": "INT64", "value": 1}, "password": {"type": "VARCHAR", "value": "hunter2"}, "token": {"type": "INT64", "value": 42}}`, string(tl.b))
+}
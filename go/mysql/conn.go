@@ -127,6 +127,11 @@ type Conn struct {
 	// It is set during the initial handshake.
 	UserData Getter
 
+	// Attributes are the connection attributes sent by the client, such
+	// as program_name. They are set during the initial handshake, for
+	// server-side connections of clients that send them.
+	Attributes map[string]string
+
 	bufferedReader *bufio.Reader
 	flushTimer     *time.Timer
 	flushDelay     time.Duration
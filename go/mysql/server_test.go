@@ -19,6 +19,7 @@ package mysql
 import (
 	"context"
 	"crypto/tls"
+	"encoding/binary"
 	"fmt"
 	"net"
 	"os"
@@ -1456,6 +1457,30 @@ func TestParseConnAttrs(t *testing.T) {
 	}
 }
 
+func TestParseClientHandshakePacketConnAttrs(t *testing.T) {
+	lenEncString := func(s string) []byte {
+		return append([]byte{byte(len(s))}, s...)
+	}
+	attrs := append(lenEncString("program_name"), lenEncString("billing")...)
+
+	data := binary.LittleEndian.AppendUint32(nil, CapabilityClientProtocol41|CapabilityClientSecureConnection|CapabilityClientPluginAuth|CapabilityClientConnAttr)
+	data = binary.LittleEndian.AppendUint32(data, MaxPacketSize)
+	data = append(data, 0x21)
+	data = append(data, make([]byte, 23)...)
+	data = append(data, "user1\x00"...)
+	data = append(data, 0)
+	data = append(data, "mysql_native_password\x00"...)
+	data = append(data, byte(len(attrs)))
+	data = append(data, attrs...)
+
+	l := &Listener{}
+	c := newConn(nil, DefaultFlushDelay, 0)
+	username, _, _, err := l.parseClientHandshakePacket(c, true, data)
+	require.NoError(t, err)
+	require.Equal(t, "user1", username)
+	require.Equal(t, map[string]string{"program_name": "billing"}, c.Attributes)
+}
+
 func TestServerFlush(t *testing.T) {
 	ctx := utils.LeakCheckContext(t)
 	mysqlServerFlushDelay := 10 * time.Millisecond
@@ -347,6 +347,13 @@ func (pool *ConnPool[C]) Available() int64 {
 	return pool.capacity.Load() - pool.borrowed.Load()
 }
 
+// Waiting returns the number of clients that are blocked waiting for a
+// connection to be returned to the pool. It doesn't lock the waitlist, so
+// it is cheap enough to call on every Get.
+func (pool *ConnPool[C]) Waiting() int {
+	return int(pool.wait.waiters.Load())
+}
+
 // Active returns the numer of connections that the pool has currently open.
 func (pool *ConnPool[C]) Active() int64 {
 	return pool.active.Load()
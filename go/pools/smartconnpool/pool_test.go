@@ -170,12 +170,13 @@ func TestOpen(t *testing.T) {
 	}()
 	for i := 0; i < 5; i++ {
 		// block until we have a client wait for a connection, then offer it
-		for p.wait.waiting() == 0 {
+		for p.Waiting() == 0 {
 			time.Sleep(time.Millisecond)
 		}
 		p.put(resources[i])
 	}
 	<-done
+	assert.Zero(t, p.Waiting())
 	assert.EqualValues(t, 5, p.Metrics.WaitCount())
 	assert.Equal(t, 5, len(state.waits))
 	// verify start times are monotonic increasing
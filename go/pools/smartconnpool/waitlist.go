@@ -19,6 +19,7 @@ package smartconnpool
 import (
 	"context"
 	"sync"
+	"sync/atomic"
 
 	"vitess.io/vitess/go/list"
 )
@@ -43,6 +44,9 @@ type waitlist[C Connection] struct {
 	nodes sync.Pool
 	mu    sync.Mutex
 	list  list.List[waiter[C]]
+	// waiters mirrors the length of list, so that it can be read without
+	// taking mu. It is only changed with mu held.
+	waiters atomic.Int64
 }
 
 // waitForConn blocks until a connection with the given Setting is returned by another client,
@@ -57,6 +61,7 @@ func (wl *waitlist[C]) waitForConn(ctx context.Context, setting *Setting) (*Pool
 	wl.mu.Lock()
 	// add ourselves as a waiter at the end of the waitlist
 	wl.list.PushBackValue(elem)
+	wl.waiters.Add(1)
 	wl.mu.Unlock()
 
 	// block on our waiter's semaphore until somebody can hand over a connection to us
@@ -99,6 +104,7 @@ func (wl *waitlist[C]) expire(force bool) (maybeStarving int) {
 	for _, e := range expired {
 		wl.list.Remove(e)
 	}
+	wl.waiters.Add(-int64(len(expired)))
 	wl.mu.Unlock()
 
 	// once all the expired waiters have been removed from the waitlist, wake them up one by one
@@ -144,6 +150,7 @@ func (wl *waitlist[D]) tryReturnConnSlow(conn *Pooled[D]) bool {
 	}
 	if target != nil {
 		wl.list.Remove(target)
+		wl.waiters.Add(-1)
 	}
 	wl.mu.Unlock()
 
@@ -36,6 +36,7 @@ type Result struct {
 	SessionStateChanges string           `json:"session_state_changes"`
 	StatusFlags         uint16           `json:"status_flags"`
 	Info                string           `json:"info"`
+	ConnectionID        uint64           `json:"connection_id"`
 }
 
 //goland:noinspection GoUnusedConst
@@ -97,6 +98,7 @@ func (result *Result) Copy() *Result {
 		SessionStateChanges: result.SessionStateChanges,
 		StatusFlags:         result.StatusFlags,
 		Info:                result.Info,
+		ConnectionID:        result.ConnectionID,
 	}
 	if result.Fields != nil {
 		out.Fields = make([]*querypb.Field, len(result.Fields))
@@ -123,6 +125,7 @@ func (result *Result) ShallowCopy() *Result {
 		Info:                result.Info,
 		SessionStateChanges: result.SessionStateChanges,
 		Rows:                result.Rows,
+		ConnectionID:        result.ConnectionID,
 	}
 }
 
@@ -136,6 +139,7 @@ func (result *Result) Metadata() *Result {
 		RowsAffected:        result.RowsAffected,
 		Info:                result.Info,
 		SessionStateChanges: result.SessionStateChanges,
+		ConnectionID:        result.ConnectionID,
 	}
 }
 
@@ -161,6 +165,7 @@ func (result *Result) Truncate(l int) *Result {
 		RowsAffected:        result.RowsAffected,
 		Info:                result.Info,
 		SessionStateChanges: result.SessionStateChanges,
+		ConnectionID:        result.ConnectionID,
 	}
 	if result.Fields != nil {
 		out.Fields = result.Fields[:l]
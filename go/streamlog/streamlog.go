@@ -18,14 +18,17 @@ limitations under the License.
 package streamlog
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"math/rand/v2"
 	"net/http"
 	"net/url"
 	"os"
+	"regexp"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/spf13/pflag"
 
@@ -68,6 +71,12 @@ type QueryLogConfig struct {
 	Mode                 string
 	RowThreshold         uint64
 	sampleRate           float64
+
+	// RedactBindVars masks the values of the bind variables whose names
+	// match it, and leaves out the query as sent, whose literals hold them.
+	// It has no effect when RedactDebugUIQueries is set, since bind
+	// variables are then redacted altogether.
+	RedactBindVars *regexp.Regexp
 }
 
 var queryLogConfigInstance = QueryLogConfig{
@@ -95,6 +104,9 @@ func registerStreamLogFlags(fs *pflag.FlagSet) {
 	// RedactDebugUIQueries controls whether full queries and bind variables are suppressed from debug UIs.
 	fs.BoolVar(&queryLogConfigInstance.RedactDebugUIQueries, "redact-debug-ui-queries", queryLogConfigInstance.RedactDebugUIQueries, "redact full queries and bind variables from debug UI")
 
+	// RedactBindVars masks the values of bind variables whose names match any of the given patterns.
+	fs.Var(&bindVarPatterns{re: &queryLogConfigInstance.RedactBindVars}, "querylog-redact-bind-vars", "regular expression matching the names of bind variables whose values should be redacted from query logs; can be repeated")
+
 	// QueryLogFormat controls the format of the query log (either text or json)
 	fs.StringVar(&queryLogConfigInstance.Format, "querylog-format", queryLogConfigInstance.Format, "format for query logs (\"text\" or \"json\")")
 
@@ -111,6 +123,33 @@ func registerStreamLogFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&queryLogConfigInstance.Mode, "querylog-mode", queryLogConfigInstance.Mode, `Mode for logging queries. "error" will only log queries that return an error. Otherwise all queries will be logged.`)
 }
 
+// bindVarPatterns is a repeatable flag that combines every pattern it is
+// given into a single regular expression.
+type bindVarPatterns struct {
+	patterns []string
+	re       **regexp.Regexp
+}
+
+// Set implements pflag.Value.
+func (p *bindVarPatterns) Set(pattern string) error {
+	if _, err := regexp.Compile(pattern); err != nil {
+		return err
+	}
+	p.patterns = append(p.patterns, pattern)
+	*p.re = regexp.MustCompile("(?:" + strings.Join(p.patterns, ")|(?:") + ")")
+	return nil
+}
+
+// String implements pflag.Value.
+func (p *bindVarPatterns) String() string {
+	return strings.Join(p.patterns, ",")
+}
+
+// Type implements pflag.Value.
+func (p *bindVarPatterns) Type() string {
+	return "regexp"
+}
+
 // StreamLogger is a non-blocking broadcaster of messages.
 // Subscribers can use channels or HTTP.
 type StreamLogger[T any] struct {
@@ -118,6 +157,29 @@ type StreamLogger[T any] struct {
 	size       int
 	mu         sync.Mutex
 	subscribed map[chan T]string
+
+	// dedupWindow is the longest a run of identical messages is held
+	// before being sent; 0 disables deduplication.
+	dedupWindow time.Duration
+	// pending is the first message of the current run of identical
+	// messages, pendingCount the length of that run.
+	pending      T
+	pendingKey   string
+	pendingCount uint64
+	hasPending   bool
+	// pendingGen identifies the current run so that the timer of an
+	// earlier run doesn't flush it.
+	pendingGen uint64
+}
+
+// Collapsible is implemented by messages that a StreamLogger can
+// deduplicate, see SetDedupWindow.
+type Collapsible interface {
+	// DedupKey returns the key under which identical messages compare equal.
+	DedupKey() string
+	// SetRepeatCount records how many identical consecutive messages the
+	// message stands for once they were collapsed into it.
+	SetRepeatCount(count uint64)
 }
 
 // LogFormatter is the function signature used to format an arbitrary
@@ -136,10 +198,69 @@ func New[T any](name string, size int) *StreamLogger[T] {
 
 // Send sends message to all the writers subscribed to logger. Calling
 // Send does not block.
+//
+// When deduplication is enabled, a Collapsible message identical to the
+// previous one is folded into it instead, and the run is sent as a single
+// message once a different message arrives or the dedup window elapses.
 func (logger *StreamLogger[T]) Send(message T) {
 	logger.mu.Lock()
 	defer logger.mu.Unlock()
 
+	sendCount.Add(logger.name, 1)
+	if logger.dedupWindow > 0 {
+		if c, ok := any(message).(Collapsible); ok {
+			key := c.DedupKey()
+			if logger.hasPending && logger.pendingKey == key {
+				logger.pendingCount++
+				return
+			}
+			logger.flushPendingLocked()
+			logger.pending, logger.pendingKey, logger.pendingCount, logger.hasPending = message, key, 1, true
+			logger.pendingGen++
+			gen := logger.pendingGen
+			time.AfterFunc(logger.dedupWindow, func() {
+				logger.mu.Lock()
+				defer logger.mu.Unlock()
+				if logger.pendingGen == gen {
+					logger.flushPendingLocked()
+				}
+			})
+			return
+		}
+	}
+	logger.flushPendingLocked()
+	logger.broadcastLocked(message)
+}
+
+// SetDedupWindow enables the deduplication of identical consecutive
+// Collapsible messages. A run is held for at most window before being
+// sent; a window of 0 disables deduplication and sends any held run.
+func (logger *StreamLogger[T]) SetDedupWindow(window time.Duration) {
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+
+	logger.dedupWindow = window
+	if window <= 0 {
+		logger.flushPendingLocked()
+	}
+}
+
+// flushPendingLocked sends the held run of identical messages, if any.
+func (logger *StreamLogger[T]) flushPendingLocked() {
+	if !logger.hasPending {
+		return
+	}
+	message := logger.pending
+	if logger.pendingCount > 1 {
+		any(message).(Collapsible).SetRepeatCount(logger.pendingCount)
+	}
+	var zero T
+	logger.pending, logger.pendingKey, logger.pendingCount, logger.hasPending = zero, "", 0, false
+	logger.pendingGen++
+	logger.broadcastLocked(message)
+}
+
+func (logger *StreamLogger[T]) broadcastLocked(message T) {
 	for ch, name := range logger.subscribed {
 		select {
 		case ch <- message:
@@ -148,7 +269,6 @@ func (logger *StreamLogger[T]) Send(message T) {
 			deliveryDropCount.Add([]string{logger.name, name}, 1)
 		}
 	}
-	sendCount.Add(logger.name, 1)
 }
 
 // Subscribe returns a channel which can be used to listen
@@ -170,6 +290,15 @@ func (logger *StreamLogger[T]) Unsubscribe(ch chan T) {
 	delete(logger.subscribed, ch)
 }
 
+// HasSubscribers returns whether any channel is subscribed to logger, so
+// that callers can skip collecting what nobody would receive.
+func (logger *StreamLogger[T]) HasSubscribers() bool {
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+
+	return len(logger.subscribed) > 0
+}
+
 // Name returns the name of StreamLogger.
 func (logger *StreamLogger[T]) Name() string {
 	return logger.name
@@ -235,6 +364,49 @@ func (logger *StreamLogger[T]) LogToFile(path string, logf LogFormatter) (chan T
 	return logChan, nil
 }
 
+// consoleMu serializes the writes of all the console sinks, so that records
+// logged concurrently to the same stream never interleave.
+var consoleMu sync.Mutex
+
+// ConsoleWriter returns the standard stream with the given name, which must be
+// either "stdout" or "stderr".
+func ConsoleWriter(name string) (io.Writer, error) {
+	switch name {
+	case "stdout":
+		return os.Stdout, nil
+	case "stderr":
+		return os.Stderr, nil
+	}
+	return nil, fmt.Errorf("invalid console %q, must be \"stdout\" or \"stderr\"", name)
+}
+
+// LogToConsole starts logging the records that pass filter to out, which is
+// normally one of the streams returned by ConsoleWriter. A nil filter logs
+// every record. Each record is formatted on its own and written with a single
+// call, so that lines are never split or interleaved with other records.
+func (logger *StreamLogger[T]) LogToConsole(out io.Writer, logf LogFormatter, filter func(T) bool) chan T {
+	logChan := logger.Subscribe("ConsoleLog")
+	formatParams := map[string][]string{"full": {}}
+
+	go func() {
+		var buf bytes.Buffer
+		for record := range logChan {
+			if filter != nil && !filter(record) {
+				continue
+			}
+			buf.Reset()
+			if err := logf(&buf, formatParams, record); err != nil || buf.Len() == 0 {
+				continue
+			}
+			consoleMu.Lock()
+			out.Write(buf.Bytes()) // nolint:errcheck
+			consoleMu.Unlock()
+		}
+	}()
+
+	return logChan
+}
+
 // Formatter is a simple interface for objects that expose a Format function
 // as needed for streamlog.
 type Formatter interface {
@@ -281,3 +453,9 @@ func (qlConfig QueryLogConfig) ShouldEmitLog(sql string, rowsAffected, rowsRetur
 	}
 	return true
 }
+
+// ShouldRedactBindVar returns whether the value of the bind variable with
+// the given name must be masked when logged.
+func (qlConfig QueryLogConfig) ShouldRedactBindVar(name string) bool {
+	return qlConfig.RedactBindVars != nil && qlConfig.RedactBindVars.MatchString(name)
+}
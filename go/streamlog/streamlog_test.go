@@ -26,6 +26,9 @@ import (
 	"net/url"
 	"os"
 	"path"
+	"slices"
+	"strings"
+	"sync"
 	"syscall"
 	"testing"
 	"time"
@@ -145,6 +148,9 @@ func TestChannel(t *testing.T) {
 	if sz := len(logger.subscribed); sz != 1 {
 		t.Errorf("want 1, got %d", sz)
 	}
+	if !logger.HasSubscribers() {
+		t.Errorf("HasSubscribers() = false, want true")
+	}
 
 	// Send/receive some messages, one at a time.
 	for i := 0; i < 10; i++ {
@@ -199,6 +205,62 @@ func TestChannel(t *testing.T) {
 	if sz := len(logger.subscribed); sz != 0 {
 		t.Errorf("want 0, got %d", sz)
 	}
+	if logger.HasSubscribers() {
+		t.Errorf("HasSubscribers() = true, want false")
+	}
+}
+
+type repeatedMessage struct {
+	val   string
+	count uint64
+}
+
+func (m *repeatedMessage) DedupKey() string            { return m.val }
+func (m *repeatedMessage) SetRepeatCount(count uint64) { m.count = count }
+
+func TestDedup(t *testing.T) {
+	logger := New[*repeatedMessage]("logger", 10)
+	logger.SetDedupWindow(time.Hour)
+	ch := logger.Subscribe("test")
+	defer logger.Unsubscribe(ch)
+
+	for _, val := range []string{"a", "a", "a", "b", "a"} {
+		logger.Send(&repeatedMessage{val: val})
+	}
+
+	// The run of "a" ends when "b" arrives, and "b" when the second "a" does.
+	require.Len(t, ch, 2)
+	first, second := <-ch, <-ch
+	assert.Equal(t, &repeatedMessage{val: "a", count: 3}, first)
+	assert.Equal(t, &repeatedMessage{val: "b"}, second)
+
+	// Disabling deduplication sends the run still being held.
+	logger.SetDedupWindow(0)
+	require.Len(t, ch, 1)
+	assert.Equal(t, &repeatedMessage{val: "a"}, <-ch)
+
+	// Without deduplication every message is sent.
+	logger.Send(&repeatedMessage{val: "c"})
+	logger.Send(&repeatedMessage{val: "c"})
+	assert.Len(t, ch, 2)
+}
+
+func TestDedupWindow(t *testing.T) {
+	logger := New[*repeatedMessage]("logger", 10)
+	logger.SetDedupWindow(10 * time.Millisecond)
+	ch := logger.Subscribe("test")
+	defer logger.Unsubscribe(ch)
+
+	logger.Send(&repeatedMessage{val: "a"})
+	logger.Send(&repeatedMessage{val: "a"})
+
+	// The run is never held past the window, even if it never ends.
+	select {
+	case msg := <-ch:
+		assert.Equal(t, &repeatedMessage{val: "a", count: 2}, msg)
+	case <-time.After(5 * time.Second):
+		t.Fatal("run of identical messages was not sent after the dedup window")
+	}
 }
 
 func TestFile(t *testing.T) {
@@ -265,6 +327,60 @@ func TestFile(t *testing.T) {
 	}
 }
 
+// writeRecorder keeps every call to Write separately.
+type writeRecorder struct {
+	mu     sync.Mutex
+	writes []string
+}
+
+func (w *writeRecorder) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.writes = append(w.writes, string(b))
+	return len(b), nil
+}
+
+func (w *writeRecorder) get() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return slices.Clone(w.writes)
+}
+
+func TestConsole(t *testing.T) {
+	out, err := ConsoleWriter("stdout")
+	require.NoError(t, err)
+	assert.Equal(t, os.Stdout, out)
+	out, err = ConsoleWriter("stderr")
+	require.NoError(t, err)
+	assert.Equal(t, os.Stderr, out)
+	_, err = ConsoleWriter("syslog")
+	assert.ErrorContains(t, err, "invalid console")
+
+	// Two sinks share the same output and only log the slow records.
+	var recorder writeRecorder
+	slowOnly := func(m *logMessage) bool { return strings.HasPrefix(m.val, "slow") }
+	logger1 := New[*logMessage]("logger1", 100)
+	logger2 := New[*logMessage]("logger2", 100)
+	ch1 := logger1.LogToConsole(&recorder, testLogf, slowOnly)
+	defer logger1.Unsubscribe(ch1)
+	ch2 := logger2.LogToConsole(&recorder, testLogf, slowOnly)
+	defer logger2.Unsubscribe(ch2)
+
+	for i := 0; i < 20; i++ {
+		logger1.Send(&logMessage{fmt.Sprintf("slow %d", i)})
+		logger2.Send(&logMessage{fmt.Sprintf("fast %d", i)})
+		logger2.Send(&logMessage{fmt.Sprintf("slow %d", i)})
+	}
+
+	assert.Eventually(t, func() bool { return len(recorder.get()) == 40 }, 5*time.Second, 10*time.Millisecond)
+	for _, line := range recorder.get() {
+		// Every record is written whole, with a single call.
+		assert.True(t, strings.HasPrefix(line, "slow "), line)
+		assert.Equal(t, 1, strings.Count(line, "\n"), line)
+		assert.True(t, strings.HasSuffix(line, "\n"), line)
+	}
+}
+
 func TestShouldSampleQuery(t *testing.T) {
 	qlConfig := QueryLogConfig{sampleRate: -1}
 	assert.False(t, qlConfig.shouldSampleQuery())
@@ -372,6 +488,24 @@ func TestShouldEmitLog(t *testing.T) {
 	}
 }
 
+func TestShouldRedactBindVar(t *testing.T) {
+	qlConfig := QueryLogConfig{}
+	assert.False(t, qlConfig.ShouldRedactBindVar("password"))
+
+	patterns := &bindVarPatterns{re: &qlConfig.RedactBindVars}
+	require.NoError(t, patterns.Set("password|token"))
+	require.NoError(t, patterns.Set("^ssn$"))
+	require.Error(t, patterns.Set("("))
+	assert.Equal(t, "password|token,^ssn$", patterns.String())
+
+	assert.True(t, qlConfig.ShouldRedactBindVar("password"))
+	assert.True(t, qlConfig.ShouldRedactBindVar("user_password"))
+	assert.True(t, qlConfig.ShouldRedactBindVar("api_token"))
+	assert.True(t, qlConfig.ShouldRedactBindVar("ssn"))
+	assert.False(t, qlConfig.ShouldRedactBindVar("ssn_hash"))
+	assert.False(t, qlConfig.ShouldRedactBindVar("id"))
+}
+
 func TestGetFormatter(t *testing.T) {
 	tests := []struct {
 		name           string
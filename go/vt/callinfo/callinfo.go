@@ -32,6 +32,19 @@ type CallInfo interface {
 	// Username is associated with this rpc call, if any.
 	Username() string
 
+	// Protocol is the protocol the client connected with, such as
+	// ProtocolMySQL or ProtocolGRPC.
+	Protocol() string
+
+	// ApplicationName is the name the client application gave itself,
+	// if any, such as the program_name connection attribute of MySQL
+	// clients.
+	ApplicationName() string
+
+	// Compression is the name of the compressor the results are sent to
+	// the client with, or "" if they are sent uncompressed.
+	Compression() string
+
 	// Text is a text version of this connection, as specifically as possible.
 	Text() string
 
@@ -39,6 +52,13 @@ type CallInfo interface {
 	HTML() safehtml.HTML
 }
 
+const (
+	// ProtocolMySQL is the Protocol of calls made over the MySQL protocol.
+	ProtocolMySQL = "mysql"
+	// ProtocolGRPC is the Protocol of gRPC calls.
+	ProtocolGRPC = "grpc"
+)
+
 // internal type and value
 type key int
 
@@ -27,6 +27,9 @@ type FakeCallInfo struct {
 	Remote string
 	Method string
 	User   string
+	Proto  string
+	App    string
+	Comp   string
 	Html   safehtml.HTML
 }
 
@@ -40,6 +43,21 @@ func (fci *FakeCallInfo) Username() string {
 	return fci.User
 }
 
+// Protocol returns the protocol.
+func (fci *FakeCallInfo) Protocol() string {
+	return fci.Proto
+}
+
+// ApplicationName returns the application name.
+func (fci *FakeCallInfo) ApplicationName() string {
+	return fci.App
+}
+
+// Compression returns the compression.
+func (fci *FakeCallInfo) Compression() string {
+	return fci.Comp
+}
+
 // Text returns the text.
 func (fci *FakeCallInfo) Text() string {
 	return fmt.Sprintf("%s:%s(fakeRPC)", fci.Remote, fci.Method)
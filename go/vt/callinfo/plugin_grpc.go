@@ -43,13 +43,20 @@ func GRPCCallInfo(ctx context.Context) context.Context {
 	if ok {
 		callinfo.remoteAddr = peer.Addr.String()
 	}
+	// The server stream is internal to gRPC, but exposes the compressor
+	// the responses are sent with, which by default is the one the client
+	// compressed its request with.
+	if stream, ok := grpc.ServerTransportStreamFromContext(ctx).(interface{ SendCompress() string }); ok {
+		callinfo.compression = stream.SendCompress()
+	}
 
 	return NewContext(ctx, callinfo)
 }
 
 type gRPCCallInfoImpl struct {
-	method     string
-	remoteAddr string
+	method      string
+	remoteAddr  string
+	compression string
 }
 
 func (gci *gRPCCallInfoImpl) RemoteAddr() string {
@@ -60,6 +67,18 @@ func (gci *gRPCCallInfoImpl) Username() string {
 	return "gRPC"
 }
 
+func (gci *gRPCCallInfoImpl) Protocol() string {
+	return ProtocolGRPC
+}
+
+func (gci *gRPCCallInfoImpl) ApplicationName() string {
+	return ""
+}
+
+func (gci *gRPCCallInfoImpl) Compression() string {
+	return gci.compression
+}
+
 func (gci *gRPCCallInfoImpl) Text() string {
 	return fmt.Sprintf("%s:%s(gRPC)", gci.remoteAddr, gci.method)
 }
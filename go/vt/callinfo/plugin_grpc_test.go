@@ -21,17 +21,47 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 )
 
 func TestGRPCCallInfo(t *testing.T) {
 	grpcCi := gRPCCallInfoImpl{
-		method:     "tcp",
-		remoteAddr: "localhost",
+		method:      "tcp",
+		remoteAddr:  "localhost",
+		compression: "snappy",
 	}
 
 	require.Equal(t, context.Background(), GRPCCallInfo(context.Background()))
 	require.Equal(t, grpcCi.remoteAddr, grpcCi.RemoteAddr())
 	require.Equal(t, "gRPC", grpcCi.Username())
+	require.Equal(t, ProtocolGRPC, grpcCi.Protocol())
+	require.Equal(t, "snappy", grpcCi.Compression())
 	require.Equal(t, "localhost:tcp(gRPC)", grpcCi.Text())
 	require.Equal(t, "<b>Method:</b> tcp <b>Remote Addr:</b> localhost", grpcCi.HTML().String())
 }
+
+// fakeServerStream is a grpc.ServerTransportStream that, like the streams of
+// the gRPC server, reports the compressor of the responses.
+type fakeServerStream struct {
+	method       string
+	sendCompress string
+}
+
+func (s *fakeServerStream) Method() string                  { return s.method }
+func (s *fakeServerStream) SetHeader(md metadata.MD) error  { return nil }
+func (s *fakeServerStream) SendHeader(md metadata.MD) error { return nil }
+func (s *fakeServerStream) SetTrailer(md metadata.MD) error { return nil }
+func (s *fakeServerStream) SendCompress() string            { return s.sendCompress }
+
+func TestGRPCCallInfoCompression(t *testing.T) {
+	ctx := grpc.NewContextWithServerTransportStream(context.Background(), &fakeServerStream{method: "/vtgateservice.Vitess/Execute", sendCompress: "snappy"})
+	ci, ok := FromContext(GRPCCallInfo(ctx))
+	require.True(t, ok)
+	require.Equal(t, "snappy", ci.Compression())
+
+	ctx = grpc.NewContextWithServerTransportStream(context.Background(), &fakeServerStream{method: "/vtgateservice.Vitess/Execute"})
+	ci, ok = FromContext(GRPCCallInfo(ctx))
+	require.True(t, ok)
+	require.Empty(t, ci.Compression())
+}
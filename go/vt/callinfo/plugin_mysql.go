@@ -34,12 +34,18 @@ func MysqlCallInfo(ctx context.Context, c *mysql.Conn) context.Context {
 	return NewContext(ctx, &mysqlCallInfoImpl{
 		remoteAddr: c.RemoteAddr().String(),
 		user:       c.User,
+		appName:    c.Attributes[mysqlProgramNameAttribute],
 	})
 }
 
+// mysqlProgramNameAttribute is the connection attribute MySQL clients
+// send their application name in.
+const mysqlProgramNameAttribute = "program_name"
+
 type mysqlCallInfoImpl struct {
 	remoteAddr string
 	user       string
+	appName    string
 }
 
 func (mci *mysqlCallInfoImpl) RemoteAddr() string {
@@ -50,6 +56,20 @@ func (mci *mysqlCallInfoImpl) Username() string {
 	return mci.user
 }
 
+func (mci *mysqlCallInfoImpl) Protocol() string {
+	return ProtocolMySQL
+}
+
+func (mci *mysqlCallInfoImpl) ApplicationName() string {
+	return mci.appName
+}
+
+// Compression is always empty, as the MySQL server doesn't support the
+// compressed protocol.
+func (mci *mysqlCallInfoImpl) Compression() string {
+	return ""
+}
+
 func (mci *mysqlCallInfoImpl) Text() string {
 	return fmt.Sprintf("%s@%s(Mysql)", mci.user, mci.remoteAddr)
 }
@@ -30,6 +30,7 @@ func TestMysqlCallInfo(t *testing.T) {
 
 	require.Equal(t, mysqlCi.remoteAddr, mysqlCi.RemoteAddr())
 	require.Equal(t, mysqlCi.user, mysqlCi.Username())
+	require.Equal(t, ProtocolMySQL, mysqlCi.Protocol())
 	require.Equal(t, "test@localhost(Mysql)", mysqlCi.Text())
 	require.Equal(t, "<b>MySQL User:</b> test <b>Remote Addr:</b> localhost", mysqlCi.HTML().String())
 }
@@ -144,11 +144,13 @@ func (thc *tabletHealthCheck) Connection(ctx context.Context) queryservice.Query
 
 func (thc *tabletHealthCheck) connectionLocked(ctx context.Context) queryservice.QueryService {
 	if thc.Conn == nil {
+		dialStart := time.Now()
 		conn, err := tabletconn.GetDialer()(ctx, thc.Tablet, grpcclient.FailFast(true))
 		if err != nil {
 			thc.LastError = err
 			return nil
 		}
+		tabletconn.ObserveDial(ctx, time.Since(dialStart))
 		thc.Conn = conn
 		thc.LastError = nil
 	}
@@ -0,0 +1,85 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package faketopo
+
+import (
+	"slices"
+	"time"
+
+	"vitess.io/vitess/go/vt/topo"
+)
+
+// RecordedCall is an operation of the call log of a FakeConn, see
+// EnableCallLog.
+type RecordedCall struct {
+	Op   CallOp
+	Path string
+	// Version is the version given to Update and Delete, if any.
+	Version topo.Version
+	// Time is when the call started, on the connection's clock.
+	Time time.Time
+}
+
+// loggedCall is a RecordedCall with the contents written by Create and
+// Update, for ReplayLog.
+type loggedCall struct {
+	RecordedCall
+	contents []byte
+}
+
+// EnableCallLog makes the connection record its Create, Update, Delete,
+// Get, List, ListDir, Watch, WatchRecursive and lock calls, with their
+// arguments, for CallLog. Calls are recorded as they start, whether or not
+// they succeed. It lets tests check, for example, that a cache hit saved a
+// Get. ReplayLog returns the same calls for ReplayCalls, to run them against
+// a reference topo.
+func (f *FakeConn) EnableCallLog() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.recordCalls = true
+}
+
+// CallLog returns the calls recorded since EnableCallLog, in the order they
+// were made. The returned slice is a copy.
+func (f *FakeConn) CallLog() []RecordedCall {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	calls := make([]RecordedCall, 0, len(f.calls))
+	for _, call := range f.calls {
+		calls = append(calls, call.RecordedCall)
+	}
+	return calls
+}
+
+// ReplayLog returns the calls recorded since EnableCallLog, in the order
+// they were made, as Calls that ReplayCalls can run. An Update or a Delete
+// that was given a version is replayed with the version the previous call
+// returned. The returned calls are copies.
+func (f *FakeConn) ReplayLog() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	calls := make([]Call, 0, len(f.calls))
+	for _, call := range f.calls {
+		calls = append(calls, Call{
+			Op:        call.Op,
+			Path:      call.Path,
+			Contents:  slices.Clone(call.contents),
+			Versioned: call.Version != nil,
+		})
+	}
+	return calls
+}
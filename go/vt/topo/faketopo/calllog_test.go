@@ -0,0 +1,109 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package faketopo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"vitess.io/vitess/go/vt/topo/memorytopo"
+)
+
+func TestCallLog(t *testing.T) {
+	ctx := context.Background()
+	conn := NewFakeConnection()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	conn.SetClock(func() time.Time { return now })
+	const path = "keyspaces/ks1/Keyspace"
+
+	// Nothing is recorded until the call log is enabled.
+	version, err := conn.Create(ctx, path, []byte("v1"))
+	require.NoError(t, err)
+	require.Empty(t, conn.CallLog())
+
+	conn.EnableCallLog()
+	_, _, err = conn.Get(ctx, path)
+	require.NoError(t, err)
+	now = now.Add(time.Second)
+	version, err = conn.Update(ctx, path, []byte("v2"), version)
+	require.NoError(t, err)
+	// Failed calls are recorded too.
+	_, err = conn.List(ctx, "keyspaces")
+	require.Error(t, err)
+	require.NoError(t, conn.Delete(ctx, path, version))
+
+	calls := conn.CallLog()
+	require.Equal(t, []RecordedCall{
+		{Op: CallGet, Path: path, Time: now.Add(-time.Second)},
+		{Op: CallUpdate, Path: path, Version: memorytopo.NodeVersion(1), Time: now},
+		{Op: CallList, Path: "keyspaces", Time: now},
+		{Op: CallDelete, Path: path, Version: memorytopo.NodeVersion(2), Time: now},
+	}, calls)
+
+	// The log can't be changed through the returned slice.
+	calls[0].Path = "changed"
+	require.Equal(t, path, conn.CallLog()[0].Path)
+}
+
+func TestCallLogReplay(t *testing.T) {
+	ctx := context.Background()
+	newConn := func() *FakeConn {
+		conn := NewFakeConnection()
+		conn.SetListFromStore(true)
+		return conn
+	}
+	conn := newConn()
+	conn.EnableCallLog()
+	const path = "keyspaces/ks1/Keyspace"
+
+	// The recorded calls can be replayed as they are.
+	version, err := conn.Create(ctx, path, []byte("v1"))
+	require.NoError(t, err)
+	contents := []byte("v2")
+	_, err = conn.Update(ctx, path, contents, version)
+	require.NoError(t, err)
+	_, err = conn.List(ctx, "keyspaces/")
+	require.NoError(t, err)
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	current, _, err := conn.Watch(watchCtx, path)
+	require.NoError(t, err)
+	require.Equal(t, []byte("v2"), current.Contents)
+	require.NoError(t, conn.Delete(ctx, path, nil))
+	_, _, err = conn.Get(ctx, path)
+	require.Error(t, err)
+
+	// The log keeps its own copy of the contents.
+	copy(contents, "xx")
+	calls := conn.ReplayLog()
+	require.Equal(t, []Call{
+		{Op: CallCreate, Path: path, Contents: []byte("v1")},
+		{Op: CallUpdate, Path: path, Contents: []byte("v2"), Versioned: true},
+		{Op: CallList, Path: "keyspaces/"},
+		{Op: CallWatch, Path: path},
+		{Op: CallDelete, Path: path},
+		{Op: CallGet, Path: path},
+	}, calls)
+	require.Empty(t, ReplayCalls(ctx, calls, newReplayReference(t, ctx), newConn()))
+
+	// Nor can it be changed through the returned contents.
+	calls[0].Contents[0] = 'x'
+	require.Equal(t, []byte("v1"), conn.ReplayLog()[0].Contents)
+}
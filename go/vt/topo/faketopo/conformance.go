@@ -0,0 +1,273 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package faketopo
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"vitess.io/vitess/go/vt/topo"
+)
+
+// conformanceTimeout bounds each conformance test, and the wait for a
+// watch event.
+const conformanceTimeout = 10 * time.Second
+
+// RunConnConformance runs the conformance tests of the file operations of
+// topo.Conn against the connections returned by newConn, one fresh and
+// empty connection per test. It checks the documented semantics of Create,
+// Get, Update, List, ListDir, Delete and Watch, including how versions
+// guard conditional writes and how watches are notified, so that a fake or
+// a new topo backend can be checked against them.
+func RunConnConformance(t *testing.T, newConn func() topo.Conn) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, ctx context.Context, conn topo.Conn)
+	}{
+		{"CreateGet", testConformanceCreateGet},
+		{"Update", testConformanceUpdate},
+		{"List", testConformanceList},
+		{"ListDir", testConformanceListDir},
+		{"Delete", testConformanceDelete},
+		{"Watch", testConformanceWatch},
+		{"WatchDelete", testConformanceWatchDelete},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), conformanceTimeout)
+			defer cancel()
+			tt.test(t, ctx, newConn())
+		})
+	}
+}
+
+func requireErrType(t *testing.T, err error, code topo.ErrorCode) {
+	t.Helper()
+	require.Error(t, err)
+	require.True(t, topo.IsErrType(err, code), "unexpected error: %v", err)
+}
+
+func testConformanceCreateGet(t *testing.T, ctx context.Context, conn topo.Conn) {
+	_, _, err := conn.Get(ctx, "conformance/file")
+	requireErrType(t, err, topo.NoNode)
+
+	version, err := conn.Create(ctx, "conformance/file", []byte("v1"))
+	require.NoError(t, err)
+	require.NotNil(t, version)
+	contents, got, err := conn.Get(ctx, "conformance/file")
+	require.NoError(t, err)
+	require.Equal(t, []byte("v1"), contents)
+	require.Equal(t, version.String(), got.String())
+
+	// Create never overwrites an existing node.
+	_, err = conn.Create(ctx, "conformance/file", []byte("v2"))
+	requireErrType(t, err, topo.NodeExists)
+	contents, _, err = conn.Get(ctx, "conformance/file")
+	require.NoError(t, err)
+	require.Equal(t, []byte("v1"), contents)
+}
+
+func testConformanceUpdate(t *testing.T, ctx context.Context, conn topo.Conn) {
+	// A conditional update needs the node to exist, an unconditional one
+	// creates it.
+	_, err := conn.Update(ctx, "conformance/file", []byte("v1"), conformanceMissingVersion(t, ctx, conn))
+	requireErrType(t, err, topo.NoNode)
+	v1, err := conn.Update(ctx, "conformance/file", []byte("v1"), nil)
+	require.NoError(t, err)
+
+	v2, err := conn.Update(ctx, "conformance/file", []byte("v2"), v1)
+	require.NoError(t, err)
+	require.NotEqual(t, v1.String(), v2.String())
+	contents, version, err := conn.Get(ctx, "conformance/file")
+	require.NoError(t, err)
+	require.Equal(t, []byte("v2"), contents)
+	require.Equal(t, v2.String(), version.String())
+
+	// An update conditional on a version that was replaced fails, and
+	// leaves the node untouched.
+	_, err = conn.Update(ctx, "conformance/file", []byte("v3"), v1)
+	requireErrType(t, err, topo.BadVersion)
+	contents, _, err = conn.Get(ctx, "conformance/file")
+	require.NoError(t, err)
+	require.Equal(t, []byte("v2"), contents)
+
+	// An unconditional update replaces the node and its version.
+	v3, err := conn.Update(ctx, "conformance/file", []byte("v3"), nil)
+	require.NoError(t, err)
+	require.NotEqual(t, v2.String(), v3.String())
+	_, err = conn.Update(ctx, "conformance/file", []byte("v4"), v2)
+	requireErrType(t, err, topo.BadVersion)
+}
+
+// conformanceMissingVersion returns a version of conn that doesn't belong
+// to any node, by creating and deleting a node.
+func conformanceMissingVersion(t *testing.T, ctx context.Context, conn topo.Conn) topo.Version {
+	version, err := conn.Create(ctx, "conformance/deleted", []byte("deleted"))
+	require.NoError(t, err)
+	require.NoError(t, conn.Delete(ctx, "conformance/deleted", version))
+	return version
+}
+
+func testConformanceList(t *testing.T, ctx context.Context, conn topo.Conn) {
+	_, err := conn.List(ctx, "conformance/")
+	requireErrType(t, err, topo.NoNode)
+
+	for _, filePath := range []string{"conformance/a", "conformance/dir/b", "conformance/other"} {
+		_, err := conn.Create(ctx, filePath, []byte(filePath))
+		require.NoError(t, err)
+	}
+	kvs, err := conn.List(ctx, "conformance/")
+	require.NoError(t, err)
+	var keys []string
+	for _, kv := range kvs {
+		require.Equal(t, kv.Key, kv.Value)
+		require.NotNil(t, kv.Version)
+		keys = append(keys, string(kv.Key))
+	}
+	slices.Sort(keys)
+	require.Equal(t, []string{"conformance/a", "conformance/dir/b", "conformance/other"}, keys)
+
+	// The prefix doesn't have to end at a path separator.
+	kvs, err = conn.List(ctx, "conformance/o")
+	require.NoError(t, err)
+	require.Len(t, kvs, 1)
+	require.Equal(t, []byte("conformance/other"), kvs[0].Key)
+}
+
+func testConformanceListDir(t *testing.T, ctx context.Context, conn topo.Conn) {
+	_, err := conn.ListDir(ctx, "conformance", false)
+	requireErrType(t, err, topo.NoNode)
+
+	for _, filePath := range []string{"conformance/b", "conformance/a/file", "conformance/a/other"} {
+		_, err := conn.Create(ctx, filePath, []byte(filePath))
+		require.NoError(t, err)
+	}
+	// Entries are sorted by name. Only the names are filled in, unless
+	// full is set.
+	entries, err := conn.ListDir(ctx, "conformance", false)
+	require.NoError(t, err)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name)
+	}
+	require.Equal(t, []string{"a", "b"}, names)
+	entries, err = conn.ListDir(ctx, "conformance", true)
+	require.NoError(t, err)
+	require.Equal(t, []topo.DirEntry{
+		{Name: "a", Type: topo.TypeDirectory},
+		{Name: "b", Type: topo.TypeFile},
+	}, entries)
+}
+
+func testConformanceDelete(t *testing.T, ctx context.Context, conn topo.Conn) {
+	requireErrType(t, conn.Delete(ctx, "conformance/file", nil), topo.NoNode)
+
+	v1, err := conn.Create(ctx, "conformance/file", []byte("v1"))
+	require.NoError(t, err)
+	v2, err := conn.Update(ctx, "conformance/file", []byte("v2"), v1)
+	require.NoError(t, err)
+
+	// A delete conditional on a version that was replaced fails.
+	requireErrType(t, conn.Delete(ctx, "conformance/file", v1), topo.BadVersion)
+	_, _, err = conn.Get(ctx, "conformance/file")
+	require.NoError(t, err)
+
+	require.NoError(t, conn.Delete(ctx, "conformance/file", v2))
+	_, _, err = conn.Get(ctx, "conformance/file")
+	requireErrType(t, err, topo.NoNode)
+
+	// An unconditional delete removes whatever version is there.
+	_, err = conn.Create(ctx, "conformance/file", []byte("v3"))
+	require.NoError(t, err)
+	require.NoError(t, conn.Delete(ctx, "conformance/file", nil))
+	_, _, err = conn.Get(ctx, "conformance/file")
+	requireErrType(t, err, topo.NoNode)
+}
+
+// nextConformanceEvent returns the next event of changes, or nil if it was
+// closed.
+func nextConformanceEvent(t *testing.T, changes <-chan *topo.WatchData) *topo.WatchData {
+	t.Helper()
+	select {
+	case event, ok := <-changes:
+		if !ok {
+			return nil
+		}
+		return event
+	case <-time.After(conformanceTimeout):
+		t.Fatal("timed out waiting for a watch event")
+		return nil
+	}
+}
+
+func testConformanceWatch(t *testing.T, ctx context.Context, conn topo.Conn) {
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	_, _, err := conn.Watch(watchCtx, "conformance/file")
+	requireErrType(t, err, topo.NoNode)
+
+	v1, err := conn.Create(ctx, "conformance/file", []byte("v1"))
+	require.NoError(t, err)
+	current, changes, err := conn.Watch(watchCtx, "conformance/file")
+	require.NoError(t, err)
+	require.NoError(t, current.Err)
+	require.Equal(t, []byte("v1"), current.Contents)
+	require.Equal(t, v1.String(), current.Version.String())
+
+	// Writes are delivered in order, with the version they created.
+	v2, err := conn.Update(ctx, "conformance/file", []byte("v2"), v1)
+	require.NoError(t, err)
+	v3, err := conn.Update(ctx, "conformance/file", []byte("v3"), v2)
+	require.NoError(t, err)
+	for _, want := range []struct {
+		contents string
+		version  topo.Version
+	}{{"v2", v2}, {"v3", v3}} {
+		event := nextConformanceEvent(t, changes)
+		require.NotNil(t, event, "watch closed before delivering %s", want.contents)
+		require.NoError(t, event.Err)
+		require.Equal(t, []byte(want.contents), event.Contents)
+		require.Equal(t, want.version.String(), event.Version.String())
+	}
+
+	// Cancelling the watch closes its channel, possibly after an
+	// Interrupted error.
+	cancel()
+	for event := nextConformanceEvent(t, changes); event != nil; event = nextConformanceEvent(t, changes) {
+		requireErrType(t, event.Err, topo.Interrupted)
+	}
+}
+
+func testConformanceWatchDelete(t *testing.T, ctx context.Context, conn topo.Conn) {
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	version, err := conn.Create(ctx, "conformance/file", []byte("v1"))
+	require.NoError(t, err)
+	_, changes, err := conn.Watch(watchCtx, "conformance/file")
+	require.NoError(t, err)
+
+	// Deleting the node ends the watch with a NoNode error.
+	require.NoError(t, conn.Delete(ctx, "conformance/file", version))
+	event := nextConformanceEvent(t, changes)
+	require.NotNil(t, event, "watch closed without reporting the deletion")
+	requireErrType(t, event.Err, topo.NoNode)
+	require.Nil(t, nextConformanceEvent(t, changes))
+}
@@ -0,0 +1,50 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package faketopo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"vitess.io/vitess/go/vt/topo"
+	"vitess.io/vitess/go/vt/topo/memorytopo"
+)
+
+// TestConnConformanceMemoryTopo checks the conformance tests against
+// memorytopo, the reference implementation of topo.Conn in tests.
+func TestConnConformanceMemoryTopo(t *testing.T) {
+	RunConnConformance(t, func() topo.Conn {
+		ts, factory := memorytopo.NewServerAndFactory(context.Background(), "cell")
+		t.Cleanup(ts.Close)
+		conn, err := factory.Create(topo.GlobalCell, "", "")
+		require.NoError(t, err)
+		return conn
+	})
+}
+
+// TestConnConformanceFakeConn checks that FakeConn, with the options that
+// make it behave like a real topo server, behaves like memorytopo.
+func TestConnConformanceFakeConn(t *testing.T) {
+	RunConnConformance(t, func() topo.Conn {
+		conn := NewFakeConnection()
+		conn.SetExclusiveCreate(true)
+		conn.SetListFromStore(true)
+		return conn
+	})
+}
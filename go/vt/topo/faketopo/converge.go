@@ -0,0 +1,83 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package faketopo
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Default values of ConvergenceOptions.
+const (
+	DefaultConvergenceTimeout      = 10 * time.Second
+	DefaultConvergencePollInterval = 10 * time.Millisecond
+)
+
+// ConvergenceOptions configures UpdateAndWaitForConvergence. Zero values
+// are replaced by DefaultConvergenceTimeout and DefaultConvergencePollInterval.
+type ConvergenceOptions struct {
+	// Timeout is how long to wait for the cached value to match.
+	Timeout time.Duration
+	// PollInterval is the time between two reads of the cached value.
+	PollInterval time.Duration
+}
+
+// UpdateAndWaitForConvergence writes contents to the existing node at
+// filePath, then polls cached until it returns want, failing the test if it
+// doesn't within the timeout. It is meant for consumers that cache a value
+// derived from a topo watch: cached reads the consumer's current value, and
+// want is the value the consumer is expected to derive from contents. Values
+// are compared with reflect.DeepEqual.
+func UpdateAndWaitForConvergence[T any](t testing.TB, conn *FakeConn, filePath string, contents []byte, want T, cached func() T, opts ConvergenceOptions) {
+	t.Helper()
+	if opts.Timeout == 0 {
+		opts.Timeout = DefaultConvergenceTimeout
+	}
+	if opts.PollInterval == 0 {
+		opts.PollInterval = DefaultConvergencePollInterval
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+	defer cancel()
+	// Update the current version, so that the write is reported to watches.
+	_, version, err := conn.Get(ctx, filePath)
+	if err != nil {
+		t.Fatalf("Get(%v) failed: %v", filePath, err)
+		return
+	}
+	if _, err := conn.Update(ctx, filePath, contents, version); err != nil {
+		t.Fatalf("Update(%v) failed: %v", filePath, err)
+		return
+	}
+
+	ticker := time.NewTicker(opts.PollInterval)
+	defer ticker.Stop()
+	for {
+		got := cached()
+		if reflect.DeepEqual(got, want) {
+			return
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			t.Fatalf("cached value did not converge after updating %v within %v: got %v, want %v", filePath, opts.Timeout, got, want)
+			return
+		}
+	}
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package faketopo
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fatalRecorder records calls to Fatalf instead of stopping the test.
+type fatalRecorder struct {
+	testing.TB
+	failure string
+}
+
+func (r *fatalRecorder) Helper() {}
+
+func (r *fatalRecorder) Fatalf(format string, args ...any) {
+	r.failure = fmt.Sprintf(format, args...)
+}
+
+func TestUpdateAndWaitForConvergence(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	conn := NewFakeConnection()
+	_, err := conn.Create(ctx, "keyspaces/ks/Keyspace", []byte("v1"))
+	require.NoError(t, err)
+
+	// A consumer caching the contents of the node from its watch.
+	var mu sync.Mutex
+	current, changes, err := conn.Watch(ctx, "keyspaces/ks/Keyspace")
+	require.NoError(t, err)
+	value := string(current.Contents)
+	go func() {
+		for change := range changes {
+			mu.Lock()
+			value = string(change.Contents)
+			mu.Unlock()
+		}
+	}()
+	cached := func() string {
+		mu.Lock()
+		defer mu.Unlock()
+		return value
+	}
+
+	UpdateAndWaitForConvergence(t, conn, "keyspaces/ks/Keyspace", []byte("v2"), "v2", cached, ConvergenceOptions{})
+
+	// A consumer that never derives the expected value fails the test.
+	recorder := &fatalRecorder{TB: t}
+	opts := ConvergenceOptions{Timeout: 50 * time.Millisecond, PollInterval: time.Millisecond}
+	UpdateAndWaitForConvergence(recorder, conn, "keyspaces/ks/Keyspace", []byte("v3"), "v4", cached, opts)
+	require.Contains(t, recorder.failure, "did not converge")
+	require.Contains(t, recorder.failure, "got v3, want v4")
+}
+
+func TestSetWatchJitter(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	conn := NewFakeConnection()
+	_, err := conn.Create(ctx, "keyspaces/ks/Keyspace", []byte("v0"))
+	require.NoError(t, err)
+	conn.SetWatchJitter("keyspaces/ks/Keyspace", time.Millisecond, 20*time.Millisecond, 42)
+
+	// A consumer caching the contents of the node from its watch.
+	var mu sync.Mutex
+	var seen []string
+	current, changes, err := conn.Watch(ctx, "keyspaces/ks/Keyspace")
+	require.NoError(t, err)
+	value := string(current.Contents)
+	go func() {
+		for change := range changes {
+			mu.Lock()
+			value = string(change.Contents)
+			seen = append(seen, value)
+			mu.Unlock()
+		}
+	}()
+	cached := func() string {
+		mu.Lock()
+		defer mu.Unlock()
+		return value
+	}
+
+	// A burst of writes is delivered late and unevenly, but in order, and
+	// the consumer ends up with the last value.
+	for i := 1; i < 10; i++ {
+		_, version, err := conn.Get(ctx, "keyspaces/ks/Keyspace")
+		require.NoError(t, err)
+		_, err = conn.Update(ctx, "keyspaces/ks/Keyspace", []byte(fmt.Sprintf("v%d", i)), version)
+		require.NoError(t, err)
+	}
+	UpdateAndWaitForConvergence(t, conn, "keyspaces/ks/Keyspace", []byte("v10"), "v10", cached, ConvergenceOptions{})
+	mu.Lock()
+	require.Equal(t, []string{"v1", "v2", "v3", "v4", "v5", "v6", "v7", "v8", "v9", "v10"}, seen)
+	mu.Unlock()
+
+	// The delays only depend on the seed.
+	delays := func() []time.Duration {
+		conn.SetWatchJitter("keyspaces/ks/Keyspace", time.Millisecond, 20*time.Millisecond, 42)
+		jitter := conn.watchJitters["keyspaces/ks/Keyspace"]
+		var delays []time.Duration
+		for range 5 {
+			delay := jitter.next()
+			require.GreaterOrEqual(t, delay, time.Millisecond)
+			require.LessOrEqual(t, delay, 20*time.Millisecond)
+			delays = append(delays, delay)
+		}
+		return delays
+	}
+	require.Equal(t, delays(), delays())
+}
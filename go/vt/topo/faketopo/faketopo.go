@@ -17,7 +17,14 @@ limitations under the License.
 package faketopo
 
 import (
+	"bytes"
 	"context"
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -31,10 +38,20 @@ import (
 
 // FakeFactory implements the Factory interface. This is supposed to be used only for testing
 type FakeFactory struct {
-	// mu protects the following field.
+	// mu protects the following fields.
 	mu sync.Mutex
 	// cells is the toplevel map that has one entry per cell. It has a list of connections that this fake server will return
 	cells map[string][]*FakeConn
+	// cellLatency is the base latency of the connections of each cell.
+	cellLatency map[string]time.Duration
+	// handedOut holds the connections of each cell returned by Create.
+	handedOut map[string][]*FakeConn
+	// cellFailures holds the operations failing on the connections of each
+	// cell, see FailCell.
+	cellFailures map[string][]CallOp
+	// reusable holds the cells whose connection is returned by every Create,
+	// see SetReusable.
+	reusable map[string]bool
 }
 
 var _ topo.Factory = (*FakeFactory)(nil)
@@ -42,8 +59,9 @@ var _ topo.Factory = (*FakeFactory)(nil)
 // NewFakeTopoFactory creates a new fake topo factory
 func NewFakeTopoFactory() *FakeFactory {
 	factory := &FakeFactory{
-		mu:    sync.Mutex{},
-		cells: map[string][]*FakeConn{},
+		mu:          sync.Mutex{},
+		cells:       map[string][]*FakeConn{},
+		cellLatency: map[string]time.Duration{},
 	}
 	factory.cells[topo.GlobalCell] = []*FakeConn{NewFakeConnection()}
 	return factory
@@ -54,6 +72,7 @@ func (f *FakeFactory) AddCell(cell string) *FakeConn {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 	conn := NewFakeConnection()
+	conn.SetBaseLatency(f.cellLatency[cell])
 	f.cells[cell] = []*FakeConn{conn}
 	return conn
 }
@@ -65,6 +84,18 @@ func (f *FakeFactory) SetCell(cell string, fakeConn *FakeConn) {
 	f.cells[cell] = []*FakeConn{fakeConn}
 }
 
+// SetCellLatency sets the base latency of every operation on the connections
+// of the given cell, including the ones already handed out for it. This lets
+// tests make a remote cell slower than the local one.
+func (f *FakeFactory) SetCellLatency(cell string, latency time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.cellLatency[cell] = latency
+	for _, conn := range f.cells[cell] {
+		conn.SetBaseLatency(latency)
+	}
+}
+
 // HasGlobalReadOnlyCell implements the Factory interface
 func (f *FakeFactory) HasGlobalReadOnlyCell(serverAddr, root string) bool {
 	return false
@@ -79,15 +110,83 @@ func (f *FakeFactory) Create(cell, serverAddr, root string) (topo.Conn, error) {
 	if !ok || len(connections) == 0 {
 		return nil, topo.NewError(topo.NoNode, cell)
 	}
-	// pick the first connection and remove it from the list.
 	conn := connections[0]
-	f.cells[cell] = connections[1:]
+	if f.reusable[cell] {
+		// keep the connection for the next Create, and reopen it in case
+		// it was closed.
+		conn.reopen()
+	} else {
+		// pick the first connection and remove it from the list.
+		f.cells[cell] = connections[1:]
+	}
 
 	conn.serverAddr = serverAddr
 	conn.cell = cell
+	if latency, ok := f.cellLatency[cell]; ok {
+		conn.SetBaseLatency(latency)
+	}
+	for _, op := range f.cellFailures[cell] {
+		conn.FailOp(op, nil)
+	}
+	if f.reusable[cell] {
+		return conn, nil
+	}
+	if f.handedOut == nil {
+		f.handedOut = map[string][]*FakeConn{}
+	}
+	f.handedOut[cell] = append(f.handedOut[cell], conn)
 	return conn, nil
 }
 
+// SetReusable makes Create return the first connection of cell on every
+// call, reopening it if it was closed, instead of handing out each
+// connection of the cell once. This lets tests exercise consumers that close
+// their connection and reconnect to the same cell.
+func (f *FakeFactory) SetReusable(cell string, reusable bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.reusable == nil {
+		f.reusable = map[string]bool{}
+	}
+	f.reusable[cell] = reusable
+}
+
+// FailCell makes every call of op, such as CallGet, on the connections of
+// cell fail with a Timeout topo error, as if the topo server of that cell
+// were unreachable, while the other cells keep working. It applies to the
+// connections already handed out for the cell as well as to the ones still
+// to be. This lets tests check that consumers fall back to a healthy cell.
+func (f *FakeFactory) FailCell(cell string, op CallOp) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.cellFailures == nil {
+		f.cellFailures = map[string][]CallOp{}
+	}
+	f.cellFailures[cell] = append(f.cellFailures[cell], op)
+	for _, conn := range f.connsLocked(cell) {
+		conn.FailOp(op, nil)
+	}
+}
+
+// RestoreCell undoes FailCell, letting every operation on the connections
+// of cell succeed again.
+func (f *FakeFactory) RestoreCell(cell string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, op := range f.cellFailures[cell] {
+		for _, conn := range f.connsLocked(cell) {
+			conn.RestoreOp(op)
+		}
+	}
+	delete(f.cellFailures, cell)
+}
+
+// connsLocked returns the connections of cell, handed out or not. The
+// caller must hold the mutex.
+func (f *FakeFactory) connsLocked(cell string) []*FakeConn {
+	return append(slices.Clone(f.cells[cell]), f.handedOut[cell]...)
+}
+
 // FakeConn implements the Conn interface. It is used only for testing
 type FakeConn struct {
 	cell       string
@@ -100,15 +199,198 @@ type FakeConn struct {
 	getResultMap map[string]result
 	// listResultMap is a map storing the resuls for each filepath prefix.
 	listResultMap map[string][]topo.KVInfo
+	// phantomListEntries holds the extra entries List returns for each
+	// filepath prefix, see AddPhantomListEntries.
+	phantomListEntries map[string][]topo.KVInfo
+	// inconsistentListVersions holds the filepath prefixes whose List
+	// results have versions ahead of Get, see SetInconsistentListVersions.
+	inconsistentListVersions map[string]bool
 	// updateErrors stores whether update function call should error or not.
 	updateErrors []updateError
 	// getErrors stores whether the get function call should error or not.
 	getErrors []bool
+	// getErrorsByPath, listErrorsByPrefix and updateErrorsByPath hold the
+	// errors of the calls on each path, consumed before the global ones,
+	// see AddGetErrorForPath, AddListErrorForPath and AddUpdateErrorForPath.
+	getErrorsByPath    map[string][]bool
+	listErrorsByPrefix map[string][]bool
+	updateErrorsByPath map[string][]updateError
+	// deleteErrors stores whether the delete function call should error or not.
+	deleteErrors []bool
+	// flakyReadsEvery makes every Nth Get call fail, see SetFlakyReads,
+	// and flakyReads counts the Get calls since it was set.
+	flakyReadsEvery int
+	flakyReads      int
 	// listErrors stores whether the list function call should error or not.
 	listErrors []bool
+	// listPageSize is the maximum number of results returned by ListPage.
+	listPageSize int
+	// listTruncateAt is the maximum number of results returned by List, and
+	// truncatedLists the number of List calls it cut short, see
+	// SetListTruncateAt.
+	listTruncateAt int
+	truncatedLists int
+	// listPageFailures holds, for each prefix, when ListPage should fail,
+	// see FailListPageAfter.
+	listPageFailures map[string]*listPageFailure
+	// pauses are set by PauseNextUpdate and PauseNextCreate, and consumed by
+	// the next call of their operation.
+	pauses map[CallOp]*opPause
+	// exclusiveCreate makes Create fail with NodeExists when the node
+	// exists, see SetExclusiveCreate.
+	exclusiveCreate bool
+	// listFromStore makes List fall back to the stored nodes, see
+	// SetListFromStore.
+	listFromStore bool
+	// baseLatency is added to every operation before it is served.
+	baseLatency time.Duration
+	// opLatency is added to the base latency of each operation, see
+	// SetLatency.
+	opLatency map[CallOp]time.Duration
+	// readOnly makes every write fail with a ReadOnlyError wrapping readOnlyErr.
+	readOnly    bool
+	readOnlyErr error
+	// validatePaths makes Create, Update and Get reject malformed paths.
+	validatePaths bool
+	// caseInsensitivePaths lower cases the paths of all operations.
+	caseInsensitivePaths bool
+	// opFailures holds the errors every call of each operation fails with,
+	// see FailOp.
+	opFailures map[CallOp]error
+	// closed makes every operation fail with Interrupted, see Close.
+	closed bool
+	// unavailable makes every operation fail, see SetUnavailable, and
+	// recoveryFailures is the number of operations still to fail while
+	// the connection recovers, see SetRecovering.
+	unavailable      bool
+	recoveryFailures int
+	// maxValueSize is the size above which writes are rejected, see
+	// SetMaxValueSize. Zero means unlimited.
+	maxValueSize int
+	// requireParent makes Create fail when the parent of the path has no
+	// node, see SetRequireParent.
+	requireParent bool
+	// watchScripts holds the events replayed by watches, keyed by the filepath.
+	watchScripts map[string]watchScript
+	// watchJitters holds the random delays of the events of watches, keyed
+	// by the filepath, see SetWatchJitter.
+	watchJitters map[string]*watchJitter
+	// clock returns the current time, as seen by lock TTLs and the
+	// visibility delay. It is time.Now unless set by SetClock.
+	clock func() time.Time
+	// clockSkew is added to the time returned by clock, see AdvanceClock.
+	clockSkew time.Duration
+	// ephemerals holds the expiry of the ephemeral nodes, keyed by the
+	// filepath, see CreateEphemeral.
+	ephemerals map[string]time.Time
+	// previous holds, for each filepath, the result its latest write replaced.
+	previous map[string]result
+	// history holds, for each filepath, the results of its writes from the
+	// oldest to the latest, for GetVersion. It retains the last
+	// historyLimit of them if it is positive, see SetHistoryLimit.
+	history      map[string][]result
+	historyLimit int
+	// visibilityDelay is how long a write stays invisible to Get, see
+	// SetVisibilityDelay.
+	visibilityDelay time.Duration
+	// invisibleWrites holds the paths whose latest write is not visible
+	// yet, see SetVisibilityDelay.
+	invisibleWrites map[string]invisibleWrite
+	// staleReads is the number of Get calls of each filepath that are still
+	// to be served from previous, see SetStaleReads.
+	staleReads map[string]int
+	// writeFailures holds the errors the next write of each filepath fails
+	// with, see FailNextWrite.
+	writeFailures map[string]error
+	// checkVersions makes versioned updates fail with BadVersion when the
+	// version doesn't match, and bump it when they succeed. It is on by
+	// default, see SetCheckVersions.
+	checkVersions bool
+	// watchInitialViaChannel makes Watch send the current value as the first
+	// event on its channel, see SetWatchInitialViaChannel.
+	watchInitialViaChannel bool
+	// watchCloseDelay is how long after their context is done the channels
+	// of watches are closed, see SetWatchCloseDelay.
+	watchCloseDelay time.Duration
+	// watchCoalesceLatest makes a value event replace the value events the
+	// watches have not read yet, see SetWatchCoalesceLatest.
+	watchCoalesceLatest bool
+	// watchReorder, when set, buffers the value events of watches to
+	// deliver them shuffled, see SetWatchReorder.
+	watchReorder *watchReorder
+	// locks records every lock acquired on the connection, see Locks.
+	locks []*LockRecord
+	// leaders holds the id of the leader of each election, see SetLeader,
+	// and leaderChanged is closed whenever one of them changes.
+	leaders       map[string]string
+	leaderChanged chan struct{}
+	// leadershipDelay is how long WaitForLeadership waits before it runs
+	// for election, see SetLeadershipDelay.
+	leadershipDelay time.Duration
+	// getSequences holds, for each filepath, the contents the next Get calls
+	// step through, see AddGetResults.
+	getSequences map[string][][]byte
+	// writeCounts holds the number of Create and Update calls of each
+	// filepath, see WriteCount.
+	writeCounts map[string]int
+	// ops holds, when recordOps is set, the operations of the connection in
+	// the order they were called, see SetRecordOperations.
+	recordOps bool
+	ops       []OrderStep
+	// calls holds, when recordCalls is set, the calls of the connection
+	// with their arguments, see EnableCallLog.
+	recordCalls bool
+	calls       []loggedCall
+
+	// watchHistory holds, when recordWatchHistory is set, the events sent
+	// to the watches of each filepath, see SetRecordWatchHistory.
+	recordWatchHistory bool
+	watchHistory       map[string][]*topo.WatchData
+	// readRedirects holds the connections that serve the reads of the
+	// paths under each prefix, see SetReadRedirect.
+	readRedirects map[string]*FakeConn
 
 	// watches is a map of all watches for this connection to the cell keyed by the filepath.
-	watches map[string][]chan *topo.WatchData
+	watches map[string][]*fakeWatch
+	// recursiveWatches holds the watches established by WatchRecursive,
+	// keyed by the path they watch the entries under.
+	recursiveWatches map[string][]*fakeWatch
+	// outbox holds the events and closes of watches queued while the mutex
+	// is held, for unlock to deliver once it is released, and delivering
+	// is set while a caller of unlock delivers them.
+	outbox     []watchSend
+	delivering bool
+}
+
+// fakeWatch is a watch established by Watch, or by WatchRecursive, which
+// sets recursive instead of notifications.
+type fakeWatch struct {
+	notifications chan *topo.WatchData
+	recursive     chan *topo.WatchDataRecursive
+	// done is closed when the context of the watch is done.
+	done <-chan struct{}
+
+	// mu protects backlog and draining.
+	mu sync.Mutex
+	// backlog holds, in order, the sends that found the channel full, for
+	// the goroutine started by send to deliver. draining is set while that
+	// goroutine runs.
+	backlog  []watchSend
+	draining bool
+}
+
+// watchSend is an event queued for a watch, or the closing of the watch
+// when close is set, see unlock.
+type watchSend struct {
+	watch *fakeWatch
+	data  *topo.WatchData
+	// path is the path data is about, sent along with it to recursive
+	// watches.
+	path string
+	// coalesce drops the value events the watch hasn't read yet before
+	// data is sent, see SetWatchCoalesceLatest.
+	coalesce bool
+	close    bool
 }
 
 // updateError contains the information whether a update call should return an error or not
@@ -123,10 +405,12 @@ func NewFakeConnection() *FakeConn {
 	return &FakeConn{
 		getResultMap:  map[string]result{},
 		listResultMap: map[string][]topo.KVInfo{},
-		watches:       map[string][]chan *topo.WatchData{},
+		watches:       map[string][]*fakeWatch{},
 		getErrors:     []bool{},
 		listErrors:    []bool{},
 		updateErrors:  []updateError{},
+		deleteErrors:  []bool{},
+		checkVersions: true,
 	}
 }
 
@@ -137,6 +421,32 @@ func (f *FakeConn) AddGetError(shouldErr bool) {
 	f.getErrors = append(f.getErrors, shouldErr)
 }
 
+// AddGetErrorForPath is like AddGetError, but the error is only consumed by
+// a Get of filePath, so that tests reading several paths can fail the reads
+// of one of them deterministically. The errors of filePath are consumed
+// before the ones added by AddGetError.
+func (f *FakeConn) AddGetErrorForPath(filePath string, shouldErr bool) {
+	filePath = f.normalizePath(filePath)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.getErrorsByPath == nil {
+		f.getErrorsByPath = map[string][]bool{}
+	}
+	f.getErrorsByPath[filePath] = append(f.getErrorsByPath[filePath], shouldErr)
+}
+
+// SetFlakyReads makes every failEvery-th Get call fail with a Timeout
+// error, counting from now and across all paths, as an unreliable topo
+// server would. Unlike AddGetError, the failures keep coming for as long as
+// it is set. Errors queued with AddGetError are returned first. A failEvery
+// of 0 turns it off.
+func (f *FakeConn) SetFlakyReads(failEvery int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.flakyReadsEvery = failEvery
+	f.flakyReads = 0
+}
+
 // AddListError is used to add a list error to the fake connection
 func (f *FakeConn) AddListError(shouldErr bool) {
 	f.mu.Lock()
@@ -144,6 +454,19 @@ func (f *FakeConn) AddListError(shouldErr bool) {
 	f.listErrors = append(f.listErrors, shouldErr)
 }
 
+// AddListErrorForPath is like AddListError, but the error is only consumed
+// by a List or ListPage of filePathPrefix. The errors of filePathPrefix are
+// consumed before the ones added by AddListError.
+func (f *FakeConn) AddListErrorForPath(filePathPrefix string, shouldErr bool) {
+	filePathPrefix = f.normalizePath(filePathPrefix)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.listErrorsByPrefix == nil {
+		f.listErrorsByPrefix = map[string][]bool{}
+	}
+	f.listErrorsByPrefix[filePathPrefix] = append(f.listErrorsByPrefix[filePathPrefix], shouldErr)
+}
+
 // AddListResult is used to add a list result to the fake connection
 func (f *FakeConn) AddListResult(filePathPrefix string, result []topo.KVInfo) {
 	f.mu.Lock()
@@ -151,6 +474,35 @@ func (f *FakeConn) AddListResult(filePathPrefix string, result []topo.KVInfo) {
 	f.listResultMap[filePathPrefix] = result
 }
 
+// AddPhantomListEntries makes List of filePathPrefix also return entries,
+// after the list result, as if stray records such as orphaned tablets or
+// leftover locks were found under the prefix. The entries are never created,
+// so Get of their keys fails with NoNode. It tests that consumers of List
+// skip or report the records they don't expect, rather than misbehave.
+func (f *FakeConn) AddPhantomListEntries(filePathPrefix string, entries ...topo.KVInfo) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.phantomListEntries == nil {
+		f.phantomListEntries = map[string][]topo.KVInfo{}
+	}
+	f.phantomListEntries[filePathPrefix] = append(f.phantomListEntries[filePathPrefix], entries...)
+}
+
+// SetInconsistentListVersions makes List of filePathPrefix return, for the
+// entries that exist, a version one ahead of the one Get returns for the
+// same key, as a topo that doesn't serve List and Get from the same snapshot
+// would. It tests that consumers don't assume the versions of a List are
+// those of a later Get, for example in a versioned Update, which fails with
+// BadVersion unless SetCheckVersions disabled the version checks.
+func (f *FakeConn) SetInconsistentListVersions(filePathPrefix string, inconsistent bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.inconsistentListVersions == nil {
+		f.inconsistentListVersions = map[string]bool{}
+	}
+	f.inconsistentListVersions[filePathPrefix] = inconsistent
+}
+
 // AddUpdateError is used to add an update error to the fake connection
 func (f *FakeConn) AddUpdateError(shouldErr bool, writePersists bool) {
 	f.mu.Lock()
@@ -161,243 +513,2158 @@ func (f *FakeConn) AddUpdateError(shouldErr bool, writePersists bool) {
 	})
 }
 
-// result keeps track of the fields needed to respond to a Get function call
-type result struct {
-	contents []byte
-	version  uint64
+// AddDeleteError is used to add a delete error to the fake connection. A
+// failed delete leaves the node in place.
+func (f *FakeConn) AddDeleteError(shouldErr bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.deleteErrors = append(f.deleteErrors, shouldErr)
 }
 
-var _ topo.Conn = (*FakeConn)(nil)
-
-// ListDir implements the Conn interface
-func (f *FakeConn) ListDir(ctx context.Context, dirPath string, full bool) ([]topo.DirEntry, error) {
+// AddUpdateErrorForPath is like AddUpdateError, but the error is only
+// consumed by an Update of filePath. The errors of filePath are consumed
+// before the ones added by AddUpdateError.
+func (f *FakeConn) AddUpdateErrorForPath(filePath string, shouldErr bool, writePersists bool) {
+	filePath = f.normalizePath(filePath)
 	f.mu.Lock()
 	defer f.mu.Unlock()
-	var res []topo.DirEntry
+	if f.updateErrorsByPath == nil {
+		f.updateErrorsByPath = map[string][]updateError{}
+	}
+	f.updateErrorsByPath[filePath] = append(f.updateErrorsByPath[filePath], updateError{
+		shouldError:   shouldErr,
+		writePersists: writePersists,
+	})
+}
 
-	for filePath := range f.getResultMap {
-		if strings.HasPrefix(filePath, dirPath) {
-			remaining := filePath[len(dirPath)+1:]
-			idx := strings.Index(remaining, "/")
-			if idx == -1 {
-				res = addToListOfDirEntries(res, topo.DirEntry{
-					Name: remaining,
-					Type: topo.TypeFile,
-				})
-			} else {
-				res = addToListOfDirEntries(res, topo.DirEntry{
-					Name: remaining[0:idx],
-					Type: topo.TypeDirectory,
-				})
-			}
+// nextError pops the next injected error of a call on path, from its queue
+// in byPath if it isn't empty, and else from the global queue. ok is false
+// when both are empty. The caller must hold the mutex.
+func nextError[T any](byPath map[string][]T, global *[]T, path string) (next T, ok bool) {
+	if queue := byPath[path]; len(queue) > 0 {
+		if len(queue) == 1 {
+			delete(byPath, path)
+		} else {
+			byPath[path] = queue[1:]
 		}
+		return queue[0], true
+	}
+	if len(*global) > 0 {
+		next = (*global)[0]
+		*global = (*global)[1:]
+		return next, true
 	}
+	return next, false
+}
 
-	if len(res) == 0 {
-		return nil, topo.NewError(topo.NoNode, dirPath)
+// SetBaseLatency makes every operation on the connection wait for the given
+// duration before it is served, or until its context is done.
+func (f *FakeConn) SetBaseLatency(latency time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.baseLatency = latency
+}
+
+// SetLatency makes every call of op wait for the given duration, on top of
+// the base latency, before it is served, or until its context is done. It
+// lets tests check that callers give up on a slow operation once their
+// deadline is exceeded. A zero duration removes the latency of op.
+func (f *FakeConn) SetLatency(op CallOp, latency time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if latency <= 0 {
+		delete(f.opLatency, op)
+		return
 	}
-	return res, nil
+	if f.opLatency == nil {
+		f.opLatency = map[CallOp]time.Duration{}
+	}
+	f.opLatency[op] = latency
 }
 
-func addToListOfDirEntries(list []topo.DirEntry, elem topo.DirEntry) []topo.DirEntry {
-	for _, entry := range list {
-		if entry.Name == elem.Name {
-			return list
-		}
+// delay waits for the configured latency of an op call on filePath. It
+// returns an Interrupted error if ctx is done first. It must be called
+// without holding the mutex.
+func (f *FakeConn) delay(ctx context.Context, op CallOp, filePath string) error {
+	f.mu.Lock()
+	latency := f.baseLatency + f.opLatency[op]
+	f.mu.Unlock()
+	if latency <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(latency)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return topo.NewError(topo.Interrupted, filePath)
 	}
-	list = append(list, elem)
-	return list
 }
 
-// Create implements the Conn interface
-func (f *FakeConn) Create(ctx context.Context, filePath string, contents []byte) (topo.Version, error) {
+// ReadOnlyError is returned by the writes of a FakeConn in read-only mode.
+// It wraps the configured error, so topo.IsErrType still applies to it.
+type ReadOnlyError struct {
+	Path string
+	Err  error
+}
+
+// Error implements the error interface.
+func (e *ReadOnlyError) Error() string {
+	return fmt.Sprintf("topo is read-only, cannot write %s: %v", e.Path, e.Err)
+}
+
+// Unwrap returns the configured error.
+func (e *ReadOnlyError) Unwrap() error {
+	return e.Err
+}
+
+// SetReadOnly makes Create, Update, Delete and CompareAndSwap fail with a
+// ReadOnlyError, as if the topo server were in maintenance. Reads and watches
+// keep working.
+func (f *FakeConn) SetReadOnly(readOnly bool) {
 	f.mu.Lock()
 	defer f.mu.Unlock()
-	f.getResultMap[filePath] = result{
-		contents: contents,
-		version:  1,
-	}
-	return memorytopo.NodeVersion(1), nil
+	f.readOnly = readOnly
 }
 
-// Update implements the Conn interface
-func (f *FakeConn) Update(ctx context.Context, filePath string, contents []byte, version topo.Version) (topo.Version, error) {
+// SetReadOnlyError sets the error wrapped in the ReadOnlyError returned by
+// writes in read-only mode. It defaults to a NoImplementation topo error.
+func (f *FakeConn) SetReadOnlyError(err error) {
 	f.mu.Lock()
 	defer f.mu.Unlock()
-	shouldErr := false
-	writeSucceeds := true
-	if len(f.updateErrors) > 0 {
-		shouldErr = f.updateErrors[0].shouldError
-		writeSucceeds = f.updateErrors[0].writePersists
-		f.updateErrors = f.updateErrors[1:]
-	}
-	if version == nil {
-		f.getResultMap[filePath] = result{
-			contents: contents,
-			version:  1,
-		}
-		return memorytopo.NodeVersion(1), nil
+	f.readOnlyErr = err
+}
+
+// checkWritableLocked returns a ReadOnlyError if the connection is read-only.
+// The caller must hold the mutex.
+func (f *FakeConn) checkWritableLocked(filePath string) error {
+	if !f.readOnly {
+		return nil
 	}
-	res, isPresent := f.getResultMap[filePath]
-	if !isPresent {
-		return nil, topo.NewError(topo.NoNode, filePath)
+	err := f.readOnlyErr
+	if err == nil {
+		err = topo.NewError(topo.NoImplementation, filePath)
 	}
-	if writeSucceeds {
-		res.contents = contents
-		f.getResultMap[filePath] = res
+	return &ReadOnlyError{Path: filePath, Err: err}
+}
+
+// FailNextWrite makes the next Create, Update or CompareAndSwap of filePath
+// fail with err without persisting anything, while writes to other paths
+// keep succeeding. A nil err fails the write with a Timeout topo error.
+//
+// It reproduces the partial failure of a logical operation that writes
+// several paths: the writes that came before filePath persist, the ones
+// from filePath on don't. Consumers can't tell such a failure from a
+// failure of the whole operation by the error alone, so they should either
+// compensate by restoring every path they already wrote to its previous
+// contents, or keep their writes idempotent so the whole operation can
+// safely be retried.
+func (f *FakeConn) FailNextWrite(filePath string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err == nil {
+		err = topo.NewError(topo.Timeout, filePath)
 	}
-	if shouldErr {
-		return nil, topo.NewError(topo.Timeout, filePath)
+	if f.writeFailures == nil {
+		f.writeFailures = map[string]error{}
 	}
+	f.writeFailures[filePath] = err
+}
 
-	// Call the watches
-	for path, watches := range f.watches {
-		if path != filePath {
-			continue
-		}
-		for _, watch := range watches {
-			watch <- &topo.WatchData{
-				Contents: res.contents,
-				Version:  memorytopo.NodeVersion(res.version),
-			}
-		}
+// checkWriteFailureLocked returns, and consumes, the error set by
+// FailNextWrite for filePath. The caller must hold the mutex.
+func (f *FakeConn) checkWriteFailureLocked(filePath string) error {
+	err, ok := f.writeFailures[filePath]
+	if !ok {
+		return nil
 	}
-	return memorytopo.NodeVersion(res.version), nil
+	delete(f.writeFailures, filePath)
+	return err
 }
 
-// Get implements the Conn interface
-func (f *FakeConn) Get(ctx context.Context, filePath string) ([]byte, topo.Version, error) {
+// topLevelEntries are the entries found at the root of a cell, as laid out
+// by topo.Server.
+var topLevelEntries = map[string]bool{
+	topo.CellsPath:             true,
+	topo.CellsAliasesPath:      true,
+	topo.KeyspacesPath:         true,
+	topo.TabletsPath:           true,
+	topo.MetadataPath:          true,
+	topo.RoutingRulesPath:      true,
+	"internal":                 true,
+	topo.ExternalClustersFile:  true,
+	topo.SrvVSchemaFile:        true,
+	topo.RoutingRulesFile:      true,
+	topo.ShardRoutingRulesFile: true,
+	topo.MirrorRulesFile:       true,
+}
+
+// InvalidPathError is returned in path validation mode for a path that
+// does not follow the topo layout.
+type InvalidPathError struct {
+	Path   string
+	Reason string
+}
+
+// Error implements the error interface.
+func (e *InvalidPathError) Error() string {
+	return fmt.Sprintf("invalid topo path %q: %s", e.Path, e.Reason)
+}
+
+// SetValidatePaths makes Create, Update and Get fail with an InvalidPathError
+// for paths that don't follow the topo layout, to catch bugs in how callers
+// build them. It is off by default.
+func (f *FakeConn) SetValidatePaths(validate bool) {
 	f.mu.Lock()
 	defer f.mu.Unlock()
-	if len(f.getErrors) > 0 {
-		shouldErr := f.getErrors[0]
-		f.getErrors = f.getErrors[1:]
-		if shouldErr {
-			return nil, nil, topo.NewError(topo.Timeout, filePath)
-		}
-	}
-	res, isPresent := f.getResultMap[filePath]
-	if !isPresent {
-		return nil, nil, topo.NewError(topo.NoNode, filePath)
-	}
-	return res.contents, memorytopo.NodeVersion(res.version), nil
+	f.validatePaths = validate
 }
 
-// GetVersion is part of topo.Conn interface.
-func (f *FakeConn) GetVersion(ctx context.Context, filePath string, version int64) ([]byte, error) {
-	return nil, topo.NewError(topo.NoImplementation, "GetVersion not supported in fake topo")
+// SetCaseInsensitivePaths makes the topo.Conn operations ignore the case of
+// paths, as some topo backends do, so that tests can catch consumers that
+// assume paths are case sensitive. Paths are lower cased before use, so
+// those given to the test helpers, such as AddListResult or GetRaw, must
+// be lower case. It is off by default.
+func (f *FakeConn) SetCaseInsensitivePaths(caseInsensitive bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.caseInsensitivePaths = caseInsensitive
 }
 
-// List is part of the topo.Conn interface.
-func (f *FakeConn) List(ctx context.Context, filePathPrefix string) ([]topo.KVInfo, error) {
+// normalizePath returns the path under which filePath is stored.
+func (f *FakeConn) normalizePath(filePath string) string {
 	f.mu.Lock()
 	defer f.mu.Unlock()
-	if len(f.listErrors) > 0 {
-		shouldErr := f.listErrors[0]
-		f.listErrors = f.listErrors[1:]
-		if shouldErr {
-			return nil, topo.NewError(topo.Timeout, filePathPrefix)
+	if !f.caseInsensitivePaths {
+		return filePath
+	}
+	return strings.ToLower(filePath)
+}
+
+// validatePathLocked checks filePath if path validation is enabled. Paths are
+// relative to the root of the cell, as topo.Server builds them, although a
+// leading slash is tolerated. They must not contain empty, "." or ".."
+// components, and must start with one of topLevelEntries. The caller must
+// hold the mutex.
+func (f *FakeConn) validatePathLocked(filePath string) error {
+	if !f.validatePaths {
+		return nil
+	}
+	trimmed := strings.TrimPrefix(filePath, "/")
+	if trimmed == "" {
+		return &InvalidPathError{Path: filePath, Reason: "path is empty"}
+	}
+	parts := strings.Split(trimmed, "/")
+	for _, part := range parts {
+		switch part {
+		case "":
+			return &InvalidPathError{Path: filePath, Reason: "path has an empty component"}
+		case ".", "..":
+			return &InvalidPathError{Path: filePath, Reason: "path is not canonical"}
 		}
 	}
-	kvInfos, isPresent := f.listResultMap[filePathPrefix]
-	if !isPresent {
-		return nil, topo.NewError(topo.NoNode, filePathPrefix)
+	if !topLevelEntries[parts[0]] {
+		return &InvalidPathError{Path: filePath, Reason: fmt.Sprintf("unknown top-level entry %q", parts[0])}
 	}
-	return kvInfos, nil
+	return nil
 }
 
-// Delete implements the Conn interface
-func (f *FakeConn) Delete(ctx context.Context, filePath string, version topo.Version) error {
-	panic("implement me")
+// FailOp makes every call of op, such as CallGet, fail with err before it is
+// served, until RestoreOp is called. A nil err fails the calls with a Timeout
+// topo error.
+func (f *FakeConn) FailOp(op CallOp, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.opFailures == nil {
+		f.opFailures = map[CallOp]error{}
+	}
+	f.opFailures[op] = err
 }
 
-// fakeLockDescriptor implements the topo.LockDescriptor interface
-type fakeLockDescriptor struct {
+// RestoreOp undoes FailOp, letting the calls of op succeed again.
+func (f *FakeConn) RestoreOp(op CallOp) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.opFailures, op)
 }
 
-// Check implements the topo.LockDescriptor interface
-func (f fakeLockDescriptor) Check(ctx context.Context) error {
-	return nil
+// SetUnavailable makes every operation fail with a Timeout topo error
+// before it is served, as during an outage of the topo server, until it is
+// called with false, which recovers at once, or SetRecovering is called.
+func (f *FakeConn) SetUnavailable(unavailable bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.unavailable = unavailable
+	f.recoveryFailures = 0
 }
 
-// Unlock implements the topo.LockDescriptor interface
-func (f fakeLockDescriptor) Unlock(ctx context.Context) error {
-	return nil
+// SetRecovering ends the outage started by SetUnavailable gradually, as a
+// topo server coming back one member at a time would: the next failures
+// operations, of any kind and on any path, still fail with a Timeout topo
+// error, and the ones after succeed. A failures of 0 recovers at once.
+func (f *FakeConn) SetRecovering(failures int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.unavailable = false
+	f.recoveryFailures = failures
 }
 
-var _ topo.LockDescriptor = (*fakeLockDescriptor)(nil)
-
-// Lock implements the Conn interface
-func (f *FakeConn) Lock(ctx context.Context, dirPath, contents string) (topo.LockDescriptor, error) {
+// checkOpFailure returns the error set by FailOp for op, if any, or the
+// error of an outage set by SetUnavailable or SetRecovering.
+func (f *FakeConn) checkOpFailure(op CallOp, filePath string) error {
 	f.mu.Lock()
 	defer f.mu.Unlock()
-	return &fakeLockDescriptor{}, nil
+	if f.closed {
+		return topo.NewError(topo.Interrupted, filePath)
+	}
+	err, ok := f.opFailures[op]
+	if !ok {
+		switch {
+		case f.unavailable:
+		case f.recoveryFailures > 0:
+			f.recoveryFailures--
+		default:
+			return nil
+		}
+	}
+	if err == nil {
+		err = topo.NewError(topo.Timeout, filePath)
+	}
+	return err
+}
+
+// ValueTooLargeError is returned by the writes of a FakeConn whose contents
+// exceed the size set by SetMaxValueSize.
+type ValueTooLargeError struct {
+	Path    string
+	Size    int
+	MaxSize int
+}
+
+// Error implements the error interface.
+func (e *ValueTooLargeError) Error() string {
+	return fmt.Sprintf("value of %d bytes written to %s exceeds the maximum value size of %d bytes", e.Size, e.Path, e.MaxSize)
 }
 
-// LockWithTTL implements the Conn interface.
-func (f *FakeConn) LockWithTTL(ctx context.Context, dirPath, contents string, _ time.Duration) (topo.LockDescriptor, error) {
+// SetMaxValueSize makes Create, Update and CompareAndSwap fail with a
+// ValueTooLargeError, without persisting anything, when the contents
+// written are larger than n bytes. Real topo backends cap the size of
+// values, e.g. etcd rejects requests above 1.5MB by default, so tests can
+// check that consumers handle oversized records cleanly. Zero, the
+// default, means unlimited.
+func (f *FakeConn) SetMaxValueSize(n int) {
 	f.mu.Lock()
 	defer f.mu.Unlock()
-	return &fakeLockDescriptor{}, nil
+	f.maxValueSize = n
 }
 
-// LockName implements the Conn interface.
-func (f *FakeConn) LockName(ctx context.Context, dirPath, contents string) (topo.LockDescriptor, error) {
+// checkValueSizeLocked returns a ValueTooLargeError if contents exceed the
+// maximum value size. The caller must hold the mutex.
+func (f *FakeConn) checkValueSizeLocked(filePath string, contents []byte) error {
+	if f.maxValueSize <= 0 || len(contents) <= f.maxValueSize {
+		return nil
+	}
+	return &ValueTooLargeError{Path: filePath, Size: len(contents), MaxSize: f.maxValueSize}
+}
+
+// MissingParentError is returned by Create on a FakeConn set with
+// SetRequireParent when the parent directory of the path doesn't exist. It
+// wraps a NoNode topo error of the parent, which is what such backends fail
+// with.
+type MissingParentError struct {
+	Path   string
+	Parent string
+}
+
+// Error implements the error interface.
+func (e *MissingParentError) Error() string {
+	return fmt.Sprintf("cannot create %s: parent directory %s does not exist", e.Path, e.Parent)
+}
+
+// Unwrap returns the NoNode topo error of the parent.
+func (e *MissingParentError) Unwrap() error {
+	return topo.NewError(topo.NoNode, e.Parent)
+}
+
+// SetRequireParent makes Create fail with a MissingParentError when the
+// parent directory of the created path doesn't exist, that is when neither
+// the parent nor any other path under it has a node, the way backends with
+// a hierarchical namespace such as ZooKeeper do. It catches consumers that
+// assume they can create a node anywhere. Children of the root are always
+// accepted. It is off by default, when the namespace is flat.
+func (f *FakeConn) SetRequireParent(require bool) {
 	f.mu.Lock()
 	defer f.mu.Unlock()
-	return &fakeLockDescriptor{}, nil
+	f.requireParent = require
 }
 
-// TryLock is part of the topo.Conn interface. Its implementation is same as Lock
-func (f *FakeConn) TryLock(ctx context.Context, dirPath, contents string) (topo.LockDescriptor, error) {
-	return f.Lock(ctx, dirPath, contents)
+// checkParentLocked returns a MissingParentError if parent directories are
+// required and the parent of filePath doesn't exist. The caller must hold
+// the mutex.
+func (f *FakeConn) checkParentLocked(filePath string) error {
+	if !f.requireParent {
+		return nil
+	}
+	idx := strings.LastIndex(filePath, "/")
+	if idx <= 0 {
+		return nil
+	}
+	parent := filePath[:idx]
+	if _, ok := f.getResultMap[parent]; ok {
+		return nil
+	}
+	for p := range f.getResultMap {
+		if strings.HasPrefix(p, parent+"/") {
+			return nil
+		}
+	}
+	return &MissingParentError{Path: filePath, Parent: parent}
 }
 
-// Watch implements the Conn interface
-func (f *FakeConn) Watch(ctx context.Context, filePath string) (*topo.WatchData, <-chan *topo.WatchData, error) {
+// opPause holds a call of an operation until it is resumed.
+type opPause struct {
+	// blocked is closed once a call is waiting on resume.
+	blocked chan struct{}
+	// resume is closed to release the call.
+	resume chan struct{}
+}
+
+// PauseNextUpdate makes the next Update call block before it applies the
+// write, until ResumeUpdate is called or the context of the Update is done.
+// It returns a channel that is closed once an Update call is blocked, so that
+// tests can drive other operations while the write is deterministically in flight.
+func (f *FakeConn) PauseNextUpdate() <-chan struct{} {
+	return f.pauseNext(CallUpdate)
+}
+
+// ResumeUpdate releases the Update call held by PauseNextUpdate. If that
+// Update has not started yet, it will not block at all.
+func (f *FakeConn) ResumeUpdate() {
+	f.resume(CallUpdate)
+}
+
+// PauseNextCreate makes the next Create call block before it applies the
+// write, until ResumeCreate is called or the context of the Create is done,
+// like PauseNextUpdate. Combined with SetExclusiveCreate, it lets a test
+// pick which of two concurrent Create calls of a path wins.
+func (f *FakeConn) PauseNextCreate() <-chan struct{} {
+	return f.pauseNext(CallCreate)
+}
+
+// ResumeCreate releases the Create call held by PauseNextCreate. If that
+// Create has not started yet, it will not block at all.
+func (f *FakeConn) ResumeCreate() {
+	f.resume(CallCreate)
+}
+
+// pauseNext makes the next call of op block until resume is called with op.
+func (f *FakeConn) pauseNext(op CallOp) <-chan struct{} {
 	f.mu.Lock()
 	defer f.mu.Unlock()
-	res, isPresent := f.getResultMap[filePath]
-	if !isPresent {
-		return nil, nil, topo.NewError(topo.NoNode, filePath)
+	if f.pauses == nil {
+		f.pauses = map[CallOp]*opPause{}
 	}
-	current := &topo.WatchData{
-		Contents: res.contents,
-		Version:  memorytopo.NodeVersion(res.version),
+	pause := &opPause{
+		blocked: make(chan struct{}),
+		resume:  make(chan struct{}),
 	}
+	f.pauses[op] = pause
+	return pause.blocked
+}
 
-	notifications := make(chan *topo.WatchData, 100)
-	f.watches[filePath] = append(f.watches[filePath], notifications)
+// resume releases the call of op held by pauseNext.
+func (f *FakeConn) resume(op CallOp) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	pause := f.pauses[op]
+	if pause == nil {
+		return
+	}
+	select {
+	case <-pause.resume:
+	default:
+		close(pause.resume)
+	}
+}
 
-	go func() {
-		<-ctx.Done()
-		watches, isPresent := f.watches[filePath]
-		if !isPresent {
-			return
+// waitForResume blocks the calling op if it was paused with pauseNext.
+// It must be called without holding the mutex.
+func (f *FakeConn) waitForResume(ctx context.Context, op CallOp, filePath string) error {
+	f.mu.Lock()
+	pause := f.pauses[op]
+	if pause != nil {
+		select {
+		case <-pause.blocked:
+			// Another call already consumed this pause.
+			pause = nil
+		default:
+			close(pause.blocked)
 		}
-		for i, watch := range watches {
-			if notifications == watch {
-				close(notifications)
-				f.watches[filePath] = append(watches[0:i], watches[i+1:]...)
-				break
-			}
+	}
+	f.mu.Unlock()
+	if pause == nil {
+		return nil
+	}
+	defer func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if f.pauses[op] == pause {
+			delete(f.pauses, op)
 		}
 	}()
-	return current, notifications, nil
+	select {
+	case <-pause.resume:
+		return nil
+	case <-ctx.Done():
+		return topo.NewError(topo.Interrupted, filePath)
+	}
 }
 
-func (f *FakeConn) WatchRecursive(ctx context.Context, path string) ([]*topo.WatchDataRecursive, <-chan *topo.WatchDataRecursive, error) {
-	panic("implement me")
+// SetExclusiveCreate makes Create fail with NodeExists when the node already
+// exists, as real topo servers do, instead of overwriting it. When two
+// Create calls of the same path race, the first one to apply its write wins
+// and the other one fails, which is what leader election and the creation
+// of singleton records rely on. Use PauseNextCreate to pick the winner.
+func (f *FakeConn) SetExclusiveCreate(exclusive bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.exclusiveCreate = exclusive
 }
 
-// NewLeaderParticipation implements the Conn interface
-func (f *FakeConn) NewLeaderParticipation(string, string) (topo.LeaderParticipation, error) {
-	panic("implement me")
+// SetListFromStore makes List and ListPage return the stored nodes under the
+// prefix, as real topo servers do, when no result was set for it with
+// AddListResult. By default they fail with NoNode.
+func (f *FakeConn) SetListFromStore(fromStore bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.listFromStore = fromStore
 }
 
-// Close implements the Conn interface
-func (f *FakeConn) Close() {
-	panic("implement me")
+// result keeps track of the fields needed to respond to a Get function call
+type result struct {
+	contents []byte
+	version  uint64
+}
+
+var _ topo.Conn = (*FakeConn)(nil)
+
+// SetReadRedirect makes Get, ListDir and Watch of filePathPrefix and of
+// the paths under it read from target instead, typically the connection of
+// another cell, the way a cell that doesn't hold some data falls back to
+// the global topo or to another cell. Writes are not redirected. Target
+// serves the reads with its own latency and errors. A nil target removes
+// the redirect. When prefixes nest, the longest one applies.
+func (f *FakeConn) SetReadRedirect(filePathPrefix string, target *FakeConn) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if target == nil {
+		delete(f.readRedirects, filePathPrefix)
+		return
+	}
+	if f.readRedirects == nil {
+		f.readRedirects = map[string]*FakeConn{}
+	}
+	f.readRedirects[filePathPrefix] = target
+}
+
+// readRedirect returns the connection the reads of filePath are redirected
+// to, or nil if they are served by f.
+func (f *FakeConn) readRedirect(filePath string) *FakeConn {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var target *FakeConn
+	longest := -1
+	for prefix, conn := range f.readRedirects {
+		if len(prefix) > longest && (filePath == prefix || strings.HasPrefix(filePath, strings.TrimSuffix(prefix, "/")+"/")) {
+			target, longest = conn, len(prefix)
+		}
+	}
+	return target
+}
+
+// ListDir implements the Conn interface. The entries are sorted by name, and
+// have their Type set whether or not full is. When full is set, Ephemeral is
+// also set on the nodes created by CreateEphemeral, and on the directories
+// holding only such nodes.
+func (f *FakeConn) ListDir(ctx context.Context, dirPath string, full bool) ([]topo.DirEntry, error) {
+	dirPath = f.normalizePath(dirPath)
+	f.recordOp(Call{Op: CallListDir, Path: dirPath}, nil)
+	if err := f.checkOpFailure(CallListDir, dirPath); err != nil {
+		return nil, err
+	}
+	if target := f.readRedirect(dirPath); target != nil {
+		return target.ListDir(ctx, dirPath, full)
+	}
+	if err := f.delay(ctx, CallListDir, dirPath); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.unlock()
+	f.expireEphemeralsLocked()
+	var res []topo.DirEntry
+	// ephemeral tells, for each entry, whether all the nodes it holds are
+	// ephemeral, see CreateEphemeral.
+	ephemeral := map[string]bool{}
+
+	prefix := strings.TrimSuffix(dirPath, "/") + "/"
+	for filePath := range f.getResultMap {
+		remaining, ok := strings.CutPrefix(filePath, prefix)
+		if prefix == "/" {
+			remaining, ok = strings.TrimPrefix(filePath, "/"), true
+		}
+		// The node at dirPath itself, if any, isn't an entry of it.
+		if !ok || remaining == "" {
+			continue
+		}
+		entry := topo.DirEntry{Name: remaining, Type: topo.TypeFile}
+		if name, _, isDir := strings.Cut(remaining, "/"); isDir {
+			entry = topo.DirEntry{Name: name, Type: topo.TypeDirectory}
+		}
+		_, isEphemeral := f.ephemerals[filePath]
+		if all, seen := ephemeral[entry.Name]; seen {
+			isEphemeral = isEphemeral && all
+		}
+		ephemeral[entry.Name] = isEphemeral
+		res = addToListOfDirEntries(res, entry)
+	}
+
+	if len(res) == 0 {
+		return nil, topo.NewError(topo.NoNode, dirPath)
+	}
+	if full {
+		for i := range res {
+			res[i].Ephemeral = ephemeral[res[i].Name]
+		}
+	}
+	topo.DirEntriesSortByName(res)
+	return res, nil
+}
+
+func addToListOfDirEntries(list []topo.DirEntry, elem topo.DirEntry) []topo.DirEntry {
+	for _, entry := range list {
+		if entry.Name == elem.Name {
+			return list
+		}
+	}
+	list = append(list, elem)
+	return list
+}
+
+// Create implements the Conn interface
+func (f *FakeConn) Create(ctx context.Context, filePath string, contents []byte) (topo.Version, error) {
+	filePath = f.normalizePath(filePath)
+	f.recordOp(Call{Op: CallCreate, Path: filePath, Contents: contents}, nil)
+	if err := f.checkOpFailure(CallCreate, filePath); err != nil {
+		return nil, err
+	}
+	if err := f.delay(ctx, CallCreate, filePath); err != nil {
+		return nil, err
+	}
+	if err := f.waitForResume(ctx, CallCreate, filePath); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.unlock()
+	f.expireEphemeralsLocked()
+	f.countWriteLocked(filePath)
+	if err := f.validatePathLocked(filePath); err != nil {
+		return nil, err
+	}
+	if err := f.checkWritableLocked(filePath); err != nil {
+		return nil, err
+	}
+	if err := f.checkWriteFailureLocked(filePath); err != nil {
+		return nil, err
+	}
+	if err := f.checkValueSizeLocked(filePath, contents); err != nil {
+		return nil, err
+	}
+	if err := f.checkParentLocked(filePath); err != nil {
+		return nil, err
+	}
+	if _, ok := f.getResultMap[filePath]; ok && f.exclusiveCreate {
+		return nil, topo.NewError(topo.NodeExists, filePath)
+	}
+	f.hideWriteLocked(filePath)
+	f.storeLocked(filePath, result{
+		contents: contents,
+		version:  1,
+	})
+	f.deliverRecursiveLocked(filePath, &topo.WatchData{
+		Contents: contents,
+		Version:  memorytopo.NodeVersion(1),
+	})
+	return memorytopo.NodeVersion(1), nil
+}
+
+// Update implements the Conn interface
+func (f *FakeConn) Update(ctx context.Context, filePath string, contents []byte, version topo.Version) (topo.Version, error) {
+	filePath = f.normalizePath(filePath)
+	if err := f.startUpdate(ctx, filePath, contents, version); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.unlock()
+	return f.updateLocked(filePath, contents, version)
+}
+
+// startUpdate records an update of filePath and applies its injected
+// failures, latency and pauses. It must be called without holding the
+// mutex.
+func (f *FakeConn) startUpdate(ctx context.Context, filePath string, contents []byte, version topo.Version) error {
+	f.recordOp(Call{Op: CallUpdate, Path: filePath, Contents: contents}, version)
+	if err := f.checkOpFailure(CallUpdate, filePath); err != nil {
+		return err
+	}
+	if err := f.delay(ctx, CallUpdate, filePath); err != nil {
+		return err
+	}
+	return f.waitForResume(ctx, CallUpdate, filePath)
+}
+
+// updateLocked writes contents to filePath if version matches, and notifies
+// the watches. The caller must hold the mutex, and release it with unlock.
+func (f *FakeConn) updateLocked(filePath string, contents []byte, version topo.Version) (topo.Version, error) {
+	f.expireEphemeralsLocked()
+	f.countWriteLocked(filePath)
+	if err := f.validatePathLocked(filePath); err != nil {
+		return nil, err
+	}
+	if err := f.checkWritableLocked(filePath); err != nil {
+		return nil, err
+	}
+	if err := f.checkWriteFailureLocked(filePath); err != nil {
+		return nil, err
+	}
+	if err := f.checkValueSizeLocked(filePath, contents); err != nil {
+		return nil, err
+	}
+	shouldErr := false
+	writeSucceeds := true
+	if updateErr, ok := nextError(f.updateErrorsByPath, &f.updateErrors, filePath); ok {
+		shouldErr = updateErr.shouldError
+		writeSucceeds = updateErr.writePersists
+	}
+	res, isPresent := f.getResultMap[filePath]
+	switch {
+	case version == nil:
+		// An unconditional update creates the node if it doesn't exist, at
+		// version 1, and otherwise bumps its version like any other write.
+		res.version++
+	case !isPresent:
+		return nil, topo.NewError(topo.NoNode, filePath)
+	case f.checkVersions:
+		if res.version != uint64(version.(memorytopo.NodeVersion)) {
+			return nil, topo.NewError(topo.BadVersion, filePath)
+		}
+		res.version++
+	}
+	if writeSucceeds {
+		res.contents = contents
+		f.hideWriteLocked(filePath)
+		f.storeLocked(filePath, res)
+	}
+	if shouldErr {
+		return nil, topo.NewError(topo.Timeout, filePath)
+	}
+
+	f.notifyWatchesLocked(filePath, res)
+	return memorytopo.NodeVersion(res.version), nil
+}
+
+// CompareAndSwap atomically replaces the contents of filePath with newContents,
+// provided its current contents are still expected. It returns a BadVersion
+// error if the contents changed in the meantime, which is what a versioned
+// Update returns to a Get-then-Update loop that lost a race. Past the contents
+// check a swap is a versioned Update: it is recorded as CallUpdate, and sees
+// the same injected failures, latency, pauses and watches.
+func (f *FakeConn) CompareAndSwap(ctx context.Context, filePath string, expected, newContents []byte) error {
+	filePath = f.normalizePath(filePath)
+	if err := f.startUpdate(ctx, filePath, newContents, nil); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.unlock()
+	f.expireEphemeralsLocked()
+	res, isPresent := f.getResultMap[filePath]
+	if !isPresent {
+		return topo.NewError(topo.NoNode, filePath)
+	}
+	if !bytes.Equal(res.contents, expected) {
+		return topo.NewError(topo.BadVersion, filePath)
+	}
+	_, err := f.updateLocked(filePath, newContents, memorytopo.NodeVersion(res.version))
+	return err
+}
+
+// notifyWatchesLocked sends the new value of filePath to its watches.
+// The caller must hold the mutex.
+func (f *FakeConn) notifyWatchesLocked(filePath string, res result) {
+	f.emitLocked(filePath, &topo.WatchData{
+		Contents: res.contents,
+		Version:  memorytopo.NodeVersion(res.version),
+	})
+}
+
+// emitLocked sends data to the watches of filePath, and records it in the
+// watch history of the path. Value events are held back instead when watch
+// events are reordered, see SetWatchReorder. The caller must hold the mutex.
+func (f *FakeConn) emitLocked(filePath string, data *topo.WatchData) {
+	if f.watchReorder != nil {
+		if data.Err == nil {
+			f.watchReorder.pending[filePath] = append(f.watchReorder.pending[filePath], data)
+			if len(f.watchReorder.pending[filePath]) >= f.watchReorder.window {
+				f.flushReorderedLocked(filePath)
+			}
+			return
+		}
+		// Errors end the watches, so the held back values go first.
+		f.flushReorderedLocked(filePath)
+	}
+	f.deliverLocked(filePath, data)
+}
+
+// deliverLocked queues data for the watches of filePath, to be sent by
+// unlock, and records it in the watch history of the path. The caller must
+// hold the mutex, and release it with unlock.
+func (f *FakeConn) deliverLocked(filePath string, data *topo.WatchData) {
+	if f.recordWatchHistory {
+		if f.watchHistory == nil {
+			f.watchHistory = map[string][]*topo.WatchData{}
+		}
+		f.watchHistory[filePath] = append(f.watchHistory[filePath], data)
+	}
+	for _, watch := range f.watches[filePath] {
+		f.outbox = append(f.outbox, watchSend{
+			watch:    watch,
+			data:     data,
+			coalesce: f.watchCoalesceLatest && data.Err == nil,
+		})
+	}
+	f.deliverRecursiveLocked(filePath, data)
+}
+
+// deliverRecursiveLocked queues data for the recursive watches of the paths
+// filePath is under, to be sent by unlock. The caller must hold the mutex,
+// and release it with unlock.
+func (f *FakeConn) deliverRecursiveLocked(filePath string, data *topo.WatchData) {
+	for dirPath, watches := range f.recursiveWatches {
+		if !isUnder(filePath, dirPath) {
+			continue
+		}
+		for _, watch := range watches {
+			f.outbox = append(f.outbox, watchSend{watch: watch, data: data, path: filePath})
+		}
+	}
+}
+
+// isUnder returns whether filePath is dirPath or a path under it.
+func isUnder(filePath, dirPath string) bool {
+	dirPath = strings.TrimSuffix(dirPath, "/")
+	return filePath == dirPath || strings.HasPrefix(filePath, dirPath+"/")
+}
+
+// unlock releases the mutex, then delivers the watch events and closes
+// queued while it was held, see fakeWatch.send. Delivering never blocks,
+// so that a consumer writing to the connection from the goroutine reading
+// its watch can't deadlock, however far behind it is. Only one caller
+// delivers at a time, and the others leave their events to it, so that the
+// watches receive them in order.
+func (f *FakeConn) unlock() {
+	if f.delivering {
+		f.mu.Unlock()
+		return
+	}
+	f.delivering = true
+	for len(f.outbox) > 0 {
+		sends := f.outbox
+		f.outbox = nil
+		f.mu.Unlock()
+		for _, send := range sends {
+			send.watch.send(send)
+		}
+		f.mu.Lock()
+	}
+	f.delivering = false
+	f.mu.Unlock()
+}
+
+// send delivers s without blocking. It goes straight to the channel of the
+// watch if there is room and nothing is backlogged, so that the reader can
+// see it as soon as the write returns. Otherwise it is added to the backlog,
+// which a goroutine of the watch delivers in order as the reader catches up.
+// The backlog is unbounded: the fake trades memory for never making a writer
+// wait on a reader.
+func (w *fakeWatch) send(s watchSend) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if s.coalesce {
+		w.backlog = slices.DeleteFunc(w.backlog, func(queued watchSend) bool {
+			return !queued.close && queued.data.Err == nil
+		})
+	}
+	if !w.draining && s.deliver(false) {
+		return
+	}
+	w.backlog = append(w.backlog, s)
+	if !w.draining {
+		w.draining = true
+		go w.drain()
+	}
+}
+
+// drain delivers the backlog of the watch, until it is empty.
+func (w *fakeWatch) drain() {
+	for {
+		w.mu.Lock()
+		if len(w.backlog) == 0 {
+			w.draining = false
+			w.mu.Unlock()
+			return
+		}
+		s := w.backlog[0]
+		w.backlog = w.backlog[1:]
+		w.mu.Unlock()
+		s.deliver(true)
+	}
+}
+
+// deliver sends the event to the watch, or closes it, and returns whether
+// it did. If block is set, it waits for room in the channel, and otherwise
+// gives up when the channel is full. Events for a watch whose context is
+// done may be dropped.
+func (s watchSend) deliver(block bool) bool {
+	if s.watch.recursive != nil {
+		if s.close {
+			close(s.watch.recursive)
+			return true
+		}
+		return sendWatchEvent(s.watch.recursive, &topo.WatchDataRecursive{Path: s.path, WatchData: *s.data}, s.watch.done, block)
+	}
+	if s.close {
+		close(s.watch.notifications)
+		return true
+	}
+	if s.coalesce {
+		coalesce(s.watch.notifications)
+	}
+	return sendWatchEvent(s.watch.notifications, s.data, s.watch.done, block)
+}
+
+// sendWatchEvent sends data on ch, unless done is closed. If block is not
+// set, it returns false instead of waiting for room in ch.
+func sendWatchEvent[T any](ch chan T, data T, done <-chan struct{}, block bool) bool {
+	if !block {
+		select {
+		case ch <- data:
+		case <-done:
+		default:
+			return false
+		}
+		return true
+	}
+	select {
+	case ch <- data:
+	case <-done:
+	}
+	return true
+}
+
+// coalesce drops the value events pending in watch, the ones its reader
+// hasn't received yet, keeping the error events in order. It is only called
+// by the single sender of the watch, see fakeWatch.send, so that no other
+// event is sent in the meantime.
+func coalesce(watch chan *topo.WatchData) {
+	var errs []*topo.WatchData
+	for len(watch) > 0 {
+		select {
+		case data := <-watch:
+			if data.Err != nil {
+				errs = append(errs, data)
+			}
+		default:
+			// The reader received the last pending event meanwhile.
+		}
+	}
+	for _, data := range errs {
+		watch <- data
+	}
+}
+
+// SetRecordWatchHistory makes the connection record every event it sends to
+// the watches of a path, whether or not a watch is established when it is
+// sent, see WatchHistory. Turning it off discards the recorded history.
+func (f *FakeConn) SetRecordWatchHistory(record bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.recordWatchHistory = record
+	if !record {
+		f.watchHistory = nil
+	}
+}
+
+// WatchHistory returns, in order, the events sent to the watches of
+// filePath since SetRecordWatchHistory was turned on, so that tests can
+// assert the complete sequence a watcher would have seen without reading
+// from live channels. Events replayed by a watch script are specific to
+// each watch, and are not recorded.
+func (f *FakeConn) WatchHistory(filePath string) []*topo.WatchData {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.watchHistory[filePath])
+}
+
+// EmitWatch sends data, as is, to every watch currently established on
+// filePath, without changing what is stored there. Tests use it to
+// deliver values, versions and errors that a consistent topo would not,
+// such as a version that goes backwards. Watches whose context is done are
+// already closed and unregistered, so they are skipped.
+func (f *FakeConn) EmitWatch(filePath string, data *topo.WatchData) {
+	f.mu.Lock()
+	defer f.unlock()
+	f.emitLocked(filePath, data)
+}
+
+// Get implements the Conn interface
+func (f *FakeConn) Get(ctx context.Context, filePath string) ([]byte, topo.Version, error) {
+	filePath = f.normalizePath(filePath)
+	f.recordOp(Call{Op: CallGet, Path: filePath}, nil)
+	if err := f.checkOpFailure(CallGet, filePath); err != nil {
+		return nil, nil, err
+	}
+	if target := f.readRedirect(filePath); target != nil {
+		return target.Get(ctx, filePath)
+	}
+	if err := f.delay(ctx, CallGet, filePath); err != nil {
+		return nil, nil, err
+	}
+	f.mu.Lock()
+	defer f.unlock()
+	f.expireEphemeralsLocked()
+	if err := f.validatePathLocked(filePath); err != nil {
+		return nil, nil, err
+	}
+	if shouldErr, _ := nextError(f.getErrorsByPath, &f.getErrors, filePath); shouldErr {
+		return nil, nil, topo.NewError(topo.Timeout, filePath)
+	}
+	if f.flakyReadsEvery > 0 {
+		f.flakyReads++
+		if f.flakyReads%f.flakyReadsEvery == 0 {
+			return nil, nil, topo.NewError(topo.Timeout, filePath)
+		}
+	}
+	if sequence := f.getSequences[filePath]; len(sequence) > 0 {
+		res := result{contents: sequence[0], version: 1}
+		if old, ok := f.getResultMap[filePath]; ok {
+			res.version = old.version + 1
+		}
+		f.storeLocked(filePath, res)
+		f.notifyWatchesLocked(filePath, res)
+		f.getSequences[filePath] = sequence[1:]
+	}
+	if invisible, ok := f.invisibleWrites[filePath]; ok {
+		if f.nowLocked().Before(invisible.visibleAt) {
+			if !invisible.exists {
+				return nil, nil, topo.NewError(topo.NoNode, filePath)
+			}
+			return invisible.visible.contents, memorytopo.NodeVersion(invisible.visible.version), nil
+		}
+		delete(f.invisibleWrites, filePath)
+	}
+	if f.staleReads[filePath] > 0 {
+		f.staleReads[filePath]--
+		if res, ok := f.previous[filePath]; ok {
+			return res.contents, memorytopo.NodeVersion(res.version), nil
+		}
+	}
+	res, isPresent := f.getResultMap[filePath]
+	if !isPresent {
+		return nil, nil, topo.NewError(topo.NoNode, filePath)
+	}
+	return res.contents, memorytopo.NodeVersion(res.version), nil
+}
+
+// storeLocked sets the result of filePath, keeping the one it replaces for
+// stale reads. The caller must hold the mutex.
+func (f *FakeConn) storeLocked(filePath string, res result) {
+	if old, ok := f.getResultMap[filePath]; ok {
+		if f.previous == nil {
+			f.previous = map[string]result{}
+		}
+		f.previous[filePath] = old
+	}
+	f.getResultMap[filePath] = res
+	if f.history == nil {
+		f.history = map[string][]result{}
+	}
+	history := append(f.history[filePath], res)
+	if f.historyLimit > 0 && len(history) > f.historyLimit {
+		history = history[len(history)-f.historyLimit:]
+	}
+	f.history[filePath] = history
+}
+
+// SetHistoryLimit makes the connection retain, for GetVersion, the last
+// limit revisions of each path, so that long running tests don't keep every
+// write. By default, or with a limit that isn't positive, all revisions are
+// retained. A lower limit applies to the revisions already written.
+func (f *FakeConn) SetHistoryLimit(limit int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.historyLimit = limit
+	if limit <= 0 {
+		return
+	}
+	for filePath, history := range f.history {
+		if len(history) > limit {
+			f.history[filePath] = history[len(history)-limit:]
+		}
+	}
+}
+
+// SetStaleReads makes the next n Get calls of filePath return the contents
+// and version that its latest write replaced, the way a lagging replica of
+// an eventually consistent topo server would. Combined with the version
+// checks of Update, it drives the retry path of Get-then-Update loops that
+// act on a version that is already behind. Paths written only once are
+// read normally.
+func (f *FakeConn) SetStaleReads(filePath string, n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.staleReads == nil {
+		f.staleReads = map[string]int{}
+	}
+	f.staleReads[filePath] = n
+}
+
+// AddGetResults makes the next Get calls of filePath return each of results
+// in turn, as if the value was written between them, and then keep returning
+// the last one. Each step bumps the version and is reported to the watches
+// of the path, so a poller sees the value evolve without the test having to
+// interleave Updates. Results are appended to any that are still pending.
+func (f *FakeConn) AddGetResults(filePath string, results [][]byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.getSequences == nil {
+		f.getSequences = map[string][][]byte{}
+	}
+	f.getSequences[filePath] = append(f.getSequences[filePath], results...)
+}
+
+// invisibleWrite is what Get returns for a path until the writes made to it
+// in the visibility delay become visible.
+type invisibleWrite struct {
+	// visible is the result of the path before the writes, if exists is set.
+	visible   result
+	exists    bool
+	visibleAt time.Time
+}
+
+// SetVisibilityDelay makes the writes of Create, Update and CompareAndSwap
+// invisible to Get for d after they return, according to the connection's
+// clock, as if they had to propagate to the server replica that serves the
+// reads. Until then, Get returns the value the path had before, or NoNode
+// if it didn't exist, so that tests can catch consumers that assume
+// read-after-write consistency. Unlike SetStaleReads, the window is a
+// duration rather than a number of reads: advance the clock, see SetClock
+// and AdvanceClock, to make the writes visible. Each write extends the
+// window of its path. Watches are still notified of writes immediately.
+// Zero, the default, makes writes visible at once.
+func (f *FakeConn) SetVisibilityDelay(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.visibilityDelay = d
+}
+
+// hideWriteLocked makes the write filePath is about to receive invisible
+// for the visibility delay, if any. The caller must hold the mutex.
+func (f *FakeConn) hideWriteLocked(filePath string) {
+	if f.visibilityDelay <= 0 {
+		return
+	}
+	now := f.nowLocked()
+	invisible, ok := f.invisibleWrites[filePath]
+	if !ok || !now.Before(invisible.visibleAt) {
+		invisible.visible, invisible.exists = f.getResultMap[filePath]
+	}
+	invisible.visibleAt = now.Add(f.visibilityDelay)
+	if f.invisibleWrites == nil {
+		f.invisibleWrites = map[string]invisibleWrite{}
+	}
+	f.invisibleWrites[filePath] = invisible
+}
+
+// SetCheckVersions controls whether versioned updates behave like a real
+// topo server. By default they do: they fail with BadVersion unless the
+// version matches the stored one, and bump the version when they succeed.
+// Disabling the checks makes the fake accept any version and leave it
+// unchanged, for tests that reuse a version they read once.
+func (f *FakeConn) SetCheckVersions(check bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.checkVersions = check
+}
+
+// WriteCount returns the number of Create and Update calls that targeted
+// filePath since the connection was created or ResetWriteCounts was called,
+// whether they succeeded or not.
+func (f *FakeConn) WriteCount(filePath string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.writeCounts[filePath]
+}
+
+// ResetWriteCounts resets the counters returned by WriteCount.
+func (f *FakeConn) ResetWriteCounts() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.writeCounts = nil
+}
+
+func (f *FakeConn) countWriteLocked(filePath string) {
+	if f.writeCounts == nil {
+		f.writeCounts = map[string]int{}
+	}
+	f.writeCounts[filePath]++
+}
+
+// GetRaw returns a copy of the contents stored at filePath and their
+// version, and whether the file exists. Unlike Get it neither consumes
+// queued errors nor waits on the configured latency, so tests can inspect
+// what was written without disturbing the fake.
+func (f *FakeConn) GetRaw(filePath string) ([]byte, uint64, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	res, isPresent := f.getResultMap[filePath]
+	if !isPresent {
+		return nil, 0, false
+	}
+	return slices.Clone(res.contents), res.version, true
+}
+
+// GetVersion is part of topo.Conn interface. It returns the contents of
+// filePath at version, from the revisions retained by the connection, see
+// SetHistoryLimit.
+func (f *FakeConn) GetVersion(ctx context.Context, filePath string, version int64) ([]byte, error) {
+	filePath = f.normalizePath(filePath)
+	if err := f.checkOpen(filePath); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	// Versions repeat once the version checks are disabled, or when a node
+	// is created again, so the latest revision with the version wins.
+	history := f.history[filePath]
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].version == uint64(version) {
+			return history[i].contents, nil
+		}
+	}
+	return nil, topo.NewError(topo.NoNode, filePath)
+}
+
+// List is part of the topo.Conn interface.
+func (f *FakeConn) List(ctx context.Context, filePathPrefix string) ([]topo.KVInfo, error) {
+	filePathPrefix = f.normalizePath(filePathPrefix)
+	f.recordOp(Call{Op: CallList, Path: filePathPrefix}, nil)
+	if err := f.checkOpFailure(CallList, filePathPrefix); err != nil {
+		return nil, err
+	}
+	if err := f.delay(ctx, CallList, filePathPrefix); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.unlock()
+	kvInfos, err := f.listLocked(filePathPrefix)
+	if err != nil {
+		return nil, err
+	}
+	if f.listTruncateAt > 0 && len(kvInfos) > f.listTruncateAt {
+		f.truncatedLists++
+		kvInfos = kvInfos[:f.listTruncateAt]
+	}
+	return kvInfos, nil
+}
+
+// SetListTruncateAt makes List return only the first n results of any
+// prefix, with no sign that the others were dropped, as a topo capping the
+// size of its responses would. Unlike ListPage, which hands out a token to
+// fetch the next page, the dropped results can't be fetched at all, so tests
+// can check that consumers don't take List to be complete. ListPage is not
+// affected. Zero, the default, returns all the results.
+func (f *FakeConn) SetListTruncateAt(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.listTruncateAt = n
+}
+
+// TruncatedLists returns the number of List calls that dropped results
+// because of SetListTruncateAt, to tell a silent truncation apart from a
+// complete listing in tests.
+func (f *FakeConn) TruncatedLists() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.truncatedLists
+}
+
+// SetListPageSize sets the maximum number of results returned by each
+// ListPage call. Zero, the default, returns all the results in one page.
+func (f *FakeConn) SetListPageSize(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.listPageSize = n
+}
+
+// listPageFailure is the failure programmed by FailListPageAfter.
+type listPageFailure struct {
+	// succeed is the number of ListPage calls still to be served before failing.
+	succeed int
+	err     error
+}
+
+// FailListPageAfter makes ListPage of filePathPrefix serve n pages, then fail
+// the next call with err, to test consumers that fail partway through a
+// paged listing. The failure happens once: later calls, such as a consumer
+// resuming from its last token, are served normally. A nil err fails with a
+// Timeout topo error, as a server giving up on a slow listing would.
+func (f *FakeConn) FailListPageAfter(filePathPrefix string, n int, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err == nil {
+		err = topo.NewError(topo.Timeout, filePathPrefix)
+	}
+	if f.listPageFailures == nil {
+		f.listPageFailures = map[string]*listPageFailure{}
+	}
+	f.listPageFailures[filePathPrefix] = &listPageFailure{succeed: n, err: err}
+}
+
+// ListPage is a fake-specific variant of List that returns the results in
+// pages of at most the configured page size, to test consumers that must
+// process large directories in chunks. The topo.Conn interface has no
+// notion of continuation, so this is only available on FakeConn.
+// An empty token fetches the first page. The returned token must be passed
+// to the next call, and is empty once the last page has been returned.
+func (f *FakeConn) ListPage(ctx context.Context, filePathPrefix string, token string) ([]topo.KVInfo, string, error) {
+	filePathPrefix = f.normalizePath(filePathPrefix)
+	if err := f.checkOpFailure(CallList, filePathPrefix); err != nil {
+		return nil, "", err
+	}
+	if err := f.delay(ctx, CallList, filePathPrefix); err != nil {
+		return nil, "", err
+	}
+	f.mu.Lock()
+	defer f.unlock()
+	if failure, ok := f.listPageFailures[filePathPrefix]; ok {
+		if failure.succeed == 0 {
+			delete(f.listPageFailures, filePathPrefix)
+			return nil, "", failure.err
+		}
+		failure.succeed--
+	}
+	kvInfos, err := f.listLocked(filePathPrefix)
+	if err != nil {
+		return nil, "", err
+	}
+	start := 0
+	if token != "" {
+		start, err = strconv.Atoi(token)
+		if err != nil || start < 0 || start > len(kvInfos) {
+			return nil, "", topo.NewError(topo.NoNode, fmt.Sprintf("%s (invalid continuation token %q)", filePathPrefix, token))
+		}
+	}
+	if f.listPageSize <= 0 || start+f.listPageSize >= len(kvInfos) {
+		return kvInfos[start:], "", nil
+	}
+	end := start + f.listPageSize
+	return kvInfos[start:end], strconv.Itoa(end), nil
+}
+
+// listLocked returns the list results for the given prefix, consuming any
+// injected list error. The result set by AddListResult, if any, is
+// returned as is. Otherwise, with SetListFromStore, the stored nodes whose
+// path starts with the prefix are returned, sorted by path. f.mu must be
+// held, and released with unlock.
+func (f *FakeConn) listLocked(filePathPrefix string) ([]topo.KVInfo, error) {
+	if shouldErr, _ := nextError(f.listErrorsByPrefix, &f.listErrors, filePathPrefix); shouldErr {
+		return nil, topo.NewError(topo.Timeout, filePathPrefix)
+	}
+	kvInfos, isPresent := f.listResultMap[filePathPrefix]
+	if !isPresent && f.listFromStore {
+		kvInfos = f.listStoredLocked(filePathPrefix)
+		isPresent = len(kvInfos) > 0
+	}
+	phantoms := f.phantomListEntries[filePathPrefix]
+	if !isPresent && len(phantoms) == 0 {
+		return nil, topo.NewError(topo.NoNode, filePathPrefix)
+	}
+	if len(phantoms) > 0 {
+		kvInfos = slices.Concat(kvInfos, phantoms)
+	}
+	if f.inconsistentListVersions[filePathPrefix] {
+		kvInfos = slices.Clone(kvInfos)
+		for i, kv := range kvInfos {
+			if res, ok := f.getResultMap[string(kv.Key)]; ok {
+				kvInfos[i].Version = memorytopo.NodeVersion(res.version + 1)
+			}
+		}
+	}
+	return kvInfos, nil
+}
+
+// listStoredLocked returns the stored nodes whose path starts with
+// filePathPrefix, sorted by path, once the expired ephemeral nodes are gone.
+// f.mu must be held.
+func (f *FakeConn) listStoredLocked(filePathPrefix string) []topo.KVInfo {
+	f.expireEphemeralsLocked()
+	var kvInfos []topo.KVInfo
+	for filePath, res := range f.getResultMap {
+		if !strings.HasPrefix(filePath, filePathPrefix) {
+			continue
+		}
+		kvInfos = append(kvInfos, topo.KVInfo{
+			Key:     []byte(filePath),
+			Value:   res.contents,
+			Version: memorytopo.NodeVersion(res.version),
+		})
+	}
+	slices.SortFunc(kvInfos, func(a, b topo.KVInfo) int {
+		return bytes.Compare(a.Key, b.Key)
+	})
+	return kvInfos
+}
+
+// Delete implements the Conn interface. It fails with NoNode if filePath
+// doesn't exist, and with BadVersion if version is set and isn't the
+// version of the node. The watches of filePath receive a NoNode error and
+// are closed.
+func (f *FakeConn) Delete(ctx context.Context, filePath string, version topo.Version) error {
+	filePath = f.normalizePath(filePath)
+	f.recordOp(Call{Op: CallDelete, Path: filePath}, version)
+	if err := f.checkOpFailure(CallDelete, filePath); err != nil {
+		return err
+	}
+	if err := f.delay(ctx, CallDelete, filePath); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.unlock()
+	f.expireEphemeralsLocked()
+	if err := f.checkWritableLocked(filePath); err != nil {
+		return err
+	}
+	if len(f.deleteErrors) > 0 {
+		shouldErr := f.deleteErrors[0]
+		f.deleteErrors = f.deleteErrors[1:]
+		if shouldErr {
+			return topo.NewError(topo.Timeout, filePath)
+		}
+	}
+	res, isPresent := f.getResultMap[filePath]
+	if !isPresent {
+		return topo.NewError(topo.NoNode, filePath)
+	}
+	if version != nil && res.version != uint64(version.(memorytopo.NodeVersion)) {
+		return topo.NewError(topo.BadVersion, filePath)
+	}
+	f.removeLocked(filePath)
+	return nil
+}
+
+// DeleteBehindBack removes filePath, as if another client deleted it
+// concurrently, so that the next versioned Update of filePath fails with
+// NoNode. It drives the race where a node vanishes between the Get and the
+// Update of a read-modify-write loop. Unlike a real deletion, it doesn't
+// notify the watches of filePath.
+func (f *FakeConn) DeleteBehindBack(filePath string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.getResultMap, filePath)
+	delete(f.previous, filePath)
+	delete(f.history, filePath)
+	delete(f.ephemerals, filePath)
+}
+
+// MoveNode atomically moves the node at oldPath to newPath, as a topo
+// migration relocating a record would. The contents are preserved and the
+// version is bumped. The watches of oldPath receive a NoNode error and are
+// closed, as when a node is deleted, and the watches of newPath, if any,
+// receive the moved contents. It fails with NoNode if oldPath doesn't exist,
+// and with NodeExists if newPath does.
+func (f *FakeConn) MoveNode(oldPath, newPath string) error {
+	f.mu.Lock()
+	defer f.unlock()
+	res, ok := f.getResultMap[oldPath]
+	if !ok {
+		return topo.NewError(topo.NoNode, oldPath)
+	}
+	if _, ok := f.getResultMap[newPath]; ok {
+		return topo.NewError(topo.NodeExists, newPath)
+	}
+	f.removeLocked(oldPath)
+
+	res.version++
+	f.storeLocked(newPath, res)
+	f.notifyWatchesLocked(newPath, res)
+	return nil
+}
+
+// removeLocked deletes the node at filePath. Its watches receive a NoNode
+// error and are closed. The caller must hold the mutex.
+func (f *FakeConn) removeLocked(filePath string) {
+	delete(f.getResultMap, filePath)
+	delete(f.previous, filePath)
+	delete(f.history, filePath)
+	delete(f.ephemerals, filePath)
+	f.emitLocked(filePath, &topo.WatchData{Err: topo.NewError(topo.NoNode, filePath)})
+	for _, watch := range f.watches[filePath] {
+		f.outbox = append(f.outbox, watchSend{watch: watch, close: true})
+	}
+	delete(f.watches, filePath)
+}
+
+// CreateEphemeral stores contents at filePath as an ephemeral node, such as
+// a ZooKeeper ephemeral or a key attached to an etcd lease, that is deleted
+// once ttl has elapsed on the connection's clock, see SetClock and
+// AdvanceClock. On expiry, the watches of filePath receive a NoNode error
+// and are closed, as when a node is deleted, so that tests can check the
+// consumers relying on ephemeral nodes for liveness. Expired nodes are
+// removed when the clock is advanced, and before the reads and writes of
+// the connection are served. Updates keep the node ephemeral, with the same
+// expiry. Like SetCorruptData, it bypasses read-only mode and injected write
+// failures, as if the node had been registered by another client.
+func (f *FakeConn) CreateEphemeral(filePath string, contents []byte, ttl time.Duration) {
+	f.mu.Lock()
+	defer f.unlock()
+	res := result{contents: contents, version: 1}
+	if old, ok := f.getResultMap[filePath]; ok {
+		res.version = old.version + 1
+	}
+	f.storeLocked(filePath, res)
+	if f.ephemerals == nil {
+		f.ephemerals = map[string]time.Time{}
+	}
+	f.ephemerals[filePath] = f.nowLocked().Add(ttl)
+	f.notifyWatchesLocked(filePath, res)
+}
+
+// expireEphemeralsLocked removes the ephemeral nodes whose TTL has elapsed
+// on the connection's clock. The caller must hold the mutex.
+func (f *FakeConn) expireEphemeralsLocked() {
+	if len(f.ephemerals) == 0 {
+		return
+	}
+	now := f.nowLocked()
+	for filePath, expiry := range f.ephemerals {
+		if !now.Before(expiry) {
+			f.removeLocked(filePath)
+		}
+	}
+}
+
+// SetCorruptData stores data at filePath as is, as if the record had been
+// corrupted in the topo server, bumping its version and notifying its
+// watches. data is typically bytes that don't unmarshal into the record
+// expected at filePath, to check that consumers fail with a clear error
+// instead of panicking. Read-only mode and injected write failures don't
+// apply, since the corruption doesn't come from the consumer's writes.
+func (f *FakeConn) SetCorruptData(filePath string, data []byte) {
+	f.mu.Lock()
+	defer f.unlock()
+	res := result{contents: data, version: 1}
+	if old, ok := f.getResultMap[filePath]; ok {
+		res.version = old.version + 1
+	}
+	f.storeLocked(filePath, res)
+	f.notifyWatchesLocked(filePath, res)
+}
+
+// SetClock makes the connection read the current time from clock when it
+// acquires and checks locks with a TTL, and when it checks whether a write
+// is visible yet, instead of time.Now.
+func (f *FakeConn) SetClock(clock func() time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.clock = clock
+}
+
+// AdvanceClock moves the clock of the connection forward by d, as if the
+// clock had skewed between the acquisition and the check of a lock. Locks
+// whose TTL is exceeded by the advanced clock fail their Check, and the
+// ephemeral nodes whose TTL is exceeded are deleted, see CreateEphemeral.
+func (f *FakeConn) AdvanceClock(d time.Duration) {
+	f.mu.Lock()
+	defer f.unlock()
+	f.clockSkew += d
+	f.expireEphemeralsLocked()
+}
+
+// nowLocked returns the current time of the connection's clock.
+func (f *FakeConn) nowLocked() time.Time {
+	clock := f.clock
+	if clock == nil {
+		clock = time.Now
+	}
+	return clock().Add(f.clockSkew)
+}
+
+// LockRecord describes a lock acquired on a FakeConn, as returned by Locks.
+type LockRecord struct {
+	DirPath  string
+	Contents string
+	// Acquired and Released are read from the connection's clock, see
+	// SetClock. Released is zero while the lock is held.
+	Acquired time.Time
+	Released time.Time
+}
+
+// Held returns true if the lock was not released yet.
+func (l LockRecord) Held() bool {
+	return l.Released.IsZero()
+}
+
+// Locks returns the locks acquired on the connection, in the order they were
+// acquired, including the ones that were released since. It lets tests
+// check that a flow locked the expected paths and released them.
+func (f *FakeConn) Locks() []LockRecord {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	locks := make([]LockRecord, 0, len(f.locks))
+	for _, lock := range f.locks {
+		locks = append(locks, *lock)
+	}
+	return locks
+}
+
+// lockLocked acquires a lock on dirPath, which expires after ttl if it is
+// positive, and records it for Locks.
+func (f *FakeConn) lockLocked(dirPath, contents string, ttl time.Duration) *fakeLockDescriptor {
+	record := &LockRecord{
+		DirPath:  dirPath,
+		Contents: contents,
+		Acquired: f.nowLocked(),
+	}
+	f.locks = append(f.locks, record)
+	lock := &fakeLockDescriptor{conn: f, dirPath: dirPath, record: record}
+	if ttl > 0 {
+		lock.expiry = record.Acquired.Add(ttl)
+	}
+	return lock
+}
+
+// fakeLockDescriptor implements the topo.LockDescriptor interface
+type fakeLockDescriptor struct {
+	conn    *FakeConn
+	dirPath string
+	// expiry is when the lock expires; it is zero for locks without a TTL.
+	expiry time.Time
+	// record is the entry of the lock in the connection's Locks.
+	record *LockRecord
+}
+
+// Check implements the topo.LockDescriptor interface. A lock acquired with a
+// TTL fails the check once the connection's clock reaches its expiry, with
+// the NoNode error a real topo returns once the lock node is gone.
+func (f fakeLockDescriptor) Check(ctx context.Context) error {
+	if f.expiry.IsZero() {
+		return nil
+	}
+	f.conn.mu.Lock()
+	defer f.conn.mu.Unlock()
+	if !f.conn.nowLocked().Before(f.expiry) {
+		return topo.NewError(topo.NoNode, f.dirPath)
+	}
+	return nil
+}
+
+// Unlock implements the topo.LockDescriptor interface. It records the
+// release time of the lock the first time it is called.
+func (f fakeLockDescriptor) Unlock(ctx context.Context) error {
+	f.conn.mu.Lock()
+	defer f.conn.mu.Unlock()
+	if f.record.Released.IsZero() {
+		f.record.Released = f.conn.nowLocked()
+	}
+	return nil
+}
+
+var _ topo.LockDescriptor = (*fakeLockDescriptor)(nil)
+
+// Lock implements the Conn interface
+func (f *FakeConn) Lock(ctx context.Context, dirPath, contents string) (topo.LockDescriptor, error) {
+	dirPath = f.normalizePath(dirPath)
+	if err := f.startLock(ctx, CallLock, dirPath); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.lockLocked(dirPath, contents, 0), nil
+}
+
+// LockWithTTL implements the Conn interface. The lock expires once the ttl
+// has elapsed on the connection's clock, see AdvanceClock.
+func (f *FakeConn) LockWithTTL(ctx context.Context, dirPath, contents string, ttl time.Duration) (topo.LockDescriptor, error) {
+	dirPath = f.normalizePath(dirPath)
+	if err := f.startLock(ctx, CallLockWithTTL, dirPath); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.lockLocked(dirPath, contents, ttl), nil
+}
+
+// LockName implements the Conn interface.
+func (f *FakeConn) LockName(ctx context.Context, dirPath, contents string) (topo.LockDescriptor, error) {
+	dirPath = f.normalizePath(dirPath)
+	if err := f.startLock(ctx, CallLockName, dirPath); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.lockLocked(dirPath, contents, 0), nil
+}
+
+// TryLock is part of the topo.Conn interface. Its implementation is same as Lock
+func (f *FakeConn) TryLock(ctx context.Context, dirPath, contents string) (topo.LockDescriptor, error) {
+	dirPath = f.normalizePath(dirPath)
+	if err := f.startLock(ctx, CallTryLock, dirPath); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.lockLocked(dirPath, contents, 0), nil
+}
+
+// startLock records a lock call of op on dirPath, and applies its injected
+// failures and latency, like the file operations. It must be called without
+// holding the mutex.
+func (f *FakeConn) startLock(ctx context.Context, op CallOp, dirPath string) error {
+	f.recordOp(Call{Op: op, Path: dirPath}, nil)
+	if err := f.checkOpFailure(op, dirPath); err != nil {
+		return err
+	}
+	return f.delay(ctx, op, dirPath)
+}
+
+// watchScript is a sequence of events replayed to the watches of a path.
+type watchScript struct {
+	events []*topo.WatchData
+	// delay is waited before each event is sent.
+	delay time.Duration
+	// closeWhenDone closes the watch channel once all events are sent.
+	closeWhenDone bool
+}
+
+// SetWatchScript makes every Watch established on filePath replay events, in
+// order, on its channel instead of reporting writes to the path. Each event is
+// sent after waiting for delay. Once all events are sent, the channel is closed
+// if closeWhenDone is set, and otherwise stays open until the context of the
+// watch is done. An event carrying an error ends the script and closes the
+// channel, as real topo watches do. Setting nil events removes the script.
+func (f *FakeConn) SetWatchScript(filePath string, events []*topo.WatchData, delay time.Duration, closeWhenDone bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if events == nil {
+		delete(f.watchScripts, filePath)
+		return
+	}
+	if f.watchScripts == nil {
+		f.watchScripts = map[string]watchScript{}
+	}
+	f.watchScripts[filePath] = watchScript{
+		events:        events,
+		delay:         delay,
+		closeWhenDone: closeWhenDone,
+	}
+}
+
+// replay sends the events of the script to notifications, stopping early if
+// ctx is done, and closes notifications when it returns.
+func (s watchScript) replay(ctx context.Context, notifications chan<- *topo.WatchData) {
+	defer close(notifications)
+	for _, event := range s.events {
+		if s.delay > 0 {
+			timer := time.NewTimer(s.delay)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			}
+		}
+		select {
+		case notifications <- event:
+		case <-ctx.Done():
+			return
+		}
+		if event.Err != nil {
+			return
+		}
+	}
+	if !s.closeWhenDone {
+		<-ctx.Done()
+	}
+}
+
+// watchJitter draws the delays of the events of the watches of a path.
+type watchJitter struct {
+	minDelay, maxDelay time.Duration
+	rand               *rand.Rand
+}
+
+// next returns a delay between minDelay and maxDelay. The caller must hold
+// the mutex of the connection.
+func (j *watchJitter) next() time.Duration {
+	return j.minDelay + time.Duration(j.rand.Int64N(int64(j.maxDelay-j.minDelay)+1))
+}
+
+// SetWatchJitter delays each event sent to the watches of filePath by a
+// random duration between minDelay and maxDelay, as a topo server under load
+// delivers events unevenly. Events are still delivered in order. The delays
+// are drawn from a source seeded with seed, shared by all the watches of the
+// path, so that a test sees the same delays on every run. A zero maxDelay
+// removes the jitter. It applies to the watches established afterwards.
+func (f *FakeConn) SetWatchJitter(filePath string, minDelay, maxDelay time.Duration, seed uint64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if maxDelay == 0 {
+		delete(f.watchJitters, filePath)
+		return
+	}
+	if f.watchJitters == nil {
+		f.watchJitters = map[string]*watchJitter{}
+	}
+	f.watchJitters[filePath] = &watchJitter{
+		minDelay: minDelay,
+		maxDelay: maxDelay,
+		rand:     rand.New(rand.NewPCG(seed, seed)),
+	}
+}
+
+// jitterLocked returns notifications, or a channel relaying its events with
+// the jitter of filePath if it has one. The caller must hold the mutex.
+func (f *FakeConn) jitterLocked(ctx context.Context, filePath string, notifications <-chan *topo.WatchData) <-chan *topo.WatchData {
+	jitter, ok := f.watchJitters[filePath]
+	if !ok {
+		return notifications
+	}
+	jittered := make(chan *topo.WatchData, cap(notifications))
+	go func() {
+		defer func() {
+			// Only close once the watch is gone, which closes notifications.
+			for range notifications {
+			}
+			close(jittered)
+		}()
+		for event := range notifications {
+			f.mu.Lock()
+			delay := jitter.next()
+			f.mu.Unlock()
+			timer := time.NewTimer(delay)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			}
+			select {
+			case jittered <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return jittered
+}
+
+// SetWatchCloseDelay makes the channels of the watches established
+// afterwards close d after their context is done, instead of right away.
+// In between, the channel is open but receives no more events, as with
+// topo clients that take a while to tear a watch down. It lets tests check
+// that a consumer doesn't expect the channel to close as soon as it cancels
+// the watch. The watch stops counting in OutstandingWatches right away.
+func (f *FakeConn) SetWatchCloseDelay(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.watchCloseDelay = d
+}
+
+// SetWatchCoalesceLatest makes the watches miss intermediate values, as with
+// topo backends that coalesce events: a new value replaces the values sent
+// to a watch that its reader has not received yet, so that after a burst of
+// updates the reader only sees the final value. Error events are never
+// dropped. By default, every event is delivered. It lets tests check that
+// consumers don't depend on seeing every intermediate state.
+func (f *FakeConn) SetWatchCoalesceLatest(coalesce bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.watchCoalesceLatest = coalesce
+}
+
+// watchReorder holds back the value events of watches to shuffle them.
+type watchReorder struct {
+	// window is the number of events of a path that are shuffled together.
+	window int
+	rand   *rand.Rand
+	// pending holds the events held back, keyed by the filepath.
+	pending map[string][]*topo.WatchData
+}
+
+// SetWatchReorder holds back the value events sent to the watches of each
+// path until n of them are pending, and then delivers them in a shuffled
+// order, the same for all the watches of the path, as a topo server relaying
+// concurrent writes through several replicas might. It catches consumers
+// that assume the versions they see only grow, rather than comparing them.
+// The order is drawn from a source seeded with seed, so that a test sees the
+// same order on every run. Error events are delivered after the events held
+// back for their path. Use FlushWatchEvents to deliver the events of an
+// incomplete batch. An n of 1 or less, the default, delivers the events in
+// order, after flushing those held back.
+func (f *FakeConn) SetWatchReorder(n int, seed uint64) {
+	f.mu.Lock()
+	defer f.unlock()
+	f.flushAllReorderedLocked()
+	if n <= 1 {
+		f.watchReorder = nil
+		return
+	}
+	f.watchReorder = &watchReorder{
+		window:  n,
+		rand:    rand.New(rand.NewPCG(seed, seed)),
+		pending: map[string][]*topo.WatchData{},
+	}
+}
+
+// FlushWatchEvents delivers, shuffled, the watch events held back by
+// SetWatchReorder.
+func (f *FakeConn) FlushWatchEvents() {
+	f.mu.Lock()
+	defer f.unlock()
+	f.flushAllReorderedLocked()
+}
+
+// flushAllReorderedLocked delivers the events held back for every path, in
+// path order. The caller must hold the mutex.
+func (f *FakeConn) flushAllReorderedLocked() {
+	if f.watchReorder == nil {
+		return
+	}
+	for _, filePath := range slices.Sorted(maps.Keys(f.watchReorder.pending)) {
+		f.flushReorderedLocked(filePath)
+	}
+}
+
+// flushReorderedLocked delivers the events held back for filePath in a
+// shuffled order. The caller must hold the mutex.
+func (f *FakeConn) flushReorderedLocked(filePath string) {
+	pending := f.watchReorder.pending[filePath]
+	delete(f.watchReorder.pending, filePath)
+	f.watchReorder.rand.Shuffle(len(pending), func(i, j int) {
+		pending[i], pending[j] = pending[j], pending[i]
+	})
+	for _, data := range pending {
+		f.deliverLocked(filePath, data)
+	}
+}
+
+// SetWatchInitialViaChannel makes Watch return a nil current value and send
+// it as the first event on the watch channel instead, as some topo
+// implementations do. By default, the current value is returned by Watch.
+func (f *FakeConn) SetWatchInitialViaChannel(viaChannel bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.watchInitialViaChannel = viaChannel
+}
+
+// Watch implements the Conn interface. The current value is read and the
+// watch registered under a single hold of the mutex, so that a concurrent
+// write is either in the current value or delivered on the channel, which
+// unlock does once the mutex is released.
+func (f *FakeConn) Watch(ctx context.Context, filePath string) (*topo.WatchData, <-chan *topo.WatchData, error) {
+	filePath = f.normalizePath(filePath)
+	f.recordOp(Call{Op: CallWatch, Path: filePath}, nil)
+	if err := f.checkOpFailure(CallWatch, filePath); err != nil {
+		return nil, nil, err
+	}
+	if target := f.readRedirect(filePath); target != nil {
+		return target.Watch(ctx, filePath)
+	}
+	if err := f.delay(ctx, CallWatch, filePath); err != nil {
+		return nil, nil, err
+	}
+	// The snapshot and the registration must not be split across two holds
+	// of the mutex, or a write in between would be missed.
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	res, isPresent := f.getResultMap[filePath]
+	if !isPresent {
+		return nil, nil, topo.NewError(topo.NoNode, filePath)
+	}
+	current := &topo.WatchData{
+		Contents: res.contents,
+		Version:  memorytopo.NodeVersion(res.version),
+	}
+
+	notifications := make(chan *topo.WatchData, 100)
+	if f.watchInitialViaChannel {
+		notifications <- current
+		current = nil
+	}
+	if script, ok := f.watchScripts[filePath]; ok {
+		go script.replay(ctx, notifications)
+		return current, f.jitterLocked(ctx, filePath, notifications), nil
+	}
+	watch := &fakeWatch{notifications: notifications, done: ctx.Done()}
+	f.watches[filePath] = append(f.watches[filePath], watch)
+
+	closeDelay := f.watchCloseDelay
+	go func() {
+		<-ctx.Done()
+		f.mu.Lock()
+		i := slices.Index(f.watches[filePath], watch)
+		if i >= 0 {
+			f.watches[filePath] = slices.Delete(f.watches[filePath], i, i+1)
+		}
+		f.mu.Unlock()
+		if i < 0 {
+			return
+		}
+		// The watch no longer receives events, but its channel only closes
+		// after the delay. The close is queued behind the events sent before
+		// the watch was removed.
+		if closeDelay > 0 {
+			time.Sleep(closeDelay)
+		}
+		f.mu.Lock()
+		f.outbox = append(f.outbox, watchSend{watch: watch, close: true})
+		f.unlock()
+	}()
+	return current, f.jitterLocked(ctx, filePath, notifications), nil
+}
+
+// OutstandingWatches returns the total number of watches currently
+// registered on this connection, across all paths.
+func (f *FakeConn) OutstandingWatches() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	count := 0
+	for _, watches := range f.watches {
+		count += len(watches)
+	}
+	return count
+}
+
+// WatchersFor returns the number of watches currently registered on the given path.
+func (f *FakeConn) WatchersFor(filePath string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.watches[filePath])
+}
+
+// WatchPaths returns the sorted list of paths that have at least one watch registered.
+func (f *FakeConn) WatchPaths() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var paths []string
+	for filePath, watches := range f.watches {
+		if len(watches) > 0 {
+			paths = append(paths, filePath)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// WatchRecursive implements the Conn interface. It returns the entries
+// under path, sorted by path, and a channel receiving the changes of the
+// entries under path: their new value when they are created or updated,
+// and a NoNode error when they are deleted. The channel is closed once ctx
+// is done, after the delay set by SetWatchCloseDelay.
+func (f *FakeConn) WatchRecursive(ctx context.Context, path string) ([]*topo.WatchDataRecursive, <-chan *topo.WatchDataRecursive, error) {
+	path = f.normalizePath(path)
+	f.recordOp(Call{Op: CallWatchRecursive, Path: path}, nil)
+	if err := f.checkOpFailure(CallWatchRecursive, path); err != nil {
+		return nil, nil, err
+	}
+	if err := f.delay(ctx, CallWatchRecursive, path); err != nil {
+		return nil, nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var current []*topo.WatchDataRecursive
+	for filePath, res := range f.getResultMap {
+		if !isUnder(filePath, path) {
+			continue
+		}
+		current = append(current, &topo.WatchDataRecursive{
+			Path: filePath,
+			WatchData: topo.WatchData{
+				Contents: res.contents,
+				Version:  memorytopo.NodeVersion(res.version),
+			},
+		})
+	}
+	slices.SortFunc(current, func(a, b *topo.WatchDataRecursive) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+
+	watch := &fakeWatch{recursive: make(chan *topo.WatchDataRecursive, 100), done: ctx.Done()}
+	if f.recursiveWatches == nil {
+		f.recursiveWatches = map[string][]*fakeWatch{}
+	}
+	f.recursiveWatches[path] = append(f.recursiveWatches[path], watch)
+
+	closeDelay := f.watchCloseDelay
+	go func() {
+		<-ctx.Done()
+		f.mu.Lock()
+		i := slices.Index(f.recursiveWatches[path], watch)
+		if i >= 0 {
+			f.recursiveWatches[path] = slices.Delete(f.recursiveWatches[path], i, i+1)
+			if len(f.recursiveWatches[path]) == 0 {
+				delete(f.recursiveWatches, path)
+			}
+		}
+		f.mu.Unlock()
+		if i < 0 {
+			return
+		}
+		if closeDelay > 0 {
+			time.Sleep(closeDelay)
+		}
+		f.mu.Lock()
+		f.outbox = append(f.outbox, watchSend{watch: watch, close: true})
+		f.unlock()
+	}()
+	return current, watch.recursive, nil
+}
+
+// Close implements the Conn interface. It marks the connection closed, so
+// that its later operations fail with an Interrupted error, until a reusable
+// factory hands it out again, see FakeFactory.SetReusable.
+func (f *FakeConn) Close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+}
+
+// reopen undoes Close.
+func (f *FakeConn) reopen() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = false
+}
+
+// checkOpen returns an Interrupted error if the connection is closed. The
+// operations that call checkOpFailure don't need it.
+func (f *FakeConn) checkOpen(filePath string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return topo.NewError(topo.Interrupted, filePath)
+	}
+	return nil
 }
 
 // NewFakeTopoServer creates a new fake topo server
@@ -0,0 +1,2106 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package faketopo
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"vitess.io/vitess/go/vt/topo"
+	"vitess.io/vitess/go/vt/topo/memorytopo"
+	"vitess.io/vitess/go/vt/topo/topoproto"
+
+	topodatapb "vitess.io/vitess/go/vt/proto/topodata"
+)
+
+func TestWatchersFor(t *testing.T) {
+	ctx := context.Background()
+	conn := NewFakeConnection()
+	_, err := conn.Create(ctx, "/keyspaces/ks1/Keyspace", []byte("ks1"))
+	require.NoError(t, err)
+	_, err = conn.Create(ctx, "/keyspaces/ks2/Keyspace", []byte("ks2"))
+	require.NoError(t, err)
+
+	require.Equal(t, 0, conn.OutstandingWatches())
+	require.Empty(t, conn.WatchPaths())
+
+	ctx1, cancel1 := context.WithCancel(ctx)
+	_, ch1, err := conn.Watch(ctx1, "/keyspaces/ks1/Keyspace")
+	require.NoError(t, err)
+	ctx2, cancel2 := context.WithCancel(ctx)
+	defer cancel2()
+	_, _, err = conn.Watch(ctx2, "/keyspaces/ks1/Keyspace")
+	require.NoError(t, err)
+	_, _, err = conn.Watch(ctx2, "/keyspaces/ks2/Keyspace")
+	require.NoError(t, err)
+
+	require.Equal(t, 3, conn.OutstandingWatches())
+	require.Equal(t, 2, conn.WatchersFor("/keyspaces/ks1/Keyspace"))
+	require.Equal(t, 1, conn.WatchersFor("/keyspaces/ks2/Keyspace"))
+	require.Equal(t, 0, conn.WatchersFor("/keyspaces/ks3/Keyspace"))
+	require.Equal(t, []string{"/keyspaces/ks1/Keyspace", "/keyspaces/ks2/Keyspace"}, conn.WatchPaths())
+
+	// Cancelling the first watch closes its channel and unregisters it.
+	cancel1()
+	for range ch1 {
+	}
+	require.Equal(t, 1, conn.WatchersFor("/keyspaces/ks1/Keyspace"))
+	require.Equal(t, 2, conn.OutstandingWatches())
+}
+
+func TestAddErrorForPath(t *testing.T) {
+	ctx := context.Background()
+	conn := NewFakeConnection()
+	versionA, err := conn.Create(ctx, "keyspaces/a/Keyspace", []byte("a"))
+	require.NoError(t, err)
+	_, err = conn.Create(ctx, "keyspaces/b/Keyspace", []byte("b"))
+	require.NoError(t, err)
+
+	// The error of a path is only consumed by a Get of that path, before
+	// the global ones.
+	conn.AddGetError(true)
+	conn.AddGetErrorForPath("keyspaces/a/Keyspace", true)
+	_, _, err = conn.Get(ctx, "keyspaces/b/Keyspace")
+	require.True(t, topo.IsErrType(err, topo.Timeout))
+	_, _, err = conn.Get(ctx, "keyspaces/b/Keyspace")
+	require.NoError(t, err)
+	_, _, err = conn.Get(ctx, "keyspaces/a/Keyspace")
+	require.True(t, topo.IsErrType(err, topo.Timeout))
+	_, _, err = conn.Get(ctx, "keyspaces/a/Keyspace")
+	require.NoError(t, err)
+
+	conn.AddListResult("keyspaces/a", nil)
+	conn.AddListResult("keyspaces/b", nil)
+	conn.AddListErrorForPath("keyspaces/a", true)
+	_, err = conn.List(ctx, "keyspaces/b")
+	require.NoError(t, err)
+	_, err = conn.List(ctx, "keyspaces/a")
+	require.True(t, topo.IsErrType(err, topo.Timeout))
+
+	// A failed update of a path can still persist its write.
+	conn.AddUpdateErrorForPath("keyspaces/a/Keyspace", true, true)
+	_, err = conn.Update(ctx, "keyspaces/b/Keyspace", []byte("b2"), versionA)
+	require.NoError(t, err)
+	_, err = conn.Update(ctx, "keyspaces/a/Keyspace", []byte("a2"), versionA)
+	require.True(t, topo.IsErrType(err, topo.Timeout))
+	contents, _, err := conn.Get(ctx, "keyspaces/a/Keyspace")
+	require.NoError(t, err)
+	require.Equal(t, []byte("a2"), contents)
+}
+
+func TestGetRaw(t *testing.T) {
+	ctx := context.Background()
+	conn := NewFakeConnection()
+	_, _, ok := conn.GetRaw("/keyspaces/ks1/Keyspace")
+	require.False(t, ok)
+
+	_, err := conn.Create(ctx, "/keyspaces/ks1/Keyspace", []byte("ks1"))
+	require.NoError(t, err)
+	conn.AddGetError(true)
+
+	contents, version, ok := conn.GetRaw("/keyspaces/ks1/Keyspace")
+	require.True(t, ok)
+	require.Equal(t, []byte("ks1"), contents)
+	require.EqualValues(t, 1, version)
+
+	// The returned slice is a copy of the stored contents.
+	contents[0] = 'X'
+	contents, _, _ = conn.GetRaw("/keyspaces/ks1/Keyspace")
+	require.Equal(t, []byte("ks1"), contents)
+
+	// The queued error was left for the next Get.
+	_, _, err = conn.Get(ctx, "/keyspaces/ks1/Keyspace")
+	require.True(t, topo.IsErrType(err, topo.Timeout))
+}
+
+func TestWriteCount(t *testing.T) {
+	ctx := context.Background()
+	conn := NewFakeConnection()
+	require.Zero(t, conn.WriteCount("/keyspaces/ks1/Keyspace"))
+
+	version, err := conn.Create(ctx, "/keyspaces/ks1/Keyspace", []byte("ks1"))
+	require.NoError(t, err)
+	_, err = conn.Update(ctx, "/keyspaces/ks1/Keyspace", []byte("ks1-updated"), version)
+	require.NoError(t, err)
+	_, err = conn.Create(ctx, "/keyspaces/ks2/Keyspace", []byte("ks2"))
+	require.NoError(t, err)
+	_, _, err = conn.Get(ctx, "/keyspaces/ks1/Keyspace")
+	require.NoError(t, err)
+
+	// Failed writes are counted too.
+	conn.FailNextWrite("/keyspaces/ks1/Keyspace", nil)
+	_, err = conn.Update(ctx, "/keyspaces/ks1/Keyspace", []byte("ks1-failed"), nil)
+	require.Error(t, err)
+
+	require.Equal(t, 3, conn.WriteCount("/keyspaces/ks1/Keyspace"))
+	require.Equal(t, 1, conn.WriteCount("/keyspaces/ks2/Keyspace"))
+
+	conn.ResetWriteCounts()
+	require.Zero(t, conn.WriteCount("/keyspaces/ks1/Keyspace"))
+	_, err = conn.Update(ctx, "/keyspaces/ks1/Keyspace", []byte("ks1-again"), nil)
+	require.NoError(t, err)
+	require.Equal(t, 1, conn.WriteCount("/keyspaces/ks1/Keyspace"))
+}
+
+func TestUpdateVersions(t *testing.T) {
+	ctx := context.Background()
+	conn := NewFakeConnection()
+	const path = "keyspaces/ks1/Keyspace"
+	v1, err := conn.Create(ctx, path, []byte("v1"))
+	require.NoError(t, err)
+
+	// Each successful write bumps the version, and a stale version fails.
+	v2, err := conn.Update(ctx, path, []byte("v2"), v1)
+	require.NoError(t, err)
+	require.Equal(t, memorytopo.NodeVersion(2), v2)
+	_, err = conn.Update(ctx, path, []byte("stale"), v1)
+	require.True(t, topo.IsErrType(err, topo.BadVersion), "unexpected error: %v", err)
+	contents, version, err := conn.Get(ctx, path)
+	require.NoError(t, err)
+	require.Equal(t, []byte("v2"), contents)
+	require.Equal(t, v2, version)
+
+	// A failed write that persists still bumps the version.
+	conn.AddUpdateError(true, true)
+	_, err = conn.Update(ctx, path, []byte("v3"), v2)
+	require.True(t, topo.IsErrType(err, topo.Timeout), "unexpected error: %v", err)
+	contents, version, err = conn.Get(ctx, path)
+	require.NoError(t, err)
+	require.Equal(t, []byte("v3"), contents)
+	require.Equal(t, memorytopo.NodeVersion(3), version)
+
+	// A nil version writes regardless of the stored one, and bumps it.
+	version, err = conn.Update(ctx, path, []byte("v4"), nil)
+	require.NoError(t, err)
+	require.Equal(t, memorytopo.NodeVersion(4), version)
+	contents, err = conn.GetVersion(ctx, path, 4)
+	require.NoError(t, err)
+	require.Equal(t, []byte("v4"), contents)
+
+	// A nil version creates a missing node at version 1.
+	version, err = conn.Update(ctx, "keyspaces/ks2/Keyspace", []byte("ks2"), nil)
+	require.NoError(t, err)
+	require.Equal(t, memorytopo.NodeVersion(1), version)
+
+	// With the checks disabled, any version is accepted and left unchanged.
+	conn.SetCheckVersions(false)
+	_, err = conn.Update(ctx, path, []byte("v5"), v2)
+	require.NoError(t, err)
+	contents, version, err = conn.Get(ctx, path)
+	require.NoError(t, err)
+	require.Equal(t, []byte("v5"), contents)
+	require.Equal(t, memorytopo.NodeVersion(4), version)
+}
+
+func TestDelete(t *testing.T) {
+	ctx := context.Background()
+	conn := NewFakeConnection()
+	const path = "keyspaces/ks1/Keyspace"
+	require.True(t, topo.IsErrType(conn.Delete(ctx, path, nil), topo.NoNode))
+
+	v1, err := conn.Create(ctx, path, []byte("v1"))
+	require.NoError(t, err)
+	_, err = conn.Update(ctx, path, []byte("v2"), v1)
+	require.NoError(t, err)
+	require.True(t, topo.IsErrType(conn.Delete(ctx, path, v1), topo.BadVersion))
+
+	// A queued error fails the delete and leaves the node in place.
+	conn.AddDeleteError(true)
+	require.True(t, topo.IsErrType(conn.Delete(ctx, path, nil), topo.Timeout))
+	current, version, err := conn.Get(ctx, path)
+	require.NoError(t, err)
+	require.Equal(t, []byte("v2"), current)
+
+	// The watches observe the deletion, and are closed.
+	_, ch, err := conn.Watch(ctx, path)
+	require.NoError(t, err)
+	require.NoError(t, conn.Delete(ctx, path, version))
+	wd := <-ch
+	require.True(t, topo.IsErrType(wd.Err, topo.NoNode), "unexpected event: %v", wd)
+	_, ok := <-ch
+	require.False(t, ok)
+	_, _, err = conn.Get(ctx, path)
+	require.True(t, topo.IsErrType(err, topo.NoNode))
+
+	// An unversioned delete removes the node whatever its version.
+	_, err = conn.Create(ctx, path, []byte("v3"))
+	require.NoError(t, err)
+	require.NoError(t, conn.Delete(ctx, path, nil))
+	require.True(t, topo.IsErrType(conn.Delete(ctx, path, nil), topo.NoNode))
+}
+
+func TestDeleteBehindBack(t *testing.T) {
+	ctx := context.Background()
+	factory := NewFakeTopoFactory()
+	conn := factory.cells[topo.GlobalCell][0]
+	ts := NewFakeTopoServer(ctx, factory)
+	_, err := conn.Create(ctx, "keyspaces/ks1/shards/0/Shard", []byte{})
+	require.NoError(t, err)
+
+	// The shard is deleted while UpdateShardFields is between its Get and
+	// its Update, which must give up rather than retry or recreate it.
+	_, err = ts.UpdateShardFields(ctx, "ks1", "0", func(si *topo.ShardInfo) error {
+		conn.DeleteBehindBack("keyspaces/ks1/shards/0/Shard")
+		si.IsPrimaryServing = true
+		return nil
+	})
+	require.True(t, topo.IsErrType(err, topo.NoNode), "unexpected error: %v", err)
+	_, _, err = conn.Get(ctx, "keyspaces/ks1/shards/0/Shard")
+	require.True(t, topo.IsErrType(err, topo.NoNode), "unexpected error: %v", err)
+}
+
+func TestSetCorruptData(t *testing.T) {
+	ctx := context.Background()
+	factory := NewFakeTopoFactory()
+	conn := factory.cells[topo.GlobalCell][0]
+	ts := NewFakeTopoServer(ctx, factory)
+	require.NoError(t, ts.CreateKeyspace(ctx, "ks1", &topodatapb.Keyspace{DurabilityPolicy: "semi_sync"}))
+	ki, err := ts.GetKeyspace(ctx, "ks1")
+	require.NoError(t, err)
+	require.Equal(t, "semi_sync", ki.DurabilityPolicy)
+
+	// A truncated varint can't be unmarshaled into a Keyspace.
+	conn.SetCorruptData("keyspaces/ks1/Keyspace", []byte{0x0a, 0xff})
+	_, err = ts.GetKeyspace(ctx, "ks1")
+	require.ErrorContains(t, err, "bad keyspace data")
+
+	// Restoring a valid record makes the keyspace readable again.
+	data, err := (&topodatapb.Keyspace{DurabilityPolicy: "none"}).MarshalVT()
+	require.NoError(t, err)
+	_, err = conn.Update(ctx, "keyspaces/ks1/Keyspace", data, nil)
+	require.NoError(t, err)
+	ki, err = ts.GetKeyspace(ctx, "ks1")
+	require.NoError(t, err)
+	require.Equal(t, "none", ki.DurabilityPolicy)
+}
+
+func TestWatchDeliveryWithoutMutex(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	conn := NewFakeConnection()
+	const path = "/keyspaces/ks1/Keyspace"
+	version, err := conn.Create(ctx, path, []byte("0"))
+	require.NoError(t, err)
+
+	// writeAll updates path more times than a watch buffers. The events
+	// that don't fit are backlogged until the watch is read or gone.
+	const updates = 250
+	writeAll := func() <-chan struct{} {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for i := 1; i <= updates; i++ {
+				v, err := conn.Update(ctx, path, []byte(strconv.Itoa(i)), version)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				version = v
+			}
+		}()
+		return done
+	}
+
+	watchCtx, cancelWatch := context.WithCancel(ctx)
+	_, ch, err := conn.Watch(watchCtx, path)
+	require.NoError(t, err)
+	done := writeAll()
+	require.Eventually(t, func() bool { return len(ch) == cap(ch) }, 5*time.Second, time.Millisecond)
+
+	// The backlogged events don't hold the connection.
+	_, _, err = conn.Get(ctx, path)
+	require.NoError(t, err)
+	_, err = conn.Create(ctx, "/keyspaces/ks2/Keyspace", []byte("ks2"))
+	require.NoError(t, err)
+
+	for i := 1; i <= updates; i++ {
+		select {
+		case wd := <-ch:
+			require.Equal(t, strconv.Itoa(i), string(wd.Contents))
+		case <-ctx.Done():
+			t.Fatalf("timed out waiting for update %d", i)
+		}
+	}
+	<-done
+	cancelWatch()
+	for range ch {
+	}
+
+	// The backlog of a watch whose context is done is dropped, and the
+	// watch is closed.
+	watchCtx, cancelWatch = context.WithCancel(ctx)
+	_, ch, err = conn.Watch(watchCtx, path)
+	require.NoError(t, err)
+	done = writeAll()
+	require.Eventually(t, func() bool { return len(ch) == cap(ch) }, 5*time.Second, time.Millisecond)
+	cancelWatch()
+	<-done
+	for range ch {
+	}
+}
+
+func TestWatchUpdateFromReader(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	conn := NewFakeConnection()
+	const path = "keyspaces/ks1/Keyspace"
+	version, err := conn.Create(ctx, path, []byte("0"))
+	require.NoError(t, err)
+	_, ch, err := conn.Watch(ctx, path)
+	require.NoError(t, err)
+
+	// The goroutine reading the watch writes to the node more times than
+	// the watch buffers, before reading it. None of its writes waits for it
+	// to read, and it then receives every event in order.
+	const updates = 300
+	for i := 1; i <= updates; i++ {
+		version, err = conn.Update(ctx, path, []byte(strconv.Itoa(i)), version)
+		require.NoError(t, err)
+	}
+	for i := 1; i <= updates; i++ {
+		select {
+		case wd := <-ch:
+			require.NoError(t, wd.Err)
+			require.Equal(t, strconv.Itoa(i), string(wd.Contents))
+		case <-ctx.Done():
+			t.Fatalf("timed out waiting for update %d", i)
+		}
+	}
+}
+
+func TestWatchConcurrentUpdate(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	const path = "keyspaces/ks1/Keyspace"
+	for range 200 {
+		conn := NewFakeConnection()
+		version, err := conn.Create(ctx, path, []byte("old"))
+		require.NoError(t, err)
+
+		// The update races with the registration of the watch, and must be
+		// seen either in the current value or on the channel.
+		start := make(chan struct{})
+		updated := make(chan error)
+		go func() {
+			<-start
+			_, err := conn.Update(ctx, path, []byte("new"), version)
+			updated <- err
+		}()
+		close(start)
+		current, ch, err := conn.Watch(ctx, path)
+		require.NoError(t, err)
+		require.NoError(t, <-updated)
+		if string(current.Contents) == "new" {
+			continue
+		}
+		select {
+		case wd := <-ch:
+			require.Equal(t, "new", string(wd.Contents))
+		case <-ctx.Done():
+			t.Fatal("the concurrent update was neither in the current value nor delivered")
+		}
+	}
+}
+
+func TestWatchRecursive(t *testing.T) {
+	ctx := context.Background()
+	conn := NewFakeConnection()
+	v1, err := conn.Create(ctx, "keyspaces/ks1/Keyspace", []byte("ks1"))
+	require.NoError(t, err)
+	_, err = conn.Create(ctx, "keyspaces/ks1/shards/0/Shard", []byte("0"))
+	require.NoError(t, err)
+	_, err = conn.Create(ctx, "keyspaces/ks10/Keyspace", []byte("ks10"))
+	require.NoError(t, err)
+
+	watchCtx, cancel := context.WithCancel(ctx)
+	current, changes, err := conn.WatchRecursive(watchCtx, "keyspaces/ks1")
+	require.NoError(t, err)
+	require.Equal(t, []*topo.WatchDataRecursive{
+		{Path: "keyspaces/ks1/Keyspace", WatchData: topo.WatchData{Contents: []byte("ks1"), Version: memorytopo.NodeVersion(1)}},
+		{Path: "keyspaces/ks1/shards/0/Shard", WatchData: topo.WatchData{Contents: []byte("0"), Version: memorytopo.NodeVersion(1)}},
+	}, current)
+
+	// Changes under other paths, even sharing the prefix, aren't sent.
+	_, err = conn.Update(ctx, "keyspaces/ks10/Keyspace", []byte("ks10-updated"), v1)
+	require.NoError(t, err)
+	_, err = conn.Update(ctx, "keyspaces/ks1/Keyspace", []byte("ks1-updated"), v1)
+	require.NoError(t, err)
+	wd := <-changes
+	require.Equal(t, "keyspaces/ks1/Keyspace", wd.Path)
+	require.Equal(t, []byte("ks1-updated"), wd.Contents)
+	require.NoError(t, wd.Err)
+
+	_, err = conn.Create(ctx, "keyspaces/ks1/shards/1/Shard", []byte("1"))
+	require.NoError(t, err)
+	wd = <-changes
+	require.Equal(t, "keyspaces/ks1/shards/1/Shard", wd.Path)
+	require.Equal(t, []byte("1"), wd.Contents)
+
+	// Unconditional updates are sent too.
+	_, err = conn.Update(ctx, "keyspaces/ks1/shards/1/Shard", []byte("1-updated"), nil)
+	require.NoError(t, err)
+	wd = <-changes
+	require.Equal(t, "keyspaces/ks1/shards/1/Shard", wd.Path)
+	require.Equal(t, []byte("1-updated"), wd.Contents)
+
+	require.NoError(t, conn.Delete(ctx, "keyspaces/ks1/shards/0/Shard", nil))
+	wd = <-changes
+	require.Equal(t, "keyspaces/ks1/shards/0/Shard", wd.Path)
+	require.True(t, topo.IsErrType(wd.Err, topo.NoNode), "unexpected event: %v", wd)
+
+	cancel()
+	_, ok := <-changes
+	require.False(t, ok)
+}
+
+func TestWatchHistory(t *testing.T) {
+	ctx := context.Background()
+	conn := NewFakeConnection()
+	conn.SetRecordWatchHistory(true)
+	oldPath, newPath := "keyspaces/ks1/Keyspace", "keyspaces/ks2/Keyspace"
+
+	_, err := conn.Create(ctx, oldPath, []byte("1"))
+	require.NoError(t, err)
+	version, err := conn.Update(ctx, oldPath, []byte("2"), memorytopo.NodeVersion(1))
+	require.NoError(t, err)
+	_, err = conn.Update(ctx, oldPath, []byte("3"), version)
+	require.NoError(t, err)
+	conn.EmitWatch(oldPath, &topo.WatchData{Contents: []byte("2"), Version: memorytopo.NodeVersion(2)})
+	require.NoError(t, conn.MoveNode(oldPath, newPath))
+
+	// Events are recorded even though no watch was established.
+	history := conn.WatchHistory(oldPath)
+	require.Len(t, history, 4)
+	require.Equal(t, &topo.WatchData{Contents: []byte("2"), Version: memorytopo.NodeVersion(2)}, history[0])
+	require.Equal(t, &topo.WatchData{Contents: []byte("3"), Version: memorytopo.NodeVersion(3)}, history[1])
+	require.Equal(t, &topo.WatchData{Contents: []byte("2"), Version: memorytopo.NodeVersion(2)}, history[2])
+	require.True(t, topo.IsErrType(history[3].Err, topo.NoNode))
+	require.Equal(t, []*topo.WatchData{{Contents: []byte("3"), Version: memorytopo.NodeVersion(4)}}, conn.WatchHistory(newPath))
+
+	conn.SetRecordWatchHistory(false)
+	require.Empty(t, conn.WatchHistory(oldPath))
+}
+
+func TestMoveNode(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	conn := NewFakeConnection()
+	oldPath, newPath := "keyspaces/ks1/Keyspace", "keyspaces/ks2/Keyspace"
+	_, err := conn.Create(ctx, oldPath, []byte("ks"))
+	require.NoError(t, err)
+	_, oldChanges, err := conn.Watch(ctx, oldPath)
+	require.NoError(t, err)
+
+	require.NoError(t, conn.MoveNode(oldPath, newPath))
+
+	// The watch of the old path sees a deletion, and is closed.
+	event := <-oldChanges
+	require.True(t, topo.IsErrType(event.Err, topo.NoNode), "unexpected event: %v", event)
+	_, ok := <-oldChanges
+	require.False(t, ok)
+	require.Zero(t, conn.WatchersFor(oldPath))
+	_, _, err = conn.Get(ctx, oldPath)
+	require.True(t, topo.IsErrType(err, topo.NoNode), "unexpected error: %v", err)
+
+	// The contents moved with a new version.
+	contents, version, err := conn.Get(ctx, newPath)
+	require.NoError(t, err)
+	require.Equal(t, "ks", string(contents))
+	require.Equal(t, memorytopo.NodeVersion(2), version)
+
+	_, err = conn.Create(ctx, oldPath, []byte("other"))
+	require.NoError(t, err)
+	require.True(t, topo.IsErrType(conn.MoveNode(newPath, oldPath), topo.NodeExists))
+	require.True(t, topo.IsErrType(conn.MoveNode("keyspaces/ks3/Keyspace", oldPath), topo.NoNode))
+
+	// A watch established on the destination before its node was deleted
+	// sees the moved contents.
+	_, newChanges, err := conn.Watch(ctx, oldPath)
+	require.NoError(t, err)
+	conn.DeleteBehindBack(oldPath)
+	require.NoError(t, conn.MoveNode(newPath, oldPath))
+	event = <-newChanges
+	require.NoError(t, event.Err)
+	require.Equal(t, "ks", string(event.Contents))
+	require.Equal(t, memorytopo.NodeVersion(3), event.Version)
+}
+
+func TestListDir(t *testing.T) {
+	ctx := context.Background()
+	conn := NewFakeConnection()
+	for _, filePath := range []string{"keyspaces/ks1/Keyspace", "keyspaces/ks1/shards/0/Shard", "keyspaces/ks10/Keyspace", "keyspaces"} {
+		_, err := conn.Create(ctx, filePath, []byte("x"))
+		require.NoError(t, err)
+	}
+	conn.CreateEphemeral("keyspaces/ks1/elections/leader", []byte("me"), time.Hour)
+	conn.CreateEphemeral("keyspaces/ks1/locks/lock", []byte("me"), time.Hour)
+	_, err := conn.Create(ctx, "keyspaces/ks1/locks/marker", []byte("x"))
+	require.NoError(t, err)
+
+	// The node at the listed path itself isn't an entry, and a trailing
+	// slash is ignored. Entries are sorted, and typed without full.
+	for _, dirPath := range []string{"keyspaces", "keyspaces/"} {
+		entries, err := conn.ListDir(ctx, dirPath, false)
+		require.NoError(t, err)
+		require.Equal(t, []topo.DirEntry{
+			{Name: "ks1", Type: topo.TypeDirectory},
+			{Name: "ks10", Type: topo.TypeDirectory},
+		}, entries)
+	}
+
+	// Only full listings tell the ephemeral entries apart: a directory is
+	// ephemeral when all its nodes are.
+	entries, err := conn.ListDir(ctx, "keyspaces/ks1", false)
+	require.NoError(t, err)
+	require.Equal(t, []topo.DirEntry{
+		{Name: "Keyspace", Type: topo.TypeFile},
+		{Name: "elections", Type: topo.TypeDirectory},
+		{Name: "locks", Type: topo.TypeDirectory},
+		{Name: "shards", Type: topo.TypeDirectory},
+	}, entries)
+	entries, err = conn.ListDir(ctx, "keyspaces/ks1", true)
+	require.NoError(t, err)
+	require.Equal(t, []topo.DirEntry{
+		{Name: "Keyspace", Type: topo.TypeFile},
+		{Name: "elections", Type: topo.TypeDirectory, Ephemeral: true},
+		{Name: "locks", Type: topo.TypeDirectory},
+		{Name: "shards", Type: topo.TypeDirectory},
+	}, entries)
+	entries, err = conn.ListDir(ctx, "keyspaces/ks1/elections", true)
+	require.NoError(t, err)
+	require.Equal(t, []topo.DirEntry{{Name: "leader", Type: topo.TypeFile, Ephemeral: true}}, entries)
+
+	_, err = conn.ListDir(ctx, "keyspaces/ks1/Keyspace", false)
+	require.True(t, topo.IsErrType(err, topo.NoNode))
+	_, err = conn.ListDir(ctx, "keyspaces/ks2", false)
+	require.True(t, topo.IsErrType(err, topo.NoNode))
+}
+
+func TestCreateEphemeral(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	conn := NewFakeConnection()
+	start := time.Now()
+	conn.SetClock(func() time.Time { return start })
+	const path = "/cells/zone1/tablets/zone1-0000000100/Tablet"
+	conn.CreateEphemeral(path, []byte("tablet"), 10*time.Second)
+	_, changes, err := conn.Watch(ctx, path)
+	require.NoError(t, err)
+
+	// The node lives until its TTL has elapsed.
+	conn.AdvanceClock(9 * time.Second)
+	contents, _, err := conn.Get(ctx, path)
+	require.NoError(t, err)
+	require.Equal(t, "tablet", string(contents))
+	require.Empty(t, changes)
+
+	// On expiry, the watch sees a deletion, and is closed.
+	conn.AdvanceClock(time.Second)
+	event := <-changes
+	require.True(t, topo.IsErrType(event.Err, topo.NoNode), "unexpected event: %v", event)
+	_, ok := <-changes
+	require.False(t, ok)
+	_, _, err = conn.Get(ctx, path)
+	require.True(t, topo.IsErrType(err, topo.NoNode), "unexpected error: %v", err)
+
+	// Expiry is also seen through an external clock, and nodes created with
+	// Create are persistent.
+	conn.CreateEphemeral(path, []byte("tablet"), 10*time.Second)
+	_, err = conn.Create(ctx, "/cells/zone1/CellInfo", []byte("cell"))
+	require.NoError(t, err)
+	start = start.Add(time.Minute)
+	_, _, err = conn.Get(ctx, path)
+	require.True(t, topo.IsErrType(err, topo.NoNode), "unexpected error: %v", err)
+	_, _, err = conn.Get(ctx, "/cells/zone1/CellInfo")
+	require.NoError(t, err)
+}
+
+func TestAddGetResults(t *testing.T) {
+	ctx := context.Background()
+	conn := NewFakeConnection()
+	filePath := "/keyspaces/ks1/shards/-80/Shard"
+	_, err := conn.Create(ctx, filePath, []byte("serving=false"))
+	require.NoError(t, err)
+	conn.AddGetResults(filePath, [][]byte{[]byte("serving=false"), []byte("migrating"), []byte("serving=true")})
+
+	// poll is a typical poller, waiting for the shard to start serving.
+	var observed []string
+	var versions []topo.Version
+	poll := func() {
+		for range 10 {
+			contents, version, err := conn.Get(ctx, filePath)
+			require.NoError(t, err)
+			observed = append(observed, string(contents))
+			versions = append(versions, version)
+			if string(contents) == "serving=true" {
+				return
+			}
+		}
+	}
+	poll()
+	require.Equal(t, []string{"serving=false", "migrating", "serving=true"}, observed)
+	require.Equal(t, []topo.Version{memorytopo.NodeVersion(2), memorytopo.NodeVersion(3), memorytopo.NodeVersion(4)}, versions)
+
+	// The last result sticks.
+	contents, version, err := conn.Get(ctx, filePath)
+	require.NoError(t, err)
+	require.Equal(t, "serving=true", string(contents))
+	require.Equal(t, memorytopo.NodeVersion(4), version)
+}
+
+func TestStaleReadsRetryConverges(t *testing.T) {
+	ctx := context.Background()
+	conn := NewFakeConnection()
+	filePath := "/keyspaces/ks1/counter"
+	_, err := conn.Create(ctx, filePath, []byte("1"))
+	require.NoError(t, err)
+	_, version, err := conn.Get(ctx, filePath)
+	require.NoError(t, err)
+	_, err = conn.Update(ctx, filePath, []byte("2"), version)
+	require.NoError(t, err)
+
+	// The next two reads lag behind the latest write.
+	conn.SetStaleReads(filePath, 2)
+
+	// increment is a typical Get-then-Update loop, retrying on BadVersion.
+	retries := 0
+	increment := func() error {
+		for {
+			contents, version, err := conn.Get(ctx, filePath)
+			if err != nil {
+				return err
+			}
+			n, err := strconv.Atoi(string(contents))
+			if err != nil {
+				return err
+			}
+			_, err = conn.Update(ctx, filePath, []byte(strconv.Itoa(n+1)), version)
+			if !topo.IsErrType(err, topo.BadVersion) {
+				return err
+			}
+			retries++
+		}
+	}
+	require.NoError(t, increment())
+	require.Equal(t, 2, retries)
+
+	contents, version2, ok := conn.GetRaw(filePath)
+	require.True(t, ok)
+	require.Equal(t, []byte("3"), contents)
+	require.EqualValues(t, 3, version2)
+}
+
+func TestSetVisibilityDelay(t *testing.T) {
+	ctx := context.Background()
+	conn := NewFakeConnection()
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	conn.SetClock(func() time.Time { return start })
+	conn.SetVisibilityDelay(10 * time.Second)
+	filePath := "keyspaces/ks1/Keyspace"
+
+	// A created node isn't visible until the delay elapses.
+	_, err := conn.Create(ctx, filePath, []byte("1"))
+	require.NoError(t, err)
+	_, _, err = conn.Get(ctx, filePath)
+	require.True(t, topo.IsErrType(err, topo.NoNode), "unexpected error: %v", err)
+	conn.AdvanceClock(10 * time.Second)
+	contents, version, err := conn.Get(ctx, filePath)
+	require.NoError(t, err)
+	require.Equal(t, []byte("1"), contents)
+
+	// Reads keep returning the old value after an update, and each update
+	// extends the window.
+	version, err = conn.Update(ctx, filePath, []byte("2"), version)
+	require.NoError(t, err)
+	conn.AdvanceClock(5 * time.Second)
+	_, err = conn.Update(ctx, filePath, []byte("3"), version)
+	require.NoError(t, err)
+	conn.AdvanceClock(5 * time.Second)
+	contents, _, err = conn.Get(ctx, filePath)
+	require.NoError(t, err)
+	require.Equal(t, []byte("1"), contents)
+
+	// Once the clock moves past the window, reads converge on the latest
+	// write.
+	conn.AdvanceClock(5 * time.Second)
+	contents, _, err = conn.Get(ctx, filePath)
+	require.NoError(t, err)
+	require.Equal(t, []byte("3"), contents)
+}
+
+func TestFailNextWriteCompensation(t *testing.T) {
+	ctx := context.Background()
+	conn := NewFakeConnection()
+	recordPath, indexPath := "/keyspaces/ks1/shards/-80/Shard", "/keyspaces/ks1/Keyspace"
+	_, err := conn.Create(ctx, recordPath, []byte("serving=false"))
+	require.NoError(t, err)
+	_, err = conn.Create(ctx, indexPath, []byte("shards="))
+	require.NoError(t, err)
+
+	// setServing writes the shard record, then the keyspace index, and
+	// restores the record if the index can't be written.
+	setServing := func() error {
+		previous, version, err := conn.Get(ctx, recordPath)
+		if err != nil {
+			return err
+		}
+		version, err = conn.Update(ctx, recordPath, []byte("serving=true"), version)
+		if err != nil {
+			return err
+		}
+		if _, err := conn.Update(ctx, indexPath, []byte("shards=-80"), nil); err != nil {
+			if _, rerr := conn.Update(ctx, recordPath, previous, version); rerr != nil {
+				return fmt.Errorf("%v, and restoring %s failed: %v", err, recordPath, rerr)
+			}
+			return err
+		}
+		return nil
+	}
+
+	// Without compensation, the failure would leave the record updated and
+	// the index stale.
+	conn.FailNextWrite(indexPath, nil)
+	err = setServing()
+	require.True(t, topo.IsErrType(err, topo.Timeout))
+	contents, _, _ := conn.GetRaw(recordPath)
+	require.Equal(t, []byte("serving=false"), contents)
+	contents, _, _ = conn.GetRaw(indexPath)
+	require.Equal(t, []byte("shards="), contents)
+
+	// The failure is consumed, so a retry goes through.
+	require.NoError(t, setServing())
+	contents, _, _ = conn.GetRaw(recordPath)
+	require.Equal(t, []byte("serving=true"), contents)
+	contents, _, _ = conn.GetRaw(indexPath)
+	require.Equal(t, []byte("shards=-80"), contents)
+
+	// A failed Create persists nothing either.
+	conn.FailNextWrite("/keyspaces/ks1/shards/80-/Shard", topo.NewError(topo.Interrupted, "create"))
+	_, err = conn.Create(ctx, "/keyspaces/ks1/shards/80-/Shard", []byte("serving=true"))
+	require.True(t, topo.IsErrType(err, topo.Interrupted))
+	_, _, ok := conn.GetRaw("/keyspaces/ks1/shards/80-/Shard")
+	require.False(t, ok)
+}
+
+func TestListPage(t *testing.T) {
+	ctx := context.Background()
+	conn := NewFakeConnection()
+	var kvs []topo.KVInfo
+	for i := 0; i < 5; i++ {
+		kvs = append(kvs, topo.KVInfo{Key: []byte(fmt.Sprintf("/tablets/%d", i)), Value: []byte{byte(i)}})
+	}
+	conn.AddListResult("/tablets", kvs)
+
+	// Without a page size everything is returned at once.
+	page, token, err := conn.ListPage(ctx, "/tablets", "")
+	require.NoError(t, err)
+	require.Equal(t, kvs, page)
+	require.Empty(t, token)
+
+	conn.SetListPageSize(2)
+	var got []topo.KVInfo
+	pages := 0
+	for {
+		page, token, err = conn.ListPage(ctx, "/tablets", token)
+		require.NoError(t, err)
+		require.LessOrEqual(t, len(page), 2)
+		got = append(got, page...)
+		pages++
+		if token == "" {
+			break
+		}
+	}
+	require.Equal(t, kvs, got)
+	require.Equal(t, 3, pages)
+
+	// List itself is unaffected by the page size.
+	all, err := conn.List(ctx, "/tablets")
+	require.NoError(t, err)
+	require.Len(t, all, 5)
+
+	_, _, err = conn.ListPage(ctx, "/tablets", "bogus")
+	require.True(t, topo.IsErrType(err, topo.NoNode))
+}
+
+func TestFailListPageAfter(t *testing.T) {
+	ctx := context.Background()
+	conn := NewFakeConnection()
+	var kvs []topo.KVInfo
+	for i := 0; i < 5; i++ {
+		kvs = append(kvs, topo.KVInfo{Key: []byte(fmt.Sprintf("/tablets/%d", i)), Value: []byte{byte(i)}})
+	}
+	conn.AddListResult("/tablets", kvs)
+	conn.SetListPageSize(2)
+
+	// listAll pages through the listing starting at token, and returns what
+	// it got along with the token to resume from when it fails.
+	listAll := func(token string) ([]topo.KVInfo, string, error) {
+		var got []topo.KVInfo
+		for {
+			page, next, err := conn.ListPage(ctx, "/tablets", token)
+			if err != nil {
+				return got, token, err
+			}
+			got = append(got, page...)
+			if next == "" {
+				return got, "", nil
+			}
+			token = next
+		}
+	}
+
+	conn.FailListPageAfter("/tablets", 1, nil)
+	got, token, err := listAll("")
+	require.True(t, topo.IsErrType(err, topo.Timeout))
+	require.Equal(t, kvs[:2], got)
+
+	// The consumer resumes where it stopped and gets the rest.
+	rest, _, err := listAll(token)
+	require.NoError(t, err)
+	require.Equal(t, kvs, append(got, rest...))
+}
+
+func TestAddPhantomListEntries(t *testing.T) {
+	ctx := context.Background()
+	factory := NewFakeTopoFactory()
+	conn := factory.cells[topo.GlobalCell][0]
+	ts := NewFakeTopoServer(ctx, factory)
+
+	shard, err := (&topodatapb.Shard{}).MarshalVT()
+	require.NoError(t, err)
+	shardsPath := "keyspaces/ks1/shards"
+	conn.AddListResult(shardsPath, []topo.KVInfo{{Key: []byte(shardsPath + "/-80/Shard"), Value: shard}})
+
+	// Phantom entries are returned after the list result, and don't exist.
+	conn.AddPhantomListEntries(shardsPath, topo.KVInfo{Key: []byte(shardsPath + "/80-/locks/1")})
+	kvInfos, err := conn.List(ctx, shardsPath)
+	require.NoError(t, err)
+	require.Len(t, kvInfos, 2)
+	require.Equal(t, shardsPath+"/80-/locks/1", string(kvInfos[1].Key))
+	_, _, err = conn.Get(ctx, shardsPath+"/80-/locks/1")
+	require.True(t, topo.IsErrType(err, topo.NoNode), err)
+
+	// The stray lock is ignored by the consumer.
+	shards, err := ts.FindAllShardsInKeyspace(ctx, "ks1", nil)
+	require.NoError(t, err)
+	require.Len(t, shards, 1)
+	require.Contains(t, shards, "-80")
+
+	// A stray shard record with an invalid name is reported.
+	conn.AddPhantomListEntries(shardsPath, topo.KVInfo{Key: []byte(shardsPath + "/80-40/Shard"), Value: shard})
+	_, err = ts.FindAllShardsInKeyspace(ctx, "ks1", nil)
+	require.ErrorContains(t, err, `invalid shard name/range "80-40"`)
+
+	// Phantom entries alone make the prefix exist.
+	conn.AddPhantomListEntries("tablets", topo.KVInfo{Key: []byte("tablets/zone1-0000000100/Tablet")})
+	kvInfos, err = conn.List(ctx, "tablets")
+	require.NoError(t, err)
+	require.Len(t, kvInfos, 1)
+}
+
+func TestSetInconsistentListVersions(t *testing.T) {
+	ctx := context.Background()
+	factory := NewFakeTopoFactory()
+	conn := factory.AddCell("zone1")
+	ts := NewFakeTopoServer(ctx, factory)
+
+	tablet := &topodatapb.Tablet{Alias: &topodatapb.TabletAlias{Cell: "zone1", Uid: 100}, Hostname: "host1"}
+	require.NoError(t, ts.CreateTablet(ctx, tablet))
+	tabletPath := "tablets/zone1-0000000100/Tablet"
+	data, _, err := conn.Get(ctx, tabletPath)
+	require.NoError(t, err)
+	conn.AddListResult(topo.TabletsPath, []topo.KVInfo{{Key: []byte(tabletPath), Value: data, Version: memorytopo.NodeVersion(1)}})
+	conn.SetInconsistentListVersions(topo.TabletsPath, true)
+
+	// The listed version is ahead of the one Get returns.
+	kvInfos, err := conn.List(ctx, topo.TabletsPath)
+	require.NoError(t, err)
+	_, version, err := conn.Get(ctx, tabletPath)
+	require.NoError(t, err)
+	require.NotEqual(t, version, kvInfos[0].Version)
+
+	// Writing a listed tablet back is rejected rather than clobbering it,
+	// and updates that read the tablet again succeed.
+	tablets, err := ts.GetTabletsByCell(ctx, "zone1", nil)
+	require.NoError(t, err)
+	require.Len(t, tablets, 1)
+	tablets[0].Hostname = "host2"
+	err = ts.UpdateTablet(ctx, tablets[0])
+	require.True(t, topo.IsErrType(err, topo.BadVersion), err)
+	_, err = ts.UpdateTabletFields(ctx, tablet.Alias, func(tablet *topodatapb.Tablet) error {
+		tablet.Hostname = "host2"
+		return nil
+	})
+	require.NoError(t, err)
+
+	conn.SetInconsistentListVersions(topo.TabletsPath, false)
+	kvInfos, err = conn.List(ctx, topo.TabletsPath)
+	require.NoError(t, err)
+	require.Equal(t, memorytopo.NodeVersion(1), kvInfos[0].Version)
+}
+
+func TestSetListTruncateAt(t *testing.T) {
+	ctx := context.Background()
+	factory := NewFakeTopoFactory()
+	conn := factory.AddCell("zone1")
+	ts := NewFakeTopoServer(ctx, factory)
+
+	var kvInfos []topo.KVInfo
+	for uid := uint32(100); uid < 103; uid++ {
+		tablet := &topodatapb.Tablet{Alias: &topodatapb.TabletAlias{Cell: "zone1", Uid: uid}, Hostname: "host"}
+		require.NoError(t, ts.CreateTablet(ctx, tablet))
+		value, err := tablet.MarshalVT()
+		require.NoError(t, err)
+		kvInfos = append(kvInfos, topo.KVInfo{Key: []byte(path.Join(topo.TabletsPath, topoproto.TabletAliasString(tablet.Alias), topo.TabletFile)), Value: value})
+	}
+	conn.AddListResult(topo.TabletsPath, kvInfos)
+
+	tablets, err := ts.GetTabletsByCell(ctx, "zone1", nil)
+	require.NoError(t, err)
+	require.Len(t, tablets, 3)
+	require.Zero(t, conn.TruncatedLists())
+
+	// The listing is cut short without any error, which a consumer can only
+	// detect by comparing it with another source, such as the aliases.
+	conn.SetListTruncateAt(2)
+	tablets, err = ts.GetTabletsByCell(ctx, "zone1", nil)
+	require.NoError(t, err)
+	require.Len(t, tablets, 2)
+	aliases, err := ts.GetTabletAliasesByCell(ctx, "zone1")
+	require.NoError(t, err)
+	require.Len(t, aliases, 3)
+	require.Equal(t, 1, conn.TruncatedLists())
+
+	// Paging through the results still returns all of them.
+	conn.SetListPageSize(2)
+	page, token, err := conn.ListPage(ctx, topo.TabletsPath, "")
+	require.NoError(t, err)
+	require.Len(t, page, 2)
+	page, token, err = conn.ListPage(ctx, topo.TabletsPath, token)
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	require.Empty(t, token)
+
+	conn.SetListTruncateAt(0)
+	tablets, err = ts.GetTabletsByCell(ctx, "zone1", nil)
+	require.NoError(t, err)
+	require.Len(t, tablets, 3)
+	require.Equal(t, 1, conn.TruncatedLists())
+}
+
+func TestPauseNextUpdate(t *testing.T) {
+	ctx := context.Background()
+	conn := NewFakeConnection()
+	version, err := conn.Create(ctx, "/keyspaces/ks1/Keyspace", []byte("v1"))
+	require.NoError(t, err)
+
+	blocked := conn.PauseNextUpdate()
+	done := make(chan error)
+	go func() {
+		_, err := conn.Update(ctx, "/keyspaces/ks1/Keyspace", []byte("v2"), version)
+		done <- err
+	}()
+	<-blocked
+
+	// While the write is in flight, reads still see the old value and
+	// other updates are not held.
+	contents, _, err := conn.Get(ctx, "/keyspaces/ks1/Keyspace")
+	require.NoError(t, err)
+	require.Equal(t, []byte("v1"), contents)
+	_, err = conn.Update(ctx, "/keyspaces/ks2/Keyspace", []byte("other"), nil)
+	require.NoError(t, err)
+
+	conn.ResumeUpdate()
+	require.NoError(t, <-done)
+	contents, version, err = conn.Get(ctx, "/keyspaces/ks1/Keyspace")
+	require.NoError(t, err)
+	require.Equal(t, []byte("v2"), contents)
+
+	// Cancelling the context releases a paused Update without writing.
+	blocked = conn.PauseNextUpdate()
+	cctx, cancel := context.WithCancel(ctx)
+	go func() {
+		_, err := conn.Update(cctx, "/keyspaces/ks1/Keyspace", []byte("v3"), version)
+		done <- err
+	}()
+	<-blocked
+	cancel()
+	err = <-done
+	require.True(t, topo.IsErrType(err, topo.Interrupted))
+	contents, _, err = conn.Get(ctx, "/keyspaces/ks1/Keyspace")
+	require.NoError(t, err)
+	require.Equal(t, []byte("v2"), contents)
+
+	// Resuming before the Update arrives means it does not block at all.
+	conn.PauseNextUpdate()
+	conn.ResumeUpdate()
+	conn.ResumeUpdate()
+	_, err = conn.Update(ctx, "/keyspaces/ks1/Keyspace", []byte("v4"), version)
+	require.NoError(t, err)
+}
+
+func TestExclusiveCreateRace(t *testing.T) {
+	ctx := context.Background()
+	conn := NewFakeConnection()
+	const path = "/keyspaces/ks1/shards/0/leader"
+
+	// Create overwrites by default.
+	_, err := conn.Create(ctx, path, []byte("a"))
+	require.NoError(t, err)
+	_, err = conn.Create(ctx, path, []byte("b"))
+	require.NoError(t, err)
+	conn.DeleteBehindBack(path)
+
+	// In exclusive mode, of two Create calls in program order, the second
+	// one fails.
+	conn.SetExclusiveCreate(true)
+	_, err = conn.Create(ctx, path, []byte("a"))
+	require.NoError(t, err)
+	_, err = conn.Create(ctx, path, []byte("b"))
+	require.True(t, topo.IsErrType(err, topo.NodeExists), "unexpected error: %v", err)
+	contents, _, err := conn.Get(ctx, path)
+	require.NoError(t, err)
+	require.Equal(t, []byte("a"), contents)
+	conn.DeleteBehindBack(path)
+
+	// When the first caller is paused in flight, the second one wins the
+	// race, and only one creator ever succeeds.
+	blocked := conn.PauseNextCreate()
+	done := make(chan error)
+	go func() {
+		_, err := conn.Create(ctx, path, []byte("first"))
+		done <- err
+	}()
+	<-blocked
+	_, err = conn.Create(ctx, path, []byte("second"))
+	require.NoError(t, err)
+	conn.ResumeCreate()
+	err = <-done
+	require.True(t, topo.IsErrType(err, topo.NodeExists), "unexpected error: %v", err)
+	contents, _, err = conn.Get(ctx, path)
+	require.NoError(t, err)
+	require.Equal(t, []byte("second"), contents)
+}
+
+func TestCompareAndSwap(t *testing.T) {
+	ctx := context.Background()
+	conn := NewFakeConnection()
+	const path = "/keyspaces/ks1/Keyspace"
+	require.True(t, topo.IsErrType(conn.CompareAndSwap(ctx, path, nil, []byte("v1")), topo.NoNode))
+
+	_, err := conn.Create(ctx, path, []byte("v1"))
+	require.NoError(t, err)
+	_, ch, err := conn.Watch(ctx, path)
+	require.NoError(t, err)
+
+	// A successful swap bumps the version and notifies the watches.
+	require.NoError(t, conn.CompareAndSwap(ctx, path, []byte("v1"), []byte("v2")))
+	contents, version, err := conn.Get(ctx, path)
+	require.NoError(t, err)
+	require.Equal(t, []byte("v2"), contents)
+	require.Equal(t, "2", version.String())
+	wd := <-ch
+	require.Equal(t, []byte("v2"), wd.Contents)
+
+	// A swap based on stale contents is rejected.
+	err = conn.CompareAndSwap(ctx, path, []byte("v1"), []byte("v3"))
+	require.True(t, topo.IsErrType(err, topo.BadVersion))
+	contents, _, err = conn.Get(ctx, path)
+	require.NoError(t, err)
+	require.Equal(t, []byte("v2"), contents)
+}
+
+func TestCompareAndSwapUsesUpdatePath(t *testing.T) {
+	ctx := context.Background()
+	conn := NewFakeConnection()
+	conn.SetCaseInsensitivePaths(true)
+	const path = "keyspaces/ks1/keyspace"
+	_, err := conn.Create(ctx, path, []byte("v1"))
+	require.NoError(t, err)
+	conn.EnableCallLog()
+
+	// Injected update failures apply to swaps.
+	conn.FailOp(CallUpdate, nil)
+	err = conn.CompareAndSwap(ctx, path, []byte("v1"), []byte("v2"))
+	require.True(t, topo.IsErrType(err, topo.Timeout), "unexpected error: %v", err)
+	conn.RestoreOp(CallUpdate)
+
+	// The path is normalized, and the swap is logged, counted and kept in
+	// the history like an Update.
+	require.NoError(t, conn.CompareAndSwap(ctx, "keyspaces/ks1/Keyspace", []byte("v1"), []byte("v2")))
+	require.Equal(t, 2, conn.WriteCount(path))
+	contents, err := conn.GetVersion(ctx, path, 1)
+	require.NoError(t, err)
+	require.Equal(t, []byte("v1"), contents)
+	calls := conn.CallLog()
+	require.Len(t, calls, 2)
+	for _, call := range calls {
+		require.Equal(t, CallUpdate, call.Op)
+		require.Equal(t, path, call.Path)
+	}
+
+	// A closed connection rejects swaps.
+	conn.Close()
+	err = conn.CompareAndSwap(ctx, path, []byte("v2"), []byte("v3"))
+	require.True(t, topo.IsErrType(err, topo.Interrupted), "unexpected error: %v", err)
+}
+
+func TestCompareAndSwapConcurrent(t *testing.T) {
+	ctx := context.Background()
+	conn := NewFakeConnection()
+	const path = "/counter"
+	_, err := conn.Create(ctx, path, []byte("0"))
+	require.NoError(t, err)
+
+	// Every writer retries its Get-then-swap loop until it wins, so no
+	// increment is lost even though they all race on the same node.
+	const writers = 10
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				contents, _, err := conn.Get(ctx, path)
+				if err != nil {
+					errs <- err
+					return
+				}
+				n, _ := strconv.Atoi(string(contents))
+				err = conn.CompareAndSwap(ctx, path, contents, []byte(strconv.Itoa(n+1)))
+				if !topo.IsErrType(err, topo.BadVersion) {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	contents, _, err := conn.Get(ctx, path)
+	require.NoError(t, err)
+	require.Equal(t, strconv.Itoa(writers), string(contents))
+}
+
+func TestSetCellLatency(t *testing.T) {
+	ctx := context.Background()
+	factory := NewFakeTopoFactory()
+	local := factory.AddCell("local")
+	remote := factory.AddCell("remote")
+	factory.SetCellLatency("remote", 50*time.Millisecond)
+	for _, conn := range []*FakeConn{local, remote} {
+		_, err := conn.Create(ctx, "/keyspaces/ks1/Keyspace", []byte("ks1"))
+		require.NoError(t, err)
+	}
+
+	timeGet := func(cell string) time.Duration {
+		conn, err := factory.Create(cell, "", "")
+		require.NoError(t, err)
+		start := time.Now()
+		_, _, err = conn.Get(ctx, "/keyspaces/ks1/Keyspace")
+		require.NoError(t, err)
+		return time.Since(start)
+	}
+	require.Less(t, timeGet("local"), 50*time.Millisecond)
+	require.GreaterOrEqual(t, timeGet("remote"), 50*time.Millisecond)
+
+	// Cancelling the context interrupts the simulated delay.
+	remote.SetBaseLatency(time.Hour)
+	cctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, _, err := remote.Get(cctx, "/keyspaces/ks1/Keyspace")
+	require.True(t, topo.IsErrType(err, topo.Interrupted))
+}
+
+func TestFailCell(t *testing.T) {
+	ctx := context.Background()
+	factory := NewFakeTopoFactory()
+	factory.AddCell("zone1")
+	factory.AddCell("zone2")
+	ts := NewFakeTopoServer(ctx, factory)
+	for _, cell := range []string{"zone1", "zone2"} {
+		require.NoError(t, ts.UpdateSrvKeyspace(ctx, cell, "ks", &topodatapb.SrvKeyspace{}))
+	}
+
+	// getSrvKeyspace reads the SrvKeyspace from the first cell that serves
+	// it, as a consumer falling back to a healthy cell would.
+	getSrvKeyspace := func() (string, error) {
+		var err error
+		for _, cell := range []string{"zone1", "zone2"} {
+			if _, err = ts.GetSrvKeyspace(ctx, cell, "ks"); err == nil {
+				return cell, nil
+			}
+		}
+		return "", err
+	}
+
+	// The connection of zone1 was already handed out, and still fails.
+	factory.FailCell("zone1", CallGet)
+	_, err := ts.GetSrvKeyspace(ctx, "zone1", "ks")
+	require.True(t, topo.IsErrType(err, topo.Timeout))
+	cell, err := getSrvKeyspace()
+	require.NoError(t, err)
+	require.Equal(t, "zone2", cell)
+	// Other operations on zone1 keep working.
+	require.NoError(t, ts.UpdateSrvKeyspace(ctx, "zone1", "ks", &topodatapb.SrvKeyspace{}))
+
+	factory.FailCell("zone2", CallGet)
+	_, err = getSrvKeyspace()
+	require.True(t, topo.IsErrType(err, topo.Timeout))
+
+	factory.RestoreCell("zone1")
+	factory.RestoreCell("zone2")
+	cell, err = getSrvKeyspace()
+	require.NoError(t, err)
+	require.Equal(t, "zone1", cell)
+
+	// Connections handed out after FailCell fail too.
+	factory.FailCell("zone3", CallCreate)
+	factory.AddCell("zone3")
+	conn, err := factory.Create("zone3", "", "")
+	require.NoError(t, err)
+	_, err = conn.Create(ctx, "/keyspaces/ks/SrvKeyspace", []byte("ks"))
+	require.True(t, topo.IsErrType(err, topo.Timeout))
+}
+
+func TestSetReadOnly(t *testing.T) {
+	ctx := context.Background()
+	conn := NewFakeConnection()
+	const path = "/keyspaces/ks1/Keyspace"
+	version, err := conn.Create(ctx, path, []byte("v1"))
+	require.NoError(t, err)
+	_, ch, err := conn.Watch(ctx, path)
+	require.NoError(t, err)
+
+	conn.SetReadOnly(true)
+
+	// Writes are rejected with a distinguishable error.
+	_, err = conn.Create(ctx, "/keyspaces/ks2/Keyspace", []byte("ks2"))
+	var roErr *ReadOnlyError
+	require.ErrorAs(t, err, &roErr)
+	require.Equal(t, "/keyspaces/ks2/Keyspace", roErr.Path)
+	require.True(t, topo.IsErrType(err, topo.NoImplementation))
+	_, err = conn.Update(ctx, path, []byte("v2"), version)
+	require.ErrorAs(t, err, &roErr)
+	require.ErrorAs(t, conn.CompareAndSwap(ctx, path, []byte("v1"), []byte("v2")), &roErr)
+	require.ErrorAs(t, conn.Delete(ctx, path, version), &roErr)
+
+	// Reads and watches keep working, and see no change.
+	contents, _, err := conn.Get(ctx, path)
+	require.NoError(t, err)
+	require.Equal(t, []byte("v1"), contents)
+	conn.AddListResult("/keyspaces", []topo.KVInfo{{Key: []byte(path), Value: contents}})
+	_, err = conn.List(ctx, "/keyspaces")
+	require.NoError(t, err)
+	require.Len(t, ch, 0)
+
+	// The wrapped error is configurable.
+	conn.SetReadOnlyError(topo.NewError(topo.Timeout, path))
+	_, err = conn.Update(ctx, path, []byte("v2"), version)
+	require.ErrorAs(t, err, &roErr)
+	require.True(t, topo.IsErrType(err, topo.Timeout))
+
+	conn.SetReadOnly(false)
+	_, err = conn.Update(ctx, path, []byte("v2"), version)
+	require.NoError(t, err)
+	wd := <-ch
+	require.Equal(t, []byte("v2"), wd.Contents)
+}
+
+func TestSetMaxValueSize(t *testing.T) {
+	ctx := context.Background()
+	conn := NewFakeConnection()
+	const path = "/keyspaces/ks1/Keyspace"
+	version, err := conn.Create(ctx, path, []byte("v1"))
+	require.NoError(t, err)
+
+	conn.SetMaxValueSize(4)
+
+	// Writes up to the limit succeed.
+	version, err = conn.Update(ctx, path, []byte("1234"), version)
+	require.NoError(t, err)
+
+	// Larger writes are rejected and persist nothing.
+	_, err = conn.Create(ctx, "/keyspaces/ks2/Keyspace", []byte("12345"))
+	var sizeErr *ValueTooLargeError
+	require.ErrorAs(t, err, &sizeErr)
+	require.Equal(t, &ValueTooLargeError{Path: "/keyspaces/ks2/Keyspace", Size: 5, MaxSize: 4}, sizeErr)
+	require.EqualError(t, err, "value of 5 bytes written to /keyspaces/ks2/Keyspace exceeds the maximum value size of 4 bytes")
+	_, _, err = conn.Get(ctx, "/keyspaces/ks2/Keyspace")
+	require.True(t, topo.IsErrType(err, topo.NoNode))
+	_, err = conn.Update(ctx, path, []byte("12345"), version)
+	require.ErrorAs(t, err, &sizeErr)
+	require.ErrorAs(t, conn.CompareAndSwap(ctx, path, []byte("1234"), []byte("12345")), &sizeErr)
+	contents, _, err := conn.Get(ctx, path)
+	require.NoError(t, err)
+	require.Equal(t, []byte("1234"), contents)
+
+	// Zero lifts the limit.
+	conn.SetMaxValueSize(0)
+	_, err = conn.Update(ctx, path, []byte("12345"), version)
+	require.NoError(t, err)
+}
+
+func TestSetRequireParent(t *testing.T) {
+	ctx := context.Background()
+	conn := NewFakeConnection()
+
+	// Flat by default.
+	_, err := conn.Create(ctx, "/keyspaces/ks1/Keyspace", []byte("data"))
+	require.NoError(t, err)
+
+	conn.SetRequireParent(true)
+
+	// Creating under a directory that has no node is rejected.
+	_, err = conn.Create(ctx, "/keyspaces/ks2/shards/0/Shard", []byte("data"))
+	var parentErr *MissingParentError
+	require.ErrorAs(t, err, &parentErr)
+	require.Equal(t, &MissingParentError{Path: "/keyspaces/ks2/shards/0/Shard", Parent: "/keyspaces/ks2/shards/0"}, parentErr)
+	require.EqualError(t, err, "cannot create /keyspaces/ks2/shards/0/Shard: parent directory /keyspaces/ks2/shards/0 does not exist")
+	require.True(t, topo.IsErrType(err, topo.NoNode))
+	_, _, err = conn.Get(ctx, "/keyspaces/ks2/shards/0/Shard")
+	require.True(t, topo.IsErrType(err, topo.NoNode))
+
+	// Children of the root, and siblings of existing nodes, are accepted.
+	_, err = conn.Create(ctx, "/global", []byte("data"))
+	require.NoError(t, err)
+	_, err = conn.Create(ctx, "/keyspaces/ks1/VSchema", []byte("data"))
+	require.NoError(t, err)
+
+	// Once the parent exists, its children can be created.
+	_, err = conn.Create(ctx, "/keyspaces/ks2", []byte("data"))
+	require.NoError(t, err)
+	_, err = conn.Create(ctx, "/keyspaces/ks2/shards", []byte("data"))
+	require.NoError(t, err)
+	_, err = conn.Create(ctx, "/keyspaces/ks2/shards/0", []byte("data"))
+	require.NoError(t, err)
+	_, err = conn.Create(ctx, "/keyspaces/ks2/shards/0/Shard", []byte("data"))
+	require.NoError(t, err)
+}
+
+func TestSetValidatePaths(t *testing.T) {
+	ctx := context.Background()
+	conn := NewFakeConnection()
+
+	// Lax by default.
+	_, err := conn.Create(ctx, "not//a/topo/path", []byte("data"))
+	require.NoError(t, err)
+
+	conn.SetValidatePaths(true)
+	for _, path := range []string{
+		"",
+		"/",
+		"keyspaces//ks1/Keyspace",
+		"keyspaces/ks1/Keyspace/",
+		"keyspaces/../tablets/zone1-100/Tablet",
+		"keyspace/ks1/Keyspace",
+		"not//a/topo/path",
+	} {
+		var pathErr *InvalidPathError
+		_, err := conn.Create(ctx, path, []byte("data"))
+		require.ErrorAs(t, err, &pathErr, path)
+		require.Equal(t, path, pathErr.Path)
+		_, err = conn.Update(ctx, path, []byte("data"), nil)
+		require.ErrorAs(t, err, &pathErr, path)
+		_, _, err = conn.Get(ctx, path)
+		require.ErrorAs(t, err, &pathErr, path)
+	}
+
+	for _, path := range []string{
+		"keyspaces/ks1/Keyspace",
+		"/keyspaces/ks1/shards/-80/Shard",
+		"tablets/zone1-0000000100/Tablet",
+		"SrvVSchema",
+	} {
+		_, err := conn.Create(ctx, path, []byte("data"))
+		require.NoError(t, err, path)
+		_, _, err = conn.Get(ctx, path)
+		require.NoError(t, err, path)
+	}
+}
+
+func TestSetValidatePathsWithServer(t *testing.T) {
+	// The paths built by topo.Server itself are all valid.
+	ctx := context.Background()
+	factory := NewFakeTopoFactory()
+	factory.cells[topo.GlobalCell][0].SetValidatePaths(true)
+	ts := NewFakeTopoServer(ctx, factory)
+	require.NoError(t, ts.CreateKeyspace(ctx, "ks1", &topodatapb.Keyspace{}))
+	_, err := ts.GetKeyspace(ctx, "ks1")
+	require.NoError(t, err)
+	require.NoError(t, ts.CreateShard(ctx, "ks1", "-80"))
+	_, err = ts.GetShard(ctx, "ks1", "-80")
+	require.NoError(t, err)
+}
+
+func TestSetWatchScript(t *testing.T) {
+	ctx := context.Background()
+	conn := NewFakeConnection()
+	_, err := conn.Create(ctx, "keyspaces/ks/Keyspace", []byte("v1"))
+	require.NoError(t, err)
+
+	conn.SetWatchScript("keyspaces/ks/Keyspace", []*topo.WatchData{
+		{Contents: []byte("v2")},
+		{Contents: []byte("v3")},
+		{Err: topo.NewError(topo.NoNode, "keyspaces/ks/Keyspace")},
+		{Contents: []byte("never sent")},
+	}, time.Millisecond, false)
+
+	current, ch, err := conn.Watch(ctx, "keyspaces/ks/Keyspace")
+	require.NoError(t, err)
+	require.Equal(t, []byte("v1"), current.Contents)
+	require.Equal(t, []byte("v2"), (<-ch).Contents)
+	require.Equal(t, []byte("v3"), (<-ch).Contents)
+	require.True(t, topo.IsErrType((<-ch).Err, topo.NoNode))
+	_, ok := <-ch
+	require.False(t, ok, "the watch should be closed after an error event")
+
+	// Scripted watches are not registered, so writes are not reported to them.
+	require.Equal(t, 0, conn.WatchersFor("keyspaces/ks/Keyspace"))
+
+	// closeWhenDone closes the channel after the last event.
+	conn.SetWatchScript("keyspaces/ks/Keyspace", []*topo.WatchData{{Contents: []byte("v2")}}, 0, true)
+	_, ch, err = conn.Watch(ctx, "keyspaces/ks/Keyspace")
+	require.NoError(t, err)
+	require.Equal(t, []byte("v2"), (<-ch).Contents)
+	_, ok = <-ch
+	require.False(t, ok)
+
+	// Cancelling the watch interrupts the script.
+	conn.SetWatchScript("keyspaces/ks/Keyspace", []*topo.WatchData{{Contents: []byte("v2")}}, time.Hour, true)
+	watchCtx, cancel := context.WithCancel(ctx)
+	_, ch, err = conn.Watch(watchCtx, "keyspaces/ks/Keyspace")
+	require.NoError(t, err)
+	cancel()
+	_, ok = <-ch
+	require.False(t, ok)
+
+	// Removing the script restores regular watches.
+	conn.SetWatchScript("keyspaces/ks/Keyspace", nil, 0, false)
+	watchCtx, cancel = context.WithCancel(ctx)
+	defer cancel()
+	_, _, err = conn.Watch(watchCtx, "keyspaces/ks/Keyspace")
+	require.NoError(t, err)
+	require.Equal(t, 1, conn.WatchersFor("keyspaces/ks/Keyspace"))
+}
+
+func TestSetWatchInitialViaChannel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	conn := NewFakeConnection()
+	version, err := conn.Create(ctx, "keyspaces/ks/Keyspace", []byte("v1"))
+	require.NoError(t, err)
+
+	conn.SetWatchInitialViaChannel(true)
+	current, ch, err := conn.Watch(ctx, "keyspaces/ks/Keyspace")
+	require.NoError(t, err)
+	require.Nil(t, current)
+	require.Equal(t, []byte("v1"), (<-ch).Contents)
+	_, err = conn.Update(ctx, "keyspaces/ks/Keyspace", []byte("v2"), version)
+	require.NoError(t, err)
+	require.Equal(t, []byte("v2"), (<-ch).Contents)
+
+	// The initial value comes before the events of a script.
+	conn.SetWatchScript("keyspaces/ks/Keyspace", []*topo.WatchData{{Contents: []byte("v3")}}, 0, true)
+	_, ch, err = conn.Watch(ctx, "keyspaces/ks/Keyspace")
+	require.NoError(t, err)
+	require.Equal(t, []byte("v2"), (<-ch).Contents)
+	require.Equal(t, []byte("v3"), (<-ch).Contents)
+
+	conn.SetWatchScript("keyspaces/ks/Keyspace", nil, 0, false)
+	conn.SetWatchInitialViaChannel(false)
+	current, _, err = conn.Watch(ctx, "keyspaces/ks/Keyspace")
+	require.NoError(t, err)
+	require.Equal(t, []byte("v2"), current.Contents)
+}
+
+func TestSetWatchCoalesceLatest(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	conn := NewFakeConnection()
+	const path = "keyspaces/ks/Keyspace"
+	version, err := conn.Create(ctx, path, []byte("v0"))
+	require.NoError(t, err)
+
+	conn.SetWatchCoalesceLatest(true)
+	_, ch, err := conn.Watch(ctx, path)
+	require.NoError(t, err)
+
+	// A burst of updates only delivers the final value.
+	for i := 1; i <= 5; i++ {
+		version, err = conn.Update(ctx, path, []byte(fmt.Sprintf("v%d", i)), version)
+		require.NoError(t, err)
+	}
+	require.Len(t, ch, 1)
+	require.Equal(t, []byte("v5"), (<-ch).Contents)
+
+	// Errors are kept, in order, ahead of the final value.
+	conn.EmitWatch(path, &topo.WatchData{Err: topo.NewError(topo.Interrupted, path)})
+	version, err = conn.Update(ctx, path, []byte("v6"), version)
+	require.NoError(t, err)
+	version, err = conn.Update(ctx, path, []byte("v7"), version)
+	require.NoError(t, err)
+	require.Len(t, ch, 2)
+	require.True(t, topo.IsErrType((<-ch).Err, topo.Interrupted))
+	require.Equal(t, []byte("v7"), (<-ch).Contents)
+
+	// By default, every event is delivered.
+	conn.SetWatchCoalesceLatest(false)
+	for i := 8; i <= 9; i++ {
+		version, err = conn.Update(ctx, path, []byte(fmt.Sprintf("v%d", i)), version)
+		require.NoError(t, err)
+	}
+	require.Equal(t, []byte("v8"), (<-ch).Contents)
+	require.Equal(t, []byte("v9"), (<-ch).Contents)
+}
+
+func TestSetWatchReorder(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	conn := NewFakeConnection()
+	const path = "keyspaces/ks/Keyspace"
+	version, err := conn.Create(ctx, path, []byte("v0"))
+	require.NoError(t, err)
+
+	conn.SetWatchReorder(4, 1)
+	_, ch1, err := conn.Watch(ctx, path)
+	require.NoError(t, err)
+	_, ch2, err := conn.Watch(ctx, path)
+	require.NoError(t, err)
+
+	// Events are held back until a batch is complete.
+	for i := 1; i <= 3; i++ {
+		version, err = conn.Update(ctx, path, []byte(fmt.Sprintf("v%d", i)), version)
+		require.NoError(t, err)
+	}
+	require.Empty(t, ch1)
+	version, err = conn.Update(ctx, path, []byte("v4"), version)
+	require.NoError(t, err)
+
+	// Both watches see the same shuffled order, and a consumer that keeps
+	// the value with the highest version ends up with the latest one.
+	receive := func(ch <-chan *topo.WatchData, n int) []string {
+		var contents []string
+		for range n {
+			contents = append(contents, string((<-ch).Contents))
+		}
+		return contents
+	}
+	order := receive(ch1, 4)
+	require.ElementsMatch(t, []string{"v1", "v2", "v3", "v4"}, order)
+	require.NotEqual(t, []string{"v1", "v2", "v3", "v4"}, order)
+	require.Equal(t, order, receive(ch2, 4))
+
+	conn.SetWatchReorder(4, 1)
+	_, ch3, err := conn.Watch(ctx, path)
+	require.NoError(t, err)
+	var latest *topo.WatchData
+	for i := 5; i <= 8; i++ {
+		version, err = conn.Update(ctx, path, []byte(fmt.Sprintf("v%d", i)), version)
+		require.NoError(t, err)
+	}
+	for range 4 {
+		event := <-ch3
+		if latest == nil || event.Version.(memorytopo.NodeVersion) > latest.Version.(memorytopo.NodeVersion) {
+			latest = event
+		}
+	}
+	require.Equal(t, []byte("v8"), latest.Contents)
+
+	// Incomplete batches are delivered on flush, and errors come after the
+	// events held back.
+	version, err = conn.Update(ctx, path, []byte("v9"), version)
+	require.NoError(t, err)
+	conn.FlushWatchEvents()
+	require.Equal(t, []byte("v9"), (<-ch3).Contents)
+	version, err = conn.Update(ctx, path, []byte("v10"), version)
+	require.NoError(t, err)
+	conn.EmitWatch(path, &topo.WatchData{Err: topo.NewError(topo.Interrupted, path)})
+	require.Equal(t, []byte("v10"), (<-ch3).Contents)
+	require.True(t, topo.IsErrType((<-ch3).Err, topo.Interrupted))
+
+	// By default, events are delivered in order.
+	conn.SetWatchReorder(0, 0)
+	for i := 11; i <= 12; i++ {
+		version, err = conn.Update(ctx, path, []byte(fmt.Sprintf("v%d", i)), version)
+		require.NoError(t, err)
+	}
+	require.Equal(t, []byte("v11"), (<-ch3).Contents)
+	require.Equal(t, []byte("v12"), (<-ch3).Contents)
+}
+
+func TestLockWithTTLClockSkew(t *testing.T) {
+	ctx := context.Background()
+	conn := NewFakeConnection()
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	conn.SetClock(func() time.Time { return start })
+
+	lock, err := conn.LockWithTTL(ctx, "keyspaces/ks", "test", time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, lock.Check(ctx))
+
+	conn.AdvanceClock(59 * time.Second)
+	require.NoError(t, lock.Check(ctx))
+
+	// Once the clock moves past the TTL, the lock is lost.
+	conn.AdvanceClock(time.Second)
+	err = lock.Check(ctx)
+	require.True(t, topo.IsErrType(err, topo.NoNode), "unexpected error: %v", err)
+	require.NoError(t, lock.Unlock(ctx))
+
+	// Locks without a TTL never expire.
+	lock, err = conn.Lock(ctx, "keyspaces/ks", "test")
+	require.NoError(t, err)
+	conn.AdvanceClock(24 * time.Hour)
+	require.NoError(t, lock.Check(ctx))
+}
+
+func TestLocks(t *testing.T) {
+	ctx := context.Background()
+	factory := NewFakeTopoFactory()
+	conn := factory.cells[topo.GlobalCell][0]
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	conn.SetClock(func() time.Time { return start })
+	ts := NewFakeTopoServer(ctx, factory)
+	require.NoError(t, ts.CreateKeyspace(ctx, "ks1", &topodatapb.Keyspace{}))
+
+	// lockAndRun is a typical locked flow, which releases the lock whether
+	// action succeeds or not.
+	lockAndRun := func(action func() error) (err error) {
+		_, unlock, lockErr := ts.LockKeyspace(ctx, "ks1", "test")
+		if lockErr != nil {
+			return lockErr
+		}
+		defer unlock(&err)
+		conn.AdvanceClock(time.Second)
+		return action()
+	}
+
+	require.NoError(t, lockAndRun(func() error { return nil }))
+	require.Error(t, lockAndRun(func() error { return fmt.Errorf("action failed") }))
+
+	locks := conn.Locks()
+	require.Len(t, locks, 2)
+	for i, lock := range locks {
+		require.Equal(t, "keyspaces/ks1", lock.DirPath)
+		require.Contains(t, lock.Contents, `"Action": "test"`)
+		require.False(t, lock.Held(), "lock %d was not released", i)
+		require.Equal(t, time.Second, lock.Released.Sub(lock.Acquired))
+	}
+	require.Equal(t, start.Add(time.Second), locks[1].Acquired)
+
+	// A lock stays held until it is unlocked.
+	lock, err := conn.LockWithTTL(ctx, "keyspaces/ks2", "test", time.Minute)
+	require.NoError(t, err)
+	require.True(t, conn.Locks()[2].Held())
+	require.NoError(t, lock.Unlock(ctx))
+	require.False(t, conn.Locks()[2].Held())
+}
+
+func TestLockFailures(t *testing.T) {
+	ctx := context.Background()
+	conn := NewFakeConnection()
+	conn.EnableCallLog()
+
+	// Locks see the injected failures and latency like the file operations.
+	conn.FailOp(CallLock, nil)
+	_, err := conn.Lock(ctx, "keyspaces/ks1", "test")
+	require.True(t, topo.IsErrType(err, topo.Timeout), "unexpected error: %v", err)
+	conn.RestoreOp(CallLock)
+	conn.SetUnavailable(true)
+	_, err = conn.LockName(ctx, "keyspaces/ks1", "test")
+	require.True(t, topo.IsErrType(err, topo.Timeout), "unexpected error: %v", err)
+	conn.SetUnavailable(false)
+	conn.SetLatency(CallTryLock, time.Minute)
+	timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err = conn.TryLock(timeoutCtx, "keyspaces/ks1", "test")
+	require.True(t, topo.IsErrType(err, topo.Interrupted), "unexpected error: %v", err)
+	conn.SetLatency(CallTryLock, 0)
+
+	lock, err := conn.LockWithTTL(ctx, "keyspaces/ks1", "test", time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, lock.Unlock(ctx))
+	require.Len(t, conn.Locks(), 1)
+
+	var ops []CallOp
+	for _, call := range conn.CallLog() {
+		require.Equal(t, "keyspaces/ks1", call.Path)
+		ops = append(ops, call.Op)
+	}
+	require.Equal(t, []CallOp{CallLock, CallLockName, CallTryLock, CallLockWithTTL}, ops)
+}
+
+func TestEmitWatch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	conn := NewFakeConnection()
+	filePath := "keyspaces/ks/Keyspace"
+	_, err := conn.Create(ctx, filePath, []byte("v1"))
+	require.NoError(t, err)
+
+	current, ch, err := conn.Watch(ctx, filePath)
+	require.NoError(t, err)
+
+	// latest is a typical consumer, which ignores values older than the
+	// one it already has.
+	latest := current
+	consume := func() {
+		data := <-ch
+		require.NoError(t, data.Err)
+		if data.Version.(memorytopo.NodeVersion) > latest.Version.(memorytopo.NodeVersion) {
+			latest = data
+		}
+	}
+
+	conn.EmitWatch(filePath, &topo.WatchData{Contents: []byte("v3"), Version: memorytopo.NodeVersion(3)})
+	consume()
+	require.Equal(t, []byte("v3"), latest.Contents)
+
+	// A version that went backwards is ignored.
+	conn.EmitWatch(filePath, &topo.WatchData{Contents: []byte("v2"), Version: memorytopo.NodeVersion(2)})
+	consume()
+	require.Equal(t, []byte("v3"), latest.Contents)
+
+	// What is stored is left untouched.
+	contents, version, ok := conn.GetRaw(filePath)
+	require.True(t, ok)
+	require.Equal(t, []byte("v1"), contents)
+	require.EqualValues(t, 1, version)
+
+	// Errors are delivered as is.
+	conn.EmitWatch(filePath, &topo.WatchData{Err: topo.NewError(topo.Interrupted, filePath)})
+	require.True(t, topo.IsErrType((<-ch).Err, topo.Interrupted))
+
+	// Watches that are closed are skipped.
+	cancel()
+	for range ch {
+	}
+	conn.EmitWatch(filePath, &topo.WatchData{Contents: []byte("v4")})
+}
+
+func TestSetReadRedirect(t *testing.T) {
+	ctx := context.Background()
+	factory := NewFakeTopoFactory()
+	local := factory.AddCell("local")
+	fallback := factory.AddCell("fallback")
+	ts := NewFakeTopoServer(ctx, factory)
+
+	srvKeyspace := &topodatapb.SrvKeyspace{Partitions: []*topodatapb.SrvKeyspace_KeyspacePartition{{ServedType: topodatapb.TabletType_PRIMARY}}}
+	require.NoError(t, ts.UpdateSrvKeyspace(ctx, "fallback", "ks1", srvKeyspace))
+	_, err := ts.GetSrvKeyspace(ctx, "local", "ks1")
+	require.True(t, topo.IsErrType(err, topo.NoNode))
+
+	// Once redirected, the local cell transparently serves the data of the
+	// fallback cell.
+	local.SetReadRedirect("keyspaces", fallback)
+	got, err := ts.GetSrvKeyspace(ctx, "local", "ks1")
+	require.NoError(t, err)
+	require.Len(t, got.Partitions, 1)
+	require.Equal(t, topodatapb.TabletType_PRIMARY, got.Partitions[0].ServedType)
+	names, err := ts.GetSrvKeyspaceNames(ctx, "local")
+	require.NoError(t, err)
+	require.Equal(t, []string{"ks1"}, names)
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	current, _, err := local.Watch(watchCtx, "keyspaces/ks1/SrvKeyspace")
+	require.NoError(t, err)
+	require.NotNil(t, current)
+	require.Equal(t, 1, fallback.WatchersFor("keyspaces/ks1/SrvKeyspace"))
+
+	// Writes are not redirected.
+	require.NoError(t, ts.UpdateSrvKeyspace(ctx, "local", "ks2", srvKeyspace))
+	_, _, ok := fallback.GetRaw("keyspaces/ks2/SrvKeyspace")
+	require.False(t, ok)
+
+	// Paths outside of the prefix are read locally.
+	_, err = ts.GetSrvVSchema(ctx, "local")
+	require.True(t, topo.IsErrType(err, topo.NoNode))
+
+	local.SetReadRedirect("keyspaces", nil)
+	_, err = ts.GetSrvKeyspace(ctx, "local", "ks1")
+	require.True(t, topo.IsErrType(err, topo.NoNode))
+}
+
+func TestSetCaseInsensitivePaths(t *testing.T) {
+	ctx := context.Background()
+	conn := NewFakeConnection()
+
+	// Paths are case sensitive by default.
+	_, err := conn.Create(ctx, "A", []byte("a"))
+	require.NoError(t, err)
+	_, _, err = conn.Get(ctx, "a")
+	require.True(t, topo.IsErrType(err, topo.NoNode))
+
+	conn = NewFakeConnection()
+	conn.SetCaseInsensitivePaths(true)
+	_, err = conn.Create(ctx, "A", []byte("a"))
+	require.NoError(t, err)
+	contents, _, err := conn.Get(ctx, "a")
+	require.NoError(t, err)
+	require.Equal(t, []byte("a"), contents)
+
+	// Writes under one case are seen by watches under another.
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	_, changes, err := conn.Watch(watchCtx, "a")
+	require.NoError(t, err)
+	_, err = conn.Update(ctx, "A", []byte("b"), memorytopo.NodeVersion(1))
+	require.NoError(t, err)
+	require.Equal(t, []byte("b"), (<-changes).Contents)
+}
+
+func TestSetWatchCloseDelay(t *testing.T) {
+	ctx := context.Background()
+	factory := NewFakeTopoFactory()
+	conn := factory.AddCell("cell1")
+	ts := NewFakeTopoServer(ctx, factory)
+	require.NoError(t, ts.UpdateSrvKeyspace(ctx, "cell1", "ks1", &topodatapb.SrvKeyspace{}))
+
+	const closeDelay = 100 * time.Millisecond
+	conn.SetWatchCloseDelay(closeDelay)
+	watchCtx, cancel := context.WithCancel(ctx)
+	current, changes, err := ts.WatchSrvKeyspace(watchCtx, "cell1", "ks1")
+	require.NoError(t, err)
+	require.NoError(t, current.Err)
+
+	// The consumer keeps draining its channel until the connection gets
+	// around to closing the watch.
+	start := time.Now()
+	cancel()
+	select {
+	case <-changes:
+		t.Fatal("watch closed before the delay")
+	case <-time.After(closeDelay / 2):
+	}
+	for range changes {
+	}
+	require.GreaterOrEqual(t, time.Since(start), closeDelay)
+	require.Equal(t, 0, conn.OutstandingWatches())
+}
+
+func TestSetFlakyReads(t *testing.T) {
+	ctx := context.Background()
+	conn := NewFakeConnection()
+	_, err := conn.Create(ctx, "keyspaces/ks1/Keyspace", []byte("ks1"))
+	require.NoError(t, err)
+
+	conn.SetFlakyReads(3)
+	var failed []int
+	for i := 1; i <= 9; i++ {
+		_, _, err := conn.Get(ctx, "keyspaces/ks1/Keyspace")
+		if err != nil {
+			require.True(t, topo.IsErrType(err, topo.Timeout))
+			failed = append(failed, i)
+		}
+	}
+	require.Equal(t, []int{3, 6, 9}, failed)
+
+	// A consumer that retries gets through.
+	conn.SetFlakyReads(2)
+	_, _, err = conn.Get(ctx, "keyspaces/ks1/Keyspace")
+	require.NoError(t, err)
+	var contents []byte
+	attempts := 0
+	for attempts < 3 {
+		attempts++
+		if contents, _, err = conn.Get(ctx, "keyspaces/ks1/Keyspace"); err == nil {
+			break
+		}
+	}
+	require.NoError(t, err)
+	require.Equal(t, 2, attempts)
+	require.Equal(t, []byte("ks1"), contents)
+
+	conn.SetFlakyReads(0)
+	for range 5 {
+		_, _, err := conn.Get(ctx, "keyspaces/ks1/Keyspace")
+		require.NoError(t, err)
+	}
+}
+
+func TestSetRecovering(t *testing.T) {
+	ctx := context.Background()
+	conn := NewFakeConnection()
+	_, err := conn.Create(ctx, "keyspaces/ks1/Keyspace", []byte("ks1"))
+	require.NoError(t, err)
+
+	conn.SetUnavailable(true)
+	for range 5 {
+		_, _, err := conn.Get(ctx, "keyspaces/ks1/Keyspace")
+		require.True(t, topo.IsErrType(err, topo.Timeout))
+	}
+	_, err = conn.ListDir(ctx, "keyspaces", false)
+	require.True(t, topo.IsErrType(err, topo.Timeout))
+
+	// The failures of the recovery window are shared by all operations.
+	conn.SetRecovering(3)
+	_, err = conn.ListDir(ctx, "keyspaces", false)
+	require.True(t, topo.IsErrType(err, topo.Timeout))
+
+	// A consumer that retries gets through once the window is over.
+	var contents []byte
+	attempts := 0
+	for attempts < 5 {
+		attempts++
+		if contents, _, err = conn.Get(ctx, "keyspaces/ks1/Keyspace"); err == nil {
+			break
+		}
+		require.True(t, topo.IsErrType(err, topo.Timeout))
+	}
+	require.NoError(t, err)
+	require.Equal(t, 3, attempts)
+	require.Equal(t, []byte("ks1"), contents)
+	for range 5 {
+		_, _, err := conn.Get(ctx, "keyspaces/ks1/Keyspace")
+		require.NoError(t, err)
+	}
+
+	// A new outage drops what is left of the recovery window, and recovering
+	// with no failures is instant.
+	conn.SetRecovering(2)
+	conn.SetUnavailable(true)
+	conn.SetUnavailable(false)
+	_, _, err = conn.Get(ctx, "keyspaces/ks1/Keyspace")
+	require.NoError(t, err)
+	conn.SetUnavailable(true)
+	conn.SetRecovering(0)
+	_, _, err = conn.Get(ctx, "keyspaces/ks1/Keyspace")
+	require.NoError(t, err)
+}
+
+func TestSetLatency(t *testing.T) {
+	ctx := context.Background()
+	conn := NewFakeConnection()
+	_, err := conn.Create(ctx, "keyspaces/ks1/Keyspace", []byte("ks1"))
+	require.NoError(t, err)
+
+	// A slow Get is abandoned once the deadline of its caller is exceeded.
+	conn.SetLatency(CallGet, time.Hour)
+	shortCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, _, err = conn.Get(shortCtx, "keyspaces/ks1/Keyspace")
+	require.True(t, topo.IsErrType(err, topo.Interrupted), "unexpected error: %v", err)
+
+	// The other operations are not slowed down, and are not blocked by a
+	// Get that is waiting.
+	getCtx, cancelGet := context.WithCancel(ctx)
+	done := make(chan error)
+	go func() {
+		_, _, err := conn.Get(getCtx, "keyspaces/ks1/Keyspace")
+		done <- err
+	}()
+	_, err = conn.Create(ctx, "keyspaces/ks2/Keyspace", []byte("ks2"))
+	require.NoError(t, err)
+	_, err = conn.ListDir(ctx, "keyspaces", false)
+	require.NoError(t, err)
+	cancelGet()
+	require.True(t, topo.IsErrType(<-done, topo.Interrupted))
+
+	// A zero latency removes it.
+	conn.SetLatency(CallGet, 0)
+	contents, _, err := conn.Get(ctx, "keyspaces/ks2/Keyspace")
+	require.NoError(t, err)
+	require.Equal(t, []byte("ks2"), contents)
+}
+
+func TestCloseAndReusableFactory(t *testing.T) {
+	ctx := context.Background()
+	factory := NewFakeTopoFactory()
+	conn := factory.AddCell("zone1")
+	_, err := conn.Create(ctx, "keyspaces/ks1/Keyspace", []byte("ks1"))
+	require.NoError(t, err)
+
+	// By default each connection is handed out once.
+	first, err := factory.Create("zone1", "", "")
+	require.NoError(t, err)
+	require.Same(t, conn, first)
+	_, err = factory.Create("zone1", "", "")
+	require.True(t, topo.IsErrType(err, topo.NoNode), "unexpected error: %v", err)
+
+	// A closed connection interrupts every operation.
+	first.Close()
+	_, _, err = first.Get(ctx, "keyspaces/ks1/Keyspace")
+	require.True(t, topo.IsErrType(err, topo.Interrupted), "unexpected error: %v", err)
+	_, err = first.Lock(ctx, "keyspaces/ks1", "lock")
+	require.True(t, topo.IsErrType(err, topo.Interrupted), "unexpected error: %v", err)
+
+	// A reusable cell hands out the same connection on every Create, and
+	// reopens it.
+	factory.SetCell("zone1", conn)
+	factory.SetReusable("zone1", true)
+	for range 3 {
+		reconnected, err := factory.Create("zone1", "", "")
+		require.NoError(t, err)
+		require.Same(t, conn, reconnected)
+		contents, _, err := reconnected.Get(ctx, "keyspaces/ks1/Keyspace")
+		require.NoError(t, err)
+		require.Equal(t, []byte("ks1"), contents)
+		reconnected.Close()
+	}
+}
+
+func TestGetVersion(t *testing.T) {
+	ctx := context.Background()
+	conn := NewFakeConnection()
+	const path = "keyspaces/ks1/Keyspace"
+	_, err := conn.GetVersion(ctx, path, 1)
+	require.True(t, topo.IsErrType(err, topo.NoNode), "unexpected error: %v", err)
+
+	version, err := conn.Create(ctx, path, []byte("v1"))
+	require.NoError(t, err)
+	for _, contents := range []string{"v2", "v3", "v4"} {
+		version, err = conn.Update(ctx, path, []byte(contents), version)
+		require.NoError(t, err)
+	}
+	for v, want := range []string{"v1", "v2", "v3", "v4"} {
+		contents, err := conn.GetVersion(ctx, path, int64(v+1))
+		require.NoError(t, err)
+		require.Equal(t, []byte(want), contents)
+	}
+	_, err = conn.GetVersion(ctx, path, 5)
+	require.True(t, topo.IsErrType(err, topo.NoNode), "unexpected error: %v", err)
+
+	// Only the last revisions are retained once the history is capped.
+	conn.SetHistoryLimit(2)
+	_, err = conn.GetVersion(ctx, path, 2)
+	require.True(t, topo.IsErrType(err, topo.NoNode), "unexpected error: %v", err)
+	_, err = conn.Update(ctx, path, []byte("v5"), version)
+	require.NoError(t, err)
+	_, err = conn.GetVersion(ctx, path, 3)
+	require.True(t, topo.IsErrType(err, topo.NoNode), "unexpected error: %v", err)
+	contents, err := conn.GetVersion(ctx, path, 4)
+	require.NoError(t, err)
+	require.Equal(t, []byte("v4"), contents)
+
+	// A deleted node has no history.
+	require.NoError(t, conn.Delete(ctx, path, nil))
+	_, err = conn.GetVersion(ctx, path, 5)
+	require.True(t, topo.IsErrType(err, topo.NoNode), "unexpected error: %v", err)
+}
@@ -0,0 +1,203 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package faketopo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"vitess.io/vitess/go/vt/topo"
+	"vitess.io/vitess/go/vt/topo/topoproto"
+	"vitess.io/vitess/go/yaml2"
+
+	topodatapb "vitess.io/vitess/go/vt/proto/topodata"
+)
+
+// fixture is the description of a topology read by LoadFixture.
+type fixture struct {
+	Cells     []string          `json:"cells"`
+	Keyspaces []fixtureKeyspace `json:"keyspaces"`
+	// Tablets are topodatapb.Tablet records, in their JSON form.
+	Tablets []json.RawMessage `json:"tablets"`
+}
+
+type fixtureKeyspace struct {
+	Name string `json:"name"`
+	// Keyspace is the optional topodatapb.Keyspace record, in its JSON form.
+	Keyspace json.RawMessage `json:"keyspace"`
+	Shards   []string        `json:"shards"`
+}
+
+// LoadFixture builds a fake topo server seeded with the topology described
+// by data, in YAML or JSON, so that tests can describe a realistic topology
+// declaratively rather than create each record in turn. For example:
+//
+//	cells: [zone1, zone2]
+//	keyspaces:
+//	- name: commerce
+//	  keyspace: {durabilityPolicy: semi_sync}
+//	  shards: ["-80", "80-"]
+//	tablets:
+//	- {alias: {cell: zone1, uid: 100}, keyspace: commerce, shard: "-80", type: PRIMARY}
+//	- {alias: {cell: zone2, uid: 101}, keyspace: commerce, shard: "-80", type: REPLICA}
+//
+// Keyspaces and tablets are given as their topodatapb records, with the
+// tablet of type PRIMARY of each shard, if any, becoming its primary. The
+// fixture is validated before anything is created: tablets must be in a
+// declared cell and, if they have one, a declared shard, aliases must be
+// unique, and shards can have at most one primary.
+func LoadFixture(ctx context.Context, data []byte) (*topo.Server, error) {
+	var f fixture
+	if err := yaml2.Unmarshal(data, &f, disallowUnknownFields); err != nil {
+		return nil, fmt.Errorf("invalid fixture: %w", err)
+	}
+	keyspaces, tablets, err := f.validate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid fixture: %w", err)
+	}
+
+	factory := NewFakeTopoFactory()
+	for _, cell := range f.Cells {
+		factory.AddCell(cell)
+	}
+	ts, err := topo.NewWithFactory(factory, "" /*serverAddress*/, "" /*root*/)
+	if err != nil {
+		return nil, err
+	}
+	for _, cell := range f.Cells {
+		if err := ts.CreateCellInfo(ctx, cell, &topodatapb.CellInfo{}); err != nil {
+			return nil, fmt.Errorf("cannot create cell %s: %w", cell, err)
+		}
+	}
+	for i, ks := range f.Keyspaces {
+		if err := ts.CreateKeyspace(ctx, ks.Name, keyspaces[i]); err != nil {
+			return nil, fmt.Errorf("cannot create keyspace %s: %w", ks.Name, err)
+		}
+		for _, shard := range ks.Shards {
+			if err := ts.CreateShard(ctx, ks.Name, shard); err != nil {
+				return nil, fmt.Errorf("cannot create shard %s/%s: %w", ks.Name, shard, err)
+			}
+		}
+	}
+	for _, tablet := range tablets {
+		alias := topoproto.TabletAliasString(tablet.Alias)
+		if err := ts.CreateTablet(ctx, tablet); err != nil {
+			return nil, fmt.Errorf("cannot create tablet %s: %w", alias, err)
+		}
+		if tablet.Type != topodatapb.TabletType_PRIMARY {
+			continue
+		}
+		if _, err := ts.UpdateShardFields(ctx, tablet.Keyspace, tablet.Shard, func(si *topo.ShardInfo) error {
+			si.PrimaryAlias = tablet.Alias
+			si.PrimaryTermStartTime = tablet.PrimaryTermStartTime
+			return nil
+		}); err != nil {
+			return nil, fmt.Errorf("cannot make tablet %s the primary of %s/%s: %w", alias, tablet.Keyspace, tablet.Shard, err)
+		}
+	}
+	return ts, nil
+}
+
+func disallowUnknownFields(d *json.Decoder) *json.Decoder {
+	d.DisallowUnknownFields()
+	return d
+}
+
+// validate checks the fixture and returns its keyspace and tablet records.
+func (f *fixture) validate() ([]*topodatapb.Keyspace, []*topodatapb.Tablet, error) {
+	if len(f.Cells) == 0 {
+		return nil, nil, fmt.Errorf("no cells")
+	}
+	for i, cell := range f.Cells {
+		if cell == "" || cell == topo.GlobalCell {
+			return nil, nil, fmt.Errorf("invalid cell name %q", cell)
+		}
+		if slices.Contains(f.Cells[:i], cell) {
+			return nil, nil, fmt.Errorf("duplicate cell %s", cell)
+		}
+	}
+
+	shards := map[string]map[string]bool{}
+	keyspaces := make([]*topodatapb.Keyspace, 0, len(f.Keyspaces))
+	for _, ks := range f.Keyspaces {
+		if err := topo.ValidateKeyspaceName(ks.Name); err != nil {
+			return nil, nil, fmt.Errorf("invalid keyspace name %q: %w", ks.Name, err)
+		}
+		if shards[ks.Name] != nil {
+			return nil, nil, fmt.Errorf("duplicate keyspace %s", ks.Name)
+		}
+		keyspace := &topodatapb.Keyspace{}
+		if len(ks.Keyspace) > 0 {
+			if err := protojson.Unmarshal(ks.Keyspace, keyspace); err != nil {
+				return nil, nil, fmt.Errorf("invalid record of keyspace %s: %w", ks.Name, err)
+			}
+		}
+		keyspaces = append(keyspaces, keyspace)
+		shards[ks.Name] = map[string]bool{}
+		for _, shard := range ks.Shards {
+			if _, _, err := topo.ValidateShardName(shard); err != nil {
+				return nil, nil, fmt.Errorf("invalid shard %s/%s: %w", ks.Name, shard, err)
+			}
+			if shards[ks.Name][shard] {
+				return nil, nil, fmt.Errorf("duplicate shard %s/%s", ks.Name, shard)
+			}
+			shards[ks.Name][shard] = true
+		}
+	}
+
+	aliases := map[string]bool{}
+	primaries := map[string]string{}
+	tablets := make([]*topodatapb.Tablet, 0, len(f.Tablets))
+	for i, data := range f.Tablets {
+		tablet := &topodatapb.Tablet{}
+		if err := protojson.Unmarshal(data, tablet); err != nil {
+			return nil, nil, fmt.Errorf("invalid record of tablet #%d: %w", i, err)
+		}
+		if tablet.Alias == nil {
+			return nil, nil, fmt.Errorf("tablet #%d has no alias", i)
+		}
+		alias := topoproto.TabletAliasString(tablet.Alias)
+		if !slices.Contains(f.Cells, tablet.Alias.Cell) {
+			return nil, nil, fmt.Errorf("tablet %s is in undeclared cell %q", alias, tablet.Alias.Cell)
+		}
+		if aliases[alias] {
+			return nil, nil, fmt.Errorf("duplicate tablet %s", alias)
+		}
+		aliases[alias] = true
+		if tablet.Keyspace != "" || tablet.Shard != "" {
+			if !shards[tablet.Keyspace][tablet.Shard] {
+				return nil, nil, fmt.Errorf("tablet %s is in undeclared shard %s/%s", alias, tablet.Keyspace, tablet.Shard)
+			}
+		}
+		if tablet.Type == topodatapb.TabletType_PRIMARY {
+			if tablet.Keyspace == "" {
+				return nil, nil, fmt.Errorf("primary tablet %s has no shard", alias)
+			}
+			shard := tablet.Keyspace + "/" + tablet.Shard
+			if other, ok := primaries[shard]; ok {
+				return nil, nil, fmt.Errorf("shard %s has two primaries, %s and %s", shard, other, alias)
+			}
+			primaries[shard] = alias
+		}
+		tablets = append(tablets, tablet)
+	}
+	return keyspaces, tablets, nil
+}
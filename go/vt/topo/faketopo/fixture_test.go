@@ -0,0 +1,149 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package faketopo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"vitess.io/vitess/go/vt/topo/topoproto"
+
+	topodatapb "vitess.io/vitess/go/vt/proto/topodata"
+)
+
+func TestLoadFixture(t *testing.T) {
+	ctx := context.Background()
+	ts, err := LoadFixture(ctx, []byte(`
+cells: [zone1, zone2]
+keyspaces:
+- name: commerce
+  keyspace: {durabilityPolicy: semi_sync}
+  shards: ["-80", "80-"]
+- name: customer
+tablets:
+- {alias: {cell: zone1, uid: 100}, keyspace: commerce, shard: "-80", type: PRIMARY, hostname: host1}
+- {alias: {cell: zone2, uid: 101}, keyspace: commerce, shard: "-80", type: REPLICA}
+- {alias: {cell: zone1, uid: 200}, keyspace: commerce, shard: "80-", type: REPLICA}
+`))
+	require.NoError(t, err)
+
+	cells, err := ts.GetCellInfoNames(ctx)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"zone1", "zone2"}, cells)
+
+	ks, err := ts.GetKeyspace(ctx, "commerce")
+	require.NoError(t, err)
+	require.Equal(t, "semi_sync", ks.DurabilityPolicy)
+	_, err = ts.GetKeyspace(ctx, "customer")
+	require.NoError(t, err)
+
+	shards, err := ts.GetShardNames(ctx, "commerce")
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"-80", "80-"}, shards)
+	si, err := ts.GetShard(ctx, "commerce", "-80")
+	require.NoError(t, err)
+	require.Equal(t, "zone1-0000000100", topoproto.TabletAliasString(si.PrimaryAlias))
+	si, err = ts.GetShard(ctx, "commerce", "80-")
+	require.NoError(t, err)
+	require.Nil(t, si.PrimaryAlias)
+
+	tablet, err := ts.GetTablet(ctx, &topodatapb.TabletAlias{Cell: "zone1", Uid: 100})
+	require.NoError(t, err)
+	require.Equal(t, "host1", tablet.Hostname)
+	require.Equal(t, topodatapb.TabletType_PRIMARY, tablet.Type)
+	aliases, err := ts.GetTabletAliasesByCell(ctx, "zone1")
+	require.NoError(t, err)
+	require.Len(t, aliases, 2)
+
+	// The replication graph of the shards is built too.
+	sri, err := ts.GetShardReplication(ctx, "zone2", "commerce", "-80")
+	require.NoError(t, err)
+	require.Len(t, sri.Nodes, 1)
+}
+
+func TestLoadFixtureJSON(t *testing.T) {
+	ctx := context.Background()
+	ts, err := LoadFixture(ctx, []byte(`{"cells": ["zone1"], "keyspaces": [{"name": "ks", "shards": ["0"]}],
+		"tablets": [{"alias": {"cell": "zone1", "uid": 1}, "keyspace": "ks", "shard": "0", "type": "PRIMARY"}]}`))
+	require.NoError(t, err)
+	si, err := ts.GetShard(ctx, "ks", "0")
+	require.NoError(t, err)
+	require.Equal(t, uint32(1), si.PrimaryAlias.Uid)
+}
+
+func TestLoadFixtureInvalid(t *testing.T) {
+	for _, tcase := range []struct {
+		name, fixture, err string
+	}{{
+		name:    "syntax",
+		fixture: "cells: [zone1",
+		err:     "invalid fixture",
+	}, {
+		name:    "unknown field",
+		fixture: "cells: [zone1]\ntablets:\n- {alias: {cell: zone1, uid: 1}, colour: blue}",
+		err:     "invalid record of tablet #0",
+	}, {
+		name:    "unknown keyspace field",
+		fixture: "cells: [zone1]\nkeyspaces: [{name: ks, shard: [\"0\"]}]",
+		err:     "invalid fixture",
+	}, {
+		name:    "no cells",
+		fixture: "keyspaces: [{name: ks}]",
+		err:     "no cells",
+	}, {
+		name:    "duplicate cell",
+		fixture: "cells: [zone1, zone1]",
+		err:     "duplicate cell zone1",
+	}, {
+		name:    "duplicate keyspace",
+		fixture: "cells: [zone1]\nkeyspaces: [{name: ks}, {name: ks}]",
+		err:     "duplicate keyspace ks",
+	}, {
+		name:    "invalid shard",
+		fixture: "cells: [zone1]\nkeyspaces: [{name: ks, shards: [80-40]}]",
+		err:     "invalid shard ks/80-40",
+	}, {
+		name:    "no alias",
+		fixture: "cells: [zone1]\ntablets: [{hostname: host1}]",
+		err:     "tablet #0 has no alias",
+	}, {
+		name:    "undeclared cell",
+		fixture: "cells: [zone1]\ntablets: [{alias: {cell: zone2, uid: 1}}]",
+		err:     `tablet zone2-0000000001 is in undeclared cell "zone2"`,
+	}, {
+		name:    "undeclared shard",
+		fixture: "cells: [zone1]\nkeyspaces: [{name: ks, shards: [\"0\"]}]\ntablets: [{alias: {cell: zone1, uid: 1}, keyspace: ks, shard: \"-80\"}]",
+		err:     "tablet zone1-0000000001 is in undeclared shard ks/-80",
+	}, {
+		name:    "duplicate tablet",
+		fixture: "cells: [zone1]\ntablets: [{alias: {cell: zone1, uid: 1}}, {alias: {cell: zone1, uid: 1}}]",
+		err:     "duplicate tablet zone1-0000000001",
+	}, {
+		name: "two primaries",
+		fixture: "cells: [zone1]\nkeyspaces: [{name: ks, shards: [\"0\"]}]\ntablets:\n" +
+			"- {alias: {cell: zone1, uid: 1}, keyspace: ks, shard: \"0\", type: PRIMARY}\n" +
+			"- {alias: {cell: zone1, uid: 2}, keyspace: ks, shard: \"0\", type: PRIMARY}",
+		err: "shard ks/0 has two primaries, zone1-0000000001 and zone1-0000000002",
+	}} {
+		t.Run(tcase.name, func(t *testing.T) {
+			_, err := LoadFixture(context.Background(), []byte(tcase.fixture))
+			require.ErrorContains(t, err, tcase.err)
+		})
+	}
+}
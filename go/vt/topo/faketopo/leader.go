@@ -0,0 +1,252 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package faketopo
+
+import (
+	"context"
+	"path"
+	"sync"
+	"time"
+
+	"vitess.io/vitess/go/vt/log"
+	"vitess.io/vitess/go/vt/topo"
+)
+
+// electionsPath is the directory of the elections, as in the real topo
+// implementations. The leader of an election holds a lock on its path.
+const electionsPath = "elections"
+
+// SetLeader makes the process with the given id the leader of the election
+// name, as if another process had won it, so that WaitForLeadership blocks
+// until the leadership is released with an empty id. The participation
+// that was the leader, if any, loses the leadership and its context is
+// cancelled.
+func (f *FakeConn) SetLeader(name, id string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.setLeaderLocked(name, id)
+}
+
+// SetLeadershipDelay makes WaitForLeadership wait for delay before it runs
+// for election, or until the participation is stopped.
+func (f *FakeConn) SetLeadershipDelay(delay time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.leadershipDelay = delay
+}
+
+// setLeaderLocked sets the leader of the election name and wakes up the
+// callers waiting for a change. The caller must hold the mutex.
+func (f *FakeConn) setLeaderLocked(name, id string) {
+	if f.leaders[name] == id {
+		return
+	}
+	if f.leaders == nil {
+		f.leaders = map[string]string{}
+	}
+	if id == "" {
+		delete(f.leaders, name)
+	} else {
+		f.leaders[name] = id
+	}
+	if f.leaderChanged != nil {
+		close(f.leaderChanged)
+		f.leaderChanged = nil
+	}
+}
+
+// leaderChangedLocked returns a channel that is closed once the leader of
+// an election changes. The caller must hold the mutex.
+func (f *FakeConn) leaderChangedLocked() <-chan struct{} {
+	if f.leaderChanged == nil {
+		f.leaderChanged = make(chan struct{})
+	}
+	return f.leaderChanged
+}
+
+// NewLeaderParticipation implements the Conn interface. The participation
+// wins the election as soon as no other process holds it, see SetLeader,
+// and holds a lock on the election path while it leads, see Locks.
+func (f *FakeConn) NewLeaderParticipation(name, id string) (topo.LeaderParticipation, error) {
+	if err := f.checkOpen(path.Join(electionsPath, name)); err != nil {
+		return nil, err
+	}
+	return &fakeLeaderParticipation{
+		conn: f,
+		name: name,
+		id:   id,
+		stop: make(chan struct{}),
+	}, nil
+}
+
+// fakeLeaderParticipation implements the topo.LeaderParticipation interface.
+type fakeLeaderParticipation struct {
+	conn *FakeConn
+	name string
+	id   string
+
+	// stop is closed when Stop is called, and stopOnce guards it.
+	stop     chan struct{}
+	stopOnce sync.Once
+	// leading tracks the goroutine that gives up the leadership, so that
+	// Stop can wait for it.
+	leading sync.WaitGroup
+}
+
+var _ topo.LeaderParticipation = (*fakeLeaderParticipation)(nil)
+
+// WaitForLeadership implements the topo.LeaderParticipation interface.
+func (mp *fakeLeaderParticipation) WaitForLeadership() (context.Context, error) {
+	f := mp.conn
+	electionPath := f.normalizePath(path.Join(electionsPath, mp.name))
+	interrupted := topo.NewError(topo.Interrupted, "Leadership")
+
+	f.mu.Lock()
+	delay := f.leadershipDelay
+	f.mu.Unlock()
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-mp.stop:
+			return nil, interrupted
+		}
+	}
+
+	var lock *fakeLockDescriptor
+	for lock == nil {
+		select {
+		case <-mp.stop:
+			return nil, interrupted
+		default:
+		}
+		f.mu.Lock()
+		if f.closed {
+			f.mu.Unlock()
+			return nil, interrupted
+		}
+		if f.leaders[mp.name] == "" {
+			f.setLeaderLocked(mp.name, mp.id)
+			lock = f.lockLocked(electionPath, mp.id, 0)
+			f.mu.Unlock()
+			continue
+		}
+		changed := f.leaderChangedLocked()
+		f.mu.Unlock()
+		select {
+		case <-changed:
+		case <-mp.stop:
+			return nil, interrupted
+		}
+	}
+
+	// The returned context is cancelled once the leadership is lost, either
+	// to Stop or to another process, see SetLeader.
+	leaderCtx, cancel := context.WithCancel(context.Background())
+	mp.leading.Add(1)
+	go mp.lead(lock, cancel)
+	return leaderCtx, nil
+}
+
+// lead holds the leadership until Stop is called or another process takes
+// it, then releases the lock and cancels the context of the leader.
+func (mp *fakeLeaderParticipation) lead(lock *fakeLockDescriptor, cancel context.CancelFunc) {
+	f := mp.conn
+	defer mp.leading.Done()
+	defer cancel()
+	defer func() {
+		if err := lock.Unlock(context.Background()); err != nil {
+			log.Errorf("failed to unlock LockDescriptor %v: %v", lock.dirPath, err)
+		}
+	}()
+	for {
+		f.mu.Lock()
+		if f.leaders[mp.name] != mp.id {
+			f.mu.Unlock()
+			return
+		}
+		changed := f.leaderChangedLocked()
+		f.mu.Unlock()
+		select {
+		case <-changed:
+		case <-mp.stop:
+			f.mu.Lock()
+			f.setLeaderLocked(mp.name, "")
+			f.mu.Unlock()
+			return
+		}
+	}
+}
+
+// Stop implements the topo.LeaderParticipation interface. It gives up the
+// leadership, if the participation holds it, before it returns.
+func (mp *fakeLeaderParticipation) Stop() {
+	mp.stopOnce.Do(func() { close(mp.stop) })
+	mp.leading.Wait()
+}
+
+// GetCurrentLeaderID implements the topo.LeaderParticipation interface. It
+// returns an empty id when the election has no leader.
+func (mp *fakeLeaderParticipation) GetCurrentLeaderID(ctx context.Context) (string, error) {
+	f := mp.conn
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return "", topo.NewError(topo.Interrupted, path.Join(electionsPath, mp.name))
+	}
+	return f.leaders[mp.name], nil
+}
+
+// WaitForNewLeader implements the topo.LeaderParticipation interface. The
+// channel receives the current leader, if any, and then every new one. It
+// is closed once ctx is done or the participation is stopped.
+func (mp *fakeLeaderParticipation) WaitForNewLeader(ctx context.Context) (<-chan string, error) {
+	f := mp.conn
+	if err := f.checkOpen(path.Join(electionsPath, mp.name)); err != nil {
+		return nil, err
+	}
+	notifications := make(chan string, 8)
+	go func() {
+		defer close(notifications)
+		var last string
+		for {
+			f.mu.Lock()
+			leader := f.leaders[mp.name]
+			changed := f.leaderChangedLocked()
+			f.mu.Unlock()
+			if leader != "" && leader != last {
+				last = leader
+				select {
+				case notifications <- leader:
+				case <-ctx.Done():
+					return
+				case <-mp.stop:
+					return
+				}
+			}
+			select {
+			case <-changed:
+			case <-ctx.Done():
+				return
+			case <-mp.stop:
+				return
+			}
+		}
+	}()
+	return notifications, nil
+}
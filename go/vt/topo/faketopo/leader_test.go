@@ -0,0 +1,106 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package faketopo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"vitess.io/vitess/go/vt/topo"
+)
+
+func TestLeaderParticipation(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	conn := NewFakeConnection()
+
+	// Another process holds the leadership, so the participation waits.
+	conn.SetLeader("vtorc", "other")
+	mp, err := conn.NewLeaderParticipation("vtorc", "me")
+	require.NoError(t, err)
+	leaders, err := mp.WaitForNewLeader(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "other", <-leaders)
+	leading := make(chan context.Context)
+	go func() {
+		leaderCtx, err := mp.WaitForLeadership()
+		if err != nil {
+			t.Error(err)
+		}
+		leading <- leaderCtx
+	}()
+	select {
+	case <-leading:
+		t.Fatal("won the election while another process leads")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	// Once it is released, the participation wins and holds the lock.
+	conn.SetLeader("vtorc", "")
+	leaderCtx := <-leading
+	require.NoError(t, leaderCtx.Err())
+	require.Equal(t, "me", <-leaders)
+	id, err := mp.GetCurrentLeaderID(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "me", id)
+	locks := conn.Locks()
+	require.Len(t, locks, 1)
+	require.Equal(t, "elections/vtorc", locks[0].DirPath)
+	require.True(t, locks[0].Held())
+
+	// Losing the leadership to another process cancels the context.
+	conn.SetLeader("vtorc", "other")
+	<-leaderCtx.Done()
+	require.Equal(t, "other", <-leaders)
+
+	// Stop gives up the leadership and interrupts a later wait.
+	conn.SetLeader("vtorc", "")
+	leaderCtx, err = mp.WaitForLeadership()
+	require.NoError(t, err)
+	mp.Stop()
+	require.Error(t, leaderCtx.Err())
+	id, err = mp.GetCurrentLeaderID(ctx)
+	require.NoError(t, err)
+	require.Empty(t, id)
+	for _, lock := range conn.Locks() {
+		require.False(t, lock.Held())
+	}
+	_, err = mp.WaitForLeadership()
+	require.True(t, topo.IsErrType(err, topo.Interrupted), "unexpected error: %v", err)
+	// The channel of new leaders is closed too.
+	for range leaders {
+	}
+}
+
+func TestLeadershipDelay(t *testing.T) {
+	conn := NewFakeConnection()
+	conn.SetLeadershipDelay(time.Hour)
+	mp, err := conn.NewLeaderParticipation("vtorc", "me")
+	require.NoError(t, err)
+
+	// Stop interrupts a participation waiting for its delay.
+	done := make(chan error)
+	go func() {
+		_, err := mp.WaitForLeadership()
+		done <- err
+	}()
+	mp.Stop()
+	require.True(t, topo.IsErrType(<-done, topo.Interrupted))
+}
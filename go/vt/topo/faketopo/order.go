@@ -0,0 +1,113 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package faketopo
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"vitess.io/vitess/go/vt/topo"
+)
+
+// The other operations of a Call. ReplayCalls doesn't replay
+// CallWatchRecursive, nor the locks.
+const (
+	CallList   CallOp = "List"
+	CallDelete CallOp = "Delete"
+	CallWatch  CallOp = "Watch"
+
+	CallWatchRecursive CallOp = "WatchRecursive"
+
+	CallLock        CallOp = "Lock"
+	CallLockWithTTL CallOp = "LockWithTTL"
+	CallLockName    CallOp = "LockName"
+	CallTryLock     CallOp = "TryLock"
+)
+
+// OrderStep is an operation on a path, as recorded by SetRecordOperations
+// and expected by AssertOrder.
+type OrderStep struct {
+	Op   CallOp
+	Path string
+}
+
+func (s OrderStep) String() string {
+	return fmt.Sprintf("%s(%s)", s.Op, s.Path)
+}
+
+// SetRecordOperations makes the connection record its Create, Update,
+// Delete, Get, List, ListDir, Watch, WatchRecursive and lock calls, in the
+// order they are made, for AssertOrder. Calls are recorded as they start, whether or not they
+// succeed. Turning it off discards the recorded operations.
+func (f *FakeConn) SetRecordOperations(record bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.recordOps = record
+	if !record {
+		f.ops = nil
+	}
+}
+
+// recordOp records call, with the version given to Update and Delete, if
+// any, for SetRecordOperations and EnableCallLog.
+func (f *FakeConn) recordOp(call Call, version topo.Version) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.recordOps {
+		f.ops = append(f.ops, OrderStep{Op: call.Op, Path: call.Path})
+	}
+	if f.recordCalls {
+		f.calls = append(f.calls, loggedCall{
+			RecordedCall: RecordedCall{Op: call.Op, Path: call.Path, Version: version, Time: f.nowLocked()},
+			// The caller may reuse its buffer once the call returns.
+			contents: slices.Clone(call.Contents),
+		})
+	}
+}
+
+// AssertOrder fails t unless the operations recorded since
+// SetRecordOperations was turned on contain steps in this relative order,
+// such as the Create of a shard before the Update of the SrvKeyspace that
+// serves it. Other operations may happen before, between and after the
+// steps. It checks multi-step reconcilers for which the order of their
+// writes matters for correctness.
+func (f *FakeConn) AssertOrder(t testing.TB, steps ...OrderStep) {
+	t.Helper()
+	f.mu.Lock()
+	ops := slices.Clone(f.ops)
+	f.mu.Unlock()
+
+	next := 0
+	for i, step := range steps {
+		found := slices.Index(ops[next:], step)
+		if found < 0 {
+			recorded := make([]string, 0, len(ops))
+			for _, op := range ops {
+				recorded = append(recorded, op.String())
+			}
+			if i > 0 && slices.Contains(ops, step) {
+				t.Errorf("%v did not happen after %v, recorded operations: %s", step, steps[i-1], strings.Join(recorded, ", "))
+			} else {
+				t.Errorf("%v did not happen, recorded operations: %s", step, strings.Join(recorded, ", "))
+			}
+			return
+		}
+		next += found + 1
+	}
+}
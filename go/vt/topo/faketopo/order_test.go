@@ -0,0 +1,86 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package faketopo
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// orderT records the failures of AssertOrder.
+type orderT struct {
+	testing.TB
+	errors []string
+}
+
+func (t *orderT) Helper() {}
+
+func (t *orderT) Errorf(format string, args ...any) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func TestAssertOrder(t *testing.T) {
+	ctx := context.Background()
+	conn := NewFakeConnection()
+	shardPath := "keyspaces/ks1/shards/0/Shard"
+	srvKeyspacePath := "keyspaces/ks1/SrvKeyspace"
+	createShard := OrderStep{CallCreate, shardPath}
+	updateSrvKeyspace := OrderStep{CallUpdate, srvKeyspacePath}
+
+	// Operations aren't recorded by default.
+	_, err := conn.Create(ctx, srvKeyspacePath, []byte("v0"))
+	require.NoError(t, err)
+	ot := &orderT{TB: t}
+	conn.AssertOrder(ot, OrderStep{CallCreate, srvKeyspacePath})
+	require.Len(t, ot.errors, 1)
+
+	conn.SetRecordOperations(true)
+	_, err = conn.Create(ctx, shardPath, []byte("shard"))
+	require.NoError(t, err)
+	_, _, err = conn.Get(ctx, srvKeyspacePath)
+	require.NoError(t, err)
+	_, err = conn.Update(ctx, srvKeyspacePath, []byte("v1"), nil)
+	require.NoError(t, err)
+
+	// Other operations may happen between the steps.
+	conn.AssertOrder(t, createShard, updateSrvKeyspace)
+	conn.AssertOrder(t, OrderStep{CallGet, srvKeyspacePath})
+
+	// A reconciler that publishes the SrvKeyspace before creating the shard
+	// is caught.
+	conn.SetRecordOperations(false)
+	conn.SetRecordOperations(true)
+	_, err = conn.Update(ctx, srvKeyspacePath, []byte("v2"), nil)
+	require.NoError(t, err)
+	_, err = conn.Create(ctx, "keyspaces/ks1/shards/1/Shard", []byte("shard"))
+	require.NoError(t, err)
+	ot = &orderT{TB: t}
+	conn.AssertOrder(ot, OrderStep{CallCreate, "keyspaces/ks1/shards/1/Shard"}, updateSrvKeyspace)
+	require.Equal(t, []string{
+		"Update(keyspaces/ks1/SrvKeyspace) did not happen after Create(keyspaces/ks1/shards/1/Shard), " +
+			"recorded operations: Update(keyspaces/ks1/SrvKeyspace), Create(keyspaces/ks1/shards/1/Shard)",
+	}, ot.errors)
+
+	// Steps that never happened are reported too.
+	ot = &orderT{TB: t}
+	conn.AssertOrder(ot, createShard)
+	require.Len(t, ot.errors, 1)
+	require.Contains(t, ot.errors[0], "Create(keyspaces/ks1/shards/0/Shard) did not happen, recorded operations")
+}
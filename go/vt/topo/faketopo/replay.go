@@ -0,0 +1,182 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package faketopo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"vitess.io/vitess/go/vt/topo"
+)
+
+// CallOp is the topo.Conn method of a Call.
+type CallOp string
+
+// The operations of a Call. See order.go for the others.
+const (
+	CallCreate  CallOp = "Create"
+	CallUpdate  CallOp = "Update"
+	CallGet     CallOp = "Get"
+	CallListDir CallOp = "ListDir"
+)
+
+// Call is a single topo.Conn operation of a call log, either written by
+// hand or returned by ReplayLog.
+type Call struct {
+	Op   CallOp
+	Path string
+	// Contents are the contents written by Create and Update.
+	Contents []byte
+	// Versioned makes an Update or a Delete conditional on the version the
+	// previous operation on Path returned. Otherwise it is unconditional.
+	Versioned bool
+}
+
+func (c Call) String() string {
+	if c.Op == CallCreate || c.Op == CallUpdate {
+		return fmt.Sprintf("%s(%s, %q)", c.Op, c.Path, c.Contents)
+	}
+	return fmt.Sprintf("%s(%s)", c.Op, c.Path)
+}
+
+// Divergence is a call of a call log that returned different results on the
+// reference and on the fake connection.
+type Divergence struct {
+	// Index is the position of Call in the call log.
+	Index     int
+	Call      Call
+	Reference string
+	Fake      string
+}
+
+func (d Divergence) String() string {
+	return fmt.Sprintf("call #%d %v: reference returned %s, fake returned %s", d.Index, d.Call, d.Reference, d.Fake)
+}
+
+// ReplayCalls runs calls, in order, against both the reference connection,
+// such as a memorytopo or a real topo server, and the fake connection, and
+// returns the calls whose results differ. Results are compared by contents
+// and error code only, since versions are numbered differently by each
+// implementation. Both connections should start out empty.
+func ReplayCalls(ctx context.Context, calls []Call, reference, fake topo.Conn) []Divergence {
+	referenceVersions := map[string]topo.Version{}
+	fakeVersions := map[string]topo.Version{}
+	var divergences []Divergence
+	for i, call := range calls {
+		want := replayCall(ctx, reference, call, referenceVersions)
+		got := replayCall(ctx, fake, call, fakeVersions)
+		if want != got {
+			divergences = append(divergences, Divergence{Index: i, Call: call, Reference: want, Fake: got})
+		}
+	}
+	return divergences
+}
+
+// replayCall runs call on conn and describes its result. versions holds the
+// latest version returned for each path, for versioned updates.
+func replayCall(ctx context.Context, conn topo.Conn, call Call, versions map[string]topo.Version) string {
+	var (
+		version topo.Version
+		result  string
+		err     error
+	)
+	switch call.Op {
+	case CallCreate:
+		version, err = conn.Create(ctx, call.Path, call.Contents)
+	case CallUpdate:
+		var expected topo.Version
+		if call.Versioned {
+			expected = versions[call.Path]
+		}
+		version, err = conn.Update(ctx, call.Path, call.Contents, expected)
+	case CallDelete:
+		var expected topo.Version
+		if call.Versioned {
+			expected = versions[call.Path]
+		}
+		err = conn.Delete(ctx, call.Path, expected)
+	case CallGet:
+		var contents []byte
+		contents, version, err = conn.Get(ctx, call.Path)
+		result = fmt.Sprintf("%q", contents)
+	case CallList:
+		var kvs []topo.KVInfo
+		kvs, err = conn.List(ctx, call.Path)
+		entries := make([]string, 0, len(kvs))
+		for _, kv := range kvs {
+			entries = append(entries, fmt.Sprintf("%s=%q", kv.Key, kv.Value))
+		}
+		result = "[" + strings.Join(entries, " ") + "]"
+	case CallWatch:
+		// Only the current value is compared, the watch is cancelled at once.
+		watchCtx, cancel := context.WithCancel(ctx)
+		var current *topo.WatchData
+		current, _, err = conn.Watch(watchCtx, call.Path)
+		cancel()
+		if err == nil {
+			result = fmt.Sprintf("%q", current.Contents)
+		}
+	case CallListDir:
+		var entries []topo.DirEntry
+		entries, err = conn.ListDir(ctx, call.Path, false)
+		names := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			names = append(names, entry.Name)
+		}
+		result = "[" + strings.Join(names, " ") + "]"
+	default:
+		return fmt.Sprintf("unknown operation %q", call.Op)
+	}
+	if err != nil {
+		return "error " + replayErrorCode(err)
+	}
+	if version != nil {
+		versions[call.Path] = version
+	}
+	if result == "" {
+		return "ok"
+	}
+	return result
+}
+
+// replayErrorCodes names the topo error codes, to compare errors by code.
+var replayErrorCodes = []struct {
+	code topo.ErrorCode
+	name string
+}{
+	{topo.NodeExists, "NodeExists"},
+	{topo.NoNode, "NoNode"},
+	{topo.NodeNotEmpty, "NodeNotEmpty"},
+	{topo.Timeout, "Timeout"},
+	{topo.Interrupted, "Interrupted"},
+	{topo.BadVersion, "BadVersion"},
+	{topo.PartialResult, "PartialResult"},
+	{topo.NoUpdateNeeded, "NoUpdateNeeded"},
+	{topo.NoImplementation, "NoImplementation"},
+	{topo.NoReadOnlyImplementation, "NoReadOnlyImplementation"},
+	{topo.ResourceExhausted, "ResourceExhausted"},
+}
+
+func replayErrorCode(err error) string {
+	for _, c := range replayErrorCodes {
+		if topo.IsErrType(err, c.code) {
+			return c.name
+		}
+	}
+	return fmt.Sprintf("%q", err.Error())
+}
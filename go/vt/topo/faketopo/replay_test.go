@@ -0,0 +1,67 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package faketopo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"vitess.io/vitess/go/vt/topo"
+	"vitess.io/vitess/go/vt/topo/memorytopo"
+)
+
+func newReplayReference(t *testing.T, ctx context.Context) topo.Conn {
+	ts, factory := memorytopo.NewServerAndFactory(ctx, "cell")
+	t.Cleanup(ts.Close)
+	conn, err := factory.Create(topo.GlobalCell, "", "")
+	require.NoError(t, err)
+	return conn
+}
+
+func TestReplayCalls(t *testing.T) {
+	ctx := context.Background()
+	filePath := "/keyspaces/ks1/Keyspace"
+	calls := []Call{
+		{Op: CallCreate, Path: filePath, Contents: []byte("v1")},
+		{Op: CallGet, Path: filePath},
+		{Op: CallUpdate, Path: filePath, Contents: []byte("v2"), Versioned: true},
+		{Op: CallGet, Path: filePath},
+		{Op: CallUpdate, Path: "/keyspaces/ks2/Keyspace", Contents: []byte("v1")},
+		{Op: CallGet, Path: "/keyspaces/ks3/Keyspace"},
+	}
+	divergences := ReplayCalls(ctx, calls, newReplayReference(t, ctx), NewFakeConnection())
+	require.Empty(t, divergences)
+}
+
+func TestReplayCallsDivergence(t *testing.T) {
+	ctx := context.Background()
+	filePath := "/keyspaces/ks1/Keyspace"
+	calls := []Call{
+		{Op: CallCreate, Path: filePath, Contents: []byte("v1")},
+		// The real topo refuses to create a node twice, the fake overwrites it.
+		{Op: CallCreate, Path: filePath, Contents: []byte("v2")},
+		{Op: CallGet, Path: filePath},
+	}
+	divergences := ReplayCalls(ctx, calls, newReplayReference(t, ctx), NewFakeConnection())
+	require.Len(t, divergences, 2)
+	require.Equal(t, 1, divergences[0].Index)
+	require.Equal(t, "error NodeExists", divergences[0].Reference)
+	require.Equal(t, "ok", divergences[0].Fake)
+	require.Equal(t, `call #2 Get(/keyspaces/ks1/Keyspace): reference returned "v1", fake returned "v2"`, divergences[1].String())
+}
@@ -0,0 +1,158 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package faketopo
+
+import (
+	"bytes"
+	"context"
+	"maps"
+	"slices"
+	"testing"
+	"time"
+
+	"vitess.io/vitess/go/vt/topo"
+)
+
+// SplitBrain makes the connections of several cells disagree on the
+// contents of the same record, to test code that detects or reconciles
+// inconsistent topo state. Each cell has its own FakeConn, so the cells
+// naturally hold separate copies of every record; SplitBrain deliberately
+// writes different values to them and checks whether they agree again.
+//
+// A typical scenario is:
+//
+//	conns := map[string]*faketopo.FakeConn{"zone1": factory.AddCell("zone1"), "zone2": factory.AddCell("zone2")}
+//	sb := faketopo.NewSplitBrain(conns)
+//	err := sb.Diverge(ctx, "SrvKeyspace", map[string][]byte{"zone1": a, "zone2": b})
+//	// ... run the consumer, which should notice that zone1 and zone2 disagree
+//	// and either report it or rewrite one of the cells ...
+//	sb.WaitForConsistency(t, "SrvKeyspace", faketopo.ConvergenceOptions{})
+type SplitBrain struct {
+	cells map[string]*FakeConn
+}
+
+// NewSplitBrain returns a SplitBrain over the given connections, keyed by
+// cell name.
+func NewSplitBrain(cells map[string]*FakeConn) *SplitBrain {
+	return &SplitBrain{cells: cells}
+}
+
+// Diverge writes contents[cell] to filePath in each of the given cells,
+// creating the record where it doesn't exist yet. Existing records are
+// updated at their current version, so that the watches of each cell see
+// their own value.
+func (sb *SplitBrain) Diverge(ctx context.Context, filePath string, contents map[string][]byte) error {
+	for _, cell := range slices.Sorted(maps.Keys(contents)) {
+		conn, ok := sb.cells[cell]
+		if !ok {
+			return topo.NewError(topo.NoNode, cell)
+		}
+		_, version, err := conn.Get(ctx, filePath)
+		switch {
+		case topo.IsErrType(err, topo.NoNode):
+			_, err = conn.Create(ctx, filePath, contents[cell])
+		case err == nil:
+			_, err = conn.Update(ctx, filePath, contents[cell], version)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Contents returns the contents of filePath in each cell, keyed by cell
+// name. Cells where the record doesn't exist are left out.
+func (sb *SplitBrain) Contents(ctx context.Context, filePath string) (map[string][]byte, error) {
+	contents := map[string][]byte{}
+	for cell, conn := range sb.cells {
+		data, _, err := conn.Get(ctx, filePath)
+		switch {
+		case topo.IsErrType(err, topo.NoNode):
+		case err != nil:
+			return nil, err
+		default:
+			contents[cell] = data
+		}
+	}
+	return contents, nil
+}
+
+// Divergent returns true if the cells don't all hold the same contents for
+// filePath. A record missing from some cells but not others is divergent.
+func (sb *SplitBrain) Divergent(ctx context.Context, filePath string) (bool, error) {
+	contents, err := sb.Contents(ctx, filePath)
+	if err != nil {
+		return false, err
+	}
+	if len(contents) != 0 && len(contents) != len(sb.cells) {
+		return true, nil
+	}
+	var first []byte
+	seen := false
+	for _, data := range contents {
+		if seen && !bytes.Equal(first, data) {
+			return true, nil
+		}
+		first, seen = data, true
+	}
+	return false, nil
+}
+
+// RequireDivergent fails the test unless the cells disagree on filePath.
+func (sb *SplitBrain) RequireDivergent(t testing.TB, filePath string) {
+	t.Helper()
+	divergent, err := sb.Divergent(context.Background(), filePath)
+	if err != nil {
+		t.Fatalf("reading %v failed: %v", filePath, err)
+		return
+	}
+	if !divergent {
+		t.Fatalf("cells agree on %v, want them to diverge", filePath)
+	}
+}
+
+// WaitForConsistency polls the cells until they all agree on filePath,
+// failing the test if they still disagree after the timeout. It confirms
+// that a consumer reconciled the divergence created by Diverge.
+func (sb *SplitBrain) WaitForConsistency(t testing.TB, filePath string, opts ConvergenceOptions) {
+	t.Helper()
+	if opts.Timeout == 0 {
+		opts.Timeout = DefaultConvergenceTimeout
+	}
+	if opts.PollInterval == 0 {
+		opts.PollInterval = DefaultConvergencePollInterval
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(opts.PollInterval)
+	defer ticker.Stop()
+	for {
+		divergent, err := sb.Divergent(ctx, filePath)
+		if err == nil && !divergent {
+			return
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			contents, _ := sb.Contents(context.Background(), filePath)
+			t.Fatalf("cells still disagree on %v after %v: %q", filePath, opts.Timeout, contents)
+			return
+		}
+	}
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package faketopo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitBrain(t *testing.T) {
+	ctx := context.Background()
+	factory := NewFakeTopoFactory()
+	zone1, zone2 := factory.AddCell("zone1"), factory.AddCell("zone2")
+	sb := NewSplitBrain(map[string]*FakeConn{"zone1": zone1, "zone2": zone2})
+
+	_, err := zone1.Create(ctx, "SrvKeyspace", []byte("v1"))
+	require.NoError(t, err)
+	sb.RequireDivergent(t, "SrvKeyspace")
+
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	_, changes, err := zone1.Watch(watchCtx, "SrvKeyspace")
+	require.NoError(t, err)
+
+	require.NoError(t, sb.Diverge(ctx, "SrvKeyspace", map[string][]byte{"zone1": []byte("v2"), "zone2": []byte("v3")}))
+	sb.RequireDivergent(t, "SrvKeyspace")
+	require.Equal(t, []byte("v2"), (<-changes).Contents, "the watches of a cell see the value of that cell")
+	contents, err := sb.Contents(ctx, "SrvKeyspace")
+	require.NoError(t, err)
+	require.Equal(t, map[string][]byte{"zone1": []byte("v2"), "zone2": []byte("v3")}, contents)
+
+	// A consumer reconciling the cells makes them consistent again.
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		_, version, _ := zone2.Get(ctx, "SrvKeyspace")
+		_, _ = zone2.Update(ctx, "SrvKeyspace", []byte("v2"), version)
+	}()
+	sb.WaitForConsistency(t, "SrvKeyspace", ConvergenceOptions{})
+
+	// Cells that never reconcile fail the test.
+	require.NoError(t, sb.Diverge(ctx, "SrvKeyspace", map[string][]byte{"zone2": []byte("v4")}))
+	recorder := &fatalRecorder{TB: t}
+	sb.WaitForConsistency(recorder, "SrvKeyspace", ConvergenceOptions{Timeout: 20 * time.Millisecond, PollInterval: time.Millisecond})
+	require.Contains(t, recorder.failure, "cells still disagree on SrvKeyspace")
+
+	require.Error(t, sb.Diverge(ctx, "SrvKeyspace", map[string][]byte{"zone3": []byte("v5")}))
+}
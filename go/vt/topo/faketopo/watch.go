@@ -0,0 +1,44 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package faketopo
+
+import (
+	"context"
+
+	"vitess.io/vitess/go/vt/topo"
+)
+
+// WithWatch watches filePath on conn and runs fn with the current value and
+// the channel of changes. When fn returns, the watch is torn down: its
+// context is cancelled and the channel is drained until the connection
+// closes it, so that no watch nor goroutine outlives the call. The error of
+// Watch is returned without running fn.
+func WithWatch(ctx context.Context, conn topo.Conn, filePath string, fn func(current *topo.WatchData, changes <-chan *topo.WatchData)) error {
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	current, changes, err := conn.Watch(watchCtx, filePath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		cancel()
+		for range changes {
+		}
+	}()
+	fn(current, changes)
+	return nil
+}
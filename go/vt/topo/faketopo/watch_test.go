@@ -0,0 +1,62 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package faketopo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"vitess.io/vitess/go/vt/topo"
+)
+
+func TestWithWatch(t *testing.T) {
+	ctx := context.Background()
+	conn := NewFakeConnection()
+	version, err := conn.Create(ctx, "keyspaces/ks1/Keyspace", []byte("v1"))
+	require.NoError(t, err)
+
+	err = WithWatch(ctx, conn, "keyspaces/ks1/Keyspace", func(current *topo.WatchData, changes <-chan *topo.WatchData) {
+		require.Equal(t, []byte("v1"), current.Contents)
+		require.Equal(t, 1, conn.OutstandingWatches())
+		_, err := conn.Update(ctx, "keyspaces/ks1/Keyspace", []byte("v2"), version)
+		require.NoError(t, err)
+		select {
+		case event := <-changes:
+			require.Equal(t, []byte("v2"), event.Contents)
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for the change")
+		}
+	})
+	require.NoError(t, err)
+	require.Equal(t, 0, conn.OutstandingWatches())
+
+	// The watch is torn down even when fn doesn't consume its events.
+	conn.SetWatchJitter("keyspaces/ks1/Keyspace", time.Millisecond, 2*time.Millisecond, 1)
+	err = WithWatch(ctx, conn, "keyspaces/ks1/Keyspace", func(current *topo.WatchData, changes <-chan *topo.WatchData) {
+		conn.EmitWatch("keyspaces/ks1/Keyspace", &topo.WatchData{Contents: []byte("v3")})
+	})
+	require.NoError(t, err)
+	require.Equal(t, 0, conn.OutstandingWatches())
+
+	err = WithWatch(ctx, conn, "keyspaces/ks2/Keyspace", func(current *topo.WatchData, changes <-chan *topo.WatchData) {
+		t.Fatal("fn should not run when Watch fails")
+	})
+	require.True(t, topo.IsErrType(err, topo.NoNode))
+}
@@ -422,6 +422,10 @@ func (t *noopVCursor) GetLogs() ([]ExecuteEntry, error) {
 func (t *noopVCursor) RecordMirrorStats(sourceExecTime, targetExecTime time.Duration, targetErr error) {
 }
 
+// RecordOnlineDDL implements VCursor.
+func (t *noopVCursor) RecordOnlineDDL(uuid string) {
+}
+
 var (
 	_ VCursor        = (*loggingVCursor)(nil)
 	_ SessionActions = (*loggingVCursor)(nil)
@@ -106,6 +106,9 @@ func (hj *HashJoin) TryExecute(ctx context.Context, vcursor VCursor, bindVars ma
 			return nil, err
 		}
 	}
+	if stats, ok := StatsSinkFromContext(ctx); ok {
+		stats.RecordRowsBuffered(len(lresult.Rows))
+	}
 
 	rresult, err := vcursor.ExecutePrimitive(ctx, hj.Right, bindVars, wantfields)
 	if err != nil {
@@ -136,6 +139,7 @@ func (hj *HashJoin) TryStreamExecute(ctx context.Context, vcursor VCursor, bindV
 	// build the probe table from the LHS result
 	pt := newHashJoinProbeTable(hj.Collation, hj.ComparisonType, hj.LHSKey, hj.RHSKey, hj.Cols, hj.Values)
 	var lfields []*querypb.Field
+	var lrows int
 	var mu sync.Mutex
 	err := vcursor.StreamExecutePrimitive(ctx, hj.Left, bindVars, wantfields, func(result *sqltypes.Result) error {
 		mu.Lock()
@@ -149,6 +153,10 @@ func (hj *HashJoin) TryStreamExecute(ctx context.Context, vcursor VCursor, bindV
 				return err
 			}
 		}
+		lrows += len(result.Rows)
+		if stats, ok := StatsSinkFromContext(ctx); ok {
+			stats.RecordRowsBuffered(lrows)
+		}
 		return nil
 	})
 	if err != nil {
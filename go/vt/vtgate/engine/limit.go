@@ -86,6 +86,9 @@ func (l *Limit) TryExecute(ctx context.Context, vcursor VCursor, bindVars map[st
 
 	// There are more rows in the response than limit + offset
 	if count+offset <= len(result.Rows) {
+		if stats, ok := StatsSinkFromContext(ctx); ok && len(result.Rows) > count+offset {
+			stats.AddRowsTruncated(len(result.Rows) - count - offset)
+		}
 		result.Rows = result.Rows[offset : count+offset]
 		return result, nil
 	}
@@ -153,6 +156,9 @@ func (l *Limit) TryStreamExecute(ctx context.Context, vcursor VCursor, bindVars
 
 			// If we require the complete input, or we are in a transaction, we cannot return io.EOF early.
 			// Instead, we return empty results as needed until input ends.
+			if stats, ok := StatsSinkFromContext(ctx); ok && len(qr.Rows) > 0 {
+				stats.AddRowsTruncated(len(qr.Rows))
+			}
 			qr.Rows = nil
 			return callback(qr)
 		}
@@ -168,6 +174,9 @@ func (l *Limit) TryStreamExecute(ctx context.Context, vcursor VCursor, bindVars
 			return callback(qr)
 		}
 
+		if stats, ok := StatsSinkFromContext(ctx); ok && resultSize > count {
+			stats.AddRowsTruncated(resultSize - count)
+		}
 		qr.Rows = qr.Rows[:count]
 		count = 0
 		if err := callback(qr); err != nil {
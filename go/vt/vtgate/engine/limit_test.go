@@ -625,3 +625,42 @@ func TestLimitInvalidCount(t *testing.T) {
 	err = l.TryStreamExecute(context.Background(), &noopVCursor{}, nil, false, func(_ *sqltypes.Result) error { return nil })
 	assert.EqualError(t, err, "requested limit is out of range: 18446744073709551615")
 }
+
+func TestLimitTruncationStats(t *testing.T) {
+	fields := sqltypes.MakeTestFields(
+		"col1|col2",
+		"int64|varchar",
+	)
+	fp := &fakePrimitive{
+		results: []*sqltypes.Result{sqltypes.MakeTestResult(
+			fields,
+			"a|1",
+			"b|2",
+			"c|3",
+			"d|4",
+		)},
+	}
+	l := &Limit{Count: evalengine.NewLiteralInt(2), Offset: evalengine.NewLiteralInt(1), Input: fp}
+
+	stats := &testStatsSink{}
+	ctx := WithStatsSink(context.Background(), stats)
+
+	result, err := l.TryExecute(ctx, &noopVCursor{}, map[string]*querypb.BindVariable{}, false)
+	require.NoError(t, err)
+	assert.Len(t, result.Rows, 2)
+	assert.Equal(t, 1, stats.rowsTruncated)
+
+	stats.rowsTruncated = 0
+	fp.rewind()
+	err = l.TryStreamExecute(ctx, &noopVCursor{}, map[string]*querypb.BindVariable{}, false, func(_ *sqltypes.Result) error { return nil })
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.rowsTruncated)
+
+	// Results that fit within the limit are not reported.
+	stats.rowsTruncated = 0
+	fp.rewind()
+	l.Count = evalengine.NewLiteralInt(10)
+	_, err = l.TryExecute(ctx, &noopVCursor{}, map[string]*querypb.BindVariable{}, false)
+	require.NoError(t, err)
+	assert.Zero(t, stats.rowsTruncated)
+}
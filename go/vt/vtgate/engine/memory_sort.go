@@ -71,6 +71,9 @@ func (ms *MemorySort) TryExecute(ctx context.Context, vcursor VCursor, bindVars
 		return nil, err
 	}
 
+	if stats, ok := StatsSinkFromContext(ctx); ok {
+		stats.RecordRowsBuffered(len(result.Rows))
+	}
 	if err = ms.OrderBy.SortResult(result); err != nil {
 		return nil, err
 	}
@@ -83,7 +86,6 @@ func (ms *MemorySort) TryExecute(ctx context.Context, vcursor VCursor, bindVars
 // TryStreamExecute satisfies the Primitive interface.
 func (ms *MemorySort) TryStreamExecute(ctx context.Context, vcursor VCursor, bindVars map[string]*querypb.BindVariable, wantfields bool, callback func(*sqltypes.Result) error) (err error) {
 	defer evalengine.PanicHandler(&err)
-
 	count, err := ms.fetchCount(ctx, vcursor, bindVars)
 	if err != nil {
 		return err
@@ -110,6 +112,9 @@ func (ms *MemorySort) TryStreamExecute(ctx context.Context, vcursor VCursor, bin
 		for _, row := range qr.Rows {
 			sorter.Push(row)
 		}
+		if stats, ok := StatsSinkFromContext(ctx); ok {
+			stats.RecordRowsBuffered(sorter.Len())
+		}
 		if vcursor.ExceedsMaxMemoryRows(sorter.Len()) {
 			return fmt.Errorf("in-memory row count exceeded allowed limit of %d", vcursor.MaxMemoryRows())
 		}
@@ -611,6 +611,33 @@ func TestMemorySortMaxMemoryRows(t *testing.T) {
 	}
 }
 
+func TestMemorySortRowsBufferedStats(t *testing.T) {
+	fields := sqltypes.MakeTestFields("c1", "int64")
+	fp := &fakePrimitive{
+		results: []*sqltypes.Result{sqltypes.MakeTestResult(fields, "3", "1", "2")},
+	}
+	ms := &MemorySort{
+		OrderBy: []evalengine.OrderByParams{{
+			WeightStringCol: -1,
+			Col:             0,
+		}},
+		Input: fp,
+	}
+
+	stats := &testStatsSink{}
+	ctx := WithStatsSink(context.Background(), stats)
+	_, err := ms.TryExecute(ctx, &noopVCursor{}, nil, false)
+	require.NoError(t, err)
+	require.Equal(t, 3, stats.peakRowsBuffered)
+
+	// The rows are streamed two at a time, and sorted as they come.
+	fp.rewind()
+	stats.peakRowsBuffered = 0
+	err = ms.TryStreamExecute(ctx, &noopVCursor{}, nil, false, func(*sqltypes.Result) error { return nil })
+	require.NoError(t, err)
+	require.Equal(t, 3, stats.peakRowsBuffered)
+}
+
 func TestMemorySortExecuteNoVarChar(t *testing.T) {
 	fields := sqltypes.MakeTestFields(
 		"c1|c2",
@@ -105,6 +105,7 @@ func (v *OnlineDDL) TryExecute(ctx context.Context, vcursor VCursor, bindVars ma
 		if _, err := vcursor.ExecutePrimitive(ctx, &s, bindVars, wantfields); err != nil {
 			return result, err
 		}
+		vcursor.RecordOnlineDDL(onlineDDL.UUID)
 		result.Rows = append(result.Rows, []sqltypes.Value{
 			sqltypes.NewVarChar(onlineDDL.UUID),
 		})
@@ -18,8 +18,12 @@ package engine
 
 import (
 	"bytes"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"slices"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -28,6 +32,7 @@ import (
 	"vitess.io/vitess/go/vt/key"
 	"vitess.io/vitess/go/vt/proto/query"
 	"vitess.io/vitess/go/vt/sqlparser"
+	"vitess.io/vitess/go/vt/vtgate/vindexes"
 	"vitess.io/vitess/go/vt/vthash"
 )
 
@@ -50,12 +55,34 @@ type (
 		ParamsCount  uint16                  // ParamsCount is the total number of bind parameters (?) in the query.
 		Optimized    atomic.Bool             // Prepared queries need to be optimized before the first execution
 
+		// PlannerVersion is the planner that built the plan. It is unset for
+		// statements that don't go through the query planner, such as DDL.
+		PlannerVersion query.ExecuteOptions_PlannerVersion
+
 		ExecCount    uint64 // ExecCount is how many times this plan has been executed.
 		ExecTime     uint64 // ExecTime is the total accumulated execution time in nanoseconds.
 		ShardQueries uint64 // ShardQueries is the total count of shard-level queries performed.
 		RowsReturned uint64 // RowsReturned is the total number of rows returned to clients.
 		RowsAffected uint64 // RowsAffected is the total number of rows affected by DML operations.
 		Errors       uint64 // Errors is the total count of errors encountered during execution.
+
+		fingerprintOnce sync.Once // fingerprintOnce guards the lazy computation of fingerprint.
+		fingerprint     string    // fingerprint identifies the shape of the plan, see Fingerprint.
+		rewrittenOnce   sync.Once // rewrittenOnce guards the lazy computation of rewritten.
+		rewritten       string    // rewritten holds the queries sent to the tablets, see RewrittenSQL.
+
+		routingReasonOnce sync.Once // routingReasonOnce guards the lazy computation of routingReason.
+		routingReason     string    // routingReason explains the routing of the plan, see RoutingReason.
+
+		postProcessingOnce sync.Once // postProcessingOnce guards the lazy computation of postProcessing.
+		postProcessing     int       // postProcessing counts the post-processing steps, see PostProcessingOps.
+
+		scatterOnce sync.Once // scatterOnce guards the lazy computation of scatter.
+		scatter     bool      // scatter is set when the plan has a scatter route, see HasScatterRoute.
+
+		referenceOnce     sync.Once // referenceOnce guards the lazy computation of reference and materialized.
+		reference         bool      // reference is set when the plan uses a reference table, see UsesReferenceTable.
+		materializedWrite bool      // materializedWrite is set when the plan writes a reference source, see WritesMaterializedSource.
 	}
 
 	// PlanKey identifies a plan uniquely based on keyspace, destination, query,
@@ -325,6 +352,176 @@ func (p *Plan) MarshalJSON() ([]byte, error) {
 	return b.Bytes(), nil
 }
 
+// Fingerprint returns a short hash identifying the shape of this plan: its
+// type, statement type, routing and the normalized query it was built for.
+// Queries that differ only in their literals, or in formatting that the
+// parser normalizes away, share a fingerprint even when their SQL text
+// differs. The value is computed once and cached on the plan.
+func (p *Plan) Fingerprint() string {
+	p.fingerprintOnce.Do(func() {
+		hasher := vthash.New256()
+		_, _ = hasher.WriteString(p.Type.String())
+		_, _ = hasher.WriteString(p.QueryType.String())
+		if p.Instructions != nil {
+			_, _ = hasher.WriteString(p.Instructions.RouteType())
+			_, _ = hasher.WriteString(p.Instructions.GetKeyspaceName())
+			_, _ = hasher.WriteString(p.Instructions.GetTableName())
+		}
+		_, _ = hasher.WriteString(p.Original)
+		var sum [32]byte
+		hasher.Sum(sum[:0])
+		p.fingerprint = hex.EncodeToString(sum[:8])
+	})
+	return p.fingerprint
+}
+
+// RewrittenSQL returns the queries this plan sends to the tablets, in plan
+// order and separated by semicolons. They differ from Original whenever
+// vtgate rewrites the query, for example when it splits a join across
+// routes or pushes a subquery down. The value is computed once and cached
+// on the plan.
+func (p *Plan) RewrittenSQL() string {
+	p.rewrittenOnce.Do(func() {
+		var queries []string
+		walkPrimitives(p.Instructions, func(prim Primitive) {
+			var query string
+			switch prim := prim.(type) {
+			case *Route:
+				query = prim.Query
+			case *Send:
+				query = prim.Query
+			case *Insert:
+				query = prim.Query
+			case *Update:
+				query = prim.Query
+			case *Delete:
+				query = prim.Query
+			}
+			if query != "" && !slices.Contains(queries, query) {
+				queries = append(queries, query)
+			}
+		})
+		p.rewritten = strings.Join(queries, "; ")
+	})
+	return p.rewritten
+}
+
+// RoutingReason explains why this plan sends its queries to the shards it
+// does, for example "vindex lookup on hash" or RoutingReasonScatterNoVindex.
+// Plans with several routes list the reason of each, in plan order and
+// separated by semicolons. The value is computed once and cached on the plan.
+func (p *Plan) RoutingReason() string {
+	p.routingReasonOnce.Do(func() {
+		var reasons []string
+		walkPrimitives(p.Instructions, func(prim Primitive) {
+			var reason string
+			switch prim := prim.(type) {
+			case *Route:
+				reason = prim.RoutingParameters.Reason()
+			case *Update:
+				reason = prim.RoutingParameters.Reason()
+			case *Delete:
+				reason = prim.RoutingParameters.Reason()
+			case *Send:
+				reason = RoutingReasonDestination
+			}
+			if reason != "" && !slices.Contains(reasons, reason) {
+				reasons = append(reasons, reason)
+			}
+		})
+		p.routingReason = strings.Join(reasons, "; ")
+	})
+	return p.routingReason
+}
+
+// PostProcessingOps returns the number of in-memory post-processing steps,
+// such as sorting, limiting, aggregating or deduplicating rows, that vtgate
+// performs on the rows gathered from the shards when running this plan. Each
+// step is counted once however many times it runs, for example once per outer
+// row under a nested-loop join. The value is computed once and cached on the
+// plan.
+func (p *Plan) PostProcessingOps() int {
+	p.postProcessingOnce.Do(func() {
+		walkPrimitives(p.Instructions, func(prim Primitive) {
+			switch prim.(type) {
+			case *Limit, *MemorySort, *Distinct, *OrderedAggregate, *ScalarAggregate:
+				p.postProcessing++
+			}
+		})
+	})
+	return p.postProcessing
+}
+
+// HasScatterRoute returns whether the plan has a route of Scatter opcode.
+// The value is computed once and cached on the plan.
+func (p *Plan) HasScatterRoute() bool {
+	p.scatterOnce.Do(func() {
+		walkPrimitives(p.Instructions, func(prim Primitive) {
+			if route, ok := prim.(*Route); ok && route.Opcode == Scatter {
+				p.scatter = true
+			}
+		})
+	})
+	return p.scatter
+}
+
+// UsesReferenceTable returns whether any of the tables of the plan is a
+// reference table of vschema or the source of reference tables, which the
+// planner may route them to. vschema must be the one the plan was built
+// with: the value is computed once and cached on the plan.
+func (p *Plan) UsesReferenceTable(vschema *vindexes.VSchema) bool {
+	p.findReferenceTables(vschema)
+	return p.reference
+}
+
+// WritesMaterializedSource returns whether the plan writes to a table that
+// is the source of reference tables of vschema, whose rows are then
+// materialized into the keyspaces of those reference tables by
+// VReplication. vschema must be the one the plan was built with: the value
+// is computed once and cached on the plan.
+func (p *Plan) WritesMaterializedSource(vschema *vindexes.VSchema) bool {
+	p.findReferenceTables(vschema)
+	return p.materializedWrite
+}
+
+func (p *Plan) findReferenceTables(vschema *vindexes.VSchema) {
+	p.referenceOnce.Do(func() {
+		var writes bool
+		switch p.QueryType {
+		case sqlparser.StmtInsert, sqlparser.StmtReplace, sqlparser.StmtUpdate, sqlparser.StmtDelete:
+			writes = true
+		}
+		for _, table := range p.TablesUsed {
+			keyspace, name, ok := strings.Cut(table, ".")
+			if !ok {
+				continue
+			}
+			t, err := vschema.FindTable(keyspace, name)
+			if err != nil {
+				continue
+			}
+			if t.Type == vindexes.TypeReference || len(t.ReferencedBy) > 0 {
+				p.reference = true
+			}
+			if writes && len(t.ReferencedBy) > 0 {
+				p.materializedWrite = true
+			}
+		}
+	})
+}
+
+// walkPrimitives calls f on prim and on all of its inputs, depth first.
+func walkPrimitives(prim Primitive, f func(Primitive)) {
+	if prim == nil {
+		return
+	}
+	f(prim)
+	inputs, _ := prim.Inputs()
+	for _, input := range inputs {
+		walkPrimitives(input, f)
+	}
+}
+
 // AddStats updates the plan execution statistics
 func (p *Plan) AddStats(execCount uint64, execTime time.Duration, shardQueries, rowsAffected, rowsReturned, errors uint64) {
 	atomic.AddUint64(&p.ExecCount, execCount)
@@ -0,0 +1,136 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"vitess.io/vitess/go/vt/key"
+	"vitess.io/vitess/go/vt/sqlparser"
+	"vitess.io/vitess/go/vt/vtgate/evalengine"
+	"vitess.io/vitess/go/vt/vtgate/vindexes"
+)
+
+func TestPlanFingerprint(t *testing.T) {
+	newPlan := func(original string, route *Route) *Plan {
+		return &Plan{
+			Type:         getPlanType(route),
+			QueryType:    sqlparser.StmtSelect,
+			Original:     original,
+			Instructions: route,
+		}
+	}
+
+	p1 := newPlan("select * from tableName where a = :a", createRoute())
+	p2 := newPlan("select * from tableName where a = :a", createRoute())
+	assert.Len(t, p1.Fingerprint(), 16)
+	assert.Equal(t, p1.Fingerprint(), p2.Fingerprint(), "plans for the same normalized query should share a fingerprint")
+
+	p3 := newPlan("select * from tableName where b = :b", createRoute())
+	assert.NotEqual(t, p1.Fingerprint(), p3.Fingerprint(), "plans for different queries should not share a fingerprint")
+
+	route := createRoute()
+	route.Keyspace = &vindexes.Keyspace{Name: "other"}
+	p4 := newPlan("select * from tableName where a = :a", route)
+	assert.NotEqual(t, p1.Fingerprint(), p4.Fingerprint(), "plans routed differently should not share a fingerprint")
+
+	// The fingerprint is stable across calls.
+	assert.Equal(t, p3.Fingerprint(), p3.Fingerprint())
+}
+
+func TestPlanRewrittenSQL(t *testing.T) {
+	ks := &vindexes.Keyspace{Name: "ks", Sharded: true}
+	plan := &Plan{
+		Original: "select u.id, m.id from `user` as u join music as m on u.id = m.user_id limit 10",
+		Instructions: &Limit{
+			Count: evalengine.NewLiteralInt(10),
+			Input: &Join{
+				Left:  NewRoute(Scatter, ks, "select u.id from `user` as u", "select u.id from `user` as u where 1 != 1"),
+				Right: NewRoute(Scatter, ks, "select m.id from music as m where m.user_id = :u_id", "select m.id from music as m where 1 != 1"),
+			},
+		},
+	}
+	assert.Equal(t, "select u.id from `user` as u; select m.id from music as m where m.user_id = :u_id", plan.RewrittenSQL())
+
+	// Plans without instructions don't send anything to the tablets.
+	assert.Empty(t, (&Plan{Original: "select 1"}).RewrittenSQL())
+}
+
+func TestPlanRoutingReason(t *testing.T) {
+	ks := &vindexes.Keyspace{Name: "ks", Sharded: true}
+	vindex, _ := vindexes.CreateVindex("hash", "user_index", nil)
+	lookup := NewRoute(EqualUnique, ks, "select u.id from `user` as u where u.id = :id", "select u.id from `user` as u where 1 != 1")
+	lookup.Vindex = vindex
+	plan := &Plan{
+		Instructions: &Join{
+			Left:  lookup,
+			Right: NewRoute(Scatter, ks, "select m.id from music as m", "select m.id from music as m where 1 != 1"),
+		},
+	}
+	assert.Equal(t, "vindex lookup on user_index; "+RoutingReasonScatterNoVindex, plan.RoutingReason())
+
+	unsharded := &Plan{Instructions: NewRoute(Unsharded, &vindexes.Keyspace{Name: "uks"}, "select 1 from t", "")}
+	assert.Equal(t, RoutingReasonUnsharded, unsharded.RoutingReason())
+
+	targeted := NewRoute(Scatter, ks, "select 1 from t", "")
+	targeted.TargetDestination = key.DestinationShard("-80")
+	assert.Equal(t, RoutingReasonDestination, (&Plan{Instructions: targeted}).RoutingReason())
+
+	assert.Empty(t, (&Plan{}).RoutingReason())
+}
+
+func TestPlanPostProcessingOps(t *testing.T) {
+	ks := &vindexes.Keyspace{Name: "ks", Sharded: true}
+	// The limit under the join runs once per row of the left side, but is
+	// counted once, like the sort and the limit on top.
+	plan := &Plan{
+		Instructions: &Limit{
+			Count: evalengine.NewLiteralInt(10),
+			Input: &MemorySort{
+				Input: &Join{
+					Left: NewRoute(Scatter, ks, "select u.id from `user` as u", "select u.id from `user` as u where 1 != 1"),
+					Right: &Limit{
+						Count: evalengine.NewLiteralInt(1),
+						Input: NewRoute(Scatter, ks, "select m.id from music as m where m.user_id = :u_id", "select m.id from music as m where 1 != 1"),
+					},
+				},
+			},
+		},
+	}
+	assert.Equal(t, 3, plan.PostProcessingOps())
+
+	unsharded := &Plan{Instructions: NewRoute(Unsharded, &vindexes.Keyspace{Name: "uks"}, "select 1 from t order by 1 limit 1", "")}
+	assert.Zero(t, unsharded.PostProcessingOps())
+	assert.Zero(t, (&Plan{}).PostProcessingOps())
+}
+
+func TestPlanHasScatterRoute(t *testing.T) {
+	ks := &vindexes.Keyspace{Name: "ks", Sharded: true}
+	scatter := &Plan{
+		Instructions: &Limit{
+			Count: evalengine.NewLiteralInt(10),
+			Input: NewRoute(Scatter, ks, "select id from `user`", "select id from `user` where 1 != 1"),
+		},
+	}
+	assert.True(t, scatter.HasScatterRoute())
+
+	unsharded := &Plan{Instructions: NewRoute(Unsharded, &vindexes.Keyspace{Name: "uks"}, "select 1 from t", "")}
+	assert.False(t, unsharded.HasScatterRoute())
+	assert.False(t, (&Plan{}).HasScatterRoute())
+}
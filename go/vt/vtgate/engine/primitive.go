@@ -148,6 +148,10 @@ type (
 		// RecordMirrorStats is used to record stats about a mirror query.
 		RecordMirrorStats(time.Duration, time.Duration, error)
 
+		// RecordOnlineDDL records that the query submitted the online schema
+		// migration with the given UUID.
+		RecordOnlineDDL(uuid string)
+
 		SetLastInsertID(uint64)
 
 		GetExecutionMetrics() *Metrics
@@ -103,6 +103,18 @@ func (code Opcode) String() string {
 	return opName[code]
 }
 
+// Routing reasons returned by RoutingParameters.Reason that don't depend
+// on a vindex.
+const (
+	RoutingReasonUnsharded         = "unsharded keyspace"
+	RoutingReasonScatterNoVindex   = "scatter: no vindex"
+	RoutingReasonSequence          = "sequence"
+	RoutingReasonInformationSchema = "information_schema"
+	RoutingReasonReference         = "reference table"
+	RoutingReasonNone              = "no rows to route"
+	RoutingReasonDestination       = "explicit target destination"
+)
+
 type RoutingParameters struct {
 	// Opcode is the execution opcode.
 	Opcode Opcode
@@ -133,6 +145,39 @@ func (code Opcode) IsSingleShard() bool {
 	return false
 }
 
+// Reason returns a human readable explanation of how these parameters pick
+// the shards a query is sent to.
+func (rp *RoutingParameters) Reason() string {
+	if rp == nil {
+		return ""
+	}
+	if rp.TargetDestination != nil {
+		return RoutingReasonDestination
+	}
+	switch rp.Opcode {
+	case Unsharded:
+		return RoutingReasonUnsharded
+	case EqualUnique, Equal, IN, Between, MultiEqual, SubShard:
+		if rp.Vindex == nil {
+			return "vindex lookup"
+		}
+		return "vindex lookup on " + rp.Vindex.String()
+	case Scatter:
+		return RoutingReasonScatterNoVindex
+	case Next:
+		return RoutingReasonSequence
+	case DBA:
+		return RoutingReasonInformationSchema
+	case Reference:
+		return RoutingReasonReference
+	case None:
+		return RoutingReasonNone
+	case ByDestination:
+		return RoutingReasonDestination
+	}
+	return rp.Opcode.String()
+}
+
 func (rp *RoutingParameters) findRoute(ctx context.Context, vcursor VCursor, bindVars map[string]*querypb.BindVariable) ([]*srvtopo.ResolvedShard, []map[string]*querypb.BindVariable, error) {
 	switch rp.Opcode {
 	case None:
@@ -0,0 +1,68 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"context"
+	"time"
+)
+
+// StatsSink collects the statistics of the execution of a query, for its
+// query log record. The primitives, and the gateway they send their queries
+// through, report to the sink attached to their context, if any, see
+// WithStatsSink.
+type StatsSink interface {
+	// AddRowsTruncated reports n rows a Limit dropped from the result of
+	// its input.
+	AddRowsTruncated(n int)
+	// RecordRowsBuffered reports the n rows an in-memory primitive, such as
+	// MemorySort or HashJoin, holds at once. It is reported as it grows, so
+	// the largest value is the peak.
+	RecordRowsBuffered(n int)
+	// AddLookupRoundTrip reports a query to the table of a lookup vindex
+	// that took d.
+	AddLookupRoundTrip(d time.Duration)
+	// AddTablet reports the alias of a tablet a query is sent to.
+	AddTablet(alias string)
+	// AddBufferTime reports that a request was buffered for d during a
+	// failover.
+	AddBufferTime(d time.Duration)
+	// AddRowsExamined reports n rows received from a tablet.
+	AddRowsExamined(n int)
+	// AddBackendConnectionID reports the id of the MySQL connection that
+	// ran a query on shard, given as keyspace/shard.
+	AddBackendConnectionID(shard string, id uint64)
+	// AddShardTiming reports a query sent to shard, given as keyspace/shard,
+	// that ran from start to end.
+	AddShardTiming(shard string, start, end time.Time)
+}
+
+type statsSinkKey struct{}
+
+// WithStatsSink returns a context that reports the statistics of the
+// execution of a query to sink while it is in use. Callers should only
+// attach a sink when something consumes the statistics, so that the
+// execution doesn't pay for them otherwise.
+func WithStatsSink(ctx context.Context, sink StatsSink) context.Context {
+	return context.WithValue(ctx, statsSinkKey{}, sink)
+}
+
+// StatsSinkFromContext returns the sink attached to ctx, if any.
+func StatsSinkFromContext(ctx context.Context) (StatsSink, bool) {
+	sink, ok := ctx.Value(statsSinkKey{}).(StatsSink)
+	return sink, ok
+}
@@ -0,0 +1,33 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+// testStatsSink records the statistics the primitives report. The methods
+// it doesn't implement panic through the nil StatsSink it embeds.
+type testStatsSink struct {
+	StatsSink
+	rowsTruncated    int
+	peakRowsBuffered int
+}
+
+func (s *testStatsSink) AddRowsTruncated(n int) {
+	s.rowsTruncated += n
+}
+
+func (s *testStatsSink) RecordRowsBuffered(n int) {
+	s.peakRowsBuffered = max(s.peakRowsBuffered, n)
+}
@@ -18,6 +18,7 @@ package engine
 
 import (
 	"context"
+	"time"
 
 	vtgatepb "vitess.io/vitess/go/vt/proto/vtgate"
 	"vitess.io/vitess/go/vt/sqlparser"
@@ -190,11 +191,15 @@ func (vr *VindexLookup) executeNonBatch(ctx context.Context, vcursor VCursor, id
 		}
 
 		var result *sqltypes.Result
+		start := time.Now()
 		if vr.Vindex.AutoCommitEnabled() {
 			result, err = vcursor.ExecutePrimitiveStandalone(ctx, vr.Lookup, bindVars, false)
 		} else {
 			result, err = vcursor.ExecutePrimitive(ctx, vr.Lookup, bindVars, false)
 		}
+		if stats, ok := StatsSinkFromContext(ctx); ok {
+			stats.AddLookupRoundTrip(time.Since(start))
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -222,11 +227,15 @@ func (vr *VindexLookup) executeBatch(ctx context.Context, vcursor VCursor, ids [
 	}
 
 	var result *sqltypes.Result
+	start := time.Now()
 	if vr.Vindex.AutoCommitEnabled() {
 		result, err = vcursor.ExecutePrimitiveStandalone(ctx, vr.Lookup, bindVars, false)
 	} else {
 		result, err = vcursor.ExecutePrimitive(ctx, vr.Lookup, bindVars, false)
 	}
+	if stats, ok := StatsSinkFromContext(ctx); ok {
+		stats.AddLookupRoundTrip(time.Since(start))
+	}
 	if err != nil {
 		return nil, vterrors.Wrapf(err, "failed while running the lookup query")
 	}
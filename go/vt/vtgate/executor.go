@@ -118,6 +118,11 @@ type (
 		AllowScatter        bool
 		WarmingReadsPercent int
 		QueryLogToFile      string
+		// QueryLogToConsole is "stdout" or "stderr" to also write the query log
+		// to that stream, limited to queries taking at least
+		// QueryLogToConsoleMinDuration.
+		QueryLogToConsole            string
+		QueryLogToConsoleMinDuration time.Duration
 	}
 
 	Executor struct {
@@ -138,6 +143,9 @@ type (
 
 		plans *PlanCache
 		epoch atomic.Uint32
+		// planEvictions remembers recently evicted plans, to flag queries
+		// whose plan is recompiled because of cache pressure.
+		planEvictions *planEvictions
 
 		vm            *VSchemaManager
 		schemaTracker SchemaInfo
@@ -197,9 +205,11 @@ func NewExecutor(
 
 		schemaTracker:       schemaTracker,
 		plans:               plans,
+		planEvictions:       newPlanEvictions(planEvictionsSize),
 		warmingReadsChannel: make(chan bool, warmingReadsConcurrency),
 		ddlConfig:           ddlConfig,
 	}
+	plans.OnRemoval = e.planEvictions.onRemoval
 	// setting the vcursor config.
 	e.initVConfig(warnOnShardedOnly, pv)
 	e.metrics = &Metrics{
@@ -258,6 +268,8 @@ func (e *Executor) Execute(
 	defer span.Finish()
 
 	logStats := logstats.NewLogStats(ctx, method, sql, safeSession.GetSessionUUID(), bindVars, streamlog.GetQueryLogConfig())
+	logStats.Prepared = prepared
+	ctx = e.withQueryLogStats(ctx, logStats, safeSession)
 	stmtType, result, err := e.execute(ctx, mysqlCtx, safeSession, sql, bindVars, prepared, logStats)
 	logStats.Error = err
 	if result == nil {
@@ -293,7 +305,9 @@ type streaminResultReceiver struct {
 	stmtType     sqlparser.StatementType
 	rowsAffected uint64
 	rowsReturned int
-	callback     func(*sqltypes.Result) error
+	// fields are the fields of the first result that had any.
+	fields   []*querypb.Field
+	callback func(*sqltypes.Result) error
 }
 
 func (s *streaminResultReceiver) storeResultStats(typ sqlparser.StatementType, qr *sqltypes.Result) error {
@@ -301,6 +315,9 @@ func (s *streaminResultReceiver) storeResultStats(typ sqlparser.StatementType, q
 	defer s.mu.Unlock()
 	s.rowsAffected += qr.RowsAffected
 	s.rowsReturned += len(qr.Rows)
+	if s.fields == nil {
+		s.fields = qr.Fields
+	}
 	s.stmtType = typ
 	return s.callback(qr)
 }
@@ -321,6 +338,7 @@ func (e *Executor) StreamExecute(
 	defer span.Finish()
 
 	logStats := logstats.NewLogStats(ctx, method, sql, safeSession.GetSessionUUID(), bindVars, streamlog.GetQueryLogConfig())
+	ctx = e.withQueryLogStats(ctx, logStats, safeSession)
 	srr := &streaminResultReceiver{callback: callback}
 	var err error
 
@@ -396,6 +414,10 @@ func (e *Executor) StreamExecute(
 		logStats.TabletType = vc.TabletType().String()
 		logStats.ExecuteTime = time.Since(execStart)
 		logStats.ActiveKeyspace = vc.GetKeyspace()
+		srr.mu.Lock()
+		logStats.QueryCategory = queryCategory(plan, logStats.ShardQueries, uint64(srr.rowsReturned))
+		logStats.SetResultFields(srr.fields)
+		srr.mu.Unlock()
 
 		e.updateQueryCounts(plan.Instructions.RouteType(), plan.Instructions.GetKeyspaceName(), plan.Instructions.GetTableName(), int64(logStats.ShardQueries))
 		e.updateQueryStats(plan.QueryType.String(), plan.Type.String(), vc.TabletType().String(), int64(logStats.ShardQueries), plan.TablesUsed)
@@ -1169,11 +1191,59 @@ func (e *Executor) fetchOrCreatePlan(
 	e.applyQueryHints(vcursor, plan)
 
 	logStats.SQL = comments.Leading + plan.Original + comments.Trailing
+	// The query as sent and the queries sent to the tablets hold the
+	// literals the normalized SQL leaves out, which can be sensitive.
+	if !logStats.RedactsOriginalSQL() {
+		logStats.OriginalSQL = queryString
+	}
+	if !logStats.Config.RedactDebugUIQueries {
+		logStats.RewrittenSQL = plan.RewrittenSQL()
+	}
+	logStats.RoutingReason = plan.RoutingReason()
+	logStats.ReferenceTable = plan.UsesReferenceTable(vcursor.GetVSchema())
+	logStats.MaterializedWrite = plan.WritesMaterializedSource(vcursor.GetVSchema())
+	logStats.FastPath = takesFastPath(plan)
+	logStats.KeyspacesTouched = countKeyspaces(plan.TablesUsed)
+	logStats.RouteHint = e.routeHint(stmt, plan)
+	if plan.PlannerVersion != querypb.ExecuteOptions_DEFAULT_PLANNER {
+		logStats.PlannerVersion = plan.PlannerVersion.String()
+	}
 	logStats.BindVariables = sqltypes.CopyBindVariables(bindVars)
 
 	return plan, vcursor, stmt, nil
 }
 
+// takesFastPath returns whether plan sends its query as is to a single
+// unsharded keyspace, or to the shard targeted by the session, without any
+// further work from vtgate.
+func takesFastPath(plan *engine.Plan) bool {
+	switch prim := plan.Instructions.(type) {
+	case *engine.Send:
+		return plan.Type == engine.PlanPassthrough
+	case *engine.Route:
+		return prim.Opcode == engine.Unsharded
+	case *engine.Update:
+		return prim.Opcode == engine.Unsharded
+	case *engine.Delete:
+		return prim.Opcode == engine.Unsharded
+	case *engine.Insert:
+		return prim.Opcode == engine.InsertUnsharded
+	}
+	return false
+}
+
+// countKeyspaces returns the number of distinct keyspaces of tables,
+// qualified with their keyspace as in engine.Plan.TablesUsed.
+func countKeyspaces(tables []string) uint64 {
+	keyspaces := map[string]bool{}
+	for _, table := range tables {
+		if keyspace, _, ok := strings.Cut(table, "."); ok {
+			keyspaces[keyspace] = true
+		}
+	}
+	return uint64(len(keyspaces))
+}
+
 func (e *Executor) newVCursor(safeSession *econtext.SafeSession, comments sqlparser.MarginComments, logStats *logstats.LogStats) (*econtext.VCursorImpl, error) {
 	return econtext.NewVCursorImpl(safeSession, comments, e, logStats, e.vm, e.VSchema(), e.resolver.resolver, e.serv, nullResultsObserver{}, e.vConfig, e.metrics)
 }
@@ -1235,7 +1305,9 @@ func (e *Executor) getCachedOrBuildPlan(
 	planKey engine.PlanKey,
 	ignoreCache bool,
 ) (plan *engine.Plan, cached bool, stmt sqlparser.Statement, err error) {
+	parseStart := time.Now()
 	stmt, reservedVars, err := parseAndValidateQuery(query, e.env.Parser())
+	vcursor.RecordParseTime(time.Since(parseStart))
 	if err != nil {
 		return nil, false, nil, err
 	}
@@ -1295,6 +1367,9 @@ func (e *Executor) getCachedOrBuildPlan(
 			planKey = buildPlanKey(ctx, vcursor, query, setVarComment)
 		}
 		plan, cached, err = e.plans.GetOrLoad(planKey.Hash(), e.epoch.Load(), func() (*engine.Plan, error) {
+			if e.planEvictions.forget(planKey.Hash()) {
+				vcursor.RecordPlanRecompiled()
+			}
 			return e.buildStatement(ctx, vcursor, query, stmt, reservedVars, bindVarNeeds, qh, paramsCount)
 		})
 		return plan, cached, stmt, err
@@ -1537,6 +1612,16 @@ func (e *Executor) prepare(ctx context.Context, safeSession *econtext.SafeSessio
 	return nil, 0, vterrors.Errorf(vtrpcpb.Code_INTERNAL, "[BUG] unrecognized prepare statement: %s", sql)
 }
 
+// connCollation returns the name of the collation the queries of
+// safeSession are run with: the collation_connection set on the session, if
+// any, or else the one of the connections of vtgate to the tablets.
+func (e *Executor) connCollation(safeSession *econtext.SafeSession) string {
+	if collation := safeSession.ConnectionCollation(); collation != "" {
+		return collation
+	}
+	return e.env.CollationEnv().LookupName(e.vConfig.Collation)
+}
+
 func (e *Executor) initVConfig(warnOnShardedOnly bool, pv plancontext.PlannerVersion) {
 	connCollation := collations.Unknown
 	if gw, isTabletGw := e.resolver.resolver.GetGateway().(*TabletGateway); isTabletGw {
@@ -1699,22 +1784,23 @@ func (e *Executor) checkThatPlanIsValid(stmt sqlparser.Statement, plan *engine.P
 	if e.config.AllowScatter || plan.Instructions == nil || sqlparser.AllowScatterDirective(stmt) {
 		return nil
 	}
-	// we go over all the primitives in the plan, searching for a route that is of SelectScatter opcode
-	badPrimitive := engine.Find(func(node engine.Primitive) bool {
-		router, ok := node.(*engine.Route)
-		if !ok {
-			return false
-		}
-		return router.Opcode == engine.Scatter
-	}, plan.Instructions)
-
-	if badPrimitive == nil {
+	if !plan.HasScatterRoute() {
 		return nil
 	}
 
 	return vterrors.Errorf(vtrpcpb.Code_INVALID_ARGUMENT, "plan includes scatter, which is disallowed using the `no_scatter` command line argument")
 }
 
+// routeHint returns the comment directive of stmt that let plan route the
+// query differently than vtgate would have by default, if any. The only such
+// directive is ALLOW_SCATTER, when scatter queries are disallowed.
+func (e *Executor) routeHint(stmt sqlparser.Statement, plan *engine.Plan) string {
+	if e.config.AllowScatter || plan.Instructions == nil || !sqlparser.AllowScatterDirective(stmt) || !plan.HasScatterRoute() {
+		return ""
+	}
+	return sqlparser.DirectiveAllowScatter
+}
+
 // getTabletThrottlerStatus uses HTTP to get the throttler status
 // on a tablet. It uses HTTP because the CheckThrottler RPC is a
 // tmclient RPC and you cannot use tmclient outside of a tablet.
@@ -69,3 +69,28 @@ func TestDDLFlags(t *testing.T) {
 		})
 	}
 }
+
+func TestDDLLogsOnlineDDL(t *testing.T) {
+	executor, _, _, _, ctx := createExecutorEnv(t)
+	logChan := executor.queryLogger.Subscribe("Test")
+	defer executor.queryLogger.Unsubscribe(logChan)
+
+	// A DDL submitted as an online schema change logs its migration.
+	session := econtext.NewSafeSession(&vtgatepb.Session{TargetString: KsTestUnsharded, DDLStrategy: "vitess"})
+	result, err := executor.Execute(ctx, nil, "TestDDLLogsOnlineDDL", session, "alter table t add column c int", nil, false)
+	require.NoError(t, err)
+	require.Len(t, result.Rows, 1)
+	logStats := getQueryLog(logChan)
+	require.NotNil(t, logStats)
+	require.True(t, logStats.OnlineDDL)
+	require.Equal(t, []string{result.Rows[0][0].ToString()}, logStats.MigrationUUIDs)
+
+	// A DDL applied directly doesn't.
+	session = econtext.NewSafeSession(&vtgatepb.Session{TargetString: KsTestUnsharded, DDLStrategy: "direct"})
+	_, err = executor.Execute(ctx, nil, "TestDDLLogsOnlineDDL", session, "alter table t add column c int", nil, false)
+	require.NoError(t, err)
+	logStats = getQueryLog(logChan)
+	require.NotNil(t, logStats)
+	require.False(t, logStats.OnlineDDL)
+	require.Empty(t, logStats.MigrationUUIDs)
+}
@@ -1293,7 +1293,8 @@ func TestInsertSharded(t *testing.T) {
 
 	testQueryLog(t, executor, logChan, "MarkSavepoint", "SAVEPOINT", "savepoint x", 0)
 	testQueryLog(t, executor, logChan, "VindexCreate", "INSERT", "insert into name_user_map(`name`, user_id) values (:name_0, :user_id_0)", 1)
-	testQueryLog(t, executor, logChan, "TestExecute", "INSERT", "insert into `user`(id, v, `name`) values (1, 2, 'myname')", 1)
+	logStats := testQueryLog(t, executor, logChan, "TestExecute", "INSERT", "insert into `user`(id, v, `name`) values (1, 2, 'myname')", 1)
+	assert.EqualValues(t, 1, logStats.LookupRoundTrips)
 
 	sbc1.Queries = nil
 	sbclookup.Queries = nil
@@ -2583,6 +2584,31 @@ func TestUpdateReference(t *testing.T) {
 	require.NoError(t, err) // Gen4 planner can redirect the query to correct source for update when reference table is involved.
 }
 
+func TestDMLLogsMaterializedWrite(t *testing.T) {
+	executor, _, _, _, ctx := createExecutorEnv(t)
+	logChan := executor.queryLogger.Subscribe("Test")
+	defer executor.queryLogger.Unsubscribe(logChan)
+
+	session := &vtgatepb.Session{TargetString: "@primary"}
+	_, err := executorExec(ctx, executor, session, "update TestUnsharded.zip_detail set status = 'CLOSED' where id = 1", nil)
+	require.NoError(t, err)
+	logStats := getQueryLog(logChan)
+	require.NotNil(t, logStats)
+	assert.True(t, logStats.MaterializedWrite)
+
+	_, err = executorExec(ctx, executor, session, "select * from TestUnsharded.zip_detail", nil)
+	require.NoError(t, err)
+	logStats = getQueryLog(logChan)
+	require.NotNil(t, logStats)
+	assert.False(t, logStats.MaterializedWrite)
+
+	_, err = executorExec(ctx, executor, session, "update `user` set a = 2 where id = 1", nil)
+	require.NoError(t, err)
+	logStats = getQueryLog(logChan)
+	require.NotNil(t, logStats)
+	assert.False(t, logStats.MaterializedWrite)
+}
+
 func TestDeleteLookupOwnedEqual(t *testing.T) {
 	executor, sbc1, sbc2, _, ctx := createExecutorEnv(t)
 
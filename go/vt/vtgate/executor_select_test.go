@@ -879,6 +879,336 @@ func TestSelectInitializedVitessAwareVariable(t *testing.T) {
 	utils.MustMatch(t, wantResult, result, "Mismatch")
 }
 
+func TestSelectLogsQueryTimeoutDeadline(t *testing.T) {
+	executor, _, _, _, ctx := createExecutorEnv(t)
+	logChan := executor.queryLogger.Subscribe("Test")
+	defer executor.queryLogger.Unsubscribe(logChan)
+
+	session := &vtgatepb.Session{
+		TargetString: "@primary",
+	}
+	sql := "select id from `user` where id = 1"
+	_, err := executorExec(ctx, executor, session, sql, nil)
+	require.NoError(t, err)
+	logStats := testQueryLog(t, executor, logChan, "TestExecute", "SELECT", sql, 1)
+	assert.Zero(t, logStats.TimeToDeadline())
+
+	// The query timeout of the session is applied as a deadline.
+	session.QueryTimeout = 1000
+	_, err = executorExec(ctx, executor, session, sql, nil)
+	require.NoError(t, err)
+	logStats = testQueryLog(t, executor, logChan, "TestExecute", "SELECT", sql, 1)
+	// The deadline is applied after planning, a little after the query started.
+	assert.GreaterOrEqual(t, logStats.TimeToDeadline(), time.Second)
+	assert.Less(t, logStats.TimeToDeadline(), 2*time.Second)
+}
+
+func TestSelectLogsPrepared(t *testing.T) {
+	executor, _, _, _, ctx := createExecutorEnv(t)
+	logChan := executor.queryLogger.Subscribe("Test")
+	defer executor.queryLogger.Unsubscribe(logChan)
+
+	sql := "select id from `user` where id = 1"
+	_, err := executorExec(ctx, executor, &vtgatepb.Session{TargetString: "@primary"}, sql, nil)
+	require.NoError(t, err)
+	logStats := testQueryLog(t, executor, logChan, "TestExecute", "SELECT", sql, 1)
+	assert.False(t, logStats.Prepared)
+
+	_, err = executor.Execute(ctx, nil, "TestExecute", econtext.NewSafeSession(&vtgatepb.Session{TargetString: "@primary"}), sql, nil, true)
+	require.NoError(t, err)
+	logStats = testQueryLog(t, executor, logChan, "TestExecute", "SELECT", sql, 1)
+	assert.True(t, logStats.Prepared)
+}
+
+func TestSelectLogsParseTime(t *testing.T) {
+	executor, _, _, _, ctx := createExecutorEnv(t)
+	logChan := executor.queryLogger.Subscribe("Test")
+	defer executor.queryLogger.Unsubscribe(logChan)
+
+	sql := "select id from `user` where id = 1"
+	_, err := executorExec(ctx, executor, &vtgatepb.Session{TargetString: "@primary"}, sql, nil)
+	require.NoError(t, err)
+	logStats := testQueryLog(t, executor, logChan, "TestExecute", "SELECT", sql, 1)
+	assert.NotZero(t, logStats.ParseTime)
+	assert.LessOrEqual(t, logStats.ParseTime, logStats.PlanTime)
+}
+
+func TestSelectLogsReferenceTable(t *testing.T) {
+	executor, _, _, _, ctx := createExecutorEnv(t)
+	logChan := executor.queryLogger.Subscribe("Test")
+	defer executor.queryLogger.Unsubscribe(logChan)
+
+	session := &vtgatepb.Session{TargetString: "@primary"}
+	_, err := executorExec(ctx, executor, session, "select * from zip_detail", nil)
+	require.NoError(t, err)
+	logStats := getQueryLog(logChan)
+	require.NotNil(t, logStats)
+	assert.True(t, logStats.ReferenceTable)
+
+	_, err = executorExec(ctx, executor, session, "select id from `user` where id = 1", nil)
+	require.NoError(t, err)
+	logStats = getQueryLog(logChan)
+	require.NotNil(t, logStats)
+	assert.False(t, logStats.ReferenceTable)
+}
+
+func TestSelectLogsPlannerVersion(t *testing.T) {
+	executor, _, _, _, ctx := createExecutorEnv(t)
+	logChan := executor.queryLogger.Subscribe("Test")
+	defer executor.queryLogger.Unsubscribe(logChan)
+
+	session := &vtgatepb.Session{TargetString: "@primary"}
+	_, err := executorExec(ctx, executor, session, "select id from `user` where id = 1", nil)
+	require.NoError(t, err)
+	logStats := getQueryLog(logChan)
+	require.NotNil(t, logStats)
+	assert.Equal(t, "Gen4", logStats.PlannerVersion)
+
+	_, err = executorExec(ctx, executor, session, "select /*vt+ PLANNER=left2right */ id from `user` where id = 1", nil)
+	require.NoError(t, err)
+	logStats = getQueryLog(logChan)
+	require.NotNil(t, logStats)
+	assert.Equal(t, "Gen4Left2Right", logStats.PlannerVersion)
+
+	_, err = executorExec(ctx, executor, session, "set @x = 1", nil)
+	require.NoError(t, err)
+	logStats = getQueryLog(logChan)
+	require.NotNil(t, logStats)
+	assert.Empty(t, logStats.PlannerVersion)
+}
+
+func TestSelectLogsFastPath(t *testing.T) {
+	executor, _, _, _, ctx := createExecutorEnv(t)
+	logChan := executor.queryLogger.Subscribe("Test")
+	defer executor.queryLogger.Unsubscribe(logChan)
+
+	for _, tcase := range []struct {
+		target, sql string
+		fastPath    bool
+	}{
+		{"@primary", "select * from main1", true},
+		{"@primary", "insert into main1(id) values (1)", true},
+		{"@primary", "select id from `user` where id = 1", false},
+		{"@primary", "select id from `user`", false},
+		{"TestExecutor/40-60", "select id from `user`", true},
+	} {
+		session := &vtgatepb.Session{TargetString: tcase.target}
+		_, err := executorExec(ctx, executor, session, tcase.sql, nil)
+		require.NoError(t, err, tcase.sql)
+		logStats := getQueryLog(logChan)
+		require.NotNil(t, logStats, tcase.sql)
+		assert.Equal(t, tcase.fastPath, logStats.FastPath, tcase.sql)
+	}
+}
+
+func TestSelectLogsKeyspacesTouched(t *testing.T) {
+	executor, _, _, _, ctx := createExecutorEnv(t)
+	logChan := executor.queryLogger.Subscribe("Test")
+	defer executor.queryLogger.Unsubscribe(logChan)
+
+	for _, tcase := range []struct {
+		sql       string
+		keyspaces uint64
+	}{
+		{"select 1 from dual", 0},
+		{"select id from `user` where id = 1", 1},
+		{"select u.id from `user` u join user_extra e on u.id = e.user_id", 1},
+		{"select m.id from main1 m join `user` u on m.id = u.id", 2},
+	} {
+		session := &vtgatepb.Session{TargetString: "@primary"}
+		_, err := executorExec(ctx, executor, session, tcase.sql, nil)
+		require.NoError(t, err, tcase.sql)
+		logStats := getQueryLog(logChan)
+		require.NotNil(t, logStats, tcase.sql)
+		assert.Equal(t, tcase.keyspaces, logStats.KeyspacesTouched, tcase.sql)
+	}
+}
+
+func TestSelectLogsResultColumns(t *testing.T) {
+	executor, _, _, _, ctx := createExecutorEnv(t)
+	logChan := executor.queryLogger.Subscribe("Test")
+	defer executor.queryLogger.Unsubscribe(logChan)
+
+	sql := "select 1, 'a' from dual"
+	session := &vtgatepb.Session{TargetString: "@primary"}
+	_, err := executorExec(ctx, executor, session, sql, nil)
+	require.NoError(t, err)
+	logStats := getQueryLog(logChan)
+	require.NotNil(t, logStats)
+	assert.EqualValues(t, 2, logStats.ResultColumns)
+	assert.Equal(t, "INT64,VARCHAR", logStats.ResultColumnTypes)
+
+	_, err = executorStream(ctx, executor, sql)
+	require.NoError(t, err)
+	logStats = getQueryLog(logChan)
+	require.NotNil(t, logStats)
+	assert.EqualValues(t, 2, logStats.ResultColumns)
+	assert.Equal(t, "INT64,VARCHAR", logStats.ResultColumnTypes)
+
+	_, err = executorExec(ctx, executor, session, "update `user` set a = 1 where id = 1", nil)
+	require.NoError(t, err)
+	logStats = getQueryLog(logChan)
+	require.NotNil(t, logStats)
+	assert.Zero(t, logStats.ResultColumns)
+	assert.Empty(t, logStats.ResultColumnTypes)
+}
+
+func TestSelectLogsRouteHint(t *testing.T) {
+	executor, _, _, _, ctx := createExecutorEnv(t)
+	logChan := executor.queryLogger.Subscribe("Test")
+	defer executor.queryLogger.Unsubscribe(logChan)
+
+	for _, tcase := range []struct {
+		allowScatter bool
+		sql          string
+		hint         string
+	}{
+		{false, "select /*vt+ ALLOW_SCATTER */ id from `user`", "ALLOW_SCATTER"},
+		{false, "select /*vt+ ALLOW_SCATTER */ id from `user` where id = 1", ""},
+		{true, "select /*vt+ ALLOW_SCATTER */ id from `user`", ""},
+	} {
+		executor.config.AllowScatter = tcase.allowScatter
+		session := &vtgatepb.Session{TargetString: "@primary"}
+		_, err := executorExec(ctx, executor, session, tcase.sql, nil)
+		require.NoError(t, err, tcase.sql)
+		logStats := getQueryLog(logChan)
+		require.NotNil(t, logStats, tcase.sql)
+		assert.Equal(t, tcase.hint, logStats.RouteHint, tcase.sql)
+	}
+}
+
+func TestSelectLogsRowsBuffered(t *testing.T) {
+	executor, _, _, sbclookup, ctx := createExecutorEnv(t)
+	logChan := executor.queryLogger.Subscribe("Test")
+	defer executor.queryLogger.Unsubscribe(logChan)
+
+	for _, tcase := range []struct {
+		sql      string
+		buffered uint64
+	}{
+		// vtgate joins each of the 3 rows of main1 with the row of its
+		// user, and holds the joined rows until the join completes.
+		{"select m.id from main1 m join `user` u on m.id = u.id", 3},
+		{"select id from `user` where id = 1", 0},
+	} {
+		sbclookup.SetResults([]*sqltypes.Result{sqltypes.MakeTestResult(sqltypes.MakeTestFields("id", "int64"), "1", "2", "3")})
+		session := &vtgatepb.Session{TargetString: "@primary"}
+		_, err := executorExec(ctx, executor, session, tcase.sql, nil)
+		require.NoError(t, err, tcase.sql)
+		logStats := getQueryLog(logChan)
+		require.NotNil(t, logStats, tcase.sql)
+		assert.Equal(t, tcase.buffered, logStats.RowsBuffered, tcase.sql)
+	}
+}
+
+func TestSelectLogsPostProcessingOps(t *testing.T) {
+	executor, _, _, _, ctx := createExecutorEnv(t)
+	logChan := executor.queryLogger.Subscribe("Test")
+	defer executor.queryLogger.Unsubscribe(logChan)
+
+	for _, tcase := range []struct {
+		sql string
+		ops uint64
+	}{
+		// The counts of the shards are summed by vtgate.
+		{"select count(*) from `user`", 1},
+		{"select id from `user` where id = 1", 0},
+	} {
+		session := &vtgatepb.Session{TargetString: "@primary"}
+		_, err := executorExec(ctx, executor, session, tcase.sql, nil)
+		require.NoError(t, err, tcase.sql)
+		logStats := getQueryLog(logChan)
+		require.NotNil(t, logStats, tcase.sql)
+		assert.Equal(t, tcase.ops, logStats.PostProcessingOps, tcase.sql)
+	}
+}
+
+func TestSelectLogsCollation(t *testing.T) {
+	executor, _, _, _, ctx := createExecutorEnv(t)
+	logChan := executor.queryLogger.Subscribe("Test")
+	defer executor.queryLogger.Unsubscribe(logChan)
+
+	session := &vtgatepb.Session{TargetString: "@primary"}
+	_, err := executorExec(ctx, executor, session, "select id from `user` where id = 1", nil)
+	require.NoError(t, err)
+	logStats := getQueryLog(logChan)
+	require.NotNil(t, logStats)
+	assert.Equal(t, executor.env.CollationEnv().LookupName(executor.vConfig.Collation), logStats.Collation)
+
+	session.SystemVariables = map[string]string{"collation_connection": "'utf8mb4_bin'"}
+	_, err = executorExec(ctx, executor, session, "select id from `user` where id = 1", nil)
+	require.NoError(t, err)
+	logStats = getQueryLog(logChan)
+	require.NotNil(t, logStats)
+	assert.Equal(t, "utf8mb4_bin", logStats.Collation)
+}
+
+func TestSelectLogsBackendConnectionIDs(t *testing.T) {
+	executor, sbc1, sbc2, _, ctx := createExecutorEnv(t)
+	logChan := executor.queryLogger.Subscribe("Test")
+	defer executor.queryLogger.Unsubscribe(logChan)
+
+	sbc1.SetResults([]*sqltypes.Result{{ConnectionID: 12}})
+	sbc2.SetResults([]*sqltypes.Result{{ConnectionID: 34}})
+	session := &vtgatepb.Session{TargetString: "@primary"}
+	_, err := executorExec(ctx, executor, session, "select id from `user`", nil)
+	require.NoError(t, err)
+	logStats := getQueryLog(logChan)
+	require.NotNil(t, logStats)
+	assert.True(t, sbc1.Options[0].GetIncludeConnectionId())
+	assert.Equal(t, map[string]uint64{"TestExecutor/-20": 12, "TestExecutor/40-60": 34}, logStats.BackendConnectionIDs)
+
+	sbc1.SetResults([]*sqltypes.Result{{ConnectionID: 56}})
+	_, err = executorStream(ctx, executor, "select id from `user` where id = 1")
+	require.NoError(t, err)
+	logStats = getQueryLog(logChan)
+	require.NotNil(t, logStats)
+	assert.Equal(t, map[string]uint64{"TestExecutor/-20": 56}, logStats.BackendConnectionIDs)
+}
+
+func TestSelectSkipsConnectionIDsWithoutQueryLog(t *testing.T) {
+	executor, sbc1, _, _, ctx := createExecutorEnv(t)
+
+	// Nobody reads the query log, so the tablets aren't asked for the ids.
+	session := &vtgatepb.Session{TargetString: "@primary"}
+	_, err := executorExec(ctx, executor, session, "select id from `user`", nil)
+	require.NoError(t, err)
+	require.NotEmpty(t, sbc1.Options)
+	assert.False(t, sbc1.Options[0].GetIncludeConnectionId())
+
+	_, err = executorStream(ctx, executor, "select id from `user` where id = 1")
+	require.NoError(t, err)
+	assert.False(t, sbc1.Options[len(sbc1.Options)-1].GetIncludeConnectionId())
+}
+
+func TestSelectLogsShardTimings(t *testing.T) {
+	executor, _, _, _, ctx := createExecutorEnv(t)
+	logChan := executor.queryLogger.Subscribe("Test")
+	defer executor.queryLogger.Unsubscribe(logChan)
+
+	session := &vtgatepb.Session{TargetString: "@primary"}
+	_, err := executorExec(ctx, executor, session, "select id from `user`", nil)
+	require.NoError(t, err)
+	logStats := getQueryLog(logChan)
+	require.NotNil(t, logStats)
+	var shards []string
+	for _, timing := range logStats.ShardTimings {
+		shards = append(shards, timing.Shard)
+		assert.False(t, timing.Start.Before(logStats.StartTime))
+		assert.False(t, timing.End.Before(timing.Start))
+	}
+	assert.Len(t, shards, 8)
+	assert.Contains(t, shards, "TestExecutor/-20")
+
+	_, err = executorStream(ctx, executor, "select id from `user` where id = 1")
+	require.NoError(t, err)
+	logStats = getQueryLog(logChan)
+	require.NotNil(t, logStats)
+	if assert.Len(t, logStats.ShardTimings, 1) {
+		assert.Equal(t, "TestExecutor/-20", logStats.ShardTimings[0].Shard)
+	}
+}
+
 func TestSelectUserDefinedVariable(t *testing.T) {
 	executor, _, _, _, ctx := createExecutorEnvWithConfig(t, createExecutorConfigWithNormalizer())
 	logChan := executor.queryLogger.Subscribe("Test")
@@ -1198,7 +1528,8 @@ func TestSelectBindvars(t *testing.T) {
 	}}
 
 	utils.MustMatch(t, wantLookupQueries, lookup.Queries)
-	testQueryLog(t, executor, logChan, "TestExecute", "SELECT", "select id from `user` where `name` = :name", 2)
+	logStats := testQueryLog(t, executor, logChan, "TestExecute", "SELECT", "select id from `user` where `name` = :name", 2)
+	assert.EqualValues(t, 1, logStats.LookupRoundTrips)
 }
 
 func TestSelectEqual(t *testing.T) {
@@ -1677,7 +2008,22 @@ func TestSelectScatter(t *testing.T) {
 	for _, conn := range conns {
 		utils.MustMatch(t, wantQueries, conn.Queries)
 	}
-	testQueryLog(t, executor, logChan, "TestExecute", "SELECT", sql, 8)
+	logStats := testQueryLog(t, executor, logChan, "TestExecute", "SELECT", sql, 8)
+	assert.EqualValues(t, 8, logStats.TabletsContacted)
+	assert.Zero(t, logStats.RowsTruncated)
+	assert.Equal(t, sql, logStats.OriginalSQL)
+	assert.Equal(t, "select id from `user`", logStats.RewrittenSQL)
+	assert.Equal(t, engine.RoutingReasonScatterNoVindex, logStats.RoutingReason)
+
+	// Every shard returns a row, so all but two are dropped by the limit.
+	sql = "select id from `user` limit 2"
+	_, err = executorExec(ctx, executor, session, sql, nil)
+	require.NoError(t, err)
+	logStats = testQueryLog(t, executor, logChan, "TestExecute", "SELECT", sql, 8)
+	assert.EqualValues(t, 6, logStats.RowsTruncated)
+	assert.EqualValues(t, 8, logStats.RowsExamined)
+	assert.EqualValues(t, 2, logStats.RowsReturned)
+	assert.EqualValues(t, 4, logStats.ExaminedRatio())
 }
 
 func TestSelectScatterPartial(t *testing.T) {
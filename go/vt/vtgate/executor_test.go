@@ -37,6 +37,7 @@ import (
 	"github.com/stretchr/testify/require"
 	"google.golang.org/protobuf/proto"
 
+	"vitess.io/vitess/go/cache/theine"
 	"vitess.io/vitess/go/streamlog"
 	"vitess.io/vitess/go/vt/key"
 	"vitess.io/vitess/go/vt/srvtopo"
@@ -1592,6 +1593,31 @@ func TestExecutorDeniedErrorNoBuffer(t *testing.T) {
 	require.GreaterOrEqual(t, endExec.Sub(startExec).Milliseconds(), int64(500))
 }
 
+func TestExecutorDeniedErrorLogged(t *testing.T) {
+	executor, sbc1, _, _, ctx := createExecutorEnv(t)
+	logChan := executor.queryLogger.Subscribe("Test")
+	defer executor.queryLogger.Unsubscribe(logChan)
+
+	defer func(timeout time.Duration) { vschemaWaitTimeout = timeout }(vschemaWaitTimeout)
+	vschemaWaitTimeout = 10 * time.Millisecond
+
+	session := econtext.NewAutocommitSession(&vtgatepb.Session{TargetString: "@primary"})
+	_, err := executorExecSession(ctx, executor, session, "select * from user where id = 1", nil)
+	require.NoError(t, err)
+	logStats := getQueryLog(logChan)
+	require.NotNil(t, logStats)
+	assert.False(t, logStats.DeniedTables)
+
+	// The query is retried, and succeeds, but the rejection is logged.
+	sbc1.EphemeralShardErr = errors.New("enforce denied tables")
+	_, err = executorExecSession(ctx, executor, session, "select * from user where id = 1", nil)
+	require.NoError(t, err)
+	logStats = getQueryLog(logChan)
+	require.NotNil(t, logStats)
+	assert.True(t, logStats.DeniedTables)
+	assert.NoError(t, logStats.Error)
+}
+
 // TestVSchemaStats makes sure the building and displaying of the
 // VSchemaStats works.
 func TestVSchemaStats(t *testing.T) {
@@ -1641,6 +1667,55 @@ func TestGetPlanUnnormalized(t *testing.T) {
 	assert.Equal(t, wantSQL, logStats.SQL)
 }
 
+func TestGetPlanRecompiledAfterEviction(t *testing.T) {
+	r, _, _, _, ctx := createExecutorEnv(t)
+	vc, _ := r.newVCursor(econtext.NewSafeSession(&vtgatepb.Session{TargetString: "@unknown"}), makeComments(""), nil)
+
+	query1 := "select * from music_user_map where id = 1"
+	_, logStats := getPlanCached(t, ctx, r, vc.SafeSession, query1, makeComments(""), map[string]*querypb.BindVariable{}, false)
+	assert.False(t, logStats.PlanRecompiled, "a plan built for the first time is not a recompilation")
+
+	// Simulate the cache evicting the plan under memory pressure.
+	key := buildPlanKey(ctx, vc, query1, "").Hash()
+	r.plans.Delete(key)
+	r.planEvictions.onRemoval(key, nil, theine.EVICTED)
+
+	_, logStats = getPlanCached(t, ctx, r, vc.SafeSession, query1, makeComments(""), map[string]*querypb.BindVariable{}, false)
+	assert.False(t, logStats.CachedPlan)
+	assert.True(t, logStats.PlanRecompiled)
+
+	_, logStats = getPlanCached(t, ctx, r, vc.SafeSession, query1, makeComments(""), map[string]*querypb.BindVariable{}, false)
+	assert.True(t, logStats.CachedPlan)
+	assert.False(t, logStats.PlanRecompiled)
+}
+
+func TestExecutorLogsSessionSettings(t *testing.T) {
+	executor, _, _, _, ctx := createExecutorEnv(t)
+	session := econtext.NewAutocommitSession(&vtgatepb.Session{TargetString: "@primary", TransactionMode: vtgatepb.TransactionMode_SINGLE})
+	logChan := executor.queryLogger.Subscribe("Test")
+	defer executor.queryLogger.Unsubscribe(logChan)
+
+	_, err := executor.Execute(ctx, nil, "TestExecute", session, "set workload = 'olap'", nil, false)
+	require.NoError(t, err)
+	// The query is logged with the settings it ran under, not the ones it set.
+	logStats := getQueryLog(logChan)
+	require.NotNil(t, logStats)
+	assert.Equal(t, "transaction_mode=SINGLE", logStats.SessionSettings)
+
+	_, err = executor.Execute(ctx, nil, "TestExecute", session, "select id from main1", nil, false)
+	require.NoError(t, err)
+	logStats = getQueryLog(logChan)
+	require.NotNil(t, logStats)
+	assert.Equal(t, "transaction_mode=SINGLE, workload=OLAP", logStats.SessionSettings)
+
+	// The settings aren't summarized while nothing reads the query log.
+	executor.queryLogger.Unsubscribe(logChan)
+	logStats = logstats.NewLogStats(ctx, "TestExecute", "select id from main1", "", nil, streamlog.NewQueryLogConfigForTest())
+	executor.withQueryLogStats(ctx, logStats, session)
+	assert.Empty(t, logStats.SessionSettings)
+	assert.Empty(t, logStats.Collation)
+}
+
 func assertCacheSize(t *testing.T, c *PlanCache, expected int) {
 	t.Helper()
 	size := c.Len()
@@ -661,6 +661,33 @@ func (session *SafeSession) GetSystemVariables(f func(k string, v string)) {
 	}
 }
 
+// SettingsSummary describes the session settings that change how queries
+// behave: the transaction mode, the workload, autocommit when it is off,
+// and every system variable set on the session, in name order.
+func (session *SafeSession) SettingsSummary() string {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	var settings []string
+	if session.TransactionMode != vtgatepb.TransactionMode_UNSPECIFIED {
+		settings = append(settings, "transaction_mode="+session.TransactionMode.String())
+	}
+	if workload := session.GetOptions().GetWorkload(); workload != querypb.ExecuteOptions_UNSPECIFIED {
+		settings = append(settings, "workload="+workload.String())
+	}
+	if !session.Autocommit {
+		settings = append(settings, "autocommit=0")
+	}
+	names := make([]string, 0, len(session.SystemVariables))
+	for name := range session.SystemVariables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		settings = append(settings, name+"="+session.SystemVariables[name])
+	}
+	return strings.Join(settings, ", ")
+}
+
 // HasSystemVariables returns whether the session has system variables that would apply to MySQL
 func (session *SafeSession) HasSystemVariables() (found bool) {
 	session.GetSystemVariables(func(_ string, _ string) {
@@ -690,6 +717,23 @@ func (session *SafeSession) TimeZone() *time.Location {
 	return loc
 }
 
+// ConnectionCollation returns the name of the collation_connection set on
+// the session, or an empty string if it wasn't set.
+func (session *SafeSession) ConnectionCollation() string {
+	session.mu.Lock()
+	collationSQL, ok := session.SystemVariables["collation_connection"]
+	session.mu.Unlock()
+
+	if !ok {
+		return ""
+	}
+	collation, err := sqltypes.DecodeStringSQL(collationSQL)
+	if err != nil {
+		return ""
+	}
+	return collation
+}
+
 // ForeignKeyChecks returns the foreign_key_checks stored in system_variables map in the session.
 func (session *SafeSession) ForeignKeyChecks() *bool {
 	session.mu.Lock()
@@ -168,6 +168,31 @@ func TestPrequeries(t *testing.T) {
 	}
 }
 
+func TestSettingsSummary(t *testing.T) {
+	session := NewSafeSession(&vtgatepb.Session{Autocommit: true})
+	assert.Empty(t, session.SettingsSummary())
+
+	session = NewSafeSession(&vtgatepb.Session{
+		TransactionMode: vtgatepb.TransactionMode_SINGLE,
+		Options:         &querypb.ExecuteOptions{Workload: querypb.ExecuteOptions_OLAP},
+		SystemVariables: map[string]string{
+			"sql_mode":  "''",
+			"time_zone": "'+02:00'",
+		},
+	})
+	assert.Equal(t, "transaction_mode=SINGLE, workload=OLAP, autocommit=0, sql_mode='', time_zone='+02:00'", session.SettingsSummary())
+}
+
+func TestConnectionCollation(t *testing.T) {
+	session := NewSafeSession(&vtgatepb.Session{})
+	assert.Empty(t, session.ConnectionCollation())
+
+	session = NewSafeSession(&vtgatepb.Session{
+		SystemVariables: map[string]string{"collation_connection": "'utf8mb4_bin'"},
+	})
+	assert.Equal(t, "utf8mb4_bin", session.ConnectionCollation())
+}
+
 func TestTimeZone(t *testing.T) {
 	testCases := []struct {
 		tz   string
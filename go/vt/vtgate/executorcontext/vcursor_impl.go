@@ -823,7 +823,11 @@ func (vc *VCursorImpl) Execute(ctx context.Context, method string, query string,
 		return nil, err
 	}
 
+	start := time.Now()
 	qr, err := vc.executor.Execute(ctx, nil, method, session, vc.marginComments.Leading+query+vc.marginComments.Trailing, bindVars, false)
+	if stats, ok := engine.StatsSinkFromContext(ctx); ok {
+		stats.AddLookupRoundTrip(time.Since(start))
+	}
 	// If there is no error, it indicates at least one successful execution,
 	// meaning a rollback should be triggered if a failure occurs later.
 	vc.setRollbackOnPartialExecIfRequired(err == nil, rollbackOnError)
@@ -931,7 +935,11 @@ func (vc *VCursorImpl) ExecuteKeyspaceID(ctx context.Context, keyspace string, k
 			vc.SafeSession.SetQueryFromVindex(false)
 		}()
 	}
+	start := time.Now()
 	qr, errs := vc.ExecuteMultiShard(ctx, nil, rss, queries, rollbackOnError, autocommit, false)
+	if stats, ok := engine.StatsSinkFromContext(ctx); ok {
+		stats.AddLookupRoundTrip(time.Since(start))
+	}
 	return qr, vterrors.Aggregate(errs)
 }
 
@@ -1640,6 +1648,25 @@ func (vc *VCursorImpl) RecordMirrorStats(sourceExecTime, targetExecTime time.Dur
 	vc.logStats.MirrorTargetError = targetErr
 }
 
+// RecordOnlineDDL records that the query submitted the online schema
+// migration with the given UUID.
+func (vc *VCursorImpl) RecordOnlineDDL(uuid string) {
+	vc.logStats.OnlineDDL = true
+	vc.logStats.MigrationUUIDs = append(vc.logStats.MigrationUUIDs, uuid)
+}
+
+// RecordPlanRecompiled records that the plan of the query is being built
+// again after it was evicted from the plan cache.
+func (vc *VCursorImpl) RecordPlanRecompiled() {
+	vc.logStats.PlanRecompiled = true
+}
+
+// RecordParseTime adds d, the time spent parsing the query, to the parse
+// time of the query.
+func (vc *VCursorImpl) RecordParseTime(d time.Duration) {
+	vc.logStats.ParseTime += d
+}
+
 func (vc *VCursorImpl) GetMarginComments() sqlparser.MarginComments {
 	return vc.marginComments
 }
@@ -0,0 +1,127 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logstats
+
+import (
+	"bytes"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/safehtml/template"
+
+	"vitess.io/vitess/go/streamlog"
+)
+
+// FormatHTML is the name of the built-in formatter that renders a
+// LogStats record as a single HTML table row.
+const FormatHTML = "html"
+
+// Formatter renders a single LogStats record. Formatters are registered
+// by name with RegisterFormatter, and are selected by name by the
+// querylog sinks and by the querylogz handler.
+type Formatter interface {
+	Format(stats *LogStats) ([]byte, error)
+}
+
+// FormatterFunc adapts an ordinary function to the Formatter interface.
+type FormatterFunc func(stats *LogStats) ([]byte, error)
+
+// Format implements the Formatter interface.
+func (f FormatterFunc) Format(stats *LogStats) ([]byte, error) {
+	return f(stats)
+}
+
+var (
+	formattersMu sync.Mutex
+	formatters   = map[string]Formatter{}
+)
+
+func init() {
+	// As with Logf without the full parameter, the text and json formats
+	// write the length of string bind variables rather than their values.
+	RegisterFormatter(streamlog.QueryLogFormatText, FormatterFunc(func(stats *LogStats) ([]byte, error) {
+		var buf bytes.Buffer
+		err := stats.format(&buf, false, false)
+		return buf.Bytes(), err
+	}))
+	RegisterFormatter(streamlog.QueryLogFormatJSON, FormatterFunc(func(stats *LogStats) ([]byte, error) {
+		var buf bytes.Buffer
+		err := stats.format(&buf, true, false)
+		return buf.Bytes(), err
+	}))
+	RegisterFormatter(FormatHTML, FormatterFunc(formatHTML))
+}
+
+// RegisterFormatter makes a Formatter available under the given name.
+// Registering a name a second time replaces the previous Formatter, which
+// allows embedders to override the built-in formats.
+func RegisterFormatter(name string, f Formatter) {
+	formattersMu.Lock()
+	defer formattersMu.Unlock()
+	formatters[name] = f
+}
+
+// GetFormatter returns the Formatter registered under the given name,
+// or nil if there is none.
+func GetFormatter(name string) Formatter {
+	formattersMu.Lock()
+	defer formattersMu.Unlock()
+	return formatters[name]
+}
+
+// FormatterNames returns the sorted names of all registered formatters.
+func FormatterNames() []string {
+	formattersMu.Lock()
+	defer formattersMu.Unlock()
+	names := make([]string, 0, len(formatters))
+	for name := range formatters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+var htmlRowTmpl = template.Must(template.New("logstats").Funcs(template.FuncMap{
+	"stampMicro": func(t time.Time) string { return t.Format(time.StampMicro) },
+}).Parse(`<tr>
+	<td>{{.Method}}</td>
+	<td>{{.EffectiveCaller}}</td>
+	<td>{{.ImmediateCaller}}</td>
+	<td>{{.SessionUUID}}</td>
+	<td>{{.StartTime | stampMicro}}</td>
+	<td>{{.EndTime | stampMicro}}</td>
+	<td>{{.TotalTime.Seconds}}</td>
+	<td>{{.PlanTime.Seconds}}</td>
+	<td>{{.ExecuteTime.Seconds}}</td>
+	<td>{{.CommitTime.Seconds}}</td>
+	<td>{{.StmtType}}</td>
+	<td>{{.SQL}}</td>
+	<td>{{.ShardQueries}}</td>
+	<td>{{.RowsAffected}}</td>
+	<td>{{.ErrorStr}}</td>
+</tr>
+`))
+
+// formatHTML renders the record as an escaped HTML table row.
+func formatHTML(stats *LogStats) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := htmlRowTmpl.Execute(&buf, stats); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logstats
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vitess.io/vitess/go/sqltypes"
+	"vitess.io/vitess/go/streamlog"
+
+	querypb "vitess.io/vitess/go/vt/proto/query"
+)
+
+func TestBuiltinFormatters(t *testing.T) {
+	assert.Subset(t, FormatterNames(), []string{streamlog.QueryLogFormatText, streamlog.QueryLogFormatJSON, FormatHTML})
+	assert.Nil(t, GetFormatter("nonexistent"))
+
+	bindVars := map[string]*querypb.BindVariable{"strVal": sqltypes.StringBindVariable("secret")}
+	logStats := NewLogStats(context.Background(), "test", "select '<b>'", "suuid", bindVars, streamlog.NewQueryLogConfigForTest())
+	logStats.StartTime = time.Date(2017, time.January, 1, 1, 2, 3, 0, time.UTC)
+	logStats.EndTime = time.Date(2017, time.January, 1, 1, 2, 4, 1234, time.UTC)
+
+	// The text formatter produces the same output as Logf without the full
+	// parameter, so string bind variable values are left out.
+	got, err := GetFormatter(streamlog.QueryLogFormatText).Format(logStats)
+	require.NoError(t, err)
+	assert.Equal(t, testFormat(t, logStats, url.Values{}), string(got))
+	assert.NotContains(t, string(got), "secret")
+
+	got, err = GetFormatter(streamlog.QueryLogFormatJSON).Format(logStats)
+	require.NoError(t, err)
+	assert.NotContains(t, string(got), "secret")
+	var parsed map[string]any
+	require.NoError(t, json.Unmarshal(got, &parsed))
+	assert.Equal(t, "select '<b>'", parsed["SQL"])
+
+	got, err = GetFormatter(FormatHTML).Format(logStats)
+	require.NoError(t, err)
+	assert.Contains(t, string(got), "<td>select &#39;&lt;b&gt;&#39;</td>")
+}
+
+func TestCustomFormatter(t *testing.T) {
+	RegisterFormatter("kv", FormatterFunc(func(stats *LogStats) ([]byte, error) {
+		return fmt.Appendf(nil, "method=%s sql=%q\n", stats.Method, stats.SQL), nil
+	}))
+	defer func() {
+		formattersMu.Lock()
+		delete(formatters, "kv")
+		formattersMu.Unlock()
+	}()
+
+	logStats := NewLogStats(context.Background(), "test", "sql1", "suuid", nil, streamlog.NewQueryLogConfigForTest())
+	logStats.Config.Format = "kv"
+	assert.Equal(t, "method=test sql=\"sql1\"\n", testFormat(t, logStats, url.Values{}))
+
+	// The emit filters still apply to custom formats.
+	logStats.Config.FilterTag = "NOT_THIS_QUERY"
+	assert.Empty(t, testFormat(t, logStats, url.Values{}))
+}
@@ -19,15 +19,23 @@ package logstats
 import (
 	"context"
 	"io"
+	"maps"
 	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/safehtml"
 
 	"vitess.io/vitess/go/logstats"
+	"vitess.io/vitess/go/mysql/sqlerror"
 	"vitess.io/vitess/go/streamlog"
 	"vitess.io/vitess/go/vt/callerid"
 	"vitess.io/vitess/go/vt/callinfo"
+	"vitess.io/vitess/go/vt/sqlparser"
+
 	querypb "vitess.io/vitess/go/vt/proto/query"
 )
 
@@ -57,12 +65,132 @@ type LogStats struct {
 	MirrorSourceExecuteTime time.Duration
 	MirrorTargetExecuteTime time.Duration
 	MirrorTargetError       error
+	// ConnectionSetupTime is the time spent dialing new tablet connections.
+	ConnectionSetupTime time.Duration
+	// PlanFingerprint identifies the shape of the plan that served the query.
+	PlanFingerprint string
+	// QueryCategory is QueryCategoryOLTP or QueryCategoryOLAP.
+	QueryCategory string
+	// TabletsContacted is the number of distinct tablets the query was sent to.
+	TabletsContacted uint64
+	// RowsTruncated is the number of rows a LIMIT dropped in vtgate.
+	RowsTruncated uint64
+	// PlanRecompiled is set when the plan was rebuilt after a cache eviction.
+	PlanRecompiled bool
+	// OriginalSQL is the query as sent by the application.
+	OriginalSQL string
+	// RewrittenSQL holds the queries vtgate sent to the tablets.
+	RewrittenSQL string
+	// RoutingReason explains why the query was sent to its shards.
+	RoutingReason string
+	// RepeatCount is the number of identical queries a deduplicated entry stands for.
+	RepeatCount uint64
+	// SessionSettings summarizes the session settings the query ran with.
+	SessionSettings string
+	// Collation is the name of the collation the query ran with.
+	Collation string
+	// RowsExamined is the number of rows vtgate received from the tablets.
+	RowsExamined uint64
+	// Deadline is the earliest deadline the query ran under.
+	Deadline time.Time
+	// Prepared is set when the query executed a prepared statement.
+	Prepared bool
+	// Annotations are the key/value pairs attached with WithAnnotation.
+	Annotations map[string]string
+	// LookupRoundTrips and LookupTime measure the queries to lookup vindexes.
+	LookupRoundTrips uint64
+	LookupTime       time.Duration
+	// ParseTime is the part of PlanTime spent parsing the query.
+	ParseTime time.Duration
+	// Buffered and BufferTime record whether and how long the query was buffered.
+	Buffered   bool
+	BufferTime time.Duration
+	// ReferenceTable is set when the query used a reference table.
+	ReferenceTable bool
+	// DeniedTables is set when a tablet rejected the query under a denied tables rule.
+	DeniedTables bool
+	// MaterializedWrite is set when the query wrote to a reference table source.
+	MaterializedWrite bool
+	// PlannerVersion is the planner that built the plan of the query.
+	PlannerVersion string
+	// RouteHint is the comment directive that changed the routing of the query.
+	RouteHint string
+	// FastPath is set when the query was sent as is to a single shard.
+	FastPath bool
+	// KeyspacesTouched is the number of distinct keyspaces of the query's tables.
+	KeyspacesTouched uint64
+	// OnlineDDL is set when the query submitted the MigrationUUIDs migrations.
+	OnlineDDL      bool
+	MigrationUUIDs []string
+	// RowsBuffered is the largest number of rows held in memory by vtgate.
+	RowsBuffered uint64
+	// PostProcessingOps is the number of in-memory operators of the plan.
+	PostProcessingOps uint64
+	// BackendConnectionIDs maps keyspace/shard to the MySQL connection id.
+	BackendConnectionIDs map[string]uint64
+	// ShardTimings hold the start and end of each shard query.
+	ShardTimings []ShardTiming
+	// ResultColumns and ResultColumnTypes describe the columns of the result.
+	ResultColumns     uint64
+	ResultColumnTypes string
+
+	// targetTablesOnce guards targetTables, the tables referenced by SQL,
+	// which are parsed on first use by TargetTables.
+	targetTablesOnce sync.Once
+	targetTables     []string
+
+	// bufferMu protects Buffered and BufferTime.
+	bufferMu sync.Mutex
+
+	// tabletsMu protects tablets.
+	tabletsMu sync.Mutex
+	// tablets holds the aliases of the tablets counted in TabletsContacted.
+	tablets map[string]struct{}
+
+	// backendConnectionsMu protects BackendConnectionIDs.
+	backendConnectionsMu sync.Mutex
+	// shardTimingsMu protects ShardTimings.
+	shardTimingsMu sync.Mutex
+}
+
+// shardDurations returns how long the queries of each shard took, in the
+// order of ShardTimings. The queries sent to the same shard are summed.
+func (stats *LogStats) shardDurations() []logstats.ShardDuration {
+	stats.shardTimingsMu.Lock()
+	defer stats.shardTimingsMu.Unlock()
+	var shards []logstats.ShardDuration
+	index := map[string]int{}
+	for _, timing := range stats.ShardTimings {
+		i, ok := index[timing.Shard]
+		if !ok {
+			i = len(shards)
+			index[timing.Shard] = i
+			shards = append(shards, logstats.ShardDuration{Shard: timing.Shard})
+		}
+		shards[i].Duration += timing.End.Sub(timing.Start)
+	}
+	return shards
+}
+
+// ShardTiming is the time span of a query vtgate sent to a shard.
+type ShardTiming struct {
+	// Shard is the shard the query was sent to, as keyspace/shard.
+	Shard      string
+	Start, End time.Time
 }
 
+const (
+	// QueryCategoryOLTP labels short, targeted queries.
+	QueryCategoryOLTP = "OLTP"
+	// QueryCategoryOLAP labels analytical queries, which aggregate or
+	// return large amounts of data across shards.
+	QueryCategoryOLAP = "OLAP"
+)
+
 // NewLogStats constructs a new LogStats with supplied Method and ctx
 // field values, and the StartTime field set to the present time.
 func NewLogStats(ctx context.Context, methodName, sql, sessionUUID string, bindVars map[string]*querypb.BindVariable, config streamlog.QueryLogConfig) *LogStats {
-	return &LogStats{
+	stats := &LogStats{
 		Ctx:           ctx,
 		Method:        methodName,
 		SQL:           sql,
@@ -70,7 +198,30 @@ func NewLogStats(ctx context.Context, methodName, sql, sessionUUID string, bindV
 		BindVariables: bindVars,
 		StartTime:     time.Now(),
 		Config:        config,
+		Annotations:   annotationsFromContext(ctx),
 	}
+	stats.RecordDeadline(ctx)
+	return stats
+}
+
+type annotationsKey struct{}
+
+// WithAnnotation returns a copy of ctx that carries the annotation key=value,
+// in addition to the annotations ctx already carries. The LogStats of the
+// queries executed with the returned context record its annotations.
+func WithAnnotation(ctx context.Context, key, value string) context.Context {
+	annotations := annotationsFromContext(ctx)
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[key] = value
+	return context.WithValue(ctx, annotationsKey{}, annotations)
+}
+
+// annotationsFromContext returns a copy of the annotations carried by ctx.
+func annotationsFromContext(ctx context.Context) map[string]string {
+	annotations, _ := ctx.Value(annotationsKey{}).(map[string]string)
+	return maps.Clone(annotations)
 }
 
 // SaveEndTime sets the end time of this request to now
@@ -78,6 +229,204 @@ func (stats *LogStats) SaveEndTime() {
 	stats.EndTime = time.Now()
 }
 
+// AddConnectionSetupTime adds the time spent dialing a new tablet connection
+// to ConnectionSetupTime. It is safe to call concurrently, since scatter
+// queries may dial several tablets at once.
+func (stats *LogStats) AddConnectionSetupTime(d time.Duration) {
+	atomic.AddInt64((*int64)(&stats.ConnectionSetupTime), int64(d))
+}
+
+// AddLookupRoundTrip records a query to the table of a lookup vindex that
+// took d, in LookupRoundTrips and LookupTime. It is safe to call
+// concurrently.
+func (stats *LogStats) AddLookupRoundTrip(d time.Duration) {
+	atomic.AddUint64(&stats.LookupRoundTrips, 1)
+	atomic.AddInt64((*int64)(&stats.LookupTime), int64(d))
+}
+
+// AddBufferTime records that the query was buffered for d during a
+// failover. It is safe to call concurrently.
+func (stats *LogStats) AddBufferTime(d time.Duration) {
+	stats.bufferMu.Lock()
+	defer stats.bufferMu.Unlock()
+	stats.Buffered = true
+	stats.BufferTime = max(stats.BufferTime, d)
+}
+
+// AddTablet records that the query was sent to the tablet with the given
+// alias, counting it in TabletsContacted the first time it is seen. It is
+// safe to call concurrently.
+func (stats *LogStats) AddTablet(alias string) {
+	stats.tabletsMu.Lock()
+	defer stats.tabletsMu.Unlock()
+	if _, ok := stats.tablets[alias]; ok {
+		return
+	}
+	if stats.tablets == nil {
+		stats.tablets = map[string]struct{}{}
+	}
+	stats.tablets[alias] = struct{}{}
+	stats.TabletsContacted = uint64(len(stats.tablets))
+}
+
+// AddBackendConnectionID records that the query ran on shard, given as
+// keyspace/shard, over the MySQL connection with the given id. It is safe to
+// call concurrently.
+func (stats *LogStats) AddBackendConnectionID(shard string, id uint64) {
+	stats.backendConnectionsMu.Lock()
+	defer stats.backendConnectionsMu.Unlock()
+	if stats.BackendConnectionIDs == nil {
+		stats.BackendConnectionIDs = map[string]uint64{}
+	}
+	stats.BackendConnectionIDs[shard] = id
+}
+
+// AddShardTiming records that a query sent to shard, given as
+// keyspace/shard, ran from start to end. It is safe to call concurrently.
+func (stats *LogStats) AddShardTiming(shard string, start, end time.Time) {
+	stats.shardTimingsMu.Lock()
+	defer stats.shardTimingsMu.Unlock()
+	stats.ShardTimings = append(stats.ShardTimings, ShardTiming{Shard: shard, Start: start, End: end})
+}
+
+// AddRowsTruncated adds n rows dropped by a LIMIT to RowsTruncated. It is
+// safe to call concurrently.
+func (stats *LogStats) AddRowsTruncated(n int) {
+	atomic.AddUint64(&stats.RowsTruncated, uint64(n))
+}
+
+// AddRowsExamined adds n rows received from a tablet to RowsExamined. It is
+// safe to call concurrently.
+func (stats *LogStats) AddRowsExamined(n int) {
+	atomic.AddUint64(&stats.RowsExamined, uint64(n))
+}
+
+// RecordRowsBuffered raises RowsBuffered to n rows, if it is lower. It is
+// safe to call concurrently.
+func (stats *LogStats) RecordRowsBuffered(n int) {
+	for {
+		peak := atomic.LoadUint64(&stats.RowsBuffered)
+		if uint64(n) <= peak || atomic.CompareAndSwapUint64(&stats.RowsBuffered, peak, uint64(n)) {
+			return
+		}
+	}
+}
+
+// SetResultFields records the number and types of the columns of the
+// result of the query from its fields.
+func (stats *LogStats) SetResultFields(fields []*querypb.Field) {
+	if len(fields) == 0 {
+		return
+	}
+	types := make([]string, 0, len(fields))
+	for _, field := range fields {
+		types = append(types, field.Type.String())
+	}
+	stats.ResultColumns = uint64(len(fields))
+	stats.ResultColumnTypes = strings.Join(types, ",")
+}
+
+// ExaminedRatio returns the number of rows examined per row returned. A
+// query that returned no rows is counted as returning one, so that the
+// ratio stays finite. High ratios flag queries that would benefit from
+// being pushed down further, or from an index.
+func (stats *LogStats) ExaminedRatio() float64 {
+	return float64(stats.RowsExamined) / float64(max(stats.RowsReturned, 1))
+}
+
+// RecordDeadline records the deadline of ctx as the deadline of the query,
+// unless the query already has an earlier one.
+func (stats *LogStats) RecordDeadline(ctx context.Context) {
+	deadline, ok := ctx.Deadline()
+	if ok && (stats.Deadline.IsZero() || deadline.Before(stats.Deadline)) {
+		stats.Deadline = deadline
+	}
+}
+
+// TimeToDeadline returns the time the query had left before its deadline
+// when it started, or zero if it had no deadline.
+func (stats *LogStats) TimeToDeadline() time.Duration {
+	if stats.Deadline.IsZero() {
+		return 0
+	}
+	return stats.Deadline.Sub(stats.StartTime)
+}
+
+// DeadlineUsed returns the share of the time to its deadline the query
+// used, as a percentage, or zero if it had no deadline. Queries that use
+// most of it are the first to time out under slightly more load.
+func (stats *LogStats) DeadlineUsed() float64 {
+	timeout := stats.TimeToDeadline()
+	if timeout <= 0 {
+		return 0
+	}
+	return 100 * float64(stats.TotalTime()) / float64(timeout)
+}
+
+// Truncated returns true if a LIMIT dropped rows from the result.
+func (stats *LogStats) Truncated() bool {
+	return stats.RowsTruncated > 0
+}
+
+// TargetTables returns the sorted names of the tables referenced by SQL, as
+// parsed by parser, qualified with their keyspace when the query does so.
+// The result is cached, so that a record rendered repeatedly is only parsed
+// once. It is empty if the query can't be parsed.
+func (stats *LogStats) TargetTables(parser *sqlparser.Parser) []string {
+	stats.targetTablesOnce.Do(func() {
+		stmt, err := parser.Parse(stats.SQL)
+		if err != nil {
+			return
+		}
+		tables := map[string]struct{}{}
+		add := func(name sqlparser.TableName) {
+			switch {
+			case name.IsEmpty():
+			case name.Qualifier.IsEmpty() && name.Name.String() == "dual":
+				// The implicit table of queries without a FROM clause.
+			case name.Qualifier.IsEmpty():
+				tables[name.Name.String()] = struct{}{}
+			default:
+				tables[name.Qualifier.String()+"."+name.Name.String()] = struct{}{}
+			}
+		}
+		if ddl, ok := stmt.(sqlparser.DDLStatement); ok {
+			for _, name := range ddl.AffectedTables() {
+				add(name)
+			}
+		}
+		_ = sqlparser.Walk(func(node sqlparser.SQLNode) (bool, error) {
+			if aliased, ok := node.(*sqlparser.AliasedTableExpr); ok {
+				if name, ok := aliased.Expr.(sqlparser.TableName); ok {
+					add(name)
+				}
+			}
+			return true, nil
+		}, stmt)
+		stats.targetTables = slices.Sorted(maps.Keys(tables))
+	})
+	return stats.targetTables
+}
+
+// DedupKey implements streamlog.Collapsible. Queries compare equal when
+// they ran the same normalized SQL through the same method and keyspace,
+// and failed with the same error or not at all.
+func (stats *LogStats) DedupKey() string {
+	return stats.Method + "\x00" + stats.ActiveKeyspace + "\x00" + stats.SQL + "\x00" + stats.ErrorStr()
+}
+
+// RedactsOriginalSQL returns whether OriginalSQL is left out of the log. Its
+// literals are the values of the bind variables, so it is left out whenever
+// any of them may be redacted.
+func (stats *LogStats) RedactsOriginalSQL() bool {
+	return stats.Config.RedactDebugUIQueries || stats.Config.RedactBindVars != nil
+}
+
+// SetRepeatCount implements streamlog.Collapsible.
+func (stats *LogStats) SetRepeatCount(count uint64) {
+	stats.RepeatCount = count
+}
+
 // ImmediateCaller returns the immediate caller stored in LogStats.Ctx
 func (stats *LogStats) ImmediateCaller() string {
 	return callerid.GetUsername(callerid.ImmediateCallerIDFromContext(stats.Ctx))
@@ -113,6 +462,16 @@ func (stats *LogStats) ErrorStr() string {
 	return ""
 }
 
+// ErrorNumber returns the MySQL error number of Error, as reported to the
+// client, or 0 if there is no error. Errors that were not returned by MySQL
+// are given the error number that their vitess error code maps to.
+func (stats *LogStats) ErrorNumber() sqlerror.ErrorCode {
+	if stats.Error == nil {
+		return 0
+	}
+	return sqlerror.NewSQLErrorFromError(stats.Error).(*sqlerror.SQLError).Number()
+}
+
 // RemoteAddrUsername returns some parts of CallInfo if set
 func (stats *LogStats) RemoteAddrUsername() (string, string) {
 	ci, ok := callinfo.FromContext(stats.Ctx)
@@ -122,6 +481,38 @@ func (stats *LogStats) RemoteAddrUsername() (string, string) {
 	return ci.RemoteAddr(), ci.Username()
 }
 
+// Protocol returns the protocol the client connected with, as recorded in
+// its CallInfo, or "" if the client is not known.
+func (stats *LogStats) Protocol() string {
+	ci, ok := callinfo.FromContext(stats.Ctx)
+	if !ok {
+		return ""
+	}
+	return ci.Protocol()
+}
+
+// ApplicationName returns the name of the client application stored in
+// LogStats.Ctx, such as the program_name connection attribute of MySQL
+// clients, or "" if the client didn't send one.
+func (stats *LogStats) ApplicationName() string {
+	ci, ok := callinfo.FromContext(stats.Ctx)
+	if !ok {
+		return ""
+	}
+	return ci.ApplicationName()
+}
+
+// Compression returns the name of the compressor the results were sent to
+// the client with, as stored in LogStats.Ctx, or "" if they were sent
+// uncompressed.
+func (stats *LogStats) Compression() string {
+	ci, ok := callinfo.FromContext(stats.Ctx)
+	if !ok {
+		return ""
+	}
+	return ci.Compression()
+}
+
 // MirorTargetErrorStr returns the mirror target error string or ""
 func (stats *LogStats) MirrorTargetErrorStr() string {
 	if stats.MirrorTargetError != nil {
@@ -131,17 +522,38 @@ func (stats *LogStats) MirrorTargetErrorStr() string {
 }
 
 // Logf formats the log record to the given writer, either as
-// tab-separated list of logged fields or as JSON.
+// tab-separated list of logged fields or as JSON. Any other format name
+// is looked up among the registered Formatters; unknown names fall back
+// to the text format.
 func (stats *LogStats) Logf(w io.Writer, params url.Values) error {
 	if !stats.Config.ShouldEmitLog(stats.SQL, stats.RowsAffected, stats.RowsReturned, stats.Error != nil) {
 		return nil
 	}
 
+	switch stats.Config.Format {
+	case streamlog.QueryLogFormatText, streamlog.QueryLogFormatJSON:
+	default:
+		if fmter := GetFormatter(stats.Config.Format); fmter != nil {
+			b, err := fmter.Format(stats)
+			if err != nil {
+				return err
+			}
+			_, err = w.Write(b)
+			return err
+		}
+	}
+
 	_, fullBindParams := params["full"]
+	return stats.format(w, stats.Config.Format == streamlog.QueryLogFormatJSON, fullBindParams)
+}
+
+// format writes the log record to the given writer, either as
+// tab-separated list of logged fields or as JSON.
+func (stats *LogStats) format(w io.Writer, json bool, fullBindParams bool) error {
 	remoteAddr, username := stats.RemoteAddrUsername()
 
 	log := logstats.NewLogger()
-	log.Init(stats.Config.Format == streamlog.QueryLogFormatJSON)
+	log.Init(json)
 	log.Key("Method")
 	log.StringUnquoted(stats.Method)
 	log.Key("RemoteAddr")
@@ -172,7 +584,7 @@ func (stats *LogStats) Logf(w io.Writer, params url.Values) error {
 	if stats.Config.RedactDebugUIQueries {
 		log.Redacted()
 	} else {
-		log.BindVariables(stats.BindVariables, fullBindParams)
+		log.RedactedBindVariables(stats.BindVariables, fullBindParams, stats.Config.RedactBindVars)
 	}
 	log.Key("ShardQueries")
 	log.Uint(stats.ShardQueries)
@@ -196,6 +608,99 @@ func (stats *LogStats) Logf(w io.Writer, params url.Values) error {
 	log.Duration(stats.MirrorTargetExecuteTime)
 	log.Key("MirrorTargetError")
 	log.String(stats.MirrorTargetErrorStr())
+	log.Key("ConnectionSetupTime")
+	log.Duration(stats.ConnectionSetupTime)
+	log.Key("PlanFingerprint")
+	log.String(stats.PlanFingerprint)
+	log.Key("QueryCategory")
+	log.String(stats.QueryCategory)
+	log.Key("TabletsContacted")
+	log.Uint(stats.TabletsContacted)
+	log.Key("RowsTruncated")
+	log.Uint(stats.RowsTruncated)
+	log.Key("PlanRecompiled")
+	log.Bool(stats.PlanRecompiled)
+	// The original and rewritten SQL hold the literals of the query, so
+	// they are left out along with the bind variables.
+	originalSQL, rewrittenSQL := stats.OriginalSQL, stats.RewrittenSQL
+	if stats.RedactsOriginalSQL() {
+		originalSQL = ""
+	}
+	if stats.Config.RedactDebugUIQueries {
+		rewrittenSQL = ""
+	}
+	log.Key("OriginalSQL")
+	log.String(originalSQL)
+	log.Key("RewrittenSQL")
+	log.String(rewrittenSQL)
+	log.Key("RoutingReason")
+	log.String(stats.RoutingReason)
+	log.Key("RepeatCount")
+	log.Uint(stats.RepeatCount)
+	log.Key("SessionSettings")
+	log.String(stats.SessionSettings)
+	log.Key("RowsExamined")
+	log.Uint(stats.RowsExamined)
+	log.Key("TimeToDeadline")
+	log.Duration(stats.TimeToDeadline())
+	log.Key("Protocol")
+	log.String(stats.Protocol())
+	log.Key("Prepared")
+	log.Bool(stats.Prepared)
+	log.Key("Annotations")
+	log.StringMap(stats.Annotations)
+	log.Key("LookupRoundTrips")
+	log.Uint(stats.LookupRoundTrips)
+	log.Key("LookupTime")
+	log.Duration(stats.LookupTime)
+	log.Key("ParseTime")
+	log.Duration(stats.ParseTime)
+	log.Key("Buffered")
+	log.Bool(stats.Buffered)
+	log.Key("BufferTime")
+	log.Duration(stats.BufferTime)
+	log.Key("ErrorNumber")
+	log.Uint(uint64(stats.ErrorNumber()))
+	log.Key("ApplicationName")
+	log.String(stats.ApplicationName())
+	log.Key("ReferenceTable")
+	log.Bool(stats.ReferenceTable)
+	log.Key("Compression")
+	log.String(stats.Compression())
+	log.Key("PlannerVersion")
+	log.String(stats.PlannerVersion)
+	log.Key("FastPath")
+	log.Bool(stats.FastPath)
+	log.Key("KeyspacesTouched")
+	log.Uint(stats.KeyspacesTouched)
+	log.Key("OnlineDDL")
+	log.Bool(stats.OnlineDDL)
+	log.Key("MigrationUUIDs")
+	log.Strings(stats.MigrationUUIDs)
+	log.Key("ResultColumns")
+	log.Uint(stats.ResultColumns)
+	log.Key("ResultColumnTypes")
+	log.String(stats.ResultColumnTypes)
+	log.Key("RouteHint")
+	log.String(stats.RouteHint)
+	log.Key("RowsBuffered")
+	log.Uint(stats.RowsBuffered)
+	log.Key("MaterializedWrite")
+	log.Bool(stats.MaterializedWrite)
+	log.Key("Collation")
+	log.String(stats.Collation)
+	log.Key("DeniedTables")
+	log.Bool(stats.DeniedTables)
+	log.Key("PostProcessingOps")
+	log.Uint(stats.PostProcessingOps)
+	log.Key("BackendConnectionIDs")
+	log.UintMap(stats.BackendConnectionIDs)
+	// The time spent on each shard is only worth comparing across shards,
+	// and the text format has a fixed set of columns.
+	if shards := stats.shardDurations(); json && len(shards) > 1 {
+		log.Key("Shards")
+		log.ShardDurations(shards)
+	}
 
 	return log.Flush(w)
 }
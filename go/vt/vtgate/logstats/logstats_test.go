@@ -24,8 +24,10 @@ import (
 	"fmt"
 	"net/url"
 	"os"
+	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -34,12 +36,16 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"vitess.io/vitess/go/hack"
+	"vitess.io/vitess/go/mysql/sqlerror"
 
 	"vitess.io/vitess/go/sqltypes"
 	"vitess.io/vitess/go/streamlog"
 	"vitess.io/vitess/go/vt/callinfo"
 	"vitess.io/vitess/go/vt/callinfo/fakecallinfo"
 	querypb "vitess.io/vitess/go/vt/proto/query"
+	vtrpcpb "vitess.io/vitess/go/vt/proto/vtrpc"
+	"vitess.io/vitess/go/vt/sqlparser"
+	"vitess.io/vitess/go/vt/vterrors"
 )
 
 func TestMain(m *testing.M) {
@@ -75,42 +81,42 @@ func TestLogStatsFormat(t *testing.T) {
 		{ // 0
 			redact:   false,
 			format:   "text",
-			expected: "test\t\t\t''\t''\t2017-01-01 01:02:03.000000\t2017-01-01 01:02:04.000001\t1.000001\t0.000000\t0.000000\t0.000000\t\t\"sql1\"\t{\"intVal\": {\"type\": \"INT64\", \"value\": 1}}\t0\t0\t\"\"\t\"PRIMARY\"\t\"suuid\"\tfalse\t[\"ks1.tbl1\",\"ks2.tbl2\"]\t\"db\"\t0.000000\t0.000000\t\"\"\n",
+			expected: "test\t\t\t''\t''\t2017-01-01 01:02:03.000000\t2017-01-01 01:02:04.000001\t1.000001\t0.000000\t0.000000\t0.000000\t\t\"sql1\"\t{\"intVal\": {\"type\": \"INT64\", \"value\": 1}}\t0\t0\t\"\"\t\"PRIMARY\"\t\"suuid\"\tfalse\t[\"ks1.tbl1\",\"ks2.tbl2\"]\t\"db\"\t0.000000\t0.000000\t\"\"\t0.000000\t\"\"\t\"\"\t0\t0\tfalse\t\"\"\t\"\"\t\"\"\t0\t\"\"\t0\t0.000000\t\"\"\tfalse\t{}\t0\t0.000000\t0.000000\tfalse\t0.000000\t0\t\"\"\tfalse\t\"\"\t\"\"\tfalse\t0\tfalse\t[]\t0\t\"\"\t\"\"\t0\tfalse\t\"\"\tfalse\t0\t{}\n",
 			bindVars: intBindVar,
 		}, { // 1
 			redact:   true,
 			format:   "text",
-			expected: "test\t\t\t''\t''\t2017-01-01 01:02:03.000000\t2017-01-01 01:02:04.000001\t1.000001\t0.000000\t0.000000\t0.000000\t\t\"sql1\"\t\"[REDACTED]\"\t0\t0\t\"\"\t\"PRIMARY\"\t\"suuid\"\tfalse\t[\"ks1.tbl1\",\"ks2.tbl2\"]\t\"db\"\t0.000000\t0.000000\t\"\"\n",
+			expected: "test\t\t\t''\t''\t2017-01-01 01:02:03.000000\t2017-01-01 01:02:04.000001\t1.000001\t0.000000\t0.000000\t0.000000\t\t\"sql1\"\t\"[REDACTED]\"\t0\t0\t\"\"\t\"PRIMARY\"\t\"suuid\"\tfalse\t[\"ks1.tbl1\",\"ks2.tbl2\"]\t\"db\"\t0.000000\t0.000000\t\"\"\t0.000000\t\"\"\t\"\"\t0\t0\tfalse\t\"\"\t\"\"\t\"\"\t0\t\"\"\t0\t0.000000\t\"\"\tfalse\t{}\t0\t0.000000\t0.000000\tfalse\t0.000000\t0\t\"\"\tfalse\t\"\"\t\"\"\tfalse\t0\tfalse\t[]\t0\t\"\"\t\"\"\t0\tfalse\t\"\"\tfalse\t0\t{}\n",
 			bindVars: intBindVar,
 		}, { // 2
 			redact:   false,
 			format:   "json",
-			expected: "{\"ActiveKeyspace\":\"db\",\"BindVars\":{\"intVal\":{\"type\":\"INT64\",\"value\":1}},\"Cached Plan\":false,\"CommitTime\":0,\"Effective Caller\":\"\",\"End\":\"2017-01-01 01:02:04.000001\",\"Error\":\"\",\"ExecuteTime\":0,\"ImmediateCaller\":\"\",\"Method\":\"test\",\"MirrorSourceExecuteTime\":0,\"MirrorTargetError\":\"\",\"MirrorTargetExecuteTime\":0,\"PlanTime\":0,\"RemoteAddr\":\"\",\"RowsAffected\":0,\"SQL\":\"sql1\",\"SessionUUID\":\"suuid\",\"ShardQueries\":0,\"Start\":\"2017-01-01 01:02:03.000000\",\"StmtType\":\"\",\"TablesUsed\":[\"ks1.tbl1\",\"ks2.tbl2\"],\"TabletType\":\"PRIMARY\",\"TotalTime\":1.000001,\"Username\":\"\"}",
+			expected: "{\"ActiveKeyspace\":\"db\",\"Annotations\":{},\"ApplicationName\":\"\",\"BackendConnectionIDs\":{},\"BindVars\":{\"intVal\":{\"type\":\"INT64\",\"value\":1}},\"BufferTime\":0,\"Buffered\":false,\"Cached Plan\":false,\"Collation\":\"\",\"CommitTime\":0,\"Compression\":\"\",\"ConnectionSetupTime\":0,\"DeniedTables\":false,\"Effective Caller\":\"\",\"End\":\"2017-01-01 01:02:04.000001\",\"Error\":\"\",\"ErrorNumber\":0,\"ExecuteTime\":0,\"FastPath\":false,\"ImmediateCaller\":\"\",\"KeyspacesTouched\":0,\"LookupRoundTrips\":0,\"LookupTime\":0,\"MaterializedWrite\":false,\"Method\":\"test\",\"MigrationUUIDs\":[],\"MirrorSourceExecuteTime\":0,\"MirrorTargetError\":\"\",\"MirrorTargetExecuteTime\":0,\"OnlineDDL\":false,\"OriginalSQL\":\"\",\"ParseTime\":0,\"PlanFingerprint\":\"\",\"PlanRecompiled\":false,\"PlanTime\":0,\"PlannerVersion\":\"\",\"PostProcessingOps\":0,\"Prepared\":false,\"Protocol\":\"\",\"QueryCategory\":\"\",\"ReferenceTable\":false,\"RemoteAddr\":\"\",\"RepeatCount\":0,\"ResultColumnTypes\":\"\",\"ResultColumns\":0,\"RewrittenSQL\":\"\",\"RouteHint\":\"\",\"RoutingReason\":\"\",\"RowsAffected\":0,\"RowsBuffered\":0,\"RowsExamined\":0,\"RowsTruncated\":0,\"SQL\":\"sql1\",\"SessionSettings\":\"\",\"SessionUUID\":\"suuid\",\"ShardQueries\":0,\"Start\":\"2017-01-01 01:02:03.000000\",\"StmtType\":\"\",\"TablesUsed\":[\"ks1.tbl1\",\"ks2.tbl2\"],\"TabletType\":\"PRIMARY\",\"TabletsContacted\":0,\"TimeToDeadline\":0,\"TotalTime\":1.000001,\"Username\":\"\"}",
 			bindVars: intBindVar,
 		}, { // 3
 			redact:   true,
 			format:   "json",
-			expected: "{\"ActiveKeyspace\":\"db\",\"BindVars\":\"[REDACTED]\",\"Cached Plan\":false,\"CommitTime\":0,\"Effective Caller\":\"\",\"End\":\"2017-01-01 01:02:04.000001\",\"Error\":\"\",\"ExecuteTime\":0,\"ImmediateCaller\":\"\",\"Method\":\"test\",\"MirrorSourceExecuteTime\":0,\"MirrorTargetError\":\"\",\"MirrorTargetExecuteTime\":0,\"PlanTime\":0,\"RemoteAddr\":\"\",\"RowsAffected\":0,\"SQL\":\"sql1\",\"SessionUUID\":\"suuid\",\"ShardQueries\":0,\"Start\":\"2017-01-01 01:02:03.000000\",\"StmtType\":\"\",\"TablesUsed\":[\"ks1.tbl1\",\"ks2.tbl2\"],\"TabletType\":\"PRIMARY\",\"TotalTime\":1.000001,\"Username\":\"\"}",
+			expected: "{\"ActiveKeyspace\":\"db\",\"Annotations\":{},\"ApplicationName\":\"\",\"BackendConnectionIDs\":{},\"BindVars\":\"[REDACTED]\",\"BufferTime\":0,\"Buffered\":false,\"Cached Plan\":false,\"Collation\":\"\",\"CommitTime\":0,\"Compression\":\"\",\"ConnectionSetupTime\":0,\"DeniedTables\":false,\"Effective Caller\":\"\",\"End\":\"2017-01-01 01:02:04.000001\",\"Error\":\"\",\"ErrorNumber\":0,\"ExecuteTime\":0,\"FastPath\":false,\"ImmediateCaller\":\"\",\"KeyspacesTouched\":0,\"LookupRoundTrips\":0,\"LookupTime\":0,\"MaterializedWrite\":false,\"Method\":\"test\",\"MigrationUUIDs\":[],\"MirrorSourceExecuteTime\":0,\"MirrorTargetError\":\"\",\"MirrorTargetExecuteTime\":0,\"OnlineDDL\":false,\"OriginalSQL\":\"\",\"ParseTime\":0,\"PlanFingerprint\":\"\",\"PlanRecompiled\":false,\"PlanTime\":0,\"PlannerVersion\":\"\",\"PostProcessingOps\":0,\"Prepared\":false,\"Protocol\":\"\",\"QueryCategory\":\"\",\"ReferenceTable\":false,\"RemoteAddr\":\"\",\"RepeatCount\":0,\"ResultColumnTypes\":\"\",\"ResultColumns\":0,\"RewrittenSQL\":\"\",\"RouteHint\":\"\",\"RoutingReason\":\"\",\"RowsAffected\":0,\"RowsBuffered\":0,\"RowsExamined\":0,\"RowsTruncated\":0,\"SQL\":\"sql1\",\"SessionSettings\":\"\",\"SessionUUID\":\"suuid\",\"ShardQueries\":0,\"Start\":\"2017-01-01 01:02:03.000000\",\"StmtType\":\"\",\"TablesUsed\":[\"ks1.tbl1\",\"ks2.tbl2\"],\"TabletType\":\"PRIMARY\",\"TabletsContacted\":0,\"TimeToDeadline\":0,\"TotalTime\":1.000001,\"Username\":\"\"}",
 			bindVars: intBindVar,
 		}, { // 4
 			redact:   false,
 			format:   "text",
-			expected: "test\t\t\t''\t''\t2017-01-01 01:02:03.000000\t2017-01-01 01:02:04.000001\t1.000001\t0.000000\t0.000000\t0.000000\t\t\"sql1\"\t{\"strVal\": {\"type\": \"VARCHAR\", \"value\": \"abc\"}}\t0\t0\t\"\"\t\"PRIMARY\"\t\"suuid\"\tfalse\t[\"ks1.tbl1\",\"ks2.tbl2\"]\t\"db\"\t0.000000\t0.000000\t\"\"\n",
+			expected: "test\t\t\t''\t''\t2017-01-01 01:02:03.000000\t2017-01-01 01:02:04.000001\t1.000001\t0.000000\t0.000000\t0.000000\t\t\"sql1\"\t{\"strVal\": {\"type\": \"VARCHAR\", \"value\": \"abc\"}}\t0\t0\t\"\"\t\"PRIMARY\"\t\"suuid\"\tfalse\t[\"ks1.tbl1\",\"ks2.tbl2\"]\t\"db\"\t0.000000\t0.000000\t\"\"\t0.000000\t\"\"\t\"\"\t0\t0\tfalse\t\"\"\t\"\"\t\"\"\t0\t\"\"\t0\t0.000000\t\"\"\tfalse\t{}\t0\t0.000000\t0.000000\tfalse\t0.000000\t0\t\"\"\tfalse\t\"\"\t\"\"\tfalse\t0\tfalse\t[]\t0\t\"\"\t\"\"\t0\tfalse\t\"\"\tfalse\t0\t{}\n",
 			bindVars: stringBindVar,
 		}, { // 5
 			redact:   true,
 			format:   "text",
-			expected: "test\t\t\t''\t''\t2017-01-01 01:02:03.000000\t2017-01-01 01:02:04.000001\t1.000001\t0.000000\t0.000000\t0.000000\t\t\"sql1\"\t\"[REDACTED]\"\t0\t0\t\"\"\t\"PRIMARY\"\t\"suuid\"\tfalse\t[\"ks1.tbl1\",\"ks2.tbl2\"]\t\"db\"\t0.000000\t0.000000\t\"\"\n",
+			expected: "test\t\t\t''\t''\t2017-01-01 01:02:03.000000\t2017-01-01 01:02:04.000001\t1.000001\t0.000000\t0.000000\t0.000000\t\t\"sql1\"\t\"[REDACTED]\"\t0\t0\t\"\"\t\"PRIMARY\"\t\"suuid\"\tfalse\t[\"ks1.tbl1\",\"ks2.tbl2\"]\t\"db\"\t0.000000\t0.000000\t\"\"\t0.000000\t\"\"\t\"\"\t0\t0\tfalse\t\"\"\t\"\"\t\"\"\t0\t\"\"\t0\t0.000000\t\"\"\tfalse\t{}\t0\t0.000000\t0.000000\tfalse\t0.000000\t0\t\"\"\tfalse\t\"\"\t\"\"\tfalse\t0\tfalse\t[]\t0\t\"\"\t\"\"\t0\tfalse\t\"\"\tfalse\t0\t{}\n",
 			bindVars: stringBindVar,
 		}, { // 6
 			redact:   false,
 			format:   "json",
-			expected: "{\"ActiveKeyspace\":\"db\",\"BindVars\":{\"strVal\":{\"type\":\"VARCHAR\",\"value\":\"abc\"}},\"Cached Plan\":false,\"CommitTime\":0,\"Effective Caller\":\"\",\"End\":\"2017-01-01 01:02:04.000001\",\"Error\":\"\",\"ExecuteTime\":0,\"ImmediateCaller\":\"\",\"Method\":\"test\",\"MirrorSourceExecuteTime\":0,\"MirrorTargetError\":\"\",\"MirrorTargetExecuteTime\":0,\"PlanTime\":0,\"RemoteAddr\":\"\",\"RowsAffected\":0,\"SQL\":\"sql1\",\"SessionUUID\":\"suuid\",\"ShardQueries\":0,\"Start\":\"2017-01-01 01:02:03.000000\",\"StmtType\":\"\",\"TablesUsed\":[\"ks1.tbl1\",\"ks2.tbl2\"],\"TabletType\":\"PRIMARY\",\"TotalTime\":1.000001,\"Username\":\"\"}",
+			expected: "{\"ActiveKeyspace\":\"db\",\"Annotations\":{},\"ApplicationName\":\"\",\"BackendConnectionIDs\":{},\"BindVars\":{\"strVal\":{\"type\":\"VARCHAR\",\"value\":\"abc\"}},\"BufferTime\":0,\"Buffered\":false,\"Cached Plan\":false,\"Collation\":\"\",\"CommitTime\":0,\"Compression\":\"\",\"ConnectionSetupTime\":0,\"DeniedTables\":false,\"Effective Caller\":\"\",\"End\":\"2017-01-01 01:02:04.000001\",\"Error\":\"\",\"ErrorNumber\":0,\"ExecuteTime\":0,\"FastPath\":false,\"ImmediateCaller\":\"\",\"KeyspacesTouched\":0,\"LookupRoundTrips\":0,\"LookupTime\":0,\"MaterializedWrite\":false,\"Method\":\"test\",\"MigrationUUIDs\":[],\"MirrorSourceExecuteTime\":0,\"MirrorTargetError\":\"\",\"MirrorTargetExecuteTime\":0,\"OnlineDDL\":false,\"OriginalSQL\":\"\",\"ParseTime\":0,\"PlanFingerprint\":\"\",\"PlanRecompiled\":false,\"PlanTime\":0,\"PlannerVersion\":\"\",\"PostProcessingOps\":0,\"Prepared\":false,\"Protocol\":\"\",\"QueryCategory\":\"\",\"ReferenceTable\":false,\"RemoteAddr\":\"\",\"RepeatCount\":0,\"ResultColumnTypes\":\"\",\"ResultColumns\":0,\"RewrittenSQL\":\"\",\"RouteHint\":\"\",\"RoutingReason\":\"\",\"RowsAffected\":0,\"RowsBuffered\":0,\"RowsExamined\":0,\"RowsTruncated\":0,\"SQL\":\"sql1\",\"SessionSettings\":\"\",\"SessionUUID\":\"suuid\",\"ShardQueries\":0,\"Start\":\"2017-01-01 01:02:03.000000\",\"StmtType\":\"\",\"TablesUsed\":[\"ks1.tbl1\",\"ks2.tbl2\"],\"TabletType\":\"PRIMARY\",\"TabletsContacted\":0,\"TimeToDeadline\":0,\"TotalTime\":1.000001,\"Username\":\"\"}",
 			bindVars: stringBindVar,
 		}, { // 7
 			redact:   true,
 			format:   "json",
-			expected: "{\"ActiveKeyspace\":\"db\",\"BindVars\":\"[REDACTED]\",\"Cached Plan\":false,\"CommitTime\":0,\"Effective Caller\":\"\",\"End\":\"2017-01-01 01:02:04.000001\",\"Error\":\"\",\"ExecuteTime\":0,\"ImmediateCaller\":\"\",\"Method\":\"test\",\"MirrorSourceExecuteTime\":0,\"MirrorTargetError\":\"\",\"MirrorTargetExecuteTime\":0,\"PlanTime\":0,\"RemoteAddr\":\"\",\"RowsAffected\":0,\"SQL\":\"sql1\",\"SessionUUID\":\"suuid\",\"ShardQueries\":0,\"Start\":\"2017-01-01 01:02:03.000000\",\"StmtType\":\"\",\"TablesUsed\":[\"ks1.tbl1\",\"ks2.tbl2\"],\"TabletType\":\"PRIMARY\",\"TotalTime\":1.000001,\"Username\":\"\"}",
+			expected: "{\"ActiveKeyspace\":\"db\",\"Annotations\":{},\"ApplicationName\":\"\",\"BackendConnectionIDs\":{},\"BindVars\":\"[REDACTED]\",\"BufferTime\":0,\"Buffered\":false,\"Cached Plan\":false,\"Collation\":\"\",\"CommitTime\":0,\"Compression\":\"\",\"ConnectionSetupTime\":0,\"DeniedTables\":false,\"Effective Caller\":\"\",\"End\":\"2017-01-01 01:02:04.000001\",\"Error\":\"\",\"ErrorNumber\":0,\"ExecuteTime\":0,\"FastPath\":false,\"ImmediateCaller\":\"\",\"KeyspacesTouched\":0,\"LookupRoundTrips\":0,\"LookupTime\":0,\"MaterializedWrite\":false,\"Method\":\"test\",\"MigrationUUIDs\":[],\"MirrorSourceExecuteTime\":0,\"MirrorTargetError\":\"\",\"MirrorTargetExecuteTime\":0,\"OnlineDDL\":false,\"OriginalSQL\":\"\",\"ParseTime\":0,\"PlanFingerprint\":\"\",\"PlanRecompiled\":false,\"PlanTime\":0,\"PlannerVersion\":\"\",\"PostProcessingOps\":0,\"Prepared\":false,\"Protocol\":\"\",\"QueryCategory\":\"\",\"ReferenceTable\":false,\"RemoteAddr\":\"\",\"RepeatCount\":0,\"ResultColumnTypes\":\"\",\"ResultColumns\":0,\"RewrittenSQL\":\"\",\"RouteHint\":\"\",\"RoutingReason\":\"\",\"RowsAffected\":0,\"RowsBuffered\":0,\"RowsExamined\":0,\"RowsTruncated\":0,\"SQL\":\"sql1\",\"SessionSettings\":\"\",\"SessionUUID\":\"suuid\",\"ShardQueries\":0,\"Start\":\"2017-01-01 01:02:03.000000\",\"StmtType\":\"\",\"TablesUsed\":[\"ks1.tbl1\",\"ks2.tbl2\"],\"TabletType\":\"PRIMARY\",\"TabletsContacted\":0,\"TimeToDeadline\":0,\"TotalTime\":1.000001,\"Username\":\"\"}",
 			bindVars: stringBindVar,
 		},
 	}
@@ -151,12 +157,12 @@ func TestLogStatsFilter(t *testing.T) {
 	params := map[string][]string{"full": {}}
 
 	got := testFormat(t, logStats, params)
-	want := "test\t\t\t''\t''\t2017-01-01 01:02:03.000000\t2017-01-01 01:02:04.000001\t1.000001\t0.000000\t0.000000\t0.000000\t\t\"sql1 /* LOG_THIS_QUERY */\"\t{\"intVal\": {\"type\": \"INT64\", \"value\": 1}}\t0\t0\t\"\"\t\"\"\t\"\"\tfalse\t[]\t\"\"\t0.000000\t0.000000\t\"\"\n"
+	want := "test\t\t\t''\t''\t2017-01-01 01:02:03.000000\t2017-01-01 01:02:04.000001\t1.000001\t0.000000\t0.000000\t0.000000\t\t\"sql1 /* LOG_THIS_QUERY */\"\t{\"intVal\": {\"type\": \"INT64\", \"value\": 1}}\t0\t0\t\"\"\t\"\"\t\"\"\tfalse\t[]\t\"\"\t0.000000\t0.000000\t\"\"\t0.000000\t\"\"\t\"\"\t0\t0\tfalse\t\"\"\t\"\"\t\"\"\t0\t\"\"\t0\t0.000000\t\"\"\tfalse\t{}\t0\t0.000000\t0.000000\tfalse\t0.000000\t0\t\"\"\tfalse\t\"\"\t\"\"\tfalse\t0\tfalse\t[]\t0\t\"\"\t\"\"\t0\tfalse\t\"\"\tfalse\t0\t{}\n"
 	assert.Equal(t, want, got)
 
 	logStats.Config.FilterTag = "LOG_THIS_QUERY"
 	got = testFormat(t, logStats, params)
-	want = "test\t\t\t''\t''\t2017-01-01 01:02:03.000000\t2017-01-01 01:02:04.000001\t1.000001\t0.000000\t0.000000\t0.000000\t\t\"sql1 /* LOG_THIS_QUERY */\"\t{\"intVal\": {\"type\": \"INT64\", \"value\": 1}}\t0\t0\t\"\"\t\"\"\t\"\"\tfalse\t[]\t\"\"\t0.000000\t0.000000\t\"\"\n"
+	want = "test\t\t\t''\t''\t2017-01-01 01:02:03.000000\t2017-01-01 01:02:04.000001\t1.000001\t0.000000\t0.000000\t0.000000\t\t\"sql1 /* LOG_THIS_QUERY */\"\t{\"intVal\": {\"type\": \"INT64\", \"value\": 1}}\t0\t0\t\"\"\t\"\"\t\"\"\tfalse\t[]\t\"\"\t0.000000\t0.000000\t\"\"\t0.000000\t\"\"\t\"\"\t0\t0\tfalse\t\"\"\t\"\"\t\"\"\t0\t\"\"\t0\t0.000000\t\"\"\tfalse\t{}\t0\t0.000000\t0.000000\tfalse\t0.000000\t0\t\"\"\tfalse\t\"\"\t\"\"\tfalse\t0\tfalse\t[]\t0\t\"\"\t\"\"\t0\tfalse\t\"\"\tfalse\t0\t{}\n"
 	assert.Equal(t, want, got)
 
 	logStats.Config.FilterTag = "NOT_THIS_QUERY"
@@ -173,11 +179,11 @@ func TestLogStatsRowThreshold(t *testing.T) {
 	params := map[string][]string{"full": {}}
 
 	got := testFormat(t, logStats, params)
-	want := "test\t\t\t''\t''\t2017-01-01 01:02:03.000000\t2017-01-01 01:02:04.000001\t1.000001\t0.000000\t0.000000\t0.000000\t\t\"sql1 /* LOG_THIS_QUERY */\"\t{\"intVal\": {\"type\": \"INT64\", \"value\": 1}}\t0\t0\t\"\"\t\"\"\t\"\"\tfalse\t[]\t\"\"\t0.000000\t0.000000\t\"\"\n"
+	want := "test\t\t\t''\t''\t2017-01-01 01:02:03.000000\t2017-01-01 01:02:04.000001\t1.000001\t0.000000\t0.000000\t0.000000\t\t\"sql1 /* LOG_THIS_QUERY */\"\t{\"intVal\": {\"type\": \"INT64\", \"value\": 1}}\t0\t0\t\"\"\t\"\"\t\"\"\tfalse\t[]\t\"\"\t0.000000\t0.000000\t\"\"\t0.000000\t\"\"\t\"\"\t0\t0\tfalse\t\"\"\t\"\"\t\"\"\t0\t\"\"\t0\t0.000000\t\"\"\tfalse\t{}\t0\t0.000000\t0.000000\tfalse\t0.000000\t0\t\"\"\tfalse\t\"\"\t\"\"\tfalse\t0\tfalse\t[]\t0\t\"\"\t\"\"\t0\tfalse\t\"\"\tfalse\t0\t{}\n"
 	assert.Equal(t, want, got)
 
 	got = testFormat(t, logStats, params)
-	want = "test\t\t\t''\t''\t2017-01-01 01:02:03.000000\t2017-01-01 01:02:04.000001\t1.000001\t0.000000\t0.000000\t0.000000\t\t\"sql1 /* LOG_THIS_QUERY */\"\t{\"intVal\": {\"type\": \"INT64\", \"value\": 1}}\t0\t0\t\"\"\t\"\"\t\"\"\tfalse\t[]\t\"\"\t0.000000\t0.000000\t\"\"\n"
+	want = "test\t\t\t''\t''\t2017-01-01 01:02:03.000000\t2017-01-01 01:02:04.000001\t1.000001\t0.000000\t0.000000\t0.000000\t\t\"sql1 /* LOG_THIS_QUERY */\"\t{\"intVal\": {\"type\": \"INT64\", \"value\": 1}}\t0\t0\t\"\"\t\"\"\t\"\"\tfalse\t[]\t\"\"\t0.000000\t0.000000\t\"\"\t0.000000\t\"\"\t\"\"\t0\t0\tfalse\t\"\"\t\"\"\t\"\"\t0\t\"\"\t0\t0.000000\t\"\"\tfalse\t{}\t0\t0.000000\t0.000000\tfalse\t0.000000\t0\t\"\"\tfalse\t\"\"\t\"\"\tfalse\t0\tfalse\t[]\t0\t\"\"\t\"\"\t0\tfalse\t\"\"\tfalse\t0\t{}\n"
 	assert.Equal(t, want, got)
 
 	logStats.Config.RowThreshold = 1
@@ -209,6 +215,19 @@ func TestLogStatsErrorStr(t *testing.T) {
 	}
 }
 
+func TestLogStatsErrorNumber(t *testing.T) {
+	logStats := NewLogStats(context.Background(), "test", "sql1", "", map[string]*querypb.BindVariable{}, streamlog.NewQueryLogConfigForTest())
+	assert.EqualValues(t, 0, logStats.ErrorNumber())
+
+	// A MySQL error keeps its number through the vitess error wrapping.
+	logStats.Error = vterrors.Wrap(sqlerror.NewSQLError(sqlerror.ERDupEntry, sqlerror.SSConstraintViolation, "Duplicate entry '1' for key 'PRIMARY'"), "target: ks.-80.primary")
+	assert.Equal(t, sqlerror.ERDupEntry, logStats.ErrorNumber())
+
+	// Other errors get the number their vitess error code maps to.
+	logStats.Error = vterrors.Errorf(vtrpcpb.Code_DEADLINE_EXCEEDED, "timeout")
+	assert.Equal(t, sqlerror.ERQueryInterrupted, logStats.ErrorNumber())
+}
+
 func TestLogStatsMirrorTargetErrorStr(t *testing.T) {
 	logStats := NewLogStats(context.Background(), "test", "sql1", "", map[string]*querypb.BindVariable{}, streamlog.NewQueryLogConfigForTest())
 	if logStats.MirrorTargetErrorStr() != "" {
@@ -248,6 +267,33 @@ func TestLogStatsRemoteAddrUsername(t *testing.T) {
 	}
 }
 
+func TestLogStatsProtocol(t *testing.T) {
+	logStats := NewLogStats(context.Background(), "test", "sql1", "", nil, streamlog.NewQueryLogConfigForTest())
+	assert.Empty(t, logStats.Protocol())
+
+	ctx := callinfo.NewContext(context.Background(), &fakecallinfo.FakeCallInfo{Proto: callinfo.ProtocolGRPC})
+	logStats = NewLogStats(ctx, "test", "sql1", "", nil, streamlog.NewQueryLogConfigForTest())
+	assert.Equal(t, callinfo.ProtocolGRPC, logStats.Protocol())
+}
+
+func TestLogStatsApplicationName(t *testing.T) {
+	logStats := NewLogStats(context.Background(), "test", "sql1", "", nil, streamlog.NewQueryLogConfigForTest())
+	assert.Empty(t, logStats.ApplicationName())
+
+	ctx := callinfo.NewContext(context.Background(), &fakecallinfo.FakeCallInfo{App: "billing"})
+	logStats = NewLogStats(ctx, "test", "sql1", "", nil, streamlog.NewQueryLogConfigForTest())
+	assert.Equal(t, "billing", logStats.ApplicationName())
+}
+
+func TestLogStatsCompression(t *testing.T) {
+	logStats := NewLogStats(context.Background(), "test", "sql1", "", nil, streamlog.NewQueryLogConfigForTest())
+	assert.Empty(t, logStats.Compression())
+
+	ctx := callinfo.NewContext(context.Background(), &fakecallinfo.FakeCallInfo{Comp: "snappy"})
+	logStats = NewLogStats(ctx, "test", "sql1", "", nil, streamlog.NewQueryLogConfigForTest())
+	assert.Equal(t, "snappy", logStats.Compression())
+}
+
 // TestLogStatsErrorsOnly tests that LogStats only logs errors when the query log mode is set to errors only for VTGate.
 func TestLogStatsErrorsOnly(t *testing.T) {
 	logStats := NewLogStats(context.Background(), "test", "sql1", "", map[string]*querypb.BindVariable{}, streamlog.NewQueryLogConfigForTest())
@@ -262,3 +308,205 @@ func TestLogStatsErrorsOnly(t *testing.T) {
 	logOutput = testFormat(t, logStats, url.Values{})
 	assert.Contains(t, logOutput, "test error")
 }
+
+func TestLogStatsDedupKeepsErrors(t *testing.T) {
+	logger := streamlog.New[*LogStats]("test", 10)
+	logger.SetDedupWindow(time.Hour)
+	ch := logger.Subscribe("test")
+	defer logger.Unsubscribe(ch)
+
+	config := streamlog.NewQueryLogConfigForTest()
+	config.Mode = streamlog.QueryLogModeError
+	newStats := func(err error) *LogStats {
+		logStats := NewLogStats(context.Background(), "test", "sql1", "", map[string]*querypb.BindVariable{}, config)
+		logStats.Error = err
+		return logStats
+	}
+
+	// A failed run of the query isn't folded into the successful one before it.
+	logger.Send(newStats(nil))
+	logger.Send(newStats(errors.New("test error")))
+	logger.Send(newStats(errors.New("test error")))
+	logger.SetDedupWindow(0)
+	require.Len(t, ch, 2)
+	assert.Empty(t, testFormat(t, <-ch, url.Values{}))
+	failed := <-ch
+	assert.EqualValues(t, 2, failed.RepeatCount)
+	assert.Contains(t, testFormat(t, failed, url.Values{}), "test error")
+}
+
+func TestLogStatsConnectionSetupTime(t *testing.T) {
+	logStats := NewLogStats(context.Background(), "test", "sql1", "", nil, streamlog.NewQueryLogConfigForTest())
+	logStats.AddConnectionSetupTime(2 * time.Millisecond)
+	logStats.AddConnectionSetupTime(3 * time.Millisecond)
+	assert.Equal(t, 5*time.Millisecond, logStats.ConnectionSetupTime)
+}
+
+func TestLogStatsRedactBindVars(t *testing.T) {
+	logStats := NewLogStats(context.Background(), "test", "sql1", "suuid", map[string]*querypb.BindVariable{
+		"id":            sqltypes.Int64BindVariable(1),
+		"user_password": sqltypes.StringBindVariable("hunter2"),
+	}, streamlog.NewQueryLogConfigForTest())
+	logStats.Config.Format = streamlog.QueryLogFormatJSON
+	logStats.Config.RedactBindVars = regexp.MustCompile("password|token")
+	params := url.Values{"full": {}}
+
+	var parsed map[string]any
+	require.NoError(t, json.Unmarshal([]byte(testFormat(t, logStats, params)), &parsed))
+	assert.Equal(t, map[string]any{
+		"id":            map[string]any{"type": "INT64", "value": float64(1)},
+		"user_password": map[string]any{"type": "VARCHAR", "value": "[REDACTED]"},
+	}, parsed["BindVars"])
+
+	// The original SQL would show the redacted values in its literals, while
+	// the rewritten SQL only names the bind variables.
+	logStats.OriginalSQL = "select * from users where password = 'hunter2'"
+	logStats.RewrittenSQL = "select * from users where password = :user_password"
+	require.NoError(t, json.Unmarshal([]byte(testFormat(t, logStats, params)), &parsed))
+	assert.Empty(t, parsed["OriginalSQL"])
+	assert.Equal(t, logStats.RewrittenSQL, parsed["RewrittenSQL"])
+	assert.NotContains(t, testFormat(t, logStats, params), "hunter2")
+
+	// Global redaction takes precedence over the patterns, and leaves out
+	// the rewritten SQL too.
+	logStats.Config.RedactDebugUIQueries = true
+	require.NoError(t, json.Unmarshal([]byte(testFormat(t, logStats, params)), &parsed))
+	assert.Equal(t, "[REDACTED]", parsed["BindVars"])
+	assert.Empty(t, parsed["OriginalSQL"])
+	assert.Empty(t, parsed["RewrittenSQL"])
+}
+
+func TestLogStatsShardsJSON(t *testing.T) {
+	logStats := NewLogStats(context.Background(), "test", "sql1", "suuid", nil, streamlog.NewQueryLogConfigForTest())
+	logStats.Config.Format = streamlog.QueryLogFormatJSON
+	start := logStats.StartTime
+	parse := func() map[string]any {
+		var parsed map[string]any
+		require.NoError(t, json.Unmarshal([]byte(testFormat(t, logStats, url.Values{})), &parsed))
+		return parsed
+	}
+
+	// Single-shard queries leave the shards out.
+	logStats.AddShardTiming("ks/-80", start, start.Add(time.Millisecond))
+	logStats.AddShardTiming("ks/-80", start.Add(2*time.Millisecond), start.Add(3*time.Millisecond))
+	assert.NotContains(t, parse(), "Shards")
+
+	// The queries of each shard are summed, in the order the shards ended.
+	logStats.AddShardTiming("ks/80-", start, start.Add(5*time.Millisecond))
+	assert.Equal(t, []any{
+		map[string]any{"Shard": "ks/-80", "Duration": 0.002},
+		map[string]any{"Shard": "ks/80-", "Duration": 0.005},
+	}, parse()["Shards"])
+
+	// The text format keeps its fixed columns.
+	logStats.Config.Format = streamlog.QueryLogFormatText
+	assert.NotContains(t, testFormat(t, logStats, url.Values{}), "ks/80-")
+}
+
+func TestLogStatsAddTablet(t *testing.T) {
+	logStats := NewLogStats(context.Background(), "test", "sql1", "", nil, streamlog.NewQueryLogConfigForTest())
+	var wg sync.WaitGroup
+	for _, alias := range []string{"zone1-100", "zone1-101", "zone1-100", "zone2-200", "zone1-101"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			logStats.AddTablet(alias)
+		}()
+	}
+	wg.Wait()
+	assert.EqualValues(t, 3, logStats.TabletsContacted)
+}
+
+func TestLogStatsAddRowsTruncated(t *testing.T) {
+	logStats := NewLogStats(context.Background(), "test", "sql1", "", nil, streamlog.NewQueryLogConfigForTest())
+	assert.False(t, logStats.Truncated())
+	logStats.AddRowsTruncated(3)
+	logStats.AddRowsTruncated(2)
+	assert.True(t, logStats.Truncated())
+	assert.EqualValues(t, 5, logStats.RowsTruncated)
+}
+
+func TestLogStatsExaminedRatio(t *testing.T) {
+	logStats := NewLogStats(context.Background(), "test", "sql1", "", nil, streamlog.NewQueryLogConfigForTest())
+	assert.Zero(t, logStats.ExaminedRatio())
+	logStats.AddRowsExamined(30)
+	logStats.AddRowsExamined(10)
+	assert.EqualValues(t, 40, logStats.RowsExamined)
+	// A query that returned no rows counts as returning one.
+	assert.EqualValues(t, 40, logStats.ExaminedRatio())
+	logStats.RowsReturned = 8
+	assert.EqualValues(t, 5, logStats.ExaminedRatio())
+}
+
+func TestLogStatsDeadline(t *testing.T) {
+	logStats := NewLogStats(context.Background(), "test", "sql1", "", nil, streamlog.NewQueryLogConfigForTest())
+	assert.Zero(t, logStats.TimeToDeadline())
+	assert.Zero(t, logStats.DeadlineUsed())
+
+	ctx, cancel := context.WithDeadline(context.Background(), logStats.StartTime.Add(time.Second))
+	defer cancel()
+	logStats.RecordDeadline(ctx)
+	assert.Equal(t, time.Second, logStats.TimeToDeadline())
+	logStats.EndTime = logStats.StartTime.Add(250 * time.Millisecond)
+	assert.EqualValues(t, 25, logStats.DeadlineUsed())
+
+	// A later deadline, such as the query timeout of the session, doesn't
+	// replace an earlier one.
+	later, cancel := context.WithDeadline(context.Background(), logStats.StartTime.Add(time.Minute))
+	defer cancel()
+	logStats.RecordDeadline(later)
+	assert.Equal(t, time.Second, logStats.TimeToDeadline())
+
+	// The deadline of the context the query started with is recorded.
+	logStats = NewLogStats(ctx, "test", "sql1", "", nil, streamlog.NewQueryLogConfigForTest())
+	assert.False(t, logStats.Deadline.IsZero())
+}
+
+func TestLogStatsAnnotations(t *testing.T) {
+	ctx := WithAnnotation(context.Background(), "request_id", "r-1")
+	flagged := WithAnnotation(ctx, "feature", "new-planner")
+	logStats := NewLogStats(flagged, "test", "sql1", "suuid", nil, streamlog.NewQueryLogConfigForTest())
+	assert.Equal(t, map[string]string{"request_id": "r-1", "feature": "new-planner"}, logStats.Annotations)
+
+	// Annotating a context leaves the context it derives from unchanged.
+	assert.Equal(t, map[string]string{"request_id": "r-1"}, NewLogStats(ctx, "test", "sql1", "suuid", nil, streamlog.NewQueryLogConfigForTest()).Annotations)
+
+	logStats.Config.Format = streamlog.QueryLogFormatJSON
+	var parsed map[string]any
+	require.NoError(t, json.Unmarshal([]byte(testFormat(t, logStats, url.Values{})), &parsed))
+	assert.Equal(t, map[string]any{"request_id": "r-1", "feature": "new-planner"}, parsed["Annotations"])
+}
+
+func TestLogStatsTargetTables(t *testing.T) {
+	parser := sqlparser.NewTestParser()
+	tcases := []struct {
+		sql  string
+		want []string
+	}{{
+		sql:  "select a.id from `user` as a join ks.music m on a.id = m.user_id where a.id in (select id from t1)",
+		want: []string{"ks.music", "t1", "user"},
+	}, {
+		sql:  "insert into t1(id) select id from t2",
+		want: []string{"t1", "t2"},
+	}, {
+		sql:  "update t1 set a = 1 where id = 2",
+		want: []string{"t1"},
+	}, {
+		sql:  "rename table t1 to t2",
+		want: []string{"t1", "t2"},
+	}, {
+		sql: "select 1",
+	}, {
+		sql: "this is not sql",
+	}}
+	for _, tcase := range tcases {
+		t.Run(tcase.sql, func(t *testing.T) {
+			logStats := NewLogStats(context.Background(), "test", tcase.sql, "", nil, streamlog.NewQueryLogConfigForTest())
+			assert.Equal(t, tcase.want, logStats.TargetTables(parser))
+
+			// The parse result is cached.
+			logStats.SQL = "select 1 from other"
+			assert.Equal(t, tcase.want, logStats.TargetTables(parser))
+		})
+	}
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vtgate
+
+import (
+	"sync"
+
+	"vitess.io/vitess/go/cache/theine"
+	"vitess.io/vitess/go/vt/vtgate/engine"
+)
+
+// planEvictionsSize bounds the number of evicted plan keys remembered by
+// planEvictions.
+const planEvictionsSize = 10000
+
+// planEvictions remembers the keys of the plans most recently evicted from
+// the plan cache, so that a query whose plan has to be built again can be
+// flagged as a recompilation.
+type planEvictions struct {
+	mu sync.Mutex
+	// keys maps each remembered key to its position in order.
+	keys map[PlanCacheKey]int
+	// order holds the keys in eviction order, as a ring; next is the
+	// position of the oldest key once the ring is full.
+	order []PlanCacheKey
+	next  int
+}
+
+func newPlanEvictions(size int) *planEvictions {
+	return &planEvictions{
+		keys:  make(map[PlanCacheKey]int, size),
+		order: make([]PlanCacheKey, 0, size),
+	}
+}
+
+// onRemoval is installed as the removal hook of the plan cache. Only
+// evictions are remembered: plans removed explicitly or invalidated by
+// a new epoch are not a sign of cache pressure.
+func (pe *planEvictions) onRemoval(key PlanCacheKey, _ *engine.Plan, reason theine.RemoveReason) {
+	if reason != theine.EVICTED {
+		return
+	}
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+	if _, ok := pe.keys[key]; ok {
+		return
+	}
+	if len(pe.order) < cap(pe.order) {
+		pe.keys[key] = len(pe.order)
+		pe.order = append(pe.order, key)
+		return
+	}
+	// The oldest slot may hold a key that was forgotten, or forgotten and
+	// evicted again since, in which case it now lives in another slot.
+	if pos, ok := pe.keys[pe.order[pe.next]]; ok && pos == pe.next {
+		delete(pe.keys, pe.order[pe.next])
+	}
+	pe.keys[key] = pe.next
+	pe.order[pe.next] = key
+	pe.next = (pe.next + 1) % len(pe.order)
+}
+
+// forget returns true if key was recently evicted, and stops tracking it
+// since its plan is about to be cached again. It is safe to call on a nil
+// tracker, which remembers nothing.
+func (pe *planEvictions) forget(key PlanCacheKey) bool {
+	if pe == nil {
+		return false
+	}
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+	if _, ok := pe.keys[key]; !ok {
+		return false
+	}
+	delete(pe.keys, key)
+	return true
+}
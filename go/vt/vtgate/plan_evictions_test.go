@@ -0,0 +1,53 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vtgate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"vitess.io/vitess/go/cache/theine"
+)
+
+func TestPlanEvictions(t *testing.T) {
+	pe := newPlanEvictions(2)
+	key := func(b byte) PlanCacheKey { return PlanCacheKey{b} }
+
+	// Only evictions are remembered.
+	pe.onRemoval(key(1), nil, theine.REMOVED)
+	pe.onRemoval(key(2), nil, theine.EXPIRED)
+	assert.False(t, pe.forget(key(1)))
+	assert.False(t, pe.forget(key(2)))
+
+	pe.onRemoval(key(1), nil, theine.EVICTED)
+	assert.True(t, pe.forget(key(1)))
+	assert.False(t, pe.forget(key(1)), "a key is only reported once")
+
+	// The oldest evictions are dropped beyond the size of the tracker.
+	pe.onRemoval(key(2), nil, theine.EVICTED)
+	pe.onRemoval(key(3), nil, theine.EVICTED)
+	pe.onRemoval(key(4), nil, theine.EVICTED)
+	assert.False(t, pe.forget(key(2)))
+	assert.True(t, pe.forget(key(3)))
+
+	// A key evicted again after being forgotten is remembered again.
+	pe.onRemoval(key(3), nil, theine.EVICTED)
+	pe.onRemoval(key(5), nil, theine.EVICTED)
+	assert.True(t, pe.forget(key(3)))
+	assert.True(t, pe.forget(key(5)))
+}
@@ -142,6 +142,7 @@ func (e *Executor) newExecute(
 		// set the overall query timeout if it is not already set
 		ctx, cancel = vcursor.GetContextWithTimeOut(ctx)
 		defer cancel()
+		logStats.RecordDeadline(ctx)
 
 		// If we have previously issued a VT15001 error, we block any new queries on this session until we receive a ROLLBACK or "show warnings".
 		if shouldBlockQueries(plan, safeSession) {
@@ -173,21 +174,22 @@ func (e *Executor) newExecute(
 			err = execPlan(ctx, plan, vcursor, bindVars, execStart)
 		}
 
+		if err != nil && isDeniedTablesError(err) {
+			logStats.DeniedTables = true
+		}
 		if err == nil || safeSession.InTransaction() {
 			return err
 		}
 
 		// Retry if needed.
-		rootCause := vterrors.RootCause(err)
-		if rootCause != nil && strings.Contains(rootCause.Error(), "enforce denied tables") {
+		if isDeniedTablesError(err) {
 			log.V(2).Infof("Retry: %d, will retry query %s due to %v", try, sql, err)
 			if try == 0 { // We are going to retry at least once
 				defer func() {
 					// Prevent any plan cache pollution from queries planned against the wrong keyspace during a MoveTables
 					// traffic switching operation.
 					if err != nil { // The error we're checking here is the return value from the newExecute function
-						cause := vterrors.RootCause(err)
-						if cause != nil && strings.Contains(cause.Error(), "enforce denied tables") {
+						if isDeniedTablesError(err) {
 							// The executor's VSchemaManager clears the plan cache when it receives a new vschema via its
 							// SrvVSchema watcher (it calls executor.SaveVSchema() in its watch's subscriber callback). This
 							// happens concurrently with the KeyspaceEventWatcher also receiving the new vschema in its
@@ -218,6 +220,14 @@ func (e *Executor) newExecute(
 	return vterrors.New(vtrpcpb.Code_INTERNAL, fmt.Sprintf("query %s failed after retries: %v ", sql, err))
 }
 
+// isDeniedTablesError returns whether err is caused by a tablet rejecting a
+// query on tables denied to it, as happens to the queries routed to the wrong
+// side of a MoveTables traffic switch.
+func isDeniedTablesError(err error) bool {
+	rootCause := vterrors.RootCause(err)
+	return rootCause != nil && strings.Contains(rootCause.Error(), "enforce denied tables")
+}
+
 // handleTransactions deals with transactional queries: begin, commit, rollback and savepoint management
 func (e *Executor) handleTransactions(
 	ctx context.Context,
@@ -425,14 +435,44 @@ func (e *Executor) logExecutionEnd(logStats *logstats.LogStats, execStart time.T
 	} else {
 		logStats.RowsAffected = qr.RowsAffected
 		logStats.RowsReturned = uint64(len(qr.Rows))
+		logStats.SetResultFields(qr.Fields)
 	}
+	logStats.QueryCategory = queryCategory(plan, logStats.ShardQueries, logStats.RowsReturned)
 	return errCount
 }
 
+// olapRowsThreshold is the number of returned rows above which a query is
+// considered analytical, regardless of its plan.
+const olapRowsThreshold = 10000
+
+// queryCategory classifies a query as OLTP or OLAP. The heuristic is
+// deliberately simple: a query is OLAP when it returns at least
+// olapRowsThreshold rows, or when it hits more than one shard and vtgate
+// has to aggregate the results itself. Everything else is OLTP.
+func queryCategory(plan *engine.Plan, shardQueries, rowsReturned uint64) string {
+	if rowsReturned >= olapRowsThreshold {
+		return logstats.QueryCategoryOLAP
+	}
+	if shardQueries > 1 && engine.Exists(isAggregate, plan.Instructions) {
+		return logstats.QueryCategoryOLAP
+	}
+	return logstats.QueryCategoryOLTP
+}
+
+func isAggregate(p engine.Primitive) bool {
+	switch p.(type) {
+	case *engine.OrderedAggregate, *engine.ScalarAggregate:
+		return true
+	}
+	return false
+}
+
 func (e *Executor) logPlanningFinished(logStats *logstats.LogStats, plan *engine.Plan) time.Time {
 	execStart := time.Now()
 	if plan != nil {
 		logStats.StmtType = plan.QueryType.String()
+		logStats.PlanFingerprint = plan.Fingerprint()
+		logStats.PostProcessingOps = uint64(plan.PostProcessingOps())
 	}
 	logStats.PlanTime = execStart.Sub(logStats.StartTime)
 	return execStart
@@ -0,0 +1,51 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vtgate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"vitess.io/vitess/go/vt/vtgate/engine"
+	"vitess.io/vitess/go/vt/vtgate/logstats"
+)
+
+func TestQueryCategory(t *testing.T) {
+	route := &engine.Route{}
+	aggregated := &engine.Plan{Instructions: &engine.ScalarAggregate{Input: route}}
+	plain := &engine.Plan{Instructions: route}
+
+	tests := []struct {
+		name         string
+		plan         *engine.Plan
+		shardQueries uint64
+		rowsReturned uint64
+		want         string
+	}{
+		{"single shard", plain, 1, 1, logstats.QueryCategoryOLTP},
+		{"scatter without aggregation", plain, 8, 100, logstats.QueryCategoryOLTP},
+		{"single shard aggregation", aggregated, 1, 1, logstats.QueryCategoryOLTP},
+		{"scatter aggregation", aggregated, 8, 1, logstats.QueryCategoryOLAP},
+		{"large result", plain, 1, olapRowsThreshold, logstats.QueryCategoryOLAP},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, queryCategory(tt.plan, tt.shardQueries, tt.rowsReturned))
+		})
+	}
+}
@@ -47,6 +47,9 @@ type (
 	planResult struct {
 		primitive engine.Primitive
 		tables    []string
+		// planner is the planner version that built the primitive, if the
+		// statement went through the gen4 planner.
+		planner querypb.ExecuteOptions_PlannerVersion
 	}
 
 	stmtPlanner func(sqlparser.Statement, *sqlparser.ReservedVars, plancontext.VSchema) (*planResult, error)
@@ -111,11 +114,15 @@ func BuildFromStmt(ctx context.Context, query string, stmt sqlparser.Statement,
 
 	var primitive engine.Primitive
 	var tablesUsed []string
+	var planner querypb.ExecuteOptions_PlannerVersion
 	if planResult != nil {
 		primitive = planResult.primitive
 		tablesUsed = planResult.tables
+		planner = planResult.planner
 	}
-	return engine.NewPlan(query, stmt, primitive, bindVarNeeds, tablesUsed), nil
+	plan := engine.NewPlan(query, stmt, primitive, bindVarNeeds, tablesUsed)
+	plan.PlannerVersion = planner
+	return plan, nil
 }
 
 func getConfiguredPlanner(vschema plancontext.VSchema, stmt sqlparser.Statement, query string) (stmtPlanner, error) {
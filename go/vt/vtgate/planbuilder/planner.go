@@ -29,18 +29,24 @@ import (
 
 func gen4Planner(query string, plannerVersion querypb.ExecuteOptions_PlannerVersion) stmtPlanner {
 	return func(stmt sqlparser.Statement, reservedVars *sqlparser.ReservedVars, vschema plancontext.VSchema) (*planResult, error) {
+		var result *planResult
+		var err error
 		switch stmt := stmt.(type) {
 		case sqlparser.SelectStatement:
-			return gen4SelectStmtPlanner(query, plannerVersion, stmt, reservedVars, vschema)
+			result, err = gen4SelectStmtPlanner(query, plannerVersion, stmt, reservedVars, vschema)
 		case *sqlparser.Update:
-			return gen4UpdateStmtPlanner(plannerVersion, stmt, reservedVars, vschema)
+			result, err = gen4UpdateStmtPlanner(plannerVersion, stmt, reservedVars, vschema)
 		case *sqlparser.Delete:
-			return gen4DeleteStmtPlanner(plannerVersion, stmt, reservedVars, vschema)
+			result, err = gen4DeleteStmtPlanner(plannerVersion, stmt, reservedVars, vschema)
 		case *sqlparser.Insert:
-			return gen4InsertStmtPlanner(plannerVersion, stmt, reservedVars, vschema)
+			result, err = gen4InsertStmtPlanner(plannerVersion, stmt, reservedVars, vschema)
 		default:
 			return nil, vterrors.VT12001(fmt.Sprintf("%T", stmt))
 		}
+		if result != nil {
+			result.planner = plannerVersion
+		}
+		return result, err
 	}
 }
 
@@ -134,7 +134,7 @@ func buildVExplainLoggingPlan(ctx context.Context, explain *sqlparser.VExplainSt
 		}
 	}
 
-	return &planResult{primitive: &engine.VExplain{Input: input.primitive, Type: explain.Type}, tables: input.tables}, nil
+	return &planResult{primitive: &engine.VExplain{Input: input.primitive, Type: explain.Type}, tables: input.tables, planner: input.planner}, nil
 }
 
 // buildExplainStmtPlan takes an EXPLAIN query and if possible sends the whole query to a single shard
@@ -17,11 +17,15 @@ limitations under the License.
 package vtgate
 
 import (
+	"context"
 	"net/http"
 
 	"vitess.io/vitess/go/streamlog"
 	"vitess.io/vitess/go/vt/servenv"
+	"vitess.io/vitess/go/vt/vtgate/engine"
+	econtext "vitess.io/vitess/go/vt/vtgate/executorcontext"
 	"vitess.io/vitess/go/vt/vtgate/logstats"
+	"vitess.io/vitess/go/vt/vttablet/tabletconn"
 )
 
 var (
@@ -31,18 +35,46 @@ var (
 	// QueryLogzHandler is the debug UI path for exposing query logs
 	QueryLogzHandler = "/debug/querylogz"
 
+	// QueryLogzPollHandler is the debug UI path for tailing query logs by
+	// long polling
+	QueryLogzPollHandler = "/debug/querylogz/poll"
+
+	// QueryLogzSummaryHandler is the debug UI path for exposing aggregate
+	// metrics over the buffered query logs
+	QueryLogzSummaryHandler = "/debug/querylogz/summary"
+
 	// QueryzHandler is the debug UI path for exposing query plan stats
 	QueryzHandler = "/debug/queryz"
 )
 
 func (e *Executor) defaultQueryLogger() error {
 	queryLogger := streamlog.New[*logstats.LogStats]("VTGate", queryLogBufferSize)
+	queryLogger.SetDedupWindow(queryLogDedupWindow)
 	queryLogger.ServeLogs(QueryLogHandler, streamlog.GetFormatter(queryLogger))
 
+	var ring *queryLogRing
+	if queryLogRingSize > 0 {
+		ring = newQueryLogRing(queryLogRingSize)
+		ringCh := queryLogger.Subscribe("querylogz-ring")
+		go func() {
+			for stats := range ringCh {
+				ring.add(stats)
+			}
+		}()
+	}
+
 	servenv.HTTPHandleFunc(QueryLogzHandler, func(w http.ResponseWriter, r *http.Request) {
 		ch := queryLogger.Subscribe("querylogz")
 		defer queryLogger.Unsubscribe(ch)
-		querylogzHandler(ch, w, r, e.env.Parser())
+		querylogzHandler(ch, ring, w, r, e.env.Parser())
+	})
+
+	servenv.HTTPHandleFunc(QueryLogzPollHandler, func(w http.ResponseWriter, r *http.Request) {
+		querylogzPollHandler(ring, w, r, e.env.Parser())
+	})
+
+	servenv.HTTPHandleFunc(QueryLogzSummaryHandler, func(w http.ResponseWriter, r *http.Request) {
+		querylogzSummaryHandler(ring, w, r, e.env.Parser())
 	})
 
 	servenv.HTTPHandleFunc(QueryzHandler, func(w http.ResponseWriter, r *http.Request) {
@@ -56,10 +88,35 @@ func (e *Executor) defaultQueryLogger() error {
 		}
 	}
 
+	if e.config.QueryLogToConsole != "" {
+		out, err := streamlog.ConsoleWriter(e.config.QueryLogToConsole)
+		if err != nil {
+			return err
+		}
+		minDuration := e.config.QueryLogToConsoleMinDuration
+		queryLogger.LogToConsole(out, streamlog.GetFormatter(queryLogger), func(stats *logstats.LogStats) bool {
+			return stats.TotalTime() >= minDuration
+		})
+	}
+
 	e.queryLogger = queryLogger
 	return nil
 }
 
+// withQueryLogStats returns a context that collects the statistics of the
+// execution of the query in logStats, and records the settings and the
+// collation of the session, while anything consumes the query log.
+// Otherwise, the primitives and the gateway don't collect them.
+func (e *Executor) withQueryLogStats(ctx context.Context, logStats *logstats.LogStats, safeSession *econtext.SafeSession) context.Context {
+	if !e.queryLogger.HasSubscribers() {
+		return ctx
+	}
+	logStats.SessionSettings = safeSession.SettingsSummary()
+	logStats.Collation = e.connCollation(safeSession)
+	ctx = tabletconn.WithDialObserver(ctx, logStats.AddConnectionSetupTime)
+	return engine.WithStatsSink(ctx, logStats)
+}
+
 func (e *Executor) SetQueryLogger(ql *streamlog.StreamLogger[*logstats.LogStats]) {
 	e.queryLogger = ql
 }
@@ -0,0 +1,110 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vtgate
+
+import (
+	"sync"
+
+	"vitess.io/vitess/go/vt/vtgate/logstats"
+)
+
+// queryLogRing keeps the most recent query log records in memory, so that
+// querylogz can render aggregate views over recent traffic instead of
+// waiting for new queries to arrive.
+type queryLogRing struct {
+	mu      sync.Mutex
+	records []*logstats.LogStats
+	// next is the position the next record is written to.
+	next int
+	// full is set once the ring has wrapped around.
+	full bool
+	// added is the number of records ever added. It is the cursor of the
+	// next record, see since.
+	added uint64
+	// changed is closed, and replaced, whenever a record is added.
+	changed chan struct{}
+}
+
+func newQueryLogRing(size int) *queryLogRing {
+	return &queryLogRing{records: make([]*logstats.LogStats, size), changed: make(chan struct{})}
+}
+
+// add stores stats, evicting the oldest record if the ring is full.
+func (r *queryLogRing) add(stats *logstats.LogStats) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.records) == 0 {
+		return
+	}
+	r.records[r.next] = stats
+	r.next++
+	if r.next == len(r.records) {
+		r.next = 0
+		r.full = true
+	}
+	r.added++
+	close(r.changed)
+	r.changed = make(chan struct{})
+}
+
+// snapshot returns the buffered records, oldest first. It is safe to call
+// on a nil ring, which holds no records.
+func (r *queryLogRing) snapshot() []*logstats.LogStats {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.full {
+		return append([]*logstats.LogStats(nil), r.records[:r.next]...)
+	}
+	out := make([]*logstats.LogStats, 0, len(r.records))
+	out = append(out, r.records[r.next:]...)
+	return append(out, r.records[:r.next]...)
+}
+
+// cursor returns the cursor of the record that will be added next.
+func (r *queryLogRing) cursor() uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.added
+}
+
+// since returns the buffered records added from cursor on, oldest first,
+// and the cursor of the record that will be added next. Records are
+// numbered from 0 in the order they were added. evicted is the number of
+// records from cursor on that were already overwritten, and changed is
+// closed when a record is added after the returned ones.
+func (r *queryLogRing) since(cursor uint64) (records []*logstats.LogStats, next, evicted uint64, changed <-chan struct{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	next, changed = r.added, r.changed
+	if cursor >= next {
+		return nil, next, 0, changed
+	}
+	buffered := uint64(r.next)
+	if r.full {
+		buffered = uint64(len(r.records))
+	}
+	if oldest := next - buffered; cursor < oldest {
+		evicted, cursor = oldest-cursor, oldest
+	}
+	for c := cursor; c < next; c++ {
+		records = append(records, r.records[c%uint64(len(r.records))])
+	}
+	return records, next, evicted, changed
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vtgate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"vitess.io/vitess/go/vt/vtgate/logstats"
+)
+
+func TestQueryLogRing(t *testing.T) {
+	var nilRing *queryLogRing
+	assert.Empty(t, nilRing.snapshot())
+
+	records := make([]*logstats.LogStats, 5)
+	for i := range records {
+		records[i] = &logstats.LogStats{SQL: string(rune('a' + i))}
+	}
+
+	ring := newQueryLogRing(3)
+	assert.Empty(t, ring.snapshot())
+	ring.add(records[0])
+	ring.add(records[1])
+	assert.Equal(t, records[:2], ring.snapshot())
+	ring.add(records[2])
+	assert.Equal(t, records[:3], ring.snapshot())
+	ring.add(records[3])
+	ring.add(records[4])
+	assert.Equal(t, records[2:], ring.snapshot())
+
+	empty := newQueryLogRing(0)
+	empty.add(records[0])
+	assert.Empty(t, empty.snapshot())
+}
+
+func TestQueryLogRingSince(t *testing.T) {
+	ring := newQueryLogRing(3)
+	records, next, evicted, changed := ring.since(0)
+	assert.Empty(t, records)
+	assert.Zero(t, next)
+	assert.Zero(t, evicted)
+
+	added := make([]*logstats.LogStats, 5)
+	for i := range added {
+		added[i] = &logstats.LogStats{SQL: string(rune('a' + i))}
+		ring.add(added[i])
+	}
+	select {
+	case <-changed:
+	default:
+		t.Fatalf("adding a record did not close the changed channel")
+	}
+	assert.EqualValues(t, 5, ring.cursor())
+
+	records, next, evicted, _ = ring.since(3)
+	assert.Equal(t, added[3:], records)
+	assert.EqualValues(t, 5, next)
+	assert.Zero(t, evicted)
+
+	// The first two records were overwritten.
+	records, _, evicted, _ = ring.since(0)
+	assert.Equal(t, added[2:], records)
+	assert.EqualValues(t, 2, evicted)
+
+	records, _, _, _ = ring.since(5)
+	assert.Empty(t, records)
+}
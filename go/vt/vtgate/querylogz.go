@@ -17,85 +17,763 @@ limitations under the License.
 package vtgate
 
 import (
+	"cmp"
+	"fmt"
+	"maps"
+	"mime"
+	"net"
 	"net/http"
+	"net/url"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/google/safehtml"
 	"github.com/google/safehtml/template"
 
 	"vitess.io/vitess/go/acl"
+	"vitess.io/vitess/go/mysql/sqlerror"
+	"vitess.io/vitess/go/streamlog"
 	"vitess.io/vitess/go/vt/log"
 	"vitess.io/vitess/go/vt/logz"
 	"vitess.io/vitess/go/vt/sqlparser"
+	"vitess.io/vitess/go/vt/vtgate/engine"
 	"vitess.io/vitess/go/vt/vtgate/logstats"
 )
 
 var (
-	querylogzHeader = []byte(`
+	querylogzHeaderTmpl = template.Must(template.New("header").Parse(`
 		<thead>
 			<tr>
+				{{if .ShowIndex}}<th>#</th>{{end}}
 				<th>Method</th>
 				<th>Context</th>
 				<th>Effective Caller</th>
 				<th>Immediate Caller</th>
+				<th>Remote Addr</th>
 				<th>SessionUUID</th>
 				<th>Start</th>
 				<th>End</th>
+				{{if .ShowAge}}<th>Age</th>{{end}}
 				<th>Duration</th>
 				<th>Plan Time</th>
 				<th>Execute Time</th>
 				<th>Commit Time</th>
 				<th>Stmt Type</th>
+				<th>Category</th>
 				<th>SQL</th>
+				{{if .ShowRewritten}}<th>Original SQL</th>
+				<th>Rewritten SQL</th>{{end}}
+				{{if .ShowDiff}}<th>Diff</th>{{end}}
+				{{if .ShowSession}}<th>Session Settings</th>{{end}}
+				<th>Tables</th>
 				<th>ShardQueries</th>
+				<th>Tablets</th>
 				<th>RowsAffected</th>
 				<th>Error</th>
+				<th>Details</th>
 			</tr>
 		</thead>
-	`)
+	`))
 	querylogzFuncMap = template.FuncMap{
 		"stampMicro":   func(t time.Time) string { return t.Format(time.StampMicro) },
 		"cssWrappable": logz.Wrappable,
 		"unquote":      func(s string) string { return strings.Trim(s, "\"") },
+		"age":          func(d time.Duration) string { return d.Truncate(time.Millisecond).String() + " ago" },
+		"duration":     querylogzFormatDuration,
 	}
 	querylogzTmpl = template.Must(template.New("example").Funcs(querylogzFuncMap).Parse(`
-		<tr class="{{.ColorLevel}}">
+		<tr class="{{.ColorLevel}}"{{if .Pin}} style="outline: 2px solid orange"{{else if .Anomaly}} style="outline: 2px dashed purple"{{end}}>
+			{{if .ShowIndex}}<td{{if .Index}} id="{{.RowID}}"{{end}}>{{if .Index}}<a href="#row-{{.Index}}">{{.Index}}</a>{{end}}</td>{{end}}
 			<td>{{.Method}}</td>
 			<td>{{.ContextHTML}}</td>
 			<td>{{.EffectiveCaller}}</td>
 			<td>{{.ImmediateCaller}}</td>
+			<td>{{.RemoteAddr}}</td>
 			<td>{{.SessionUUID}}</td>
 			<td>{{.StartTime | stampMicro}}</td>
 			<td>{{.EndTime | stampMicro}}</td>
-			<td>{{.TotalTime.Seconds}}</td>
-			<td>{{.PlanTime.Seconds}}</td>
-			<td>{{.ExecuteTime.Seconds}}</td>
-			<td>{{.CommitTime.Seconds}}</td>
+			{{if .ShowAge}}<td>{{.Age | age}}</td>{{end}}
+			<td>{{duration .TotalTime .Humanize}}</td>
+			<td>{{duration .PlanTime .Humanize}}</td>
+			<td>{{duration .ExecuteTime .Humanize}}</td>
+			<td>{{duration .CommitTime .Humanize}}</td>
 			<td>{{.StmtType}}</td>
-			<td>{{.SQL | .Parser.TruncateForUI | unquote | cssWrappable}}</td>
+			<td>{{.QueryCategory}}</td>
+			<td>{{.SQL | .Parser.TruncateForUI | unquote | cssWrappable}}{{if and .ShowCopy (not .Config.RedactDebugUIQueries)}} <button class="copy-sql" data-sql="{{.SQL}}" hidden>Copy</button>{{end}}</td>
+			{{if .ShowRewritten}}{{if .RedactsOriginalSQL}}<td>[REDACTED]</td>{{else}}<td>{{.OriginalSQL | .Parser.TruncateForUI | unquote | cssWrappable}}</td>{{end}}
+			{{if .Config.RedactDebugUIQueries}}<td>[REDACTED]</td>{{else}}<td>{{.RewrittenSQL | .Parser.TruncateForUI | unquote | cssWrappable}}</td>{{end}}{{end}}
+			{{if .ShowDiff}}<td>{{.Diff}}</td>{{end}}
+			{{if .ShowSession}}<td>{{.SessionSettings | cssWrappable}}</td>{{end}}
+			<td>{{.Tables}}</td>
 			<td>{{.ShardQueries}}</td>
+			<td>{{.TabletsContacted}}</td>
 			<td>{{.RowsAffected}}</td>
 			<td>{{.ErrorStr}}</td>
+			<td>{{range .Details}}{{.Name}}: {{.Value}}<br>{{end}}{{range .Timeline}}{{.Shard}}: <span style="{{.Spacer}}"></span><span style="{{.Bar}}" title="+{{.Offset}}, {{.Duration}}"></span> +{{.Offset}}, {{.Duration}}<br>{{end}}</td>
+		</tr>
+	`))
+	querylogzSummaryTmpl = template.Must(template.New("summary").Parse(`
+		<thead>
+			<tr>
+				<th>Stmt Type</th>
+				<th>Count</th>
+				<th>Avg Duration</th>
+				<th>P95 Duration</th>
+			</tr>
+		</thead>
+		{{range .}}
+		<tr>
+			<td>{{.Group}}</td>
+			<td>{{.Count}}</td>
+			<td>{{.Avg.Seconds}}</td>
+			<td>{{.P95.Seconds}}</td>
 		</tr>
+		{{end}}
+	`))
+	querylogzApdexTmpl = template.Must(template.New("apdex").Parse(`
+		<h2>Apdex({{.Target}}): {{if .Total}}{{printf "%.2f" .Score}}{{else}}n/a{{end}}</h2>
+		<p>{{.Satisfied}} satisfied, {{.Tolerating}} tolerating, {{.Frustrated}} frustrated out of {{.Total}} buffered queries</p>
+	`))
+	querylogzPinnedTmpl = template.Must(template.New("pinned").Parse(`
+		<h3>Pinned queries</h3>
+	`))
+	querylogzKeyspaceTmpl = template.Must(template.New("keyspace").Parse(`
+		<h3>Keyspace: {{.}}</h3>
 	`))
+	querylogzHistogramTmpl = template.Must(template.New("histogram").Parse(`
+		<thead>
+			<tr>
+				<th>Latency</th>
+				<th>Count</th>
+				<th>Distribution</th>
+			</tr>
+		</thead>
+		{{range .}}
+		<tr>
+			<td>{{.Label}}</td>
+			<td>{{.Count}}</td>
+			<td><code>{{.Bar}}</code></td>
+		</tr>
+		{{end}}
+	`))
+	querylogzBreakdownTmpl = template.Must(template.New("breakdown").Parse(`
+		<thead>
+			<tr>
+				<th>SQL</th>
+				<th>Duration</th>
+				<th>Breakdown: {{range .Legend}}<span style="{{.Style}}"></span> {{.Name}} {{end}}</th>
+			</tr>
+		</thead>
+		{{range .Rows}}
+		<tr>
+			<td>{{.SQL}}</td>
+			<td>{{.Total.Seconds}}</td>
+			<td style="width: 50%">{{range .Segments}}<span style="{{.Style}}" title="{{.Name}}: {{.Duration.Seconds}}s"></span>{{end}}</td>
+		</tr>
+		{{end}}
+	`))
+	querylogzShapesTmpl = template.Must(template.New("shapes").Parse(`
+		<thead>
+			<tr>
+				<th>Shape</th>
+				<th>Count</th>
+				<th>Avg Duration</th>
+				<th>Recent Durations</th>
+			</tr>
+		</thead>
+		{{range .}}
+		<tr>
+			<td>{{.Shape}}</td>
+			<td>{{.Count}}</td>
+			<td>{{.Avg.Seconds}}</td>
+			<td>{{range .Sparkline}}<span style="{{.Style}}" title="{{.Duration.Seconds}}s"></span>{{end}}</td>
+		</tr>
+		{{end}}
+	`))
+	querylogzCallersTmpl = template.Must(template.New("callers").Parse(`
+		<thead>
+			<tr>
+				<th>Effective Caller</th>
+				<th>Count</th>
+				<th>Total Time</th>
+				<th>Avg Duration</th>
+			</tr>
+		</thead>
+		{{range .}}
+		<tr>
+			<td>{{.Caller}}</td>
+			<td>{{.Count}}</td>
+			<td>{{.Total.Seconds}}</td>
+			<td>{{.Avg.Seconds}}</td>
+		</tr>
+		{{end}}
+	`))
+	querylogzPagerTmpl = template.Must(template.New("pager").Parse(`
+<p>
+	{{if .Prev}}<a href="{{.Prev}}">&laquo; prev</a>{{end}}
+	<a href="{{.Next}}">next &raquo;</a>
+</p>
+`))
 )
 
 // querylogzHandler serves a human readable snapshot of the
-// current query log.
-func querylogzHandler(ch chan *logstats.LogStats, w http.ResponseWriter, r *http.Request, parser *sqlparser.Parser) {
+// current query log. The format parameter selects one of the
+// registered logstats formatters instead of the default HTML table.
+// Aggregate views, selected with the view parameter, and the prometheus
+// and explain formats are computed over the recent records buffered in ring instead of
+// the live stream.
+func querylogzHandler(ch chan *logstats.LogStats, ring *queryLogRing, w http.ResponseWriter, r *http.Request, parser *sqlparser.Parser) {
 	if err := acl.CheckAccessHTTP(r, acl.DEBUGGING); err != nil {
 		acl.SendError(w, err)
 		return
 	}
+	if err := applyQuerylogzPreset(r); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	marked, err := applyQuerylogzMarks(r, ring)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if marked {
+		http.Redirect(w, r, r.URL.String(), http.StatusSeeOther)
+		return
+	}
 	timeout, limit := parseTimeoutLimitParams(r)
-	logz.StartHTMLTable(w)
-	defer logz.EndHTMLTable(w)
-	w.Write(querylogzHeader)
+	offset := parseOffsetParam(r)
+	filter := parseQuerylogzFilter(r, parser)
+
+	switch view := r.URL.Query().Get("view"); view {
+	case "":
+	case "histogram":
+		querylogzHistogram(w, ring.snapshot(), filter)
+		return
+	case "breakdown":
+		querylogzBreakdown(w, querylogzLast(ring.snapshot(), filter, limit), parser)
+		return
+	case "shapes":
+		querylogzShapes(w, ring.snapshot(), filter, limit, parser)
+		return
+	case "byCaller":
+		querylogzCallers(w, ring.snapshot(), filter, limit)
+		return
+	case "errors":
+		querylogzErrors(w, ring.snapshot(), filter, limit, parser)
+		return
+	default:
+		http.Error(w, fmt.Sprintf("unknown view %q", view), http.StatusBadRequest)
+		return
+	}
+
+	// topPerKeyspace=N shows the N slowest buffered queries of each keyspace,
+	// so that a single busy keyspace doesn't crowd out the others.
+	if n, err := strconv.Atoi(r.URL.Query().Get("topPerKeyspace")); err == nil && n > 0 {
+		querylogzTopPerKeyspace(w, ring.snapshot(), filter, adjustValue(n, 1, maxQuerylogzTopPerKeyspace), parser)
+		return
+	}
+
+	format := parseQuerylogzFormat(r)
+	if format == querylogzFormatExplain {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", querylogzExplainFilename))
+		writeQuerylogzExplain(w, ring.snapshot(), filter, limit, parser)
+		return
+	}
+	if format == querylogzFormatPrometheus {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		writeQuerylogzPrometheus(w, ring.snapshot(), filter)
+		return
+	}
+	if format != "" && format != logstats.FormatHTML {
+		fmter := logstats.GetFormatter(format)
+		if fmter == nil {
+			http.Error(w, fmt.Sprintf("unknown format %q, must be one of %v", format, logstats.FormatterNames()), http.StatusBadRequest)
+			return
+		}
+		if format == streamlog.QueryLogFormatJSON {
+			w.Header().Set("Content-Type", "application/json")
+		}
+		write := func(stats *logstats.LogStats) {
+			b, err := fmter.Format(stats)
+			if err != nil {
+				log.Errorf("querylogz: couldn't format record as %s: %v", format, err)
+				return
+			}
+			w.Write(b)
+		}
+		// backfill=N writes up to N of the most recent buffered records
+		// before streaming, so that a collector that connects mid-stream
+		// doesn't miss recent queries. A record can be both buffered and
+		// received on ch, so the backfilled records are skipped from the
+		// stream.
+		if n, err := strconv.Atoi(r.URL.Query().Get("backfill")); err == nil && n > 0 {
+			backfill := querylogzLast(ring.snapshot(), filter, n)
+			filter.skip = make(map[*logstats.LogStats]bool, len(backfill))
+			for _, stats := range backfill {
+				write(stats)
+				filter.skip[stats] = true
+			}
+		}
+		readQuerylogz(ch, timeout, limit, offset, filter, write)
+		return
+	}
+
+	columns := querylogzColumns{
+		// index=1 adds a column numbering each row with the position of its
+		// query among the matching ones, counting the skipped offset, so
+		// that a row can be referred to, and linked to, as #row-N.
+		ShowIndex: r.URL.Query().Get("index") == "1",
+		// age=1 adds a column showing how long ago each query started,
+		// relative to the moment its row is rendered.
+		ShowAge: r.URL.Query().Get("age") == "1",
+		// rewritten=1 adds the SQL sent by the application and the queries
+		// vtgate sent to the tablets next to the normalized SQL.
+		ShowRewritten: r.URL.Query().Get("rewritten") == "1",
+		// session=1 adds the session settings, such as the system variables
+		// set with SET, that were in effect for each query.
+		ShowSession: r.URL.Query().Get("session") == "1",
+		// copy=1 adds a button to each SQL cell copying the full query, as
+		// it was received, to the clipboard.
+		ShowCopy: r.URL.Query().Get("copy") == "1",
+		// compact=1 renders each query as a card holding its key fields
+		// instead of a row of the wide table, for use from a phone.
+		Compact: r.URL.Query().Get("compact") == "1",
+		// humanize=1 renders the times as durations, such as 1.2ms, rather
+		// than as a number of seconds, which tools reading the page expect.
+		Humanize: r.URL.Query().Get("humanize") == "1",
+		// sticky=1 renders the table in a scrollable box whose header row
+		// stays in place, so that the column names remain visible in long
+		// tables. It has no effect in compact mode.
+		Sticky: r.URL.Query().Get("sticky") == "1",
+		// timeline=1 adds to the details of each query a timeline of the
+		// queries it sent to the shards, to tell a slow shard from shards
+		// queried one after another. It has no effect in compact mode.
+		ShowTimeline: r.URL.Query().Get("timeline") == "1",
+	}
+	// diff=<query> adds a column showing which literals and bind variables
+	// of each query differ from the given reference query. Every logged
+	// query has to be parsed and normalized, so this is opt-in.
+	if reference := r.URL.Query().Get("diff"); reference != "" {
+		tmpl, err := newQuerylogzTemplate(parser, reference, nil)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid diff reference query: %v", err), http.StatusBadRequest)
+			return
+		}
+		columns.ShowDiff, columns.diffReference = true, tmpl
+	}
+	// anomalies=1 highlights the queries that took longer than the p95 of
+	// the buffered queries of the same shape, so that queries slow for
+	// their shape stand out even when their latency looks fine.
+	if r.URL.Query().Get("anomalies") == "1" {
+		columns.shapeP95s = querylogzShapeP95s(ring.snapshot(), parser)
+	}
+	// percentiles=1 colors the rows by the latency percentile bucket they
+	// fall into among the buffered queries matching the filters, rather
+	// than by fixed thresholds, and tags each row with its bucket.
+	if r.URL.Query().Get("percentiles") == "1" {
+		columns.percentiles = querylogzComputePercentiles(ring.snapshot(), filter)
+	}
+	// low=<duration> and high=<duration> override the total times below
+	// which rows are colored low and medium.
+	if columns.levels, err = parseQuerylogzLevels(r); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// alert_error_rate=<percent> and alert_p95=<duration> override the
+	// thresholds above which a banner is shown at the top of the page.
+	thresholds, err := parseQuerylogzAlertThresholds(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// target=<duration> shows the Apdex score of the buffered queries for
+	// that target latency above the table.
+	var target time.Duration
+	if t := r.URL.Query().Get("target"); t != "" {
+		target, err = time.ParseDuration(t)
+		if err != nil || target <= 0 {
+			http.Error(w, fmt.Sprintf("invalid apdex target %q, must be a positive duration", t), http.StatusBadRequest)
+			return
+		}
+	}
+	// self-contained=1 downloads the view as a standalone HTML file. Its
+	// links to other pages of the live log would be dead, so the pager is
+	// left out.
+	selfContained := r.URL.Query().Get("self-contained") == "1"
+	if selfContained {
+		querylogzSnapshot(w, r)
+	}
+	// permalink=1 adds a link to this exact view, with every parameter
+	// spelled out, to share it with a teammate.
+	if r.URL.Query().Get("permalink") == "1" {
+		querylogzPermalinkLink(w, r)
+	}
+	querylogzAlerts(w, ring.snapshot(), filter, thresholds)
+	querylogzQPS(w, ring.snapshot(), filter)
+	// trend=1 shows the moving average of the latency of the buffered
+	// queries over time, to tell whether it is rising or falling.
+	if r.URL.Query().Get("trend") == "1" {
+		querylogzTrend(w, ring.snapshot(), filter)
+	}
+	// scatter=1 shows the share of the buffered queries that were sent to
+	// more than one shard, a common sign of schema or vindex problems.
+	if r.URL.Query().Get("scatter") == "1" {
+		querylogzScatter(w, ring.snapshot(), filter)
+	}
+	if target > 0 {
+		querylogzApdex(w, ring.snapshot(), filter, target)
+	}
+	// summary=1 adds a panel summarizing the latency of the buffered
+	// queries per statement type above the table.
+	if r.URL.Query().Get("summary") == "1" {
+		querylogzSummary(w, ring.snapshot(), filter)
+	}
+	querylogzPinned(w, parser, columns)
+
+	querylogzStartTable(w, columns)
+
+	// collapse=1 folds consecutive queries of the same shape into the row
+	// of the first one, keeping the log in order but less repetitive.
+	if r.URL.Query().Get("collapse") == "1" {
+		collapser := &querylogzCollapser{render: func(stats *logstats.LogStats, index, collapsed int) {
+			querylogzRow(w, stats, parser, columns, index, 0, collapsed)
+		}, parser: parser, seen: offset}
+		readQuerylogz(ch, timeout, limit, offset, filter, collapser.add)
+		collapser.flush()
+	} else {
+		index := offset
+		readQuerylogz(ch, timeout, limit, offset, filter, func(stats *logstats.LogStats) {
+			index++
+			querylogzRow(w, stats, parser, columns, index, 0, 0)
+		})
+	}
+	querylogzEndTable(w, columns)
+	if columns.ShowCopy {
+		w.Write([]byte(querylogzCopyScript))
+	}
+
+	if selfContained {
+		return
+	}
+	prev, next := querylogzPageLinks(r, offset, limit)
+	if err := querylogzPagerTmpl.Execute(w, struct{ Prev, Next string }{prev, next}); err != nil {
+		log.Errorf("querylogz: couldn't execute pager template: %v", err)
+	}
+}
+
+// querylogzCopyScript shows the copy buttons of the SQL cells and makes
+// them copy their query to the clipboard. Without JavaScript, the buttons
+// stay hidden.
+const querylogzCopyScript = `
+<script type="text/javascript">
+document.querySelectorAll("button.copy-sql").forEach(function(button) {
+  button.hidden = false;
+  button.addEventListener("click", function() {
+    navigator.clipboard.writeText(button.dataset.sql);
+  });
+});
+</script>
+`
+
+// querylogzColumns are the optional columns of the querylogz table.
+type querylogzColumns struct {
+	ShowIndex     bool
+	ShowAge       bool
+	ShowRewritten bool
+	ShowDiff      bool
+	ShowSession   bool
+	ShowCopy      bool
+	// Compact renders the queries as cards instead, see querylogzStartTable.
+	Compact bool
+	// Humanize renders the times as durations, see querylogzFormatDuration.
+	Humanize bool
+	// Sticky keeps the header row visible while scrolling the table, see
+	// querylogzStartTable.
+	Sticky bool
+	// ShowTimeline adds the shard timeline to the details, see
+	// querylogzShardTimeline.
+	ShowTimeline bool
+
+	// diffReference is the reference query of the Diff column.
+	diffReference *querylogzTemplate
+	// shapeP95s, when set, highlights the queries slower than the p95 of
+	// their shape, see querylogzShapeP95s.
+	shapeP95s map[string]time.Duration
+	// percentiles, when set, colors the rows by latency percentile bucket
+	// instead of fixed thresholds, see querylogzPercentiles.
+	percentiles *querylogzPercentiles
+	// levels, when set, overrides the default thresholds of the row colors.
+	levels *querylogzLevels
+}
 
+// Default thresholds of the row colors. Queries faster than
+// querylogzLowThreshold are low, the ones faster than
+// querylogzHighThreshold are medium, and the others are high.
+var (
+	querylogzLowThreshold  = 10 * time.Millisecond
+	querylogzHighThreshold = 100 * time.Millisecond
+)
+
+// querylogzLevels are the thresholds of the row colors.
+type querylogzLevels struct {
+	low, high time.Duration
+}
+
+// level returns the color of a row whose query took total. Nil levels use
+// the default thresholds.
+func (l *querylogzLevels) level(total time.Duration) string {
+	low, high := querylogzLowThreshold, querylogzHighThreshold
+	if l != nil {
+		low, high = l.low, l.high
+	}
+	switch {
+	case total < low:
+		return "low"
+	case total < high:
+		return "medium"
+	default:
+		return "high"
+	}
+}
+
+// parseQuerylogzLevels returns the thresholds given by the low and high
+// parameters of r, as durations or numbers of seconds, or nil if there is
+// neither.
+func parseQuerylogzLevels(r *http.Request) (*querylogzLevels, error) {
+	lowParam, highParam := r.URL.Query().Get("low"), r.URL.Query().Get("high")
+	if lowParam == "" && highParam == "" {
+		return nil, nil
+	}
+	levels := &querylogzLevels{low: querylogzLowThreshold, high: querylogzHighThreshold}
+	parse := func(name, v string, threshold *time.Duration) error {
+		if v == "" {
+			return nil
+		}
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			seconds, ferr := strconv.ParseFloat(v, 64)
+			if ferr != nil {
+				return fmt.Errorf("invalid %s %q, must be a duration or a number of seconds", name, v)
+			}
+			d = time.Duration(seconds * float64(time.Second))
+		}
+		if d < 0 {
+			return fmt.Errorf("invalid %s %q, must not be negative", name, v)
+		}
+		*threshold = d
+		return nil
+	}
+	if err := parse("low", lowParam, &levels.low); err != nil {
+		return nil, err
+	}
+	if err := parse("high", highParam, &levels.high); err != nil {
+		return nil, err
+	}
+	if levels.low > levels.high {
+		return nil, fmt.Errorf("low threshold %v is above high threshold %v", levels.low, levels.high)
+	}
+	return levels, nil
+}
+
+// querylogzRow renders stats as a row of the querylogz table. index is the
+// position of stats among the matching queries, starting at 1, or 0 for rows
+// that aren't numbered. Pinned rows are given their position among the
+// pinned queries as pin, starting at 1, and are highlighted; other rows pass
+// 0. collapsed is the number of
+// queries of the same shape that followed stats and were folded into its
+// row, see querylogzCollapser.
+func querylogzRow(w http.ResponseWriter, stats *logstats.LogStats, parser *sqlparser.Parser, columns querylogzColumns, index, pin, collapsed int) {
+	level := columns.levels.level(stats.TotalTime())
+	tmplData := struct {
+		*logstats.LogStats
+		querylogzColumns
+		ColorLevel string
+		Parser     *sqlparser.Parser
+		Age        time.Duration
+		Details    []querylogzDetail
+		Tables     string
+		Diff       string
+		Index      int
+		RowID      safehtml.Identifier
+		Pin        int
+		Anomaly    bool
+		RemoteAddr string
+		Timeline   []querylogzTimelineSpan
+	}{stats, columns, level, parser, time.Since(stats.StartTime), querylogzDetails(stats, columns.Humanize), strings.Join(stats.TargetTables(parser), ", "), "", index, safehtml.IdentifierFromConstantPrefix("row", strconv.Itoa(index)), pin, false, "", nil}
+	if columns.percentiles != nil {
+		var bucket string
+		bucket, tmplData.ColorLevel = columns.percentiles.bucket(stats.TotalTime())
+		tmplData.Details = append([]querylogzDetail{{"Latency Bucket", bucket}}, tmplData.Details...)
+	}
+	// Failed queries stand out whatever their latency.
+	if stats.Error != nil {
+		tmplData.ColorLevel = "error"
+	}
+	if columns.shapeP95s != nil {
+		var p95 time.Duration
+		if p95, tmplData.Anomaly = querylogzShapeAnomaly(columns.shapeP95s, parser, stats); tmplData.Anomaly {
+			tmplData.Details = append([]querylogzDetail{{"Above Shape P95", querylogzFormatDuration(p95, columns.Humanize)}}, tmplData.Details...)
+		}
+	}
+	if collapsed > 0 {
+		tmplData.Details = append([]querylogzDetail{{"Collapsed", strconv.Itoa(collapsed) + " more of this shape"}}, tmplData.Details...)
+	}
+	if pin > 0 {
+		tmplData.Details = append([]querylogzDetail{{"Pinned", "#" + strconv.Itoa(pin)}}, tmplData.Details...)
+	}
+	// The client address identifies the application host, which is hidden
+	// along with the queries when the debug UI is redacted.
+	if stats.Config.RedactDebugUIQueries {
+		tmplData.RemoteAddr = "[REDACTED]"
+	} else {
+		tmplData.RemoteAddr, _ = stats.RemoteAddrUsername()
+	}
+	if columns.ShowTimeline {
+		tmplData.Timeline = querylogzShardTimeline(stats, columns.Humanize)
+	}
+	if columns.diffReference != nil {
+		tmplData.Diff = querylogzDiff(columns.diffReference, parser, stats.SQL, stats.BindVariables, stats.Config)
+	}
+	tmpl := querylogzTmpl
+	if columns.Compact {
+		tmpl = querylogzCardTmpl
+	}
+	if err := tmpl.Execute(w, tmplData); err != nil {
+		log.Errorf("querylogz: couldn't execute template: %v", err)
+	}
+}
+
+// querylogzCollapser folds runs of consecutive queries of the same shape,
+// as normalized for the diff column, into their first query.
+type querylogzCollapser struct {
+	// render is called with the first query of each run, its position
+	// among the added queries, counting from seen, and the number of queries
+	// that followed it in the run.
+	render func(stats *logstats.LogStats, index, collapsed int)
+	parser *sqlparser.Parser
+	// seen is the number of queries added so far. It can start at the
+	// number of queries skipped before the first one.
+	seen int
+
+	first     *logstats.LogStats
+	index     int
+	shape     string
+	collapsed int
+}
+
+// add adds stats to the current run, or renders the current run and starts
+// a new one if stats has a different shape.
+func (c *querylogzCollapser) add(stats *logstats.LogStats) {
+	c.seen++
+	shape := querylogzShape(c.parser, stats)
+	if c.first != nil && shape == c.shape {
+		c.collapsed++
+		return
+	}
+	c.flush()
+	c.first, c.index, c.shape = stats, c.seen, shape
+}
+
+// querylogzShape returns the shape of the query of stats, as normalized
+// for the diff column, or its SQL if it can't be normalized.
+func querylogzShape(parser *sqlparser.Parser, stats *logstats.LogStats) string {
+	if tmpl, err := newQuerylogzTemplate(parser, stats.SQL, stats.BindVariables); err == nil {
+		return tmpl.shape
+	}
+	return stats.SQL
+}
+
+// flush renders the current run, if any.
+func (c *querylogzCollapser) flush() {
+	if c.first != nil {
+		c.render(c.first, c.index, c.collapsed)
+	}
+	c.first, c.index, c.shape, c.collapsed = nil, 0, "", 0
+}
+
+// maxQuerylogzPresets bounds the number of saved filter presets. Saving a
+// new preset beyond it evicts the oldest one.
+const maxQuerylogzPresets = 50
+
+// querylogzPresets are the filter presets saved with savePreset. They are
+// kept in memory and are local to this process.
+var querylogzPresets = &querylogzPresetStore{presets: map[string]url.Values{}}
+
+// querylogzPresetStore is a bounded set of named querylogz parameters.
+type querylogzPresetStore struct {
+	mu      sync.Mutex
+	presets map[string]url.Values
+	// names holds the preset names from the oldest to the most recently saved.
+	names []string
+}
+
+func (ps *querylogzPresetStore) save(name string, params url.Values) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if _, ok := ps.presets[name]; ok {
+		ps.names = slices.DeleteFunc(ps.names, func(n string) bool { return n == name })
+	} else if len(ps.names) >= maxQuerylogzPresets {
+		delete(ps.presets, ps.names[0])
+		ps.names = ps.names[1:]
+	}
+	ps.presets[name] = params
+	ps.names = append(ps.names, name)
+}
+
+func (ps *querylogzPresetStore) get(name string) (url.Values, bool) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	params, ok := ps.presets[name]
+	return params, ok
+}
+
+// applyQuerylogzPreset handles the preset parameters of a querylogz request.
+// savePreset=name stores the other parameters of the request under name.
+// preset=name expands into the parameters saved under name, with the ones
+// given explicitly in the request taking precedence. The request URL is
+// rewritten in place, so the rest of the handler, including the pager links,
+// only ever sees the expanded parameters.
+func applyQuerylogzPreset(r *http.Request) error {
+	query := r.URL.Query()
+	if name := query.Get("savePreset"); name != "" {
+		params := url.Values{}
+		for k, v := range query {
+			switch k {
+			case "savePreset", "preset", "offset":
+			default:
+				params[k] = slices.Clone(v)
+			}
+		}
+		querylogzPresets.save(name, params)
+		query.Del("savePreset")
+	}
+	if name := query.Get("preset"); name != "" {
+		params, ok := querylogzPresets.get(name)
+		if !ok {
+			return fmt.Errorf("unknown preset %q", name)
+		}
+		for k, v := range params {
+			if _, ok := query[k]; !ok {
+				query[k] = slices.Clone(v)
+			}
+		}
+		query.Del("preset")
+	}
+	r.URL.RawQuery = query.Encode()
+	return nil
+}
+
+// readQuerylogz calls render for up to limit records received on ch that
+// match filter, after skipping the first offset matching records. It stops
+// early once timeout has elapsed.
+func readQuerylogz(ch chan *logstats.LogStats, timeout time.Duration, limit, offset int, filter querylogzFilter, render func(*logstats.LogStats)) {
 	tmr := time.NewTimer(timeout)
 	defer tmr.Stop()
-	for i := 0; i < limit; i++ {
+	for i := 0; i < limit; {
 		select {
 		case stats := <-ch:
 			select {
@@ -103,28 +781,946 @@ func querylogzHandler(ch chan *logstats.LogStats, w http.ResponseWriter, r *http
 				return
 			default:
 			}
-			var level string
-			if stats.TotalTime().Seconds() < 0.01 {
-				level = "low"
-			} else if stats.TotalTime().Seconds() < 0.1 {
-				level = "medium"
-			} else {
-				level = "high"
+			if !filter.matches(stats) {
+				continue
 			}
-			tmplData := struct {
-				*logstats.LogStats
-				ColorLevel string
-				Parser     *sqlparser.Parser
-			}{stats, level, parser}
-			if err := querylogzTmpl.Execute(w, tmplData); err != nil {
-				log.Errorf("querylogz: couldn't execute template: %v", err)
+			if offset > 0 {
+				offset--
+				continue
 			}
+			render(stats)
+			i++
 		case <-tmr.C:
 			return
 		}
 	}
 }
 
+// querylogzLast returns the last n of records that match filter,
+// oldest first.
+func querylogzLast(records []*logstats.LogStats, filter querylogzFilter, n int) []*logstats.LogStats {
+	records = slices.DeleteFunc(records, func(stats *logstats.LogStats) bool { return !filter.matches(stats) })
+	return records[max(len(records)-n, 0):]
+}
+
+// querylogzLatencyBands are the bands of the histogram view. Each band
+// holds the records faster than its upper bound that don't fit an earlier
+// band; the last band is unbounded.
+var querylogzLatencyBands = []struct {
+	label string
+	upper time.Duration
+}{
+	{"< 1ms", time.Millisecond},
+	{"1ms - 10ms", 10 * time.Millisecond},
+	{"10ms - 100ms", 100 * time.Millisecond},
+	{"100ms - 1s", time.Second},
+	{">= 1s", 0},
+}
+
+// querylogzHistogramWidth is the length of the bar of the largest band.
+const querylogzHistogramWidth = 60
+
+type querylogzHistogramBand struct {
+	Label string
+	Count int
+	Bar   string
+}
+
+// querylogzHistogram renders the distribution of the total time of the
+// records matching filter, bucketed into querylogzLatencyBands.
+func querylogzHistogram(w http.ResponseWriter, records []*logstats.LogStats, filter querylogzFilter) {
+	bands := make([]querylogzHistogramBand, len(querylogzLatencyBands))
+	for i, band := range querylogzLatencyBands {
+		bands[i].Label = band.label
+	}
+	for _, stats := range records {
+		if !filter.matches(stats) {
+			continue
+		}
+		total := stats.TotalTime()
+		i := 0
+		for ; i < len(querylogzLatencyBands)-1; i++ {
+			if total < querylogzLatencyBands[i].upper {
+				break
+			}
+		}
+		bands[i].Count++
+	}
+
+	maxCount := 0
+	for _, band := range bands {
+		maxCount = max(maxCount, band.Count)
+	}
+	for i := range bands {
+		if bands[i].Count > 0 {
+			// Scale the bars to the largest band, keeping non-empty bands visible.
+			bands[i].Bar = strings.Repeat("#", max(1, bands[i].Count*querylogzHistogramWidth/maxCount))
+		}
+	}
+
+	logz.StartHTMLTable(w)
+	defer logz.EndHTMLTable(w)
+	if err := querylogzHistogramTmpl.Execute(w, bands); err != nil {
+		log.Errorf("querylogz: couldn't execute histogram template: %v", err)
+	}
+}
+
+// querylogzBreakdownSegments are the segments of the time of a query shown
+// by the breakdown view, with their color. Other is the time not accounted
+// for by the other segments.
+var querylogzBreakdownSegments = []struct {
+	name  string
+	color string
+	time  func(stats *logstats.LogStats) time.Duration
+}{
+	{"Parse", "#76b7b2", func(stats *logstats.LogStats) time.Duration { return stats.ParseTime }},
+	{"Plan", "#4e79a7", func(stats *logstats.LogStats) time.Duration { return stats.PlanTime - stats.ParseTime }},
+	{"Execute", "#f28e2b", func(stats *logstats.LogStats) time.Duration { return stats.ExecuteTime }},
+	{"Commit", "#59a14f", func(stats *logstats.LogStats) time.Duration { return stats.CommitTime }},
+	{"Other", "#bab0ab", func(stats *logstats.LogStats) time.Duration {
+		return max(stats.TotalTime()-stats.PlanTime-stats.ExecuteTime-stats.CommitTime, 0)
+	}},
+}
+
+type querylogzBreakdownSegment struct {
+	Name     string
+	Duration time.Duration
+	Style    safehtml.Style
+}
+
+type querylogzBreakdownRow struct {
+	SQL      string
+	Total    time.Duration
+	Segments []querylogzBreakdownSegment
+}
+
+// querylogzBreakdown renders the time of each of records as a bar made of
+// one segment per querylogzBreakdownSegments. Bars are scaled to the
+// slowest record, so that both the share of each segment and the overall
+// latency can be compared at a glance.
+func querylogzBreakdown(w http.ResponseWriter, records []*logstats.LogStats, parser *sqlparser.Parser) {
+	segmentStyle := func(color string, width string) safehtml.Style {
+		return safehtml.StyleFromProperties(safehtml.StyleProperties{
+			Display:         "inline-block",
+			Height:          "1em",
+			Width:           width,
+			BackgroundColor: color,
+		})
+	}
+	var slowest time.Duration
+	for _, stats := range records {
+		slowest = max(slowest, stats.TotalTime())
+	}
+
+	var data struct {
+		Legend []querylogzBreakdownSegment
+		Rows   []querylogzBreakdownRow
+	}
+	for _, segment := range querylogzBreakdownSegments {
+		data.Legend = append(data.Legend, querylogzBreakdownSegment{Name: segment.name, Style: segmentStyle(segment.color, "1em")})
+	}
+	for _, stats := range records {
+		row := querylogzBreakdownRow{SQL: parser.TruncateForUI(stats.SQL), Total: stats.TotalTime()}
+		for _, segment := range querylogzBreakdownSegments {
+			d := segment.time(stats)
+			if d <= 0 {
+				continue
+			}
+			width := strconv.FormatFloat(100*float64(d)/float64(slowest), 'f', 2, 64) + "%"
+			row.Segments = append(row.Segments, querylogzBreakdownSegment{segment.name, d, segmentStyle(segment.color, width)})
+		}
+		data.Rows = append(data.Rows, row)
+	}
+
+	logz.StartHTMLTable(w)
+	defer logz.EndHTMLTable(w)
+	if err := querylogzBreakdownTmpl.Execute(w, data); err != nil {
+		log.Errorf("querylogz: couldn't execute breakdown template: %v", err)
+	}
+}
+
+// querylogzSparklineSamples is the number of most recent durations drawn
+// in the sparkline of each shape.
+const querylogzSparklineSamples = 30
+
+type querylogzSparklineBar struct {
+	Duration time.Duration
+	Style    safehtml.Style
+}
+
+type querylogzShapeRow struct {
+	Shape     string
+	Count     int
+	Avg       time.Duration
+	Sparkline []querylogzSparklineBar
+
+	total   time.Duration
+	samples []time.Duration
+}
+
+// querylogzShapes renders the records matching filter grouped by the shape
+// of their query, the most frequent shapes first, up to limit shapes. Each
+// shape has a sparkline of the total time of its most recent records, so
+// that a shape getting slower stands out even when its average doesn't.
+func querylogzShapes(w http.ResponseWriter, records []*logstats.LogStats, filter querylogzFilter, limit int, parser *sqlparser.Parser) {
+	shapes := map[string]*querylogzShapeRow{}
+	for _, stats := range records {
+		if !filter.matches(stats) {
+			continue
+		}
+		shape := querylogzShape(parser, stats)
+		row := shapes[shape]
+		if row == nil {
+			row = &querylogzShapeRow{Shape: parser.TruncateForUI(shape)}
+			shapes[shape] = row
+		}
+		row.Count++
+		row.total += stats.TotalTime()
+		row.samples = append(row.samples, stats.TotalTime())
+	}
+
+	rows := make([]*querylogzShapeRow, 0, len(shapes))
+	for _, row := range shapes {
+		rows = append(rows, row)
+	}
+	slices.SortFunc(rows, func(a, b *querylogzShapeRow) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Shape, b.Shape))
+	})
+	rows = rows[:min(len(rows), limit)]
+	for _, row := range rows {
+		row.Avg = row.total / time.Duration(row.Count)
+		samples := row.samples[max(len(row.samples)-querylogzSparklineSamples, 0):]
+		// Scale the bars to the slowest sample of the shape, keeping every
+		// bar visible.
+		slowest := slices.Max(samples)
+		for _, d := range samples {
+			height := 0.1
+			if slowest > 0 {
+				height = max(height, 2*float64(d)/float64(slowest))
+			}
+			row.Sparkline = append(row.Sparkline, querylogzSparklineBar{d, safehtml.StyleFromProperties(safehtml.StyleProperties{
+				Display:         "inline-block",
+				Width:           "3px",
+				Height:          strconv.FormatFloat(height, 'f', 2, 64) + "em",
+				BackgroundColor: "#4e79a7",
+			})})
+		}
+	}
+
+	logz.StartHTMLTable(w)
+	defer logz.EndHTMLTable(w)
+	if err := querylogzShapesTmpl.Execute(w, rows); err != nil {
+		log.Errorf("querylogz: couldn't execute shapes template: %v", err)
+	}
+}
+
+// querylogzNoCaller is the row of queries without an effective caller.
+const querylogzNoCaller = "(none)"
+
+type querylogzCallerRow struct {
+	Caller string
+	Count  int
+	Total  time.Duration
+	Avg    time.Duration
+}
+
+// querylogzCallers renders the records matching filter grouped by their
+// effective caller, the callers that took the most total time first, up to
+// limit callers. It shows when a single client dominates a vtgate shared
+// by several applications.
+func querylogzCallers(w http.ResponseWriter, records []*logstats.LogStats, filter querylogzFilter, limit int) {
+	callers := map[string]*querylogzCallerRow{}
+	for _, stats := range records {
+		if !filter.matches(stats) {
+			continue
+		}
+		caller := stats.EffectiveCaller()
+		if caller == "" {
+			caller = querylogzNoCaller
+		}
+		row := callers[caller]
+		if row == nil {
+			row = &querylogzCallerRow{Caller: caller}
+			callers[caller] = row
+		}
+		row.Count++
+		row.Total += stats.TotalTime()
+	}
+
+	rows := slices.Collect(maps.Values(callers))
+	slices.SortFunc(rows, func(a, b *querylogzCallerRow) int {
+		return cmp.Or(cmp.Compare(b.Total, a.Total), cmp.Compare(a.Caller, b.Caller))
+	})
+	rows = rows[:min(len(rows), limit)]
+	for _, row := range rows {
+		row.Avg = row.Total / time.Duration(row.Count)
+	}
+
+	logz.StartHTMLTable(w)
+	defer logz.EndHTMLTable(w)
+	if err := querylogzCallersTmpl.Execute(w, rows); err != nil {
+		log.Errorf("querylogz: couldn't execute callers template: %v", err)
+	}
+}
+
+// querylogzNoStmtType is the summary row of queries without a statement
+// type, such as those that failed to parse.
+const querylogzNoStmtType = "(unknown)"
+
+// querylogzSummaryRow summarizes a group of records.
+type querylogzSummaryRow struct {
+	Group  string
+	Count  int
+	Errors int
+	Avg    time.Duration
+	P50    time.Duration
+	P95    time.Duration
+	P99    time.Duration
+}
+
+// querylogzSummarize returns the number of records matching filter, and
+// the number of errors and the average and percentiles of the total time
+// among them, per group as returned by group, sorted by group.
+func querylogzSummarize(records []*logstats.LogStats, filter querylogzFilter, group func(*logstats.LogStats) string) []querylogzSummaryRow {
+	durations := map[string][]time.Duration{}
+	errors := map[string]int{}
+	for _, stats := range records {
+		if !filter.matches(stats) {
+			continue
+		}
+		g := group(stats)
+		durations[g] = append(durations[g], stats.TotalTime())
+		if stats.Error != nil {
+			errors[g]++
+		}
+	}
+
+	var rows []querylogzSummaryRow
+	for _, g := range slices.Sorted(maps.Keys(durations)) {
+		sorted := durations[g]
+		slices.Sort(sorted)
+		var total time.Duration
+		for _, d := range sorted {
+			total += d
+		}
+		rows = append(rows, querylogzSummaryRow{
+			Group:  g,
+			Count:  len(sorted),
+			Errors: errors[g],
+			Avg:    total / time.Duration(len(sorted)),
+			P50:    percentile(sorted, 0.50),
+			P95:    percentile(sorted, 0.95),
+			P99:    percentile(sorted, 0.99),
+		})
+	}
+	return rows
+}
+
+// querylogzStmtType returns the statement type of stats, to group records
+// by.
+func querylogzStmtType(stats *logstats.LogStats) string {
+	if stats.StmtType == "" {
+		return querylogzNoStmtType
+	}
+	return stats.StmtType
+}
+
+// querylogzSummary renders the number of records matching filter, and
+// their average and 95th percentile total time, per statement type.
+func querylogzSummary(w http.ResponseWriter, records []*logstats.LogStats, filter querylogzFilter) {
+	rows := querylogzSummarize(records, filter, querylogzStmtType)
+	logz.StartHTMLTable(w)
+	defer logz.EndHTMLTable(w)
+	if err := querylogzSummaryTmpl.Execute(w, rows); err != nil {
+		log.Errorf("querylogz: couldn't execute summary template: %v", err)
+	}
+}
+
+type querylogzApdexScore struct {
+	Target     time.Duration
+	Satisfied  int
+	Tolerating int
+	Frustrated int
+	Total      int
+	Score      float64
+}
+
+// querylogzApdex renders the Apdex score of the records matching filter for
+// the target latency: records faster than target are satisfied, records
+// faster than four times target are tolerating, and the others are
+// frustrated. The score is (satisfied + tolerating/2) / total.
+func querylogzApdex(w http.ResponseWriter, records []*logstats.LogStats, filter querylogzFilter, target time.Duration) {
+	apdex := querylogzApdexScore{Target: target}
+	for _, stats := range records {
+		if !filter.matches(stats) {
+			continue
+		}
+		switch total := stats.TotalTime(); {
+		case total < target:
+			apdex.Satisfied++
+		case total < 4*target:
+			apdex.Tolerating++
+		default:
+			apdex.Frustrated++
+		}
+		apdex.Total++
+	}
+	if apdex.Total > 0 {
+		apdex.Score = (float64(apdex.Satisfied) + float64(apdex.Tolerating)/2) / float64(apdex.Total)
+	}
+	if err := querylogzApdexTmpl.Execute(w, apdex); err != nil {
+		log.Errorf("querylogz: couldn't execute apdex template: %v", err)
+	}
+}
+
+// maxQuerylogzTopPerKeyspace bounds the number of queries shown per keyspace
+// by the topPerKeyspace view.
+const maxQuerylogzTopPerKeyspace = 1000
+
+// querylogzNoKeyspace is the section header of queries that didn't target
+// a keyspace.
+const querylogzNoKeyspace = "(none)"
+
+// querylogzKeyspace returns the keyspace targeted by stats, to group
+// records by.
+func querylogzKeyspace(stats *logstats.LogStats) string {
+	if stats.ActiveKeyspace == "" {
+		return querylogzNoKeyspace
+	}
+	return stats.ActiveKeyspace
+}
+
+// querylogzTopPerKeyspace renders, for each keyspace, a section holding the
+// n slowest records matching filter, slowest first. Sections are sorted by
+// keyspace name.
+func querylogzTopPerKeyspace(w http.ResponseWriter, records []*logstats.LogStats, filter querylogzFilter, n int, parser *sqlparser.Parser) {
+	byKeyspace := map[string][]*logstats.LogStats{}
+	for _, stats := range records {
+		if !filter.matches(stats) {
+			continue
+		}
+		keyspace := querylogzKeyspace(stats)
+		byKeyspace[keyspace] = append(byKeyspace[keyspace], stats)
+	}
+
+	for _, keyspace := range slices.Sorted(maps.Keys(byKeyspace)) {
+		top := byKeyspace[keyspace]
+		slices.SortStableFunc(top, func(a, b *logstats.LogStats) int {
+			return cmp.Compare(b.TotalTime(), a.TotalTime())
+		})
+		if len(top) > n {
+			top = top[:n]
+		}
+
+		if err := querylogzKeyspaceTmpl.Execute(w, keyspace); err != nil {
+			log.Errorf("querylogz: couldn't execute keyspace template: %v", err)
+		}
+		logz.StartHTMLTable(w)
+		if err := querylogzHeaderTmpl.Execute(w, querylogzColumns{}); err != nil {
+			log.Errorf("querylogz: couldn't execute header template: %v", err)
+		}
+		for _, stats := range top {
+			querylogzRow(w, stats, parser, querylogzColumns{}, 0, 0, 0)
+		}
+		logz.EndHTMLTable(w)
+	}
+}
+
+// querylogzFilter selects the records rendered by querylogz, based on the
+// request's query parameters. Records that don't match are skipped before
+// they count against the limit. An empty filter matches every record.
+type querylogzFilter struct {
+	// planFingerprint matches records served by the plan with this fingerprint.
+	planFingerprint string
+	// category matches records classified into this query category.
+	category string
+	// stmtType matches records of this statement type, such as SELECT,
+	// regardless of case.
+	stmtType string
+	// minTablets matches records that contacted at least this many tablets.
+	minTablets uint64
+	// minKeyspaces matches records that touched at least this many
+	// keyspaces.
+	minKeyspaces uint64
+	// minRowsBuffered matches records whose in-memory operators held at
+	// least this many rows at once.
+	minRowsBuffered uint64
+	// minPostProcessing matches records for which vtgate performed at least
+	// this many in-memory post-processing steps.
+	minPostProcessing uint64
+	// table matches records referencing this table, given either with or
+	// without its keyspace qualifier.
+	table string
+	// parser parses the queries to find their tables for the table filter.
+	parser *sqlparser.Parser
+	// truncated matches records whose result was truncated by a LIMIT.
+	truncated bool
+	// recompiled matches records whose plan was rebuilt after an eviction.
+	recompiled bool
+	// scatterNoVindex matches records with a route that scattered because
+	// no vindex could be used.
+	scatterNoVindex bool
+	// minLookups matches records that sent at least this many queries to
+	// lookup vindexes.
+	minLookups uint64
+	// minParseTime matches records that spent at least this long parsing
+	// their query.
+	minParseTime time.Duration
+	// minExaminedRatio matches records that examined at least this many
+	// rows per row returned.
+	minExaminedRatio float64
+	// minDeadlineUsed matches records that used at least this percentage of
+	// the time to their deadline.
+	minDeadlineUsed float64
+	// protocol matches records of clients connected with this protocol,
+	// such as callinfo.ProtocolMySQL.
+	protocol string
+	// app matches records of clients that gave this application name.
+	app string
+	// compression matches records whose results were sent with this
+	// compressor, or uncompressed if it is querylogzNoCompression.
+	compression string
+	// planner matches records whose plan was built by this planner version,
+	// such as Gen4.
+	planner string
+	// routeHint matches records routed by this comment directive, such as
+	// ALLOW_SCATTER.
+	routeHint string
+	// collation matches records run with this collation, such as
+	// utf8mb4_bin.
+	collation string
+	// prepared, when set, matches either the executions of prepared
+	// statements or the ad-hoc queries.
+	prepared *bool
+	// buffered, when set, matches either the records that were buffered
+	// during a failover or the ones that weren't.
+	buffered *bool
+	// reference, when set, matches either the records that read from a
+	// reference table or the ones that didn't.
+	reference *bool
+	// materialized, when set, matches either the records that wrote to the
+	// source of materialized reference tables or the ones that didn't.
+	materialized *bool
+	// deniedTables, when set, matches either the records rejected by a
+	// tablet under a denied tables rule or the ones that weren't.
+	deniedTables *bool
+	// fastPath, when set, matches either the records that took the fast
+	// path to a single unsharded keyspace or targeted shard, or the ones
+	// that didn't.
+	fastPath *bool
+	// onlineDDL, when set, matches either the DDLs submitted as online
+	// schema changes or the other records.
+	onlineDDL *bool
+	// migration matches records that submitted the online schema migration
+	// with this UUID.
+	migration string
+	// failed, when set, matches either the records that failed or the ones
+	// that succeeded, as given by the status parameter.
+	failed *bool
+	// errno matches records that failed with this MySQL error number.
+	errno sqlerror.ErrorCode
+	// annotationKey matches records carrying this annotation and, unless
+	// annotationValue is empty, with this value.
+	annotationKey, annotationValue string
+	// remote matches records of clients connecting from this address,
+	// given either as a host or as a host:port.
+	remote string
+	// excludeInternal hides the internal queries, such as health checks,
+	// see querylogzInternalQueries.
+	excludeInternal bool
+	// skip holds records that were already written, such as the backfilled
+	// ones, so that they aren't written a second time.
+	skip map[*logstats.LogStats]bool
+}
+
+func parseQuerylogzFilter(r *http.Request, parser *sqlparser.Parser) querylogzFilter {
+	query := r.URL.Query()
+	filter := querylogzFilter{
+		planFingerprint: query.Get("plan_fingerprint"),
+		category:        strings.ToUpper(query.Get("category")),
+		stmtType:        query.Get("stmt_type"),
+		table:           query.Get("table"),
+		remote:          query.Get("remote"),
+		protocol:        strings.ToLower(query.Get("protocol")),
+		app:             query.Get("app"),
+		compression:     query.Get("compression"),
+		planner:         query.Get("planner"),
+		routeHint:       query.Get("route_hint"),
+		collation:       query.Get("collation"),
+		migration:       query.Get("migration"),
+		parser:          parser,
+	}
+	if n, err := strconv.ParseUint(query.Get("min_tablets"), 10, 64); err == nil {
+		filter.minTablets = n
+	}
+	if n, err := strconv.ParseUint(query.Get("min_keyspaces"), 10, 64); err == nil {
+		filter.minKeyspaces = n
+	}
+	if n, err := strconv.ParseUint(query.Get("min_rows_buffered"), 10, 64); err == nil {
+		filter.minRowsBuffered = n
+	}
+	if n, err := strconv.ParseUint(query.Get("min_post_processing"), 10, 64); err == nil {
+		filter.minPostProcessing = n
+	}
+	if n, err := strconv.ParseUint(query.Get("min_lookups"), 10, 64); err == nil {
+		filter.minLookups = n
+	}
+	if d, err := time.ParseDuration(query.Get("min_parse_time")); err == nil {
+		filter.minParseTime = d
+	}
+	if t, err := strconv.ParseBool(query.Get("truncated")); err == nil {
+		filter.truncated = t
+	}
+	if rc, err := strconv.ParseBool(query.Get("recompiled")); err == nil {
+		filter.recompiled = rc
+	}
+	if sc, err := strconv.ParseBool(query.Get("scatter_no_vindex")); err == nil {
+		filter.scatterNoVindex = sc
+	}
+	if ratio, err := strconv.ParseFloat(query.Get("min_examined_ratio"), 64); err == nil {
+		filter.minExaminedRatio = ratio
+	}
+	if used, err := strconv.ParseFloat(query.Get("min_deadline_used"), 64); err == nil {
+		filter.minDeadlineUsed = used
+	}
+	if ex, err := strconv.ParseBool(query.Get("excludeInternal")); err == nil {
+		filter.excludeInternal = ex
+	}
+	if p, err := strconv.ParseBool(query.Get("prepared")); err == nil {
+		filter.prepared = &p
+	}
+	if b, err := strconv.ParseBool(query.Get("buffered")); err == nil {
+		filter.buffered = &b
+	}
+	if ref, err := strconv.ParseBool(query.Get("reference")); err == nil {
+		filter.reference = &ref
+	}
+	if m, err := strconv.ParseBool(query.Get("materialized")); err == nil {
+		filter.materialized = &m
+	}
+	if d, err := strconv.ParseBool(query.Get("denied_tables")); err == nil {
+		filter.deniedTables = &d
+	}
+	if fp, err := strconv.ParseBool(query.Get("fast_path")); err == nil {
+		filter.fastPath = &fp
+	}
+	if o, err := strconv.ParseBool(query.Get("online_ddl")); err == nil {
+		filter.onlineDDL = &o
+	}
+	// status is either ok, for the queries that succeeded, or error, for
+	// the ones that failed.
+	switch query.Get("status") {
+	case "ok":
+		failed := false
+		filter.failed = &failed
+	case "error":
+		failed := true
+		filter.failed = &failed
+	}
+	if n, err := strconv.ParseUint(query.Get("errno"), 10, 16); err == nil {
+		filter.errno = sqlerror.ErrorCode(n)
+	}
+	// annotation is either a key, or a key:value pair.
+	filter.annotationKey, filter.annotationValue, _ = strings.Cut(query.Get("annotation"), ":")
+	return filter
+}
+
+func (f querylogzFilter) matches(stats *logstats.LogStats) bool {
+	if f.excludeInternal && querylogzInternalQueries.matches(stats) {
+		return false
+	}
+	if f.skip[stats] {
+		return false
+	}
+	if f.planFingerprint != "" && stats.PlanFingerprint != f.planFingerprint {
+		return false
+	}
+	if f.category != "" && stats.QueryCategory != f.category {
+		return false
+	}
+	if f.stmtType != "" && !strings.EqualFold(stats.StmtType, f.stmtType) {
+		return false
+	}
+	if stats.TabletsContacted < f.minTablets {
+		return false
+	}
+	if stats.KeyspacesTouched < f.minKeyspaces {
+		return false
+	}
+	if stats.RowsBuffered < f.minRowsBuffered {
+		return false
+	}
+	if stats.PostProcessingOps < f.minPostProcessing {
+		return false
+	}
+	if stats.LookupRoundTrips < f.minLookups {
+		return false
+	}
+	if stats.ParseTime < f.minParseTime {
+		return false
+	}
+	if f.table != "" && !slices.ContainsFunc(stats.TargetTables(f.parser), f.matchesTable) {
+		return false
+	}
+	if f.truncated && !stats.Truncated() {
+		return false
+	}
+	if f.recompiled && !stats.PlanRecompiled {
+		return false
+	}
+	if f.scatterNoVindex && !strings.Contains(stats.RoutingReason, engine.RoutingReasonScatterNoVindex) {
+		return false
+	}
+	if f.minExaminedRatio > 0 && stats.ExaminedRatio() < f.minExaminedRatio {
+		return false
+	}
+	if f.minDeadlineUsed > 0 && stats.DeadlineUsed() < f.minDeadlineUsed {
+		return false
+	}
+	if f.protocol != "" && stats.Protocol() != f.protocol {
+		return false
+	}
+	if f.app != "" && stats.ApplicationName() != f.app {
+		return false
+	}
+	if f.compression != "" && querylogzCompression(stats) != f.compression {
+		return false
+	}
+	if f.planner != "" && !strings.EqualFold(stats.PlannerVersion, f.planner) {
+		return false
+	}
+	if f.routeHint != "" && !strings.EqualFold(stats.RouteHint, f.routeHint) {
+		return false
+	}
+	if f.collation != "" && !strings.EqualFold(stats.Collation, f.collation) {
+		return false
+	}
+	if f.prepared != nil && stats.Prepared != *f.prepared {
+		return false
+	}
+	if f.buffered != nil && stats.Buffered != *f.buffered {
+		return false
+	}
+	if f.reference != nil && stats.ReferenceTable != *f.reference {
+		return false
+	}
+	if f.materialized != nil && stats.MaterializedWrite != *f.materialized {
+		return false
+	}
+	if f.deniedTables != nil && stats.DeniedTables != *f.deniedTables {
+		return false
+	}
+	if f.fastPath != nil && stats.FastPath != *f.fastPath {
+		return false
+	}
+	if f.onlineDDL != nil && stats.OnlineDDL != *f.onlineDDL {
+		return false
+	}
+	if f.migration != "" && !slices.Contains(stats.MigrationUUIDs, f.migration) {
+		return false
+	}
+	if f.failed != nil && (stats.Error != nil) != *f.failed {
+		return false
+	}
+	if f.errno != 0 && stats.ErrorNumber() != f.errno {
+		return false
+	}
+	if f.annotationKey != "" {
+		value, ok := stats.Annotations[f.annotationKey]
+		if !ok || (f.annotationValue != "" && value != f.annotationValue) {
+			return false
+		}
+	}
+	if f.remote != "" && !f.matchesRemote(stats) {
+		return false
+	}
+	return true
+}
+
+// querylogzNoCompression is the compression filter of records whose
+// results were sent uncompressed.
+const querylogzNoCompression = "none"
+
+// querylogzCompression returns the compressor the results of stats were
+// sent with, or querylogzNoCompression.
+func querylogzCompression(stats *logstats.LogStats) string {
+	if compression := stats.Compression(); compression != "" {
+		return compression
+	}
+	return querylogzNoCompression
+}
+
+// matchesRemote returns true if the client of stats connected from the
+// address of the filter. A filter without a port matches any port.
+func (f querylogzFilter) matchesRemote(stats *logstats.LogStats) bool {
+	addr, _ := stats.RemoteAddrUsername()
+	if addr == f.remote {
+		return true
+	}
+	host, _, err := net.SplitHostPort(addr)
+	return err == nil && host == f.remote
+}
+
+// matchesTable returns true if table, as returned by TargetTables, is the
+// table of the filter.
+func (f querylogzFilter) matchesTable(table string) bool {
+	return table == f.table || strings.HasSuffix(table, "."+f.table)
+}
+
+// querylogzDetail is a single name/value pair rendered in the Details
+// column of querylogz.
+type querylogzDetail struct {
+	Name  string
+	Value string
+}
+
+// querylogzFormatDuration formats d as a number of seconds or, if humanize
+// is set, as a duration rounded to a tenth of its unit, such as 340µs, 1.2ms
+// or 2.1s.
+func querylogzFormatDuration(d time.Duration, humanize bool) string {
+	if !humanize {
+		return strconv.FormatFloat(d.Seconds(), 'g', -1, 64)
+	}
+	switch {
+	case d >= time.Second:
+		d = d.Round(100 * time.Millisecond)
+	case d >= time.Millisecond:
+		d = d.Round(100 * time.Microsecond)
+	case d >= time.Microsecond:
+		d = d.Round(100 * time.Nanosecond)
+	}
+	return d.String()
+}
+
+// querylogzDetails returns the less common per-query attributes that are
+// worth showing for this record. Attributes are only listed when set, to
+// keep the table narrow for the common case. Times are formatted as by
+// querylogzFormatDuration.
+func querylogzDetails(stats *logstats.LogStats, humanize bool) []querylogzDetail {
+	var details []querylogzDetail
+	if stats.ConnectionSetupTime > 0 {
+		details = append(details, querylogzDetail{"Conn Setup Time", querylogzFormatDuration(stats.ConnectionSetupTime, humanize)})
+	}
+	if stats.ParseTime > 0 {
+		details = append(details, querylogzDetail{"Parse Time", querylogzFormatDuration(stats.ParseTime, humanize)})
+	}
+	if errno := stats.ErrorNumber(); errno != 0 {
+		details = append(details, querylogzDetail{"Error Number", strconv.Itoa(int(errno))})
+	}
+	if stats.PlanFingerprint != "" {
+		details = append(details, querylogzDetail{"Plan Fingerprint", stats.PlanFingerprint})
+	}
+	if stats.RoutingReason != "" {
+		details = append(details, querylogzDetail{"Routing Reason", stats.RoutingReason})
+	}
+	if stats.ReferenceTable {
+		details = append(details, querylogzDetail{"Reference Table", "true"})
+	}
+	if stats.MaterializedWrite {
+		details = append(details, querylogzDetail{"Materialized Write", "true"})
+	}
+	if stats.DeniedTables {
+		details = append(details, querylogzDetail{"Denied Tables", "true"})
+	}
+	if stats.PlannerVersion != "" {
+		details = append(details, querylogzDetail{"Planner", stats.PlannerVersion})
+	}
+	if stats.RouteHint != "" {
+		details = append(details, querylogzDetail{"Route Hint", stats.RouteHint})
+	}
+	if stats.Collation != "" {
+		details = append(details, querylogzDetail{"Collation", stats.Collation})
+	}
+	if stats.FastPath {
+		details = append(details, querylogzDetail{"Fast Path", "true"})
+	}
+	if stats.OnlineDDL {
+		details = append(details, querylogzDetail{"Online DDL", strings.Join(stats.MigrationUUIDs, ", ")})
+	}
+	if stats.KeyspacesTouched > 1 {
+		details = append(details, querylogzDetail{"Keyspaces Touched", strconv.FormatUint(stats.KeyspacesTouched, 10)})
+	}
+	if stats.RowsBuffered > 0 {
+		details = append(details, querylogzDetail{"Rows Buffered", strconv.FormatUint(stats.RowsBuffered, 10)})
+	}
+	if stats.PostProcessingOps > 0 {
+		details = append(details, querylogzDetail{"Post-Processing Ops", strconv.FormatUint(stats.PostProcessingOps, 10)})
+	}
+	if len(stats.BackendConnectionIDs) > 0 {
+		var conns []string
+		for _, shard := range slices.Sorted(maps.Keys(stats.BackendConnectionIDs)) {
+			conns = append(conns, fmt.Sprintf("%s: %d", shard, stats.BackendConnectionIDs[shard]))
+		}
+		details = append(details, querylogzDetail{"Backend Connections", strings.Join(conns, ", ")})
+	}
+	if stats.ResultColumns > 0 {
+		details = append(details, querylogzDetail{"Result Columns", fmt.Sprintf("%d (%s)", stats.ResultColumns, stats.ResultColumnTypes)})
+	}
+	if stats.PlanRecompiled {
+		details = append(details, querylogzDetail{"Plan Recompiled", "true"})
+	}
+	if stats.LookupRoundTrips > 0 {
+		details = append(details,
+			querylogzDetail{"Lookup Round Trips", strconv.FormatUint(stats.LookupRoundTrips, 10)},
+			querylogzDetail{"Lookup Time", querylogzFormatDuration(stats.LookupTime, humanize)},
+		)
+	}
+	if stats.Truncated() {
+		details = append(details, querylogzDetail{"Rows Truncated", strconv.FormatUint(stats.RowsTruncated, 10)})
+	}
+	if protocol := stats.Protocol(); protocol != "" {
+		details = append(details, querylogzDetail{"Protocol", protocol})
+	}
+	if app := stats.ApplicationName(); app != "" {
+		details = append(details, querylogzDetail{"Application", app})
+	}
+	if compression := stats.Compression(); compression != "" {
+		details = append(details, querylogzDetail{"Compression", compression})
+	}
+	if stats.Prepared {
+		details = append(details, querylogzDetail{"Prepared", "true"})
+	}
+	if stats.Buffered {
+		details = append(details, querylogzDetail{"Buffer Time", querylogzFormatDuration(stats.BufferTime, humanize)})
+	}
+	for _, key := range slices.Sorted(maps.Keys(stats.Annotations)) {
+		details = append(details, querylogzDetail{key, stats.Annotations[key]})
+	}
+	if timeout := stats.TimeToDeadline(); timeout != 0 {
+		details = append(details,
+			querylogzDetail{"Time To Deadline", querylogzFormatDuration(timeout, humanize)},
+			querylogzDetail{"Deadline Used", strconv.FormatFloat(stats.DeadlineUsed(), 'f', 0, 64) + "%"},
+		)
+	}
+	if stats.RowsExamined > 0 {
+		details = append(details,
+			querylogzDetail{"Rows Examined", strconv.FormatUint(stats.RowsExamined, 10)},
+			querylogzDetail{"Rows Returned", strconv.FormatUint(stats.RowsReturned, 10)},
+			querylogzDetail{"Examined/Returned", strconv.FormatFloat(stats.ExaminedRatio(), 'f', 1, 64)},
+		)
+	}
+	if stats.RepeatCount > 1 {
+		details = append(details, querylogzDetail{"Repeated", strconv.FormatUint(stats.RepeatCount, 10) + " times"})
+	}
+	return details
+}
+
+// parseQuerylogzFormat returns the name of the format the records are
+// rendered in, as given by the format query parameter. Without it, a client
+// that accepts application/json, such as a scraper, gets the json format,
+// and browsers get the html table.
+func parseQuerylogzFormat(req *http.Request) string {
+	if format := req.URL.Query().Get("format"); format != "" {
+		return format
+	}
+	for _, accept := range strings.Split(req.Header.Get("Accept"), ",") {
+		if mediaType, _, err := mime.ParseMediaType(accept); err == nil && mediaType == "application/json" {
+			return streamlog.QueryLogFormatJSON
+		}
+	}
+	return ""
+}
+
 func parseTimeoutLimitParams(req *http.Request) (time.Duration, int) {
 	timeout := 10
 	limit := 300
@@ -141,6 +1737,39 @@ func parseTimeoutLimitParams(req *http.Request) (time.Duration, int) {
 	return time.Duration(timeout) * time.Second, limit
 }
 
+// parseOffsetParam returns the number of matching records to skip before
+// rendering, as given by the offset query parameter.
+func parseOffsetParam(req *http.Request) int {
+	if o, ok := req.URL.Query()["offset"]; ok {
+		if offset, err := strconv.Atoi(o[0]); err == nil {
+			return adjustValue(offset, 0, 200000)
+		}
+	}
+	return 0
+}
+
+// querylogzPageLinks returns the links to the previous and next pages of
+// the given request. The links are built from a copy of the request's query
+// parameters where only the offset is changed, so that every active filter
+// and display option carries over when navigating. prev is empty on the
+// first page.
+func querylogzPageLinks(r *http.Request, offset, limit int) (prev, next string) {
+	link := func(offset int) string {
+		query := url.Values{}
+		for k, v := range r.URL.Query() {
+			query[k] = slices.Clone(v)
+		}
+		query.Set("offset", strconv.Itoa(offset))
+		u := *r.URL
+		u.RawQuery = query.Encode()
+		return u.RequestURI()
+	}
+	if offset > 0 {
+		prev = link(max(offset-limit, 0))
+	}
+	return prev, link(offset + limit)
+}
+
 func adjustValue(val int, lower int, upper int) int {
 	if val < lower {
 		return lower
@@ -0,0 +1,109 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vtgate
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/google/safehtml/template"
+
+	"vitess.io/vitess/go/vt/log"
+	"vitess.io/vitess/go/vt/vtgate/logstats"
+)
+
+// Default thresholds of the querylogz alert banner. They can be overridden
+// per request with the alert_error_rate and alert_p95 parameters.
+var (
+	// querylogzAlertErrorRate is the percentage of failed buffered queries
+	// above which the banner is shown.
+	querylogzAlertErrorRate = 5.0
+	// querylogzAlertP95 is the 95th percentile total time of the buffered
+	// queries above which the banner is shown.
+	querylogzAlertP95 = time.Second
+)
+
+var querylogzAlertTmpl = template.Must(template.New("alert").Parse(`
+<div style="background-color: #ff3300; color: white; font-weight: bold; padding: 8px; margin-bottom: 8px">
+	{{range .}}<p>{{.}}</p>{{end}}
+</div>
+`))
+
+// querylogzAlertThresholds are the thresholds checked by querylogzAlerts.
+// A zero threshold is not checked.
+type querylogzAlertThresholds struct {
+	errorRate float64
+	p95       time.Duration
+}
+
+// parseQuerylogzAlertThresholds returns the default thresholds, overridden
+// by the alert_error_rate and alert_p95 parameters of r.
+func parseQuerylogzAlertThresholds(r *http.Request) (querylogzAlertThresholds, error) {
+	thresholds := querylogzAlertThresholds{errorRate: querylogzAlertErrorRate, p95: querylogzAlertP95}
+	if v := r.URL.Query().Get("alert_error_rate"); v != "" {
+		rate, err := strconv.ParseFloat(v, 64)
+		if err != nil || rate < 0 {
+			return thresholds, fmt.Errorf("invalid alert_error_rate %q, must be a non-negative percentage", v)
+		}
+		thresholds.errorRate = rate
+	}
+	if v := r.URL.Query().Get("alert_p95"); v != "" {
+		p95, err := time.ParseDuration(v)
+		if err != nil || p95 < 0 {
+			return thresholds, fmt.Errorf("invalid alert_p95 %q, must be a non-negative duration", v)
+		}
+		thresholds.p95 = p95
+	}
+	return thresholds, nil
+}
+
+// querylogzAlerts renders a banner listing the thresholds exceeded by the
+// records matching filter, if any.
+func querylogzAlerts(w http.ResponseWriter, records []*logstats.LogStats, filter querylogzFilter, thresholds querylogzAlertThresholds) {
+	var failed int
+	var durations []time.Duration
+	for _, stats := range records {
+		if !filter.matches(stats) {
+			continue
+		}
+		if stats.Error != nil {
+			failed++
+		}
+		durations = append(durations, stats.TotalTime())
+	}
+	if len(durations) == 0 {
+		return
+	}
+
+	var alerts []string
+	if rate := 100 * float64(failed) / float64(len(durations)); thresholds.errorRate > 0 && rate > thresholds.errorRate {
+		alerts = append(alerts, fmt.Sprintf("Error rate %.1f%% is above %g%% (%d of %d buffered queries failed)", rate, thresholds.errorRate, failed, len(durations)))
+	}
+	slices.Sort(durations)
+	if p95 := percentile(durations, 0.95); thresholds.p95 > 0 && p95 > thresholds.p95 {
+		alerts = append(alerts, fmt.Sprintf("P95 latency %v is above %v over %d buffered queries", p95, thresholds.p95, len(durations)))
+	}
+	if len(alerts) == 0 {
+		return
+	}
+	if err := querylogzAlertTmpl.Execute(w, alerts); err != nil {
+		log.Errorf("querylogz: couldn't execute alert template: %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vtgate
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"vitess.io/vitess/go/streamlog"
+	"vitess.io/vitess/go/vt/sqlparser"
+	"vitess.io/vitess/go/vt/vtgate/logstats"
+)
+
+func TestQuerylogzHandlerAlerts(t *testing.T) {
+	ring := newQueryLogRing(20)
+	for i := range 20 {
+		logStats := logstats.NewLogStats(context.Background(), "Execute", "select 1", "suuid", nil, streamlog.NewQueryLogConfigForTest())
+		logStats.EndTime = logStats.StartTime.Add(time.Duration(i+1) * 100 * time.Millisecond)
+		if i < 2 {
+			logStats.Error = errors.New("failed")
+		}
+		ring.add(logStats)
+	}
+	serve := func(path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", path, nil)
+		response := httptest.NewRecorder()
+		querylogzHandler(make(chan *logstats.LogStats), ring, response, req, sqlparser.NewTestParser())
+		return response
+	}
+	page := func(path string) string {
+		body, _ := io.ReadAll(serve(path).Body)
+		return string(body)
+	}
+
+	// 10% of the queries failed, and the p95 is 1.9s: both are above the
+	// default thresholds.
+	body := page("/querylogz?timeout=0&limit=1")
+	assert.Contains(t, body, "Error rate 10.0% is above 5% (2 of 20 buffered queries failed)")
+	assert.Contains(t, body, "P95 latency 1.9s is above 1s over 20 buffered queries")
+
+	body = page("/querylogz?timeout=0&limit=1&alert_error_rate=20&alert_p95=2s")
+	assert.NotContains(t, body, "Error rate")
+	assert.NotContains(t, body, "P95 latency")
+
+	// A zero threshold is not checked.
+	body = page("/querylogz?timeout=0&limit=1&alert_error_rate=0&alert_p95=1.5s")
+	assert.NotContains(t, body, "Error rate")
+	assert.Contains(t, body, "P95 latency 1.9s is above 1.5s")
+
+	// The banner only considers the queries matching the filters.
+	body = page("/querylogz?timeout=0&limit=1&category=OLAP")
+	assert.NotContains(t, body, "Error rate")
+
+	assert.Equal(t, http.StatusBadRequest, serve("/querylogz?alert_p95=fast").Code)
+	assert.Equal(t, http.StatusBadRequest, serve("/querylogz?alert_error_rate=-1").Code)
+}
@@ -0,0 +1,59 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vtgate
+
+import (
+	"slices"
+	"time"
+
+	"vitess.io/vitess/go/vt/sqlparser"
+	"vitess.io/vitess/go/vt/vtgate/logstats"
+)
+
+// querylogzMinShapeSamples is the number of buffered queries a shape needs
+// before its p95 is used to flag anomalies. Below it, the p95 is little more
+// than the slowest query seen so far.
+const querylogzMinShapeSamples = 5
+
+// querylogzShapeP95s returns the 95th percentile total time of each query
+// shape, as returned by querylogzShape, over records. Shapes with fewer than
+// querylogzMinShapeSamples records are left out. Every record is parsed, so
+// the cost is bounded by the size of the buffer.
+func querylogzShapeP95s(records []*logstats.LogStats, parser *sqlparser.Parser) map[string]time.Duration {
+	durations := map[string][]time.Duration{}
+	for _, stats := range records {
+		shape := querylogzShape(parser, stats)
+		durations[shape] = append(durations[shape], stats.TotalTime())
+	}
+	p95s := make(map[string]time.Duration, len(durations))
+	for shape, samples := range durations {
+		if len(samples) < querylogzMinShapeSamples {
+			continue
+		}
+		slices.Sort(samples)
+		p95s[shape] = percentile(samples, 0.95)
+	}
+	return p95s
+}
+
+// querylogzShapeAnomaly returns the p95 of the shape of stats, and whether
+// stats took longer than it. Such queries are slow relative to their own
+// norm, even when their absolute latency looks fine.
+func querylogzShapeAnomaly(p95s map[string]time.Duration, parser *sqlparser.Parser, stats *logstats.LogStats) (time.Duration, bool) {
+	p95, ok := p95s[querylogzShape(parser, stats)]
+	return p95, ok && stats.TotalTime() > p95
+}
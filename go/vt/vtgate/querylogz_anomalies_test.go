@@ -0,0 +1,83 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vtgate
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"vitess.io/vitess/go/streamlog"
+	"vitess.io/vitess/go/vt/sqlparser"
+	"vitess.io/vitess/go/vt/vtgate/logstats"
+)
+
+func TestQuerylogzShapeAnomalies(t *testing.T) {
+	parser := sqlparser.NewTestParser()
+	newStats := func(sql string, d time.Duration) *logstats.LogStats {
+		logStats := logstats.NewLogStats(context.Background(), "Execute", sql, "suuid", nil, streamlog.NewQueryLogConfigForTest())
+		logStats.EndTime = logStats.StartTime.Add(d)
+		return logStats
+	}
+	ring := newQueryLogRing(20)
+	for i := 1; i <= 10; i++ {
+		ring.add(newStats("select * from t where id = 1", time.Duration(i)*time.Millisecond))
+	}
+	// Too few samples to have a p95.
+	ring.add(newStats("select * from u", time.Millisecond))
+
+	p95s := querylogzShapeP95s(ring.snapshot(), parser)
+	assert.Len(t, p95s, 1)
+	p95, anomaly := querylogzShapeAnomaly(p95s, parser, newStats("select * from t where id = 2", 11*time.Millisecond))
+	assert.Equal(t, 10*time.Millisecond, p95)
+	assert.True(t, anomaly)
+	_, anomaly = querylogzShapeAnomaly(p95s, parser, newStats("select * from t where id = 3", 9*time.Millisecond))
+	assert.False(t, anomaly)
+	_, anomaly = querylogzShapeAnomaly(p95s, parser, newStats("select * from u", time.Second))
+	assert.False(t, anomaly)
+
+	render := func(url string, records ...*logstats.LogStats) string {
+		req, _ := http.NewRequest("GET", url, nil)
+		response := httptest.NewRecorder()
+		ch := make(chan *logstats.LogStats, len(records))
+		for _, stats := range records {
+			ch <- stats
+		}
+		querylogzHandler(ch, ring, response, req, parser)
+		close(ch)
+		body, _ := io.ReadAll(response.Body)
+		return string(body)
+	}
+	slow := newStats("select * from t where id = 4", 20*time.Millisecond)
+	fast := newStats("select * from t where id = 5", 2*time.Millisecond)
+
+	body := render("/querylogz?timeout=1&limit=2&anomalies=1", slow, fast)
+	assert.Equal(t, 1, strings.Count(body, "outline: 2px dashed purple"), body)
+	assert.Contains(t, body, "Above Shape P95: 0.01<br>")
+	row, _, _ := strings.Cut(body[strings.Index(body, "dashed purple"):], "</tr>")
+	assert.Contains(t, row, "id = 4")
+
+	body = render("/querylogz?timeout=1&limit=2", slow, fast)
+	assert.NotContains(t, body, "dashed purple")
+	assert.NotContains(t, body, "Above Shape P95")
+}
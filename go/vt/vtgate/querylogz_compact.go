@@ -0,0 +1,126 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vtgate
+
+import (
+	"net/http"
+
+	"github.com/google/safehtml/template"
+
+	"vitess.io/vitess/go/vt/log"
+	"vitess.io/vitess/go/vt/logz"
+)
+
+var (
+	querylogzCompactStartTmpl = template.Must(template.New("compactStart").Parse(`<!DOCTYPE html>
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<style type="text/css">
+	div.cards {
+		font-family: verdana,arial,sans-serif;
+		font-size: 13px;
+		max-width: 40em;
+	}
+	div.card {
+		border: 1px solid #999999;
+		border-radius: 4px;
+		margin: 0 0 8px 0;
+		padding: 6px 8px;
+	}
+	div.card.low {
+		background-color: #f0f0f0;
+	}
+	div.card.medium {
+		background-color: #ffcc00;
+	}
+	div.card.high {
+		background-color: #ff3300;
+	}
+	div.card.error {
+		background-color: #00ddff;
+	}
+	div.card div.sql {
+		font-family: monospace;
+		overflow-wrap: anywhere;
+		margin: 4px 0;
+	}
+	div.card div.error {
+		font-weight: bold;
+		overflow-wrap: anywhere;
+	}
+</style>
+<div class="cards">
+`))
+	querylogzCardTmpl = template.Must(template.New("card").Funcs(querylogzFuncMap).Parse(`
+	<div class="card {{.ColorLevel}}"{{if .Pin}} style="outline: 2px solid orange"{{else if .Anomaly}} style="outline: 2px dashed purple"{{end}}>
+		<div>{{if and .ShowIndex .Index}}<a id="{{.RowID}}" href="#row-{{.Index}}">#{{.Index}}</a> {{end}}<b>{{.Method}}</b> {{duration .TotalTime .Humanize}}{{if not .Humanize}}s{{end}}</div>
+		<div class="sql">{{.SQL | .Parser.TruncateForUI | unquote}}</div>
+		{{if .ErrorStr}}<div class="error">{{.ErrorStr}}</div>{{end}}
+	</div>
+`))
+	// querylogzStickyStartTmpl wraps the table in a scrollable box whose
+	// header row stays visible. The header cells keep their opaque
+	// background so that the color-coded rows scroll underneath them.
+	querylogzStickyStartTmpl = template.Must(template.New("stickyStart").Parse(`<style type="text/css">
+	div.sticky {
+		max-height: 85vh;
+		overflow: auto;
+	}
+	div.sticky table.gridtable {
+		overflow: visible;
+	}
+	div.sticky table.gridtable thead th {
+		position: sticky;
+		top: 0;
+		z-index: 1;
+	}
+</style>
+<div class="sticky">
+`))
+)
+
+// querylogzStartTable writes the start of a querylogz table, or of a list
+// of cards, one per query, in compact mode. Cards are stacked vertically so
+// that the log stays readable on a narrow screen, such as a phone.
+func querylogzStartTable(w http.ResponseWriter, columns querylogzColumns) {
+	if columns.Compact {
+		if err := querylogzCompactStartTmpl.Execute(w, nil); err != nil {
+			log.Errorf("querylogz: couldn't execute compact template: %v", err)
+		}
+		return
+	}
+	if columns.Sticky {
+		if err := querylogzStickyStartTmpl.Execute(w, nil); err != nil {
+			log.Errorf("querylogz: couldn't execute sticky template: %v", err)
+		}
+	}
+	logz.StartHTMLTable(w)
+	if err := querylogzHeaderTmpl.Execute(w, columns); err != nil {
+		log.Errorf("querylogz: couldn't execute header template: %v", err)
+	}
+}
+
+// querylogzEndTable writes the end of a table started by querylogzStartTable.
+func querylogzEndTable(w http.ResponseWriter, columns querylogzColumns) {
+	if columns.Compact {
+		w.Write([]byte("</div>\n"))
+		return
+	}
+	logz.EndHTMLTable(w)
+	if columns.Sticky {
+		w.Write([]byte("</div>\n"))
+	}
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vtgate
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"vitess.io/vitess/go/streamlog"
+	"vitess.io/vitess/go/vt/sqlparser"
+	"vitess.io/vitess/go/vt/vtgate/logstats"
+)
+
+func TestQuerylogzHandlerCompact(t *testing.T) {
+	render := func(path string) string {
+		logStats := logstats.NewLogStats(context.Background(), "Execute", "select name from t where id = 1", "suuid", nil, streamlog.NewQueryLogConfigForTest())
+		logStats.EndTime = logStats.StartTime.Add(200 * time.Millisecond)
+		logStats.Error = errors.New("table t not found")
+		ch := make(chan *logstats.LogStats, 1)
+		ch <- logStats
+		req, _ := http.NewRequest("GET", path, nil)
+		response := httptest.NewRecorder()
+		querylogzHandler(ch, newQueryLogRing(1), response, req, sqlparser.NewTestParser())
+		body, _ := io.ReadAll(response.Body)
+		return string(body)
+	}
+
+	page := render("/querylogz?timeout=1&limit=1&compact=1")
+	assert.Contains(t, page, `<meta name="viewport"`)
+	assert.Contains(t, page, `<div class="card error">`)
+	assert.Contains(t, page, "<div><b>Execute</b> 0.2s</div>")
+	assert.Contains(t, page, `<div class="sql">select name from t where id = 1</div>`)
+	assert.Contains(t, page, `<div class="error">table t not found</div>`)
+	assert.NotContains(t, page, "<table")
+
+	// The wide table stays the default.
+	page = render("/querylogz?timeout=1&limit=1")
+	assert.Contains(t, page, `<table class="gridtable">`)
+	assert.NotContains(t, page, `class="card`)
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vtgate
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"vitess.io/vitess/go/sqltypes"
+	"vitess.io/vitess/go/streamlog"
+	"vitess.io/vitess/go/vt/sqlparser"
+
+	querypb "vitess.io/vitess/go/vt/proto/query"
+)
+
+// querylogzTemplate is a query reduced to its normalized shape, with the
+// values of its literals and bind variables listed by position. Queries
+// that only differ in their values share the same shape.
+type querylogzTemplate struct {
+	shape  string
+	values []string
+	// names holds, by position, the name of the bind variable the value
+	// was looked up in, or "" for a literal.
+	names []string
+}
+
+// newQuerylogzTemplate normalizes sql and collects the values of its bind
+// variables in the order they appear. Values that were already bound, as
+// in the normalized SQL vtgate logs, are looked up in bindVars, so that a
+// logged query and a literal reference query line up position by position.
+func newQuerylogzTemplate(parser *sqlparser.Parser, sql string, bindVars map[string]*querypb.BindVariable) (*querylogzTemplate, error) {
+	stmt, reservedVars, err := parser.Parse2(sql)
+	if err != nil {
+		return nil, err
+	}
+	normalizedVars := map[string]*querypb.BindVariable{}
+	out, err := sqlparser.Normalize(stmt, sqlparser.NewReservedVars("v", reservedVars), normalizedVars, true, "ks", 0, "", map[string]string{}, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl := &querylogzTemplate{}
+	add := func(name string) {
+		value, bindVar := ":"+name, ""
+		if bv, ok := normalizedVars[name]; ok {
+			value = formatQuerylogzBindVar(bv)
+		} else if bv, ok := bindVars[name]; ok {
+			value, bindVar = formatQuerylogzBindVar(bv), name
+		}
+		tmpl.values = append(tmpl.values, value)
+		tmpl.names = append(tmpl.names, bindVar)
+	}
+	ast := sqlparser.SafeRewrite(out.AST, nil, func(cursor *sqlparser.Cursor) bool {
+		switch node := cursor.Node().(type) {
+		case *sqlparser.Argument:
+			add(node.Name)
+			cursor.Replace(sqlparser.NewArgument("p" + strconv.Itoa(len(tmpl.values))))
+		case sqlparser.ListArg:
+			add(string(node))
+			cursor.Replace(sqlparser.NewListArg("p" + strconv.Itoa(len(tmpl.values))))
+		}
+		return true
+	})
+	tmpl.shape = sqlparser.String(ast)
+	return tmpl, nil
+}
+
+// diff describes how other differs from the reference tmpl. For the values
+// config redacts, only whether they differ is reported, not the values
+// themselves.
+func (tmpl *querylogzTemplate) diff(other *querylogzTemplate, config streamlog.QueryLogConfig) string {
+	if tmpl.shape != other.shape {
+		return "different shape"
+	}
+	var diffs []string
+	for i, want := range tmpl.values {
+		if got := other.values[i]; got != want {
+			if config.RedactDebugUIQueries || config.ShouldRedactBindVar(other.names[i]) {
+				diffs = append(diffs, fmt.Sprintf("#%d", i+1))
+			} else {
+				diffs = append(diffs, fmt.Sprintf("#%d: %s -> %s", i+1, want, got))
+			}
+		}
+	}
+	if len(diffs) == 0 {
+		return "identical"
+	}
+	return strings.Join(diffs, ", ")
+}
+
+// querylogzDiff describes how sql, with its bind variables, differs from
+// the reference template, position by position.
+func querylogzDiff(reference *querylogzTemplate, parser *sqlparser.Parser, sql string, bindVars map[string]*querypb.BindVariable, config streamlog.QueryLogConfig) string {
+	tmpl, err := newQuerylogzTemplate(parser, sql, bindVars)
+	if err != nil {
+		return "unparsable"
+	}
+	return reference.diff(tmpl, config)
+}
+
+func formatQuerylogzBindVar(bv *querypb.BindVariable) string {
+	var b strings.Builder
+	if bv.Type != querypb.Type_TUPLE {
+		sqltypes.MakeTrusted(bv.Type, bv.Value).EncodeSQLStringBuilder(&b)
+		return b.String()
+	}
+	b.WriteByte('(')
+	for i, v := range bv.Values {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		sqltypes.MakeTrusted(v.Type, v.Value).EncodeSQLStringBuilder(&b)
+	}
+	b.WriteByte(')')
+	return b.String()
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vtgate
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vitess.io/vitess/go/sqltypes"
+	"vitess.io/vitess/go/streamlog"
+	"vitess.io/vitess/go/vt/sqlparser"
+	"vitess.io/vitess/go/vt/vtgate/logstats"
+
+	querypb "vitess.io/vitess/go/vt/proto/query"
+)
+
+func TestQuerylogzTemplateDiff(t *testing.T) {
+	parser := sqlparser.NewTestParser()
+	reference, err := newQuerylogzTemplate(parser, "select a from t where id = 5 and name = 'x' and k in (1, 2)", nil)
+	require.NoError(t, err)
+
+	tcases := []struct {
+		sql      string
+		bindVars map[string]*querypb.BindVariable
+		want     string
+	}{{
+		sql:  "select a from t where id = 5 and name = 'x' and k in (1, 2)",
+		want: "identical",
+	}, {
+		sql:  "select a from t where id = 7 and name = 'x' and k in (1, 3)",
+		want: "#1: 5 -> 7, #3: (1, 2) -> (1, 3)",
+	}, {
+		// Normalized queries are compared through their bind variables.
+		sql: "select a from t where id = :vtg1 and name = :vtg2 and k in ::vtg3",
+		bindVars: map[string]*querypb.BindVariable{
+			"vtg1": sqltypes.Int64BindVariable(5),
+			"vtg2": sqltypes.StringBindVariable("y"),
+			"vtg3": sqltypes.TestBindVariable([]any{1, 2}),
+		},
+		want: "#2: 'x' -> 'y'",
+	}, {
+		sql:  "select a from t where id = 5",
+		want: "different shape",
+	}, {
+		sql:  "select a from",
+		want: "unparsable",
+	}}
+	for _, tcase := range tcases {
+		t.Run(tcase.sql, func(t *testing.T) {
+			assert.Equal(t, tcase.want, querylogzDiff(reference, parser, tcase.sql, tcase.bindVars, streamlog.NewQueryLogConfigForTest()))
+		})
+	}
+
+	// Redacted queries only report the positions that differ.
+	config := streamlog.NewQueryLogConfigForTest()
+	config.RedactDebugUIQueries = true
+	assert.Equal(t, "#1", querylogzDiff(reference, parser, "select a from t where id = 7 and name = 'x' and k in (1, 2)", nil, config))
+
+	// So do the bind variables whose values are redacted.
+	config = streamlog.NewQueryLogConfigForTest()
+	config.RedactBindVars = regexp.MustCompile("vtg2")
+	assert.Equal(t, "#1: 5 -> 7, #2", querylogzDiff(reference, parser, "select a from t where id = :vtg1 and name = :vtg2 and k in ::vtg3", map[string]*querypb.BindVariable{
+		"vtg1": sqltypes.Int64BindVariable(7),
+		"vtg2": sqltypes.StringBindVariable("secret"),
+		"vtg3": sqltypes.TestBindVariable([]any{1, 2}),
+	}, config))
+}
+
+func TestQuerylogzHandlerDiff(t *testing.T) {
+	logStats := logstats.NewLogStats(context.Background(), "Execute", "select a from t where id = :vtg1", "suuid", map[string]*querypb.BindVariable{
+		"vtg1": sqltypes.Int64BindVariable(7),
+	}, streamlog.NewQueryLogConfigForTest())
+	logStats.EndTime = logStats.StartTime.Add(1 * time.Millisecond)
+
+	render := func(reference string) (int, string) {
+		req, _ := http.NewRequest("GET", "/querylogz?timeout=1&limit=1&diff="+url.QueryEscape(reference), nil)
+		response := httptest.NewRecorder()
+		ch := make(chan *logstats.LogStats, 1)
+		ch <- logStats
+		querylogzHandler(ch, nil, response, req, sqlparser.NewTestParser())
+		close(ch)
+		body, _ := io.ReadAll(response.Body)
+		return response.Code, string(body)
+	}
+
+	code, page := render("select a from t where id = 5")
+	require.Equal(t, http.StatusOK, code)
+	if !strings.Contains(page, "<th>Diff</th>") || !strings.Contains(page, "<td>#1: 5 -&gt; 7</td>") {
+		t.Fatalf("querylogz did not diff the query against the reference: %s", page)
+	}
+
+	code, _ = render("select from")
+	require.Equal(t, http.StatusBadRequest, code)
+}
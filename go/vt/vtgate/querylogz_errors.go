@@ -0,0 +1,89 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vtgate
+
+import (
+	"cmp"
+	"maps"
+	"net/http"
+	"slices"
+
+	"github.com/google/safehtml/template"
+
+	"vitess.io/vitess/go/vt/log"
+	"vitess.io/vitess/go/vt/logz"
+	"vitess.io/vitess/go/vt/sqlparser"
+	"vitess.io/vitess/go/vt/vtgate/errorsanitizer"
+	"vitess.io/vitess/go/vt/vtgate/logstats"
+)
+
+var querylogzErrorsTmpl = template.Must(template.New("errors").Parse(`
+		<thead>
+			<tr>
+				<th>Error</th>
+				<th>Count</th>
+				<th>Example Query</th>
+			</tr>
+		</thead>
+		{{range .}}
+		<tr>
+			<td>{{.Error}}</td>
+			<td>{{.Count}}</td>
+			<td>{{.Example}}</td>
+		</tr>
+		{{end}}
+	`))
+
+type querylogzErrorRow struct {
+	Error   string
+	Count   int
+	Example string
+}
+
+// querylogzErrors renders the failed records matching filter grouped by
+// their error message, the most frequent errors first, up to limit errors.
+// Messages are normalized with errorsanitizer, so that errors that differ
+// only by their values, such as duplicate keys, are counted together. Each
+// error shows the most recent query that failed with it.
+func querylogzErrors(w http.ResponseWriter, records []*logstats.LogStats, filter querylogzFilter, limit int, parser *sqlparser.Parser) {
+	errors := map[string]*querylogzErrorRow{}
+	for _, stats := range records {
+		if stats.Error == nil || !filter.matches(stats) {
+			continue
+		}
+		msg := errorsanitizer.NormalizeError(stats.Error.Error())
+		row := errors[msg]
+		if row == nil {
+			row = &querylogzErrorRow{Error: msg}
+			errors[msg] = row
+		}
+		row.Count++
+		row.Example = parser.TruncateForUI(stats.SQL)
+	}
+
+	rows := slices.Collect(maps.Values(errors))
+	slices.SortFunc(rows, func(a, b *querylogzErrorRow) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Error, b.Error))
+	})
+	rows = rows[:min(len(rows), limit)]
+
+	logz.StartHTMLTable(w)
+	defer logz.EndHTMLTable(w)
+	if err := querylogzErrorsTmpl.Execute(w, rows); err != nil {
+		log.Errorf("querylogz: couldn't execute errors template: %v", err)
+	}
+}
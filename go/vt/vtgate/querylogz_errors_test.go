@@ -0,0 +1,70 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vtgate
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"vitess.io/vitess/go/streamlog"
+	"vitess.io/vitess/go/vt/sqlparser"
+	"vitess.io/vitess/go/vt/vtgate/logstats"
+)
+
+func TestQuerylogzHandlerErrors(t *testing.T) {
+	ring := newQueryLogRing(10)
+	add := func(sql, category string, err error) {
+		logStats := logstats.NewLogStats(context.Background(), "Execute", sql, "suuid", nil, streamlog.NewQueryLogConfigForTest())
+		logStats.QueryCategory = category
+		logStats.Error = err
+		ring.add(logStats)
+	}
+	add("insert into t values (1)", "", errors.New("Duplicate entry '1' for key 'PRIMARY'"))
+	add("select * from t", "", nil)
+	add("select * from missing", "OLAP", errors.New("table missing not found"))
+	add("insert into t values (2)", "", errors.New("Duplicate entry '2' for key 'PRIMARY'"))
+
+	render := func(url string) string {
+		req, _ := http.NewRequest("GET", url, nil)
+		response := httptest.NewRecorder()
+		querylogzHandler(make(chan *logstats.LogStats), ring, response, req, sqlparser.NewTestParser())
+		body, _ := io.ReadAll(response.Body)
+		return string(body)
+	}
+
+	// Errors that differ only by their values are grouped, the most
+	// frequent first, with the most recent query as the example.
+	page := render("/querylogz?view=errors")
+	want := `<td>Duplicate entry &#39;&lt;val&gt;&#39; for key &#39;PRIMARY&#39;</td>\s*<td>2</td>\s*<td>insert into t values \(2\)</td>` +
+		`[\s\S]*<td>table missing not found</td>\s*<td>1</td>\s*<td>select \* from missing</td>`
+	assert.Regexp(t, regexp.MustCompile(want), page)
+	assert.NotContains(t, page, "<td>select * from t</td>")
+
+	page = render("/querylogz?view=errors&limit=1")
+	assert.NotContains(t, page, "table missing not found")
+
+	page = render("/querylogz?view=errors&category=OLAP")
+	assert.Contains(t, page, "table missing not found")
+	assert.NotContains(t, page, "Duplicate entry")
+}
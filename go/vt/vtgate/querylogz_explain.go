@@ -0,0 +1,118 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vtgate
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+
+	"vitess.io/vitess/go/vt/sqlparser"
+	"vitess.io/vitess/go/vt/vtgate/logstats"
+)
+
+// querylogzFormatExplain is the querylogz format that generates a script of
+// EXPLAIN statements, one per query shape among the buffered records, for
+// an operator to audit the plans of the workload on another server.
+const querylogzFormatExplain = "explain"
+
+// querylogzExplainFilename is the name the explain script is downloaded as.
+const querylogzExplainFilename = "querylogz-explain.sql"
+
+// querylogzExplainRow is a query shape of the explain script.
+type querylogzExplainRow struct {
+	shape string
+	count int
+	// sample is the most recent record of the shape.
+	sample *logstats.LogStats
+}
+
+// writeQuerylogzExplain writes an EXPLAIN statement for each of the limit
+// most frequent shapes of the records matching filter, most frequent first.
+// Each statement explains the most recent query of its shape, with its bind
+// variables substituted so that it can be run as is, unless the query log
+// redacts queries, in which case the shape is explained with placeholders
+// for the values. Statements that can't be explained, such as SET or
+// BEGIN, are left out. The statements are only generated, never executed.
+func writeQuerylogzExplain(w io.Writer, records []*logstats.LogStats, filter querylogzFilter, limit int, parser *sqlparser.Parser) {
+	shapes := map[string]*querylogzExplainRow{}
+	for _, stats := range records {
+		if !filter.matches(stats) {
+			continue
+		}
+		stmt, err := parser.Parse(stats.SQL)
+		if err != nil || !querylogzExplainable(stmt) {
+			continue
+		}
+		shape := querylogzShape(parser, stats)
+		row := shapes[shape]
+		if row == nil {
+			row = &querylogzExplainRow{shape: shape}
+			shapes[shape] = row
+		}
+		row.count++
+		row.sample = stats
+	}
+
+	rows := make([]*querylogzExplainRow, 0, len(shapes))
+	for _, row := range shapes {
+		rows = append(rows, row)
+	}
+	slices.SortFunc(rows, func(a, b *querylogzExplainRow) int {
+		return cmp.Or(cmp.Compare(b.count, a.count), cmp.Compare(a.shape, b.shape))
+	})
+	rows = rows[:min(len(rows), limit)]
+
+	fmt.Fprintf(w, "-- EXPLAIN statements for %d query shapes of the vtgate query log.\n", len(rows))
+	for _, row := range rows {
+		fmt.Fprintf(w, "\n-- %d queries: %s\n", row.count, row.shape)
+		fmt.Fprintf(w, "EXPLAIN %s;\n", querylogzExplainSQL(parser, row.sample, row.shape))
+	}
+}
+
+// querylogzExplainable returns whether MySQL can EXPLAIN stmt.
+func querylogzExplainable(stmt sqlparser.Statement) bool {
+	switch stmt.(type) {
+	case sqlparser.SelectStatement, *sqlparser.Insert, *sqlparser.Update, *sqlparser.Delete:
+		return true
+	}
+	return false
+}
+
+// querylogzExplainSQL returns the query of stats with its bind variables
+// substituted, or shape if the query log redacts queries or any of the
+// values, or the values can't be substituted.
+func querylogzExplainSQL(parser *sqlparser.Parser, stats *logstats.LogStats, shape string) string {
+	if stats.Config.RedactDebugUIQueries {
+		return shape
+	}
+	for name := range stats.BindVariables {
+		if stats.Config.ShouldRedactBindVar(name) {
+			return shape
+		}
+	}
+	stmt, err := parser.Parse(stats.SQL)
+	if err != nil {
+		return shape
+	}
+	sql, err := sqlparser.NewParsedQuery(stmt).GenerateQuery(stats.BindVariables, nil)
+	if err != nil {
+		return shape
+	}
+	return sql
+}
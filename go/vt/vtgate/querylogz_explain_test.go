@@ -0,0 +1,85 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vtgate
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"vitess.io/vitess/go/sqltypes"
+	"vitess.io/vitess/go/streamlog"
+	querypb "vitess.io/vitess/go/vt/proto/query"
+	"vitess.io/vitess/go/vt/sqlparser"
+	"vitess.io/vitess/go/vt/vtgate/logstats"
+)
+
+func TestQuerylogzHandlerExplain(t *testing.T) {
+	newRing := func(config streamlog.QueryLogConfig) *queryLogRing {
+		ring := newQueryLogRing(10)
+		add := func(sql string, id int64) {
+			bindVars := map[string]*querypb.BindVariable{"vtg1": sqltypes.Int64BindVariable(id)}
+			ring.add(logstats.NewLogStats(context.Background(), "Execute", sql, "suuid", bindVars, config))
+		}
+		add("select * from t where id = :vtg1", 1)
+		add("insert into t(id) values (:vtg1)", 2)
+		add("select * from t where id = :vtg1", 3)
+		add("set @x = :vtg1", 4)
+		return ring
+	}
+	explain := func(ring *queryLogRing) (*httptest.ResponseRecorder, string) {
+		req, _ := http.NewRequest("GET", "/querylogz?format=explain", nil)
+		response := httptest.NewRecorder()
+		querylogzHandler(make(chan *logstats.LogStats), ring, response, req, sqlparser.NewTestParser())
+		body, _ := io.ReadAll(response.Body)
+		return response, string(body)
+	}
+
+	// The most recent query of each shape is explained, most frequent shape
+	// first, and statements that can't be explained are left out.
+	response, body := explain(newRing(streamlog.NewQueryLogConfigForTest()))
+	want := `-- EXPLAIN statements for 2 query shapes of the vtgate query log.
+
+-- 2 queries: select * from t where id = :p1
+EXPLAIN select * from t where id = 3;
+
+-- 1 queries: insert into t(id) values (:p1)
+EXPLAIN insert into t(id) values (2);
+`
+	assert.Equal(t, want, body)
+	assert.Equal(t, "text/plain; charset=utf-8", response.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="querylogz-explain.sql"`, response.Header().Get("Content-Disposition"))
+
+	// Values are not disclosed when the query log redacts queries.
+	config := streamlog.NewQueryLogConfigForTest()
+	config.RedactDebugUIQueries = true
+	_, body = explain(newRing(config))
+	assert.Contains(t, body, "EXPLAIN select * from t where id = :p1;\n")
+	assert.NotContains(t, body, "id = 3")
+
+	// Nor when it redacts any of the bind variables of the query.
+	config = streamlog.NewQueryLogConfigForTest()
+	config.RedactBindVars = regexp.MustCompile("vtg1")
+	_, body = explain(newRing(config))
+	assert.Contains(t, body, "EXPLAIN select * from t where id = :p1;\n")
+	assert.NotContains(t, body, "id = 3")
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vtgate
+
+import (
+	"regexp"
+	"strings"
+
+	"vitess.io/vitess/go/vt/vtgate/logstats"
+)
+
+// defaultQuerylogzInternalQueries match the health checks and monitoring
+// probes most clients and tools send.
+var defaultQuerylogzInternalQueries = []string{
+	`(?i)^select\s+1(\s+from\s+dual)?$`,
+	`(?i)^select\s+@@version(_comment)?(\s+limit\s+1)?$`,
+	`(?i)^show\s+(global\s+|session\s+)?(status|variables)\b`,
+	`(?i)^show\s+(full\s+)?processlist$`,
+}
+
+// querylogzInternalQueries matches the internal queries that querylogz
+// hides with excludeInternal=1, see --querylogz-internal-queries.
+var querylogzInternalQueries = newQuerylogzInternalPatterns(defaultQuerylogzInternalQueries)
+
+// querylogzInternalPatterns is a repeatable flag that combines every pattern
+// it is given into a single regular expression. The first pattern given
+// replaces the default ones.
+type querylogzInternalPatterns struct {
+	patterns []string
+	re       *regexp.Regexp
+	// overridden is set once the default patterns were replaced.
+	overridden bool
+}
+
+func newQuerylogzInternalPatterns(patterns []string) *querylogzInternalPatterns {
+	p := &querylogzInternalPatterns{patterns: patterns}
+	p.compile()
+	return p
+}
+
+func (p *querylogzInternalPatterns) compile() {
+	p.re = regexp.MustCompile("(?:" + strings.Join(p.patterns, ")|(?:") + ")")
+}
+
+// Set implements pflag.Value.
+func (p *querylogzInternalPatterns) Set(pattern string) error {
+	if _, err := regexp.Compile(pattern); err != nil {
+		return err
+	}
+	if !p.overridden {
+		p.patterns, p.overridden = nil, true
+	}
+	p.patterns = append(p.patterns, pattern)
+	p.compile()
+	return nil
+}
+
+// String implements pflag.Value.
+func (p *querylogzInternalPatterns) String() string {
+	return strings.Join(p.patterns, ",")
+}
+
+// Type implements pflag.Value.
+func (p *querylogzInternalPatterns) Type() string {
+	return "regexp"
+}
+
+// matches returns whether stats is an internal query. It matches the query
+// as the client sent it, before vtgate normalized it.
+func (p *querylogzInternalPatterns) matches(stats *logstats.LogStats) bool {
+	sql := stats.OriginalSQL
+	if sql == "" {
+		sql = stats.SQL
+	}
+	return p.re.MatchString(strings.TrimSpace(sql))
+}
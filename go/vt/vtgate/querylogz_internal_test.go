@@ -0,0 +1,80 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vtgate
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vitess.io/vitess/go/streamlog"
+	"vitess.io/vitess/go/vt/sqlparser"
+	"vitess.io/vitess/go/vt/vtgate/logstats"
+)
+
+func TestQuerylogzHandlerExcludeInternal(t *testing.T) {
+	render := func(url string) string {
+		req, _ := http.NewRequest("GET", url, nil)
+		response := httptest.NewRecorder()
+		ch := make(chan *logstats.LogStats, 4)
+		for _, sql := range []string{"SELECT 1", "show global status like 'Uptime'", "select @@version_comment limit 1", "select 2"} {
+			logStats := logstats.NewLogStats(context.Background(), "Execute", sql, "suuid", nil, streamlog.NewQueryLogConfigForTest())
+			logStats.EndTime = logStats.StartTime.Add(1 * time.Millisecond)
+			ch <- logStats
+		}
+		querylogzHandler(ch, nil, response, req, sqlparser.NewTestParser())
+		close(ch)
+		body, _ := io.ReadAll(response.Body)
+		return string(body)
+	}
+
+	page := render("/querylogz?timeout=1&limit=4")
+	assert.Contains(t, page, "<td>SELECT 1</td>")
+
+	page = render("/querylogz?timeout=1&limit=1&excludeInternal=1")
+	assert.NotContains(t, page, "<td>SELECT 1</td>")
+	assert.NotContains(t, page, "show global status")
+	assert.NotContains(t, page, "@@version_comment")
+	assert.Contains(t, page, "<td>select 2</td>")
+}
+
+func TestQuerylogzInternalPatterns(t *testing.T) {
+	isInternal := func(p *querylogzInternalPatterns, sql string) bool {
+		return p.matches(logstats.NewLogStats(context.Background(), "Execute", sql, "suuid", nil, streamlog.NewQueryLogConfigForTest()))
+	}
+
+	p := newQuerylogzInternalPatterns(defaultQuerylogzInternalQueries)
+	assert.True(t, isInternal(p, "select 1 from dual"))
+	assert.True(t, isInternal(p, " show full processlist "))
+	assert.False(t, isInternal(p, "select 1 from t"))
+
+	// The first pattern set replaces the defaults, and later ones add to it.
+	require.NoError(t, p.Set(`^select \* from heartbeat`))
+	require.NoError(t, p.Set(`^select now\(\)$`))
+	assert.False(t, isInternal(p, "select 1"))
+	assert.True(t, isInternal(p, "select * from heartbeat where id = 1"))
+	assert.True(t, isInternal(p, "select now()"))
+	assert.Equal(t, `^select \* from heartbeat,^select now\(\)$`, p.String())
+
+	assert.Error(t, p.Set("("))
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vtgate
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+
+	"vitess.io/vitess/go/vt/log"
+	"vitess.io/vitess/go/vt/sqlparser"
+	"vitess.io/vitess/go/vt/vtgate/logstats"
+)
+
+// maxQuerylogzMarks bounds the number of pinned queries. Pinning a query
+// beyond it unpins the oldest one.
+const maxQuerylogzMarks = 50
+
+// querylogzMarks are the queries pinned with mark. They are kept in memory
+// and are local to this process.
+var querylogzMarks = &querylogzMarkStore{}
+
+// querylogzMarkStore is a bounded list of pinned query log records, in the
+// order they were pinned.
+type querylogzMarkStore struct {
+	mu     sync.Mutex
+	marked []*logstats.LogStats
+}
+
+func (ms *querylogzMarkStore) mark(stats *logstats.LogStats) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if slices.Contains(ms.marked, stats) {
+		return
+	}
+	if len(ms.marked) >= maxQuerylogzMarks {
+		ms.marked = ms.marked[1:]
+	}
+	ms.marked = append(ms.marked, stats)
+}
+
+// unmark unpins the nth pinned record, counting from 1.
+func (ms *querylogzMarkStore) unmark(n int) bool {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if n < 1 || n > len(ms.marked) {
+		return false
+	}
+	ms.marked = slices.Delete(ms.marked, n-1, n)
+	return true
+}
+
+func (ms *querylogzMarkStore) list() []*logstats.LogStats {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	return slices.Clone(ms.marked)
+}
+
+// applyQuerylogzMarks handles the mark parameters of a querylogz request.
+// mark=N pins the Nth most recent query buffered in ring, counting from 1,
+// and unmark=N unpins the Nth pinned query. Pinned queries are kept even
+// once they leave the ring. The parameters are removed from the request
+// URL, and applyQuerylogzMarks reports whether there were any, so that the
+// handler can redirect and a refresh of the page doesn't pin whatever query
+// has since become the Nth most recent one.
+func applyQuerylogzMarks(r *http.Request, ring *queryLogRing) (bool, error) {
+	query := r.URL.Query()
+	if !query.Has("mark") && !query.Has("unmark") {
+		return false, nil
+	}
+	for _, param := range query["mark"] {
+		n, err := strconv.Atoi(param)
+		records := ring.snapshot()
+		if err != nil || n < 1 || n > len(records) {
+			return false, fmt.Errorf("no buffered query %q to mark, there are %d", param, len(records))
+		}
+		querylogzMarks.mark(records[len(records)-n])
+	}
+	for _, param := range query["unmark"] {
+		n, err := strconv.Atoi(param)
+		if err != nil || !querylogzMarks.unmark(n) {
+			return false, fmt.Errorf("no pinned query %q to unmark", param)
+		}
+	}
+	query.Del("mark")
+	query.Del("unmark")
+	r.URL.RawQuery = query.Encode()
+	return true, nil
+}
+
+// querylogzPinned renders the pinned queries, highlighted, in a table of
+// their own. It renders nothing when no query is pinned.
+func querylogzPinned(w http.ResponseWriter, parser *sqlparser.Parser, columns querylogzColumns) {
+	marked := querylogzMarks.list()
+	if len(marked) == 0 {
+		return
+	}
+	if err := querylogzPinnedTmpl.Execute(w, nil); err != nil {
+		log.Errorf("querylogz: couldn't execute pinned template: %v", err)
+	}
+	querylogzStartTable(w, columns)
+	for i, stats := range marked {
+		querylogzRow(w, stats, parser, columns, 0, i+1, 0)
+	}
+	querylogzEndTable(w, columns)
+}
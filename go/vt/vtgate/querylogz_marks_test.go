@@ -0,0 +1,101 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vtgate
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vitess.io/vitess/go/streamlog"
+	"vitess.io/vitess/go/vt/sqlparser"
+	"vitess.io/vitess/go/vt/vtgate/logstats"
+)
+
+func TestQuerylogzHandlerMarks(t *testing.T) {
+	defer func(marks *querylogzMarkStore) { querylogzMarks = marks }(querylogzMarks)
+	querylogzMarks = &querylogzMarkStore{}
+
+	ring := newQueryLogRing(10)
+	for _, sql := range []string{"select 1", "select 2", "select 3"} {
+		logStats := logstats.NewLogStats(context.Background(), "Execute", sql, "suuid", nil, streamlog.NewQueryLogConfigForTest())
+		logStats.EndTime = logStats.StartTime.Add(1 * time.Millisecond)
+		ring.add(logStats)
+	}
+	serve := func(path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", path, nil)
+		response := httptest.NewRecorder()
+		ch := make(chan *logstats.LogStats)
+		querylogzHandler(ch, ring, response, req, sqlparser.NewTestParser())
+		return response
+	}
+	page := func() string {
+		body, _ := io.ReadAll(serve("/querylogz?timeout=0&limit=1").Body)
+		return string(body)
+	}
+
+	// Marking redirects to the same page without the mark, so that
+	// refreshing it doesn't pin another query.
+	response := serve("/querylogz?timeout=0&limit=1&mark=2")
+	require.Equal(t, http.StatusSeeOther, response.Code)
+	assert.Equal(t, "/querylogz?limit=1&timeout=0", response.Header().Get("Location"))
+
+	got := page()
+	assert.Contains(t, got, "Pinned queries")
+	assert.Contains(t, got, "<td>select 2</td>")
+	assert.Contains(t, got, "Pinned: #1<br>")
+	assert.NotContains(t, got, "<td>select 3</td>")
+
+	// A pinned query stays pinned once newer queries push it out of the ring.
+	for i := 0; i < 10; i++ {
+		ring.add(logstats.NewLogStats(context.Background(), "Execute", "select 4", "suuid", nil, streamlog.NewQueryLogConfigForTest()))
+	}
+	assert.Contains(t, page(), "<td>select 2</td>")
+
+	response = serve("/querylogz?unmark=1")
+	require.Equal(t, http.StatusSeeOther, response.Code)
+	got = page()
+	if strings.Contains(got, "Pinned queries") || strings.Contains(got, "select 2") {
+		t.Fatalf("querylogz still shows the unpinned query: %s", got)
+	}
+
+	assert.Equal(t, http.StatusBadRequest, serve("/querylogz?mark=11").Code)
+	assert.Equal(t, http.StatusBadRequest, serve("/querylogz?unmark=1").Code)
+}
+
+func TestQuerylogzMarkStoreBounded(t *testing.T) {
+	ms := &querylogzMarkStore{}
+	var records []*logstats.LogStats
+	for i := 0; i <= maxQuerylogzMarks; i++ {
+		stats := &logstats.LogStats{}
+		records = append(records, stats)
+		ms.mark(stats)
+	}
+	// Marking the same record twice pins it once.
+	ms.mark(records[maxQuerylogzMarks])
+
+	marked := ms.list()
+	require.Len(t, marked, maxQuerylogzMarks)
+	assert.Same(t, records[1], marked[0], "the oldest pin is evicted first")
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vtgate
+
+import (
+	"slices"
+	"time"
+
+	"vitess.io/vitess/go/vt/vtgate/logstats"
+)
+
+// querylogzPercentiles are the p50, p90 and p99 total times of the buffered
+// queries, against which percentiles=1 colors the rows instead of the fixed
+// thresholds, so that the coloring follows the baseline of the workload.
+type querylogzPercentiles struct {
+	p50, p90, p99 time.Duration
+}
+
+// querylogzComputePercentiles returns the percentiles of the total time of
+// the records matching filter, or nil when none match.
+func querylogzComputePercentiles(records []*logstats.LogStats, filter querylogzFilter) *querylogzPercentiles {
+	var durations []time.Duration
+	for _, stats := range records {
+		if filter.matches(stats) {
+			durations = append(durations, stats.TotalTime())
+		}
+	}
+	if len(durations) == 0 {
+		return nil
+	}
+	slices.Sort(durations)
+	return &querylogzPercentiles{
+		p50: percentile(durations, 0.5),
+		p90: percentile(durations, 0.9),
+		p99: percentile(durations, 0.99),
+	}
+}
+
+// bucket returns the percentile bucket d falls into and the color level of
+// its row. Only the tail is highlighted: the queries at or above the p90
+// are medium, and the ones at or above the p99 high.
+func (p *querylogzPercentiles) bucket(d time.Duration) (bucket, level string) {
+	switch {
+	case d >= p.p99:
+		return "p99+", "high"
+	case d >= p.p90:
+		return "p90-p99", "medium"
+	case d >= p.p50:
+		return "p50-p90", "low"
+	default:
+		return "<p50", "low"
+	}
+}
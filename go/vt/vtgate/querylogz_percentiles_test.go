@@ -0,0 +1,84 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vtgate
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"vitess.io/vitess/go/streamlog"
+	"vitess.io/vitess/go/vt/sqlparser"
+	"vitess.io/vitess/go/vt/vtgate/logstats"
+)
+
+func TestQuerylogzPercentiles(t *testing.T) {
+	ring := newQueryLogRing(100)
+	add := func(sql string, d time.Duration) {
+		logStats := logstats.NewLogStats(context.Background(), "Execute", sql, "suuid", nil, streamlog.NewQueryLogConfigForTest())
+		logStats.EndTime = logStats.StartTime.Add(d)
+		ring.add(logStats)
+	}
+	// The slowest query takes 100µs: all of them are below the fixed
+	// thresholds.
+	for i := 1; i <= 100; i++ {
+		add("select "+strconv.Itoa(i), time.Duration(i)*time.Microsecond)
+	}
+
+	percentiles := querylogzComputePercentiles(ring.snapshot(), querylogzFilter{})
+	assert.Equal(t, &querylogzPercentiles{p50: 50 * time.Microsecond, p90: 90 * time.Microsecond, p99: 99 * time.Microsecond}, percentiles)
+	for _, tcase := range []struct {
+		d             time.Duration
+		bucket, level string
+	}{
+		{10 * time.Microsecond, "<p50", "low"},
+		{50 * time.Microsecond, "p50-p90", "low"},
+		{95 * time.Microsecond, "p90-p99", "medium"},
+		{100 * time.Microsecond, "p99+", "high"},
+	} {
+		bucket, level := percentiles.bucket(tcase.d)
+		assert.Equal(t, tcase.bucket, bucket, tcase.d)
+		assert.Equal(t, tcase.level, level, tcase.d)
+	}
+	assert.Nil(t, querylogzComputePercentiles(nil, querylogzFilter{}))
+
+	render := func(url string) string {
+		req, _ := http.NewRequest("GET", url, nil)
+		response := httptest.NewRecorder()
+		ch := make(chan *logstats.LogStats, 1)
+		logStats := logstats.NewLogStats(context.Background(), "Execute", "select 0", "suuid", nil, streamlog.NewQueryLogConfigForTest())
+		logStats.EndTime = logStats.StartTime.Add(time.Millisecond)
+		ch <- logStats
+		querylogzHandler(ch, ring, response, req, sqlparser.NewTestParser())
+		close(ch)
+		body, _ := io.ReadAll(response.Body)
+		return string(body)
+	}
+	page := render("/querylogz?timeout=1&limit=1&percentiles=1")
+	assert.Contains(t, page, `<tr class="high">`)
+	assert.Contains(t, page, "Latency Bucket: p99+<br>")
+	// The fixed thresholds stay the default.
+	page = render("/querylogz?timeout=1&limit=1")
+	assert.Contains(t, page, `<tr class="low">`)
+	assert.NotContains(t, page, "Latency Bucket")
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vtgate
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/google/safehtml/template"
+
+	"vitess.io/vitess/go/vt/log"
+)
+
+var querylogzPermalinkTmpl = template.Must(template.New("permalink").Parse(`
+<p><a href="{{.}}">Permalink</a> <button class="copy-permalink" data-url="{{.}}" hidden>Copy</button></p>
+<script type="text/javascript">
+document.querySelectorAll("button.copy-permalink").forEach(function(button) {
+  button.hidden = false;
+  button.addEventListener("click", function() {
+    navigator.clipboard.writeText(button.dataset.url);
+  });
+});
+</script>
+`))
+
+// querylogzPermalink returns the absolute URL of the querylogz view of r,
+// with its parameters in a canonical order. Presets are process-local, so
+// r must have them expanded already, see applyQuerylogzPreset. The offset
+// only makes sense against the live stream of the page it came from, so it
+// is left out.
+func querylogzPermalink(r *http.Request) string {
+	query := r.URL.Query()
+	query.Del("offset")
+	u := url.URL{
+		Scheme:   "http",
+		Host:     r.Host,
+		Path:     r.URL.Path,
+		RawQuery: query.Encode(),
+	}
+	if r.TLS != nil {
+		u.Scheme = "https"
+	}
+	return u.String()
+}
+
+// querylogzPermalinkLink renders a link to, and a button copying, the
+// permalink of the querylogz view of r, so that it can be shared.
+func querylogzPermalinkLink(w http.ResponseWriter, r *http.Request) {
+	if err := querylogzPermalinkTmpl.Execute(w, querylogzPermalink(r)); err != nil {
+		log.Errorf("querylogz: couldn't execute permalink template: %v", err)
+	}
+}
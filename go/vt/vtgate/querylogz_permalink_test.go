@@ -0,0 +1,69 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vtgate
+
+import (
+	"html"
+	"io"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vitess.io/vitess/go/vt/sqlparser"
+	"vitess.io/vitess/go/vt/vtgate/logstats"
+)
+
+func TestQuerylogzPermalink(t *testing.T) {
+	hrefRE := regexp.MustCompile(`<a href="([^"]*)">Permalink</a>`)
+	render := func(target string) string {
+		req := httptest.NewRequest("GET", target, nil)
+		response := httptest.NewRecorder()
+		querylogzHandler(make(chan *logstats.LogStats), nil, response, req, sqlparser.NewTestParser())
+		body, _ := io.ReadAll(response.Body)
+		return string(body)
+	}
+	permalink := func(target string) string {
+		body := render(target)
+		match := hrefRE.FindStringSubmatch(body)
+		require.Len(t, match, 2, "no permalink in %s", body)
+		return html.UnescapeString(match[1])
+	}
+
+	params := "timeout=0&limit=20&permalink=1&table=user&category=OLTP&min_tablets=2&humanize=1&age=1&sticky=1&diff=select+1"
+	link := permalink("/querylogz?offset=40&" + params)
+	u, err := url.Parse(link)
+	require.NoError(t, err)
+	assert.Equal(t, "http", u.Scheme)
+	assert.Equal(t, "example.com", u.Host)
+	assert.Equal(t, "/querylogz", u.Path)
+	want, err := url.ParseQuery(params)
+	require.NoError(t, err)
+	// Every active parameter but the offset round-trips.
+	assert.Equal(t, want, u.Query())
+	// The permalink is canonical.
+	assert.Equal(t, link, permalink(u.RequestURI()))
+
+	// Presets are expanded, since they are local to the process.
+	assert.NotContains(t, render("/querylogz?timeout=0&table=user&category=OLAP&savePreset=permalink-test"), "Permalink")
+	u, err = url.Parse(permalink("/querylogz?timeout=0&preset=permalink-test&permalink=1&category=OLTP"))
+	require.NoError(t, err)
+	assert.Equal(t, url.Values{"timeout": {"0"}, "table": {"user"}, "category": {"OLTP"}, "permalink": {"1"}}, u.Query())
+}
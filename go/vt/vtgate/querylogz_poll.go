@@ -0,0 +1,119 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vtgate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"vitess.io/vitess/go/acl"
+	"vitess.io/vitess/go/streamlog"
+	"vitess.io/vitess/go/vt/log"
+	"vitess.io/vitess/go/vt/sqlparser"
+	"vitess.io/vitess/go/vt/vtgate/logstats"
+)
+
+// querylogzPollResponse is the JSON document returned by the long-polling
+// query log endpoint.
+type querylogzPollResponse struct {
+	// Cursor is the cursor to poll with next.
+	Cursor uint64 `json:"cursor"`
+	// Evicted is the number of records since the polled cursor that were
+	// evicted from the buffer before they could be returned.
+	Evicted uint64 `json:"evicted"`
+	// Records are the records since the polled cursor matching the filters,
+	// formatted as JSON, oldest first.
+	Records []json.RawMessage `json:"records"`
+}
+
+// querylogzPollHandler serves the records buffered in ring from the cursor
+// parameter on as JSON. If there are none yet, it blocks until one arrives
+// or the timeout expires, so that a client can tail the query log with
+// plain requests instead of a persistent connection. Without a cursor, only
+// records added after the request are returned. Each response holds the
+// cursor of the next request, so that no record is missed between two
+// requests unless it is evicted from ring first. The querylogz filters and
+// the timeout and limit parameters apply. The handler doesn't subscribe to
+// the query log, so there is nothing to clean up when a client goes away.
+func querylogzPollHandler(ring *queryLogRing, w http.ResponseWriter, r *http.Request, parser *sqlparser.Parser) {
+	if err := acl.CheckAccessHTTP(r, acl.DEBUGGING); err != nil {
+		acl.SendError(w, err)
+		return
+	}
+	if ring == nil {
+		http.Error(w, "the query log buffer is disabled, see --querylog-ring-size", http.StatusNotFound)
+		return
+	}
+	cursor := ring.cursor()
+	if c := r.URL.Query().Get("cursor"); c != "" {
+		var err error
+		if cursor, err = strconv.ParseUint(c, 10, 64); err != nil {
+			http.Error(w, fmt.Sprintf("invalid cursor %q", c), http.StatusBadRequest)
+			return
+		}
+	}
+	timeout, limit := parseTimeoutLimitParams(r)
+	filter := parseQuerylogzFilter(r, parser)
+	formatter := logstats.GetFormatter(streamlog.QueryLogFormatJSON)
+
+	tmr := time.NewTimer(timeout)
+	defer tmr.Stop()
+	response := querylogzPollResponse{Records: []json.RawMessage{}}
+poll:
+	for {
+		records, next, evicted, changed := ring.since(cursor)
+		response.Evicted += evicted
+		cursor = next - uint64(len(records))
+		for _, stats := range records {
+			if len(response.Records) == limit {
+				break
+			}
+			cursor++
+			if !filter.matches(stats) {
+				continue
+			}
+			b, err := formatter.Format(stats)
+			if err != nil {
+				log.Errorf("querylogz: couldn't format record as json: %v", err)
+				continue
+			}
+			if b = bytes.TrimSpace(b); len(b) > 0 {
+				response.Records = append(response.Records, b)
+			}
+		}
+		if len(response.Records) > 0 || response.Evicted > 0 {
+			break
+		}
+		select {
+		case <-changed:
+		case <-tmr.C:
+			break poll
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	response.Cursor = cursor
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Errorf("querylogz: couldn't write poll response: %v", err)
+	}
+}
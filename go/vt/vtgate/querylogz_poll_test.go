@@ -0,0 +1,107 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vtgate
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vitess.io/vitess/go/streamlog"
+	"vitess.io/vitess/go/vt/sqlparser"
+	"vitess.io/vitess/go/vt/vtgate/logstats"
+)
+
+func TestQuerylogzPollHandler(t *testing.T) {
+	ring := newQueryLogRing(3)
+	add := func(sql, category string) {
+		logStats := logstats.NewLogStats(context.Background(), "Execute", sql, "suuid", nil, streamlog.NewQueryLogConfigForTest())
+		logStats.QueryCategory = category
+		logStats.EndTime = logStats.StartTime.Add(1 * time.Millisecond)
+		ring.add(logStats)
+	}
+	poll := func(ctx context.Context, url string) (*httptest.ResponseRecorder, querylogzPollResponse) {
+		req, _ := http.NewRequestWithContext(ctx, "GET", url, nil)
+		response := httptest.NewRecorder()
+		querylogzPollHandler(ring, response, req, sqlparser.NewTestParser())
+		var result querylogzPollResponse
+		if response.Code == http.StatusOK && response.Body.Len() > 0 {
+			require.NoError(t, json.Unmarshal(response.Body.Bytes(), &result))
+		}
+		return response, result
+	}
+	sqls := func(result querylogzPollResponse) []string {
+		var sqls []string
+		for _, record := range result.Records {
+			var fields struct{ SQL string }
+			require.NoError(t, json.Unmarshal(record, &fields))
+			sqls = append(sqls, fields.SQL)
+		}
+		return sqls
+	}
+	ctx := context.Background()
+
+	add("select 1", logstats.QueryCategoryOLTP)
+	add("select 2", logstats.QueryCategoryOLTP)
+	_, result := poll(ctx, "/debug/querylogz/poll?cursor=0")
+	assert.Equal(t, []string{"select 1", "select 2"}, sqls(result))
+	assert.EqualValues(t, 2, result.Cursor)
+
+	// A poll with no new record times out with an empty response.
+	_, result = poll(ctx, "/debug/querylogz/poll?cursor=2&timeout=0")
+	assert.Empty(t, result.Records)
+	assert.EqualValues(t, 2, result.Cursor)
+
+	// A poll blocks until a record matching the filters arrives.
+	done := make(chan querylogzPollResponse)
+	go func() {
+		_, result := poll(ctx, "/debug/querylogz/poll?cursor=2&category=OLAP")
+		done <- result
+	}()
+	add("select 3", logstats.QueryCategoryOLTP)
+	add("select 4", logstats.QueryCategoryOLAP)
+	select {
+	case result = <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("poll did not return after a matching record was added")
+	}
+	assert.Equal(t, []string{"select 4"}, sqls(result))
+	assert.EqualValues(t, 4, result.Cursor)
+
+	// Records overwritten before they were polled are reported as evicted.
+	_, result = poll(ctx, "/debug/querylogz/poll?cursor=0&limit=1")
+	assert.EqualValues(t, 1, result.Evicted)
+	assert.Equal(t, []string{"select 2"}, sqls(result))
+	assert.EqualValues(t, 2, result.Cursor)
+
+	// A client that goes away gets no response.
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	response, _ := poll(canceled, "/debug/querylogz/poll")
+	assert.Zero(t, response.Body.Len())
+
+	assert.Equal(t, http.StatusBadRequest, func() int {
+		response, _ := poll(ctx, "/debug/querylogz/poll?cursor=next")
+		return response.Code
+	}())
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vtgate
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"vitess.io/vitess/go/vt/vtgate/logstats"
+)
+
+// querylogzFormatPrometheus is the querylogz format that exports metrics
+// aggregated over the buffered records, in the Prometheus text format.
+const querylogzFormatPrometheus = "prometheus"
+
+// querylogzQuantiles are the quantiles of the exported duration summaries.
+var querylogzQuantiles = []float64{0.5, 0.95, 0.99}
+
+// querylogzMetricsKey identifies a series of the exported metrics.
+type querylogzMetricsKey struct {
+	stmtType string
+	keyspace string
+}
+
+// querylogzMetrics aggregates the records of a single series.
+type querylogzMetrics struct {
+	errors    int
+	durations []time.Duration
+}
+
+// writeQuerylogzPrometheus writes the query count, error count and duration
+// summary of the records matching filter, labeled by statement type and
+// keyspace. These are derived from the buffered records only, and are not a
+// replacement for the vtgate metrics.
+func writeQuerylogzPrometheus(w io.Writer, records []*logstats.LogStats, filter querylogzFilter) {
+	series := map[querylogzMetricsKey]*querylogzMetrics{}
+	for _, stats := range records {
+		if !filter.matches(stats) {
+			continue
+		}
+		key := querylogzMetricsKey{stmtType: stats.StmtType, keyspace: stats.ActiveKeyspace}
+		m := series[key]
+		if m == nil {
+			m = &querylogzMetrics{}
+			series[key] = m
+		}
+		if stats.Error != nil {
+			m.errors++
+		}
+		m.durations = append(m.durations, stats.TotalTime())
+	}
+
+	keys := make([]querylogzMetricsKey, 0, len(series))
+	for key, m := range series {
+		keys = append(keys, key)
+		slices.Sort(m.durations)
+	}
+	slices.SortFunc(keys, func(a, b querylogzMetricsKey) int {
+		if c := strings.Compare(a.stmtType, b.stmtType); c != 0 {
+			return c
+		}
+		return strings.Compare(a.keyspace, b.keyspace)
+	})
+
+	fmt.Fprintf(w, "# HELP vtgate_querylog_queries_total Number of buffered query log records.\n")
+	fmt.Fprintf(w, "# TYPE vtgate_querylog_queries_total counter\n")
+	for _, key := range keys {
+		fmt.Fprintf(w, "vtgate_querylog_queries_total{%s} %d\n", key.labels(), len(series[key].durations))
+	}
+	fmt.Fprintf(w, "# HELP vtgate_querylog_errors_total Number of buffered query log records that failed.\n")
+	fmt.Fprintf(w, "# TYPE vtgate_querylog_errors_total counter\n")
+	for _, key := range keys {
+		fmt.Fprintf(w, "vtgate_querylog_errors_total{%s} %d\n", key.labels(), series[key].errors)
+	}
+	fmt.Fprintf(w, "# HELP vtgate_querylog_duration_seconds Total time of the buffered query log records.\n")
+	fmt.Fprintf(w, "# TYPE vtgate_querylog_duration_seconds summary\n")
+	for _, key := range keys {
+		m := series[key]
+		labels := key.labels()
+		for _, q := range querylogzQuantiles {
+			fmt.Fprintf(w, "vtgate_querylog_duration_seconds{%s,quantile=\"%g\"} %g\n", labels, q, percentile(m.durations, q).Seconds())
+		}
+		var sum time.Duration
+		for _, d := range m.durations {
+			sum += d
+		}
+		fmt.Fprintf(w, "vtgate_querylog_duration_seconds_sum{%s} %g\n", labels, sum.Seconds())
+		fmt.Fprintf(w, "vtgate_querylog_duration_seconds_count{%s} %d\n", labels, len(m.durations))
+	}
+}
+
+func (key querylogzMetricsKey) labels() string {
+	return fmt.Sprintf(`stmt_type="%s",keyspace="%s"`, escapePrometheusLabel(key.stmtType), escapePrometheusLabel(key.keyspace))
+}
+
+var prometheusLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapePrometheusLabel(value string) string {
+	return prometheusLabelEscaper.Replace(value)
+}
+
+// percentile returns the q-th quantile of sorted, using the nearest-rank
+// method. It returns zero for an empty slice.
+func percentile(sorted []time.Duration, q float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(q * float64(len(sorted))))
+	return sorted[min(max(rank-1, 0), len(sorted)-1)]
+}
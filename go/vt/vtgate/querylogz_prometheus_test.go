@@ -0,0 +1,87 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vtgate
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"vitess.io/vitess/go/streamlog"
+	"vitess.io/vitess/go/vt/sqlparser"
+	"vitess.io/vitess/go/vt/vtgate/logstats"
+)
+
+func TestQuerylogzHandlerPrometheus(t *testing.T) {
+	ring := newQueryLogRing(10)
+	add := func(stmtType, keyspace string, d time.Duration, err error) {
+		logStats := logstats.NewLogStats(context.Background(), "Execute", "select 1", "suuid", nil, streamlog.NewQueryLogConfigForTest())
+		logStats.EndTime = logStats.StartTime.Add(d)
+		logStats.StmtType = stmtType
+		logStats.ActiveKeyspace = keyspace
+		logStats.Error = err
+		ring.add(logStats)
+	}
+	add("SELECT", "ks1", 10*time.Millisecond, nil)
+	add("SELECT", "ks1", 20*time.Millisecond, nil)
+	add("SELECT", "ks1", 30*time.Millisecond, errors.New("boom"))
+	add("INSERT", `k"s2`, time.Second, nil)
+
+	req, _ := http.NewRequest("GET", "/querylogz?format=prometheus", nil)
+	response := httptest.NewRecorder()
+	querylogzHandler(make(chan *logstats.LogStats), ring, response, req, sqlparser.NewTestParser())
+	body, _ := io.ReadAll(response.Body)
+
+	want := `# HELP vtgate_querylog_queries_total Number of buffered query log records.
+# TYPE vtgate_querylog_queries_total counter
+vtgate_querylog_queries_total{stmt_type="INSERT",keyspace="k\"s2"} 1
+vtgate_querylog_queries_total{stmt_type="SELECT",keyspace="ks1"} 3
+# HELP vtgate_querylog_errors_total Number of buffered query log records that failed.
+# TYPE vtgate_querylog_errors_total counter
+vtgate_querylog_errors_total{stmt_type="INSERT",keyspace="k\"s2"} 0
+vtgate_querylog_errors_total{stmt_type="SELECT",keyspace="ks1"} 1
+# HELP vtgate_querylog_duration_seconds Total time of the buffered query log records.
+# TYPE vtgate_querylog_duration_seconds summary
+vtgate_querylog_duration_seconds{stmt_type="INSERT",keyspace="k\"s2",quantile="0.5"} 1
+vtgate_querylog_duration_seconds{stmt_type="INSERT",keyspace="k\"s2",quantile="0.95"} 1
+vtgate_querylog_duration_seconds{stmt_type="INSERT",keyspace="k\"s2",quantile="0.99"} 1
+vtgate_querylog_duration_seconds_sum{stmt_type="INSERT",keyspace="k\"s2"} 1
+vtgate_querylog_duration_seconds_count{stmt_type="INSERT",keyspace="k\"s2"} 1
+vtgate_querylog_duration_seconds{stmt_type="SELECT",keyspace="ks1",quantile="0.5"} 0.02
+vtgate_querylog_duration_seconds{stmt_type="SELECT",keyspace="ks1",quantile="0.95"} 0.03
+vtgate_querylog_duration_seconds{stmt_type="SELECT",keyspace="ks1",quantile="0.99"} 0.03
+vtgate_querylog_duration_seconds_sum{stmt_type="SELECT",keyspace="ks1"} 0.06
+vtgate_querylog_duration_seconds_count{stmt_type="SELECT",keyspace="ks1"} 3
+`
+	assert.Equal(t, want, string(body))
+	assert.Equal(t, "text/plain; version=0.0.4", response.Header().Get("Content-Type"))
+}
+
+func TestPercentile(t *testing.T) {
+	assert.Zero(t, percentile(nil, 0.5))
+	sorted := []time.Duration{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	assert.EqualValues(t, 5, percentile(sorted, 0.5))
+	assert.EqualValues(t, 10, percentile(sorted, 0.95))
+	assert.EqualValues(t, 1, percentile(sorted, 0))
+	assert.EqualValues(t, 10, percentile(sorted, 1))
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vtgate
+
+import (
+	"cmp"
+	"maps"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/google/safehtml/template"
+
+	"vitess.io/vitess/go/vt/log"
+	"vitess.io/vitess/go/vt/vtgate/logstats"
+)
+
+var querylogzQPSTmpl = template.Must(template.New("qps").Parse(`
+<p>{{if .Window}}QPS over the last {{.Window}}: {{printf "%.2f" .Total}}{{range .ByStmtType}}, {{.StmtType}}: {{printf "%.2f" .QPS}}{{end}}{{else}}QPS: n/a{{end}}</p>
+`))
+
+type querylogzStmtTypeQPS struct {
+	StmtType string
+	QPS      float64
+}
+
+// querylogzQPSRate is the query rate rendered by querylogzQPS.
+type querylogzQPSRate struct {
+	// Window is the time span of the buffered records, or zero when there
+	// aren't enough of them to compute a rate.
+	Window     time.Duration
+	Total      float64
+	ByStmtType []querylogzStmtTypeQPS
+}
+
+// querylogzComputeQPS returns the rate of the records matching filter over
+// the time span of records, from the start of the oldest one to the end of
+// the most recent one. The span covers all the buffered records, so that
+// the rate of a filtered subset compares with the rate of all queries.
+func querylogzComputeQPS(records []*logstats.LogStats, filter querylogzFilter) querylogzQPSRate {
+	var rate querylogzQPSRate
+	if len(records) < 2 {
+		return rate
+	}
+	start, end := records[0].StartTime, records[0].EndTime
+	counts := map[string]int{}
+	var total int
+	for _, stats := range records {
+		if stats.StartTime.Before(start) {
+			start = stats.StartTime
+		}
+		if stats.EndTime.After(end) {
+			end = stats.EndTime
+		}
+		if filter.matches(stats) {
+			counts[querylogzStmtType(stats)]++
+			total++
+		}
+	}
+	window := end.Sub(start)
+	if window <= 0 {
+		return rate
+	}
+	rate.Window = window.Round(time.Millisecond)
+	rate.Total = float64(total) / window.Seconds()
+	for _, stmtType := range slices.Sorted(maps.Keys(counts)) {
+		rate.ByStmtType = append(rate.ByStmtType, querylogzStmtTypeQPS{stmtType, float64(counts[stmtType]) / window.Seconds()})
+	}
+	slices.SortStableFunc(rate.ByStmtType, func(a, b querylogzStmtTypeQPS) int {
+		return cmp.Compare(b.QPS, a.QPS)
+	})
+	return rate
+}
+
+// querylogzQPS renders the query rate of the records matching filter over
+// the buffered window, in total and per statement type, as an instant load
+// reading.
+func querylogzQPS(w http.ResponseWriter, records []*logstats.LogStats, filter querylogzFilter) {
+	if err := querylogzQPSTmpl.Execute(w, querylogzComputeQPS(records, filter)); err != nil {
+		log.Errorf("querylogz: couldn't execute qps template: %v", err)
+	}
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vtgate
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"vitess.io/vitess/go/streamlog"
+	"vitess.io/vitess/go/vt/sqlparser"
+	"vitess.io/vitess/go/vt/vtgate/logstats"
+)
+
+func TestQuerylogzQPS(t *testing.T) {
+	ring := newQueryLogRing(10)
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	add := func(stmtType, category string, at time.Duration) {
+		logStats := logstats.NewLogStats(context.Background(), "Execute", "select 1", "suuid", nil, streamlog.NewQueryLogConfigForTest())
+		logStats.StmtType = stmtType
+		logStats.QueryCategory = category
+		logStats.StartTime = start.Add(at)
+		logStats.EndTime = logStats.StartTime.Add(time.Millisecond)
+		ring.add(logStats)
+	}
+	add("SELECT", "", 0)
+	add("SELECT", "OLAP", time.Second)
+	add("INSERT", "", 2*time.Second)
+	add("SELECT", "", 4*time.Second-time.Millisecond)
+
+	// 4 queries over the 4s between the start of the oldest one and the
+	// end of the most recent one.
+	rate := querylogzComputeQPS(ring.snapshot(), querylogzFilter{})
+	assert.Equal(t, 4*time.Second, rate.Window)
+	assert.Equal(t, 1.0, rate.Total)
+	assert.Equal(t, []querylogzStmtTypeQPS{{"SELECT", 0.75}, {"INSERT", 0.25}}, rate.ByStmtType)
+
+	// Filtered queries are counted over the same window.
+	rate = querylogzComputeQPS(ring.snapshot(), querylogzFilter{category: "OLAP"})
+	assert.Equal(t, 0.25, rate.Total)
+	assert.Equal(t, []querylogzStmtTypeQPS{{"SELECT", 0.25}}, rate.ByStmtType)
+
+	render := func(ring *queryLogRing, url string) string {
+		req, _ := http.NewRequest("GET", url, nil)
+		response := httptest.NewRecorder()
+		querylogzHandler(make(chan *logstats.LogStats), ring, response, req, sqlparser.NewTestParser())
+		body, _ := io.ReadAll(response.Body)
+		return string(body)
+	}
+	assert.Contains(t, render(ring, "/querylogz?timeout=0"), "<p>QPS over the last 4s: 1.00, SELECT: 0.75, INSERT: 0.25</p>")
+	assert.Contains(t, render(ring, "/querylogz?timeout=0&category=OLAP"), "<p>QPS over the last 4s: 0.25, SELECT: 0.25</p>")
+	assert.Contains(t, render(newQueryLogRing(10), "/querylogz?timeout=0"), "<p>QPS: n/a</p>")
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vtgate
+
+import (
+	"net/http"
+
+	"github.com/google/safehtml/template"
+
+	"vitess.io/vitess/go/vt/log"
+	"vitess.io/vitess/go/vt/vtgate/logstats"
+)
+
+var querylogzScatterTmpl = template.Must(template.New("scatter").Parse(`
+<p style="font-size: 1.2em">{{if .Total}}<b>Scatter queries: {{printf "%.1f" .Percent}}%</b> ({{.Scatter}} multi-shard, {{.Single}} single-shard){{else}}Scatter queries: n/a{{end}}</p>
+`))
+
+// querylogzScatterRate is the share of scatter queries rendered by
+// querylogzScatter.
+type querylogzScatterRate struct {
+	// Scatter is the number of queries sent to more than one shard, and
+	// Single the number of queries sent to exactly one.
+	Scatter, Single int
+	// Total is the number of queries sent to at least one shard, and
+	// Percent the share of scatter queries among them.
+	Total   int
+	Percent float64
+}
+
+// querylogzComputeScatter returns the share of scatter queries among the
+// records matching filter. Queries that vtgate served without sending a
+// query to any shard, such as SELECT 1, are left out.
+func querylogzComputeScatter(records []*logstats.LogStats, filter querylogzFilter) querylogzScatterRate {
+	var rate querylogzScatterRate
+	for _, stats := range records {
+		if !filter.matches(stats) {
+			continue
+		}
+		switch {
+		case stats.ShardQueries > 1:
+			rate.Scatter++
+		case stats.ShardQueries == 1:
+			rate.Single++
+		}
+	}
+	rate.Total = rate.Scatter + rate.Single
+	if rate.Total > 0 {
+		rate.Percent = 100 * float64(rate.Scatter) / float64(rate.Total)
+	}
+	return rate
+}
+
+// querylogzScatter renders the share of the buffered queries matching
+// filter that were sent to more than one shard. A high share usually points
+// at queries that don't filter on the primary vindex of their tables, or at
+// vindexes that don't fit the workload.
+func querylogzScatter(w http.ResponseWriter, records []*logstats.LogStats, filter querylogzFilter) {
+	if err := querylogzScatterTmpl.Execute(w, querylogzComputeScatter(records, filter)); err != nil {
+		log.Errorf("querylogz: couldn't execute scatter template: %v", err)
+	}
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vtgate
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"vitess.io/vitess/go/streamlog"
+	"vitess.io/vitess/go/vt/sqlparser"
+	"vitess.io/vitess/go/vt/vtgate/logstats"
+)
+
+func TestQuerylogzScatter(t *testing.T) {
+	ring := newQueryLogRing(10)
+	add := func(category string, shardQueries uint64) {
+		logStats := logstats.NewLogStats(context.Background(), "Execute", "select 1", "suuid", nil, streamlog.NewQueryLogConfigForTest())
+		logStats.QueryCategory = category
+		logStats.ShardQueries = shardQueries
+		logStats.EndTime = logStats.StartTime.Add(time.Millisecond)
+		ring.add(logStats)
+	}
+	add("", 0)
+	add("", 1)
+	add("", 1)
+	add("OLAP", 4)
+	add("OLAP", 1)
+
+	// The query that didn't reach any shard is left out.
+	assert.Equal(t, querylogzScatterRate{Scatter: 1, Single: 3, Total: 4, Percent: 25}, querylogzComputeScatter(ring.snapshot(), querylogzFilter{}))
+	assert.Equal(t, querylogzScatterRate{Scatter: 1, Single: 1, Total: 2, Percent: 50}, querylogzComputeScatter(ring.snapshot(), querylogzFilter{category: "OLAP"}))
+
+	render := func(ring *queryLogRing, url string) string {
+		req, _ := http.NewRequest("GET", url, nil)
+		response := httptest.NewRecorder()
+		querylogzHandler(make(chan *logstats.LogStats), ring, response, req, sqlparser.NewTestParser())
+		body, _ := io.ReadAll(response.Body)
+		return string(body)
+	}
+	assert.Contains(t, render(ring, "/querylogz?timeout=0&scatter=1"), "<b>Scatter queries: 25.0%</b> (1 multi-shard, 3 single-shard)")
+	assert.Contains(t, render(ring, "/querylogz?timeout=0&scatter=1&category=OLAP"), "<b>Scatter queries: 50.0%</b> (1 multi-shard, 1 single-shard)")
+	assert.Contains(t, render(newQueryLogRing(10), "/querylogz?timeout=0&scatter=1"), "Scatter queries: n/a")
+	assert.NotContains(t, render(ring, "/querylogz?timeout=0"), "Scatter queries")
+}
@@ -0,0 +1,52 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vtgate
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/safehtml/template"
+
+	"vitess.io/vitess/go/vt/log"
+)
+
+var querylogzSnapshotTmpl = template.Must(template.New("snapshot").Parse(`
+<p>Snapshot of <a href="{{.URL}}">{{.URL}}</a> taken at {{.Time}}</p>
+`))
+
+// querylogzSnapshot makes the querylogz view of r download as a standalone
+// HTML file, to attach to a ticket or keep as evidence of an investigation,
+// and writes the permalink of the view and the time it was taken at the top
+// of it. The page already inlines its styles and scripts, so it renders the
+// same offline. It must be called before anything else is written to w.
+func querylogzSnapshot(w http.ResponseWriter, r *http.Request) {
+	now := time.Now().UTC()
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "querylogz-"+now.Format("20060102-150405")+".html"))
+
+	// The permalink reopens the live view rather than another download.
+	view := r.Clone(r.Context())
+	query := view.URL.Query()
+	query.Del("self-contained")
+	view.URL.RawQuery = query.Encode()
+	data := struct{ URL, Time string }{querylogzPermalink(view), now.Format(time.RFC3339)}
+	if err := querylogzSnapshotTmpl.Execute(w, data); err != nil {
+		log.Errorf("querylogz: couldn't execute snapshot template: %v", err)
+	}
+}
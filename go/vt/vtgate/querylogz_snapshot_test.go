@@ -0,0 +1,65 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vtgate
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"vitess.io/vitess/go/streamlog"
+	"vitess.io/vitess/go/vt/sqlparser"
+	"vitess.io/vitess/go/vt/vtgate/logstats"
+)
+
+func TestQuerylogzSnapshot(t *testing.T) {
+	render := func(target string) *httptest.ResponseRecorder {
+		logStats := logstats.NewLogStats(context.Background(), "Execute", "select 1", "suuid", nil, streamlog.NewQueryLogConfigForTest())
+		logStats.EndTime = logStats.StartTime.Add(1 * time.Millisecond)
+		req := httptest.NewRequest("GET", target, nil)
+		response := httptest.NewRecorder()
+		ch := make(chan *logstats.LogStats, 1)
+		ch <- logStats
+		querylogzHandler(ch, nil, response, req, sqlparser.NewTestParser())
+		close(ch)
+		return response
+	}
+
+	// The live view is not downloaded.
+	response := render("/querylogz?timeout=1&limit=1")
+	assert.Empty(t, response.Header().Get("Content-Disposition"))
+	body, _ := io.ReadAll(response.Body)
+	assert.Contains(t, string(body), "next &raquo;")
+
+	response = render("/querylogz?timeout=1&limit=1&self-contained=1&humanize=1")
+	assert.Equal(t, "text/html; charset=utf-8", response.Header().Get("Content-Type"))
+	assert.Regexp(t, `^attachment; filename="querylogz-\d{8}-\d{6}\.html"$`, response.Header().Get("Content-Disposition"))
+	body, _ = io.ReadAll(response.Body)
+	page := string(body)
+	// The snapshot links back to the live view it was taken from.
+	assert.Contains(t, page, `Snapshot of <a href="http://example.com/querylogz?humanize=1&amp;limit=1&amp;timeout=1">`)
+	assert.Contains(t, page, "select 1")
+	// It has no links to the other pages of the live log, nor to anything
+	// else that must be fetched.
+	assert.NotContains(t, page, "next &raquo;")
+	assert.NotContains(t, page, "<link")
+	assert.NotContains(t, page, "<script src")
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vtgate
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"vitess.io/vitess/go/acl"
+	"vitess.io/vitess/go/vt/log"
+	"vitess.io/vitess/go/vt/sqlparser"
+	"vitess.io/vitess/go/vt/vtgate/logstats"
+)
+
+// querylogzSummaryGroup is the JSON summary of a group of records.
+type querylogzSummaryGroup struct {
+	Queries      int     `json:"queries"`
+	Errors       int     `json:"errors"`
+	ErrorPercent float64 `json:"error_percent"`
+	P50          float64 `json:"p50_seconds"`
+	P95          float64 `json:"p95_seconds"`
+	P99          float64 `json:"p99_seconds"`
+}
+
+// querylogzSummaryResponse is the JSON document returned by the query log
+// summary endpoint.
+type querylogzSummaryResponse struct {
+	querylogzSummaryGroup
+	// ByStmtType summarizes the records per statement type.
+	ByStmtType map[string]querylogzSummaryGroup `json:"by_stmt_type"`
+	// ByKeyspace summarizes the records per keyspace.
+	ByKeyspace map[string]querylogzSummaryGroup `json:"by_keyspace"`
+}
+
+func newQuerylogzSummaryGroup(row querylogzSummaryRow) querylogzSummaryGroup {
+	return querylogzSummaryGroup{
+		Queries:      row.Count,
+		Errors:       row.Errors,
+		ErrorPercent: 100 * float64(row.Errors) / float64(row.Count),
+		P50:          row.P50.Seconds(),
+		P95:          row.P95.Seconds(),
+		P99:          row.P99.Seconds(),
+	}
+}
+
+// querylogzSummaryHandler serves the number of records buffered in ring
+// matching the querylogz filters, their error rate and their total time
+// percentiles as JSON, overall and per statement type and keyspace, for
+// dashboards to scrape.
+func querylogzSummaryHandler(ring *queryLogRing, w http.ResponseWriter, r *http.Request, parser *sqlparser.Parser) {
+	if err := acl.CheckAccessHTTP(r, acl.DEBUGGING); err != nil {
+		acl.SendError(w, err)
+		return
+	}
+	if ring == nil {
+		http.Error(w, "the query log buffer is disabled, see --querylog-ring-size", http.StatusNotFound)
+		return
+	}
+	filter := parseQuerylogzFilter(r, parser)
+	records := ring.snapshot()
+
+	response := querylogzSummaryResponse{
+		ByStmtType: map[string]querylogzSummaryGroup{},
+		ByKeyspace: map[string]querylogzSummaryGroup{},
+	}
+	for _, row := range querylogzSummarize(records, filter, func(*logstats.LogStats) string { return "" }) {
+		response.querylogzSummaryGroup = newQuerylogzSummaryGroup(row)
+	}
+	for _, row := range querylogzSummarize(records, filter, querylogzStmtType) {
+		response.ByStmtType[row.Group] = newQuerylogzSummaryGroup(row)
+	}
+	for _, row := range querylogzSummarize(records, filter, querylogzKeyspace) {
+		response.ByKeyspace[row.Group] = newQuerylogzSummaryGroup(row)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Errorf("querylogz: couldn't write summary response: %v", err)
+	}
+}
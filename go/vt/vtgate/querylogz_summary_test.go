@@ -0,0 +1,87 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vtgate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"vitess.io/vitess/go/streamlog"
+	"vitess.io/vitess/go/vt/sqlparser"
+	"vitess.io/vitess/go/vt/vtgate/logstats"
+)
+
+func TestQuerylogzSummaryHandler(t *testing.T) {
+	ring := newQueryLogRing(10)
+	add := func(sql, stmtType, keyspace, category string, total time.Duration, err error) {
+		logStats := logstats.NewLogStats(context.Background(), "Execute", sql, "suuid", nil, streamlog.NewQueryLogConfigForTest())
+		logStats.StmtType = stmtType
+		logStats.ActiveKeyspace = keyspace
+		logStats.QueryCategory = category
+		logStats.Error = err
+		logStats.EndTime = logStats.StartTime.Add(total)
+		ring.add(logStats)
+	}
+	summary := func(url string) querylogzSummaryResponse {
+		req, _ := http.NewRequest("GET", url, nil)
+		response := httptest.NewRecorder()
+		querylogzSummaryHandler(ring, response, req, sqlparser.NewTestParser())
+		require.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, "application/json", response.Header().Get("Content-Type"))
+		var result querylogzSummaryResponse
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &result))
+		return result
+	}
+
+	add("select 1", "SELECT", "ks1", logstats.QueryCategoryOLTP, 1*time.Second, nil)
+	add("select 2", "SELECT", "ks2", logstats.QueryCategoryOLAP, 2*time.Second, nil)
+	add("select 3", "SELECT", "ks1", logstats.QueryCategoryOLTP, 3*time.Second, errors.New("boom"))
+	add("insert into t values (1)", "INSERT", "ks1", logstats.QueryCategoryOLTP, 4*time.Second, nil)
+	add("bogus", "", "", logstats.QueryCategoryOLTP, 5*time.Second, errors.New("syntax error"))
+
+	result := summary("/debug/querylogz/summary")
+	assert.Equal(t, querylogzSummaryGroup{Queries: 5, Errors: 2, ErrorPercent: 40, P50: 3, P95: 5, P99: 5}, result.querylogzSummaryGroup)
+	assert.Equal(t, map[string]querylogzSummaryGroup{
+		"SELECT":            {Queries: 3, Errors: 1, ErrorPercent: 100.0 / 3, P50: 2, P95: 3, P99: 3},
+		"INSERT":            {Queries: 1, P50: 4, P95: 4, P99: 4},
+		querylogzNoStmtType: {Queries: 1, Errors: 1, ErrorPercent: 100, P50: 5, P95: 5, P99: 5},
+	}, result.ByStmtType)
+	assert.Equal(t, map[string]querylogzSummaryGroup{
+		"ks1":               {Queries: 3, Errors: 1, ErrorPercent: 100.0 / 3, P50: 3, P95: 4, P99: 4},
+		"ks2":               {Queries: 1, P50: 2, P95: 2, P99: 2},
+		querylogzNoKeyspace: {Queries: 1, Errors: 1, ErrorPercent: 100, P50: 5, P95: 5, P99: 5},
+	}, result.ByKeyspace)
+
+	// The querylogz filters apply.
+	result = summary("/debug/querylogz/summary?category=OLAP")
+	assert.Equal(t, 1, result.Queries)
+	assert.Equal(t, map[string]querylogzSummaryGroup{"ks2": {Queries: 1, P50: 2, P95: 2, P99: 2}}, result.ByKeyspace)
+
+	// Without a ring buffer there is nothing to summarize.
+	req, _ := http.NewRequest("GET", "/debug/querylogz/summary", nil)
+	response := httptest.NewRecorder()
+	querylogzSummaryHandler(nil, response, req, sqlparser.NewTestParser())
+	assert.Equal(t, http.StatusNotFound, response.Code)
+}
@@ -18,19 +18,28 @@ package vtgate
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"regexp"
+	"slices"
 	"strings"
 	"testing"
 	"time"
 
+	"vitess.io/vitess/go/mysql/sqlerror"
 	"vitess.io/vitess/go/streamlog"
 	"vitess.io/vitess/go/vt/sqlparser"
+	"vitess.io/vitess/go/vt/vtgate/engine"
 	"vitess.io/vitess/go/vt/vtgate/logstats"
 
 	"vitess.io/vitess/go/vt/callerid"
+	"vitess.io/vitess/go/vt/callinfo"
+	"vitess.io/vitess/go/vt/callinfo/fakecallinfo"
 )
 
 func TestQuerylogzHandlerFormatting(t *testing.T) {
@@ -38,8 +47,10 @@ func TestQuerylogzHandlerFormatting(t *testing.T) {
 	logStats := logstats.NewLogStats(context.Background(), "Execute",
 		"select name, 'inject <script>alert();</script>' from test_table limit 1000", "suuid", nil, streamlog.NewQueryLogConfigForTest())
 	logStats.StmtType = "select"
+	logStats.QueryCategory = logstats.QueryCategoryOLTP
 	logStats.RowsAffected = 1000
 	logStats.ShardQueries = 1
+	logStats.TabletsContacted = 2
 	logStats.StartTime, _ = time.Parse("Jan 2 15:04:05", "Nov 29 13:33:09")
 	logStats.PlanTime = 1 * time.Millisecond
 	logStats.ExecuteTime = 2 * time.Millisecond
@@ -57,6 +68,7 @@ func TestQuerylogzHandlerFormatting(t *testing.T) {
 		`<td></td>`,
 		`<td>effective-caller</td>`,
 		`<td>immediate-caller</td>`,
+		`<td></td>`,
 		`<td>suuid</td>`,
 		`<td>Nov 29 13:33:09.000000</td>`,
 		`<td>Nov 29 13:33:09.001000</td>`,
@@ -65,17 +77,21 @@ func TestQuerylogzHandlerFormatting(t *testing.T) {
 		`<td>0.002</td>`,
 		`<td>0.003</td>`,
 		`<td>select</td>`,
+		`<td>OLTP</td>`,
 		regexp.QuoteMeta("<td>select name,\u200b &#39;inject &lt;script&gt;alert()\u200b;&lt;/script&gt;&#39; from test_table limit 1000</td>"),
+		`<td>test_table</td>`,
 		`<td>1</td>`,
+		`<td>2</td>`,
 		`<td>1000</td>`,
 		`<td></td>`,
+		`<td></td>`,
 		`</tr>`,
 	}
 	logStats.EndTime = logStats.StartTime.Add(1 * time.Millisecond)
 	response := httptest.NewRecorder()
 	ch := make(chan *logstats.LogStats, 1)
 	ch <- logStats
-	querylogzHandler(ch, response, req, sqlparser.NewTestParser())
+	querylogzHandler(ch, nil, response, req, sqlparser.NewTestParser())
 	close(ch)
 	body, _ := io.ReadAll(response.Body)
 	checkQuerylogzHasStats(t, fastQueryPattern, logStats, body)
@@ -87,6 +103,7 @@ func TestQuerylogzHandlerFormatting(t *testing.T) {
 		`<td></td>`,
 		`<td>effective-caller</td>`,
 		`<td>immediate-caller</td>`,
+		`<td></td>`,
 		`<td>suuid</td>`,
 		`<td>Nov 29 13:33:09.000000</td>`,
 		`<td>Nov 29 13:33:09.020000</td>`,
@@ -95,17 +112,21 @@ func TestQuerylogzHandlerFormatting(t *testing.T) {
 		`<td>0.002</td>`,
 		`<td>0.003</td>`,
 		`<td>select</td>`,
+		`<td>OLTP</td>`,
 		regexp.QuoteMeta("<td>select name,\u200b &#39;inject &lt;script&gt;alert()\u200b;&lt;/script&gt;&#39; from test_table limit 1000</td>"),
+		`<td>test_table</td>`,
 		`<td>1</td>`,
+		`<td>2</td>`,
 		`<td>1000</td>`,
 		`<td></td>`,
+		`<td></td>`,
 		`</tr>`,
 	}
 	logStats.EndTime = logStats.StartTime.Add(20 * time.Millisecond)
 	response = httptest.NewRecorder()
 	ch = make(chan *logstats.LogStats, 1)
 	ch <- logStats
-	querylogzHandler(ch, response, req, sqlparser.NewTestParser())
+	querylogzHandler(ch, nil, response, req, sqlparser.NewTestParser())
 	close(ch)
 	body, _ = io.ReadAll(response.Body)
 	checkQuerylogzHasStats(t, mediumQueryPattern, logStats, body)
@@ -117,6 +138,7 @@ func TestQuerylogzHandlerFormatting(t *testing.T) {
 		`<td></td>`,
 		`<td>effective-caller</td>`,
 		`<td>immediate-caller</td>`,
+		`<td></td>`,
 		`<td>suuid</td>`,
 		`<td>Nov 29 13:33:09.000000</td>`,
 		`<td>Nov 29 13:33:09.500000</td>`,
@@ -125,16 +147,20 @@ func TestQuerylogzHandlerFormatting(t *testing.T) {
 		`<td>0.002</td>`,
 		`<td>0.003</td>`,
 		`<td>select</td>`,
+		`<td>OLTP</td>`,
 		regexp.QuoteMeta("<td>select name,\u200b &#39;inject &lt;script&gt;alert()\u200b;&lt;/script&gt;&#39; from test_table limit 1000</td>"),
+		`<td>test_table</td>`,
 		`<td>1</td>`,
+		`<td>2</td>`,
 		`<td>1000</td>`,
 		`<td></td>`,
+		`<td></td>`,
 		`</tr>`,
 	}
 	logStats.EndTime = logStats.StartTime.Add(500 * time.Millisecond)
 	ch = make(chan *logstats.LogStats, 1)
 	ch <- logStats
-	querylogzHandler(ch, response, req, sqlparser.NewTestParser())
+	querylogzHandler(ch, nil, response, req, sqlparser.NewTestParser())
 	close(ch)
 	body, _ = io.ReadAll(response.Body)
 	checkQuerylogzHasStats(t, slowQueryPattern, logStats, body)
@@ -143,11 +169,84 @@ func TestQuerylogzHandlerFormatting(t *testing.T) {
 	logStats.Config.FilterTag = "XXX_SKIP_ME"
 	ch = make(chan *logstats.LogStats, 1)
 	ch <- logStats
-	querylogzHandler(ch, response, req, sqlparser.NewTestParser())
+	querylogzHandler(ch, nil, response, req, sqlparser.NewTestParser())
 	close(ch)
 	body, _ = io.ReadAll(response.Body)
 	checkQuerylogzHasStats(t, slowQueryPattern, logStats, body)
 
+	// failed query, colored as such whatever its latency
+	failedQueryPattern := []string{
+		`<tr class="error">`,
+		`<td>Execute</td>`,
+		`<td></td>`,
+		`<td>effective-caller</td>`,
+		`<td>immediate-caller</td>`,
+		`<td></td>`,
+		`<td>suuid</td>`,
+		`<td>Nov 29 13:33:09.000000</td>`,
+		`<td>Nov 29 13:33:09.001000</td>`,
+		`<td>0.001</td>`,
+		`<td>0.001</td>`,
+		`<td>0.002</td>`,
+		`<td>0.003</td>`,
+		`<td>select</td>`,
+		`<td>OLTP</td>`,
+		regexp.QuoteMeta("<td>select name,\u200b &#39;inject &lt;script&gt;alert()\u200b;&lt;/script&gt;&#39; from test_table limit 1000</td>"),
+		`<td>test_table</td>`,
+		`<td>1</td>`,
+		`<td>2</td>`,
+		`<td>1000</td>`,
+		regexp.QuoteMeta(`<td>unknown column &#39;&lt;script&gt;alert()&lt;/script&gt;&#39;</td>`),
+	}
+	logStats.EndTime = logStats.StartTime.Add(1 * time.Millisecond)
+	logStats.Error = errors.New("unknown column '<script>alert()</script>'")
+	response = httptest.NewRecorder()
+	ch = make(chan *logstats.LogStats, 1)
+	ch <- logStats
+	querylogzHandler(ch, nil, response, req, sqlparser.NewTestParser())
+	close(ch)
+	body, _ = io.ReadAll(response.Body)
+	checkQuerylogzHasStats(t, failedQueryPattern, logStats, body)
+}
+
+func TestQuerylogzHandlerThresholds(t *testing.T) {
+	logStats := logstats.NewLogStats(context.Background(), "Execute", "select 1", "suuid", nil, streamlog.NewQueryLogConfigForTest())
+	logStats.EndTime = logStats.StartTime.Add(20 * time.Millisecond)
+	render := func(query string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "/querylogz?timeout=10&limit=1"+query, nil)
+		response := httptest.NewRecorder()
+		ch := make(chan *logstats.LogStats, 1)
+		ch <- logStats
+		querylogzHandler(ch, nil, response, req, sqlparser.NewTestParser())
+		close(ch)
+		return response
+	}
+
+	tests := []struct {
+		query string
+		class string
+	}{
+		{"", "medium"},
+		{"&low=5ms", "medium"},
+		{"&high=15ms", "high"},
+		{"&low=0.05", "low"},
+		{"&low=1ms&high=0.02", "high"},
+		{"&low=20ms&high=1s", "medium"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			body := render(tt.query).Body.String()
+			if !strings.Contains(body, fmt.Sprintf(`<tr class="%s">`, tt.class)) {
+				t.Fatalf("row is not colored %s: %s", tt.class, body)
+			}
+		})
+	}
+
+	for _, query := range []string{"&low=fast", "&high=-1s", "&low=1s&high=10ms", "&low=200ms"} {
+		if code := render(query).Code; code != http.StatusBadRequest {
+			t.Fatalf("expected bad request for %q, got %d", query, code)
+		}
+	}
 }
 
 func checkQuerylogzHasStats(t *testing.T, pattern []string, logStats *logstats.LogStats, page []byte) {
@@ -157,3 +256,1869 @@ func checkQuerylogzHasStats(t *testing.T, pattern []string, logStats *logstats.L
 		t.Fatalf("querylogz page does not contain stats: %v, pattern: %v, page: %s", logStats, pattern, string(page))
 	}
 }
+
+func TestQuerylogzHandlerAge(t *testing.T) {
+	logStats := logstats.NewLogStats(context.Background(), "Execute", "select 1", "suuid", nil, streamlog.NewQueryLogConfigForTest())
+	logStats.StartTime = time.Now().Add(-2 * time.Second)
+	logStats.EndTime = logStats.StartTime.Add(1 * time.Millisecond)
+
+	req, _ := http.NewRequest("GET", "/querylogz?timeout=10&limit=1", nil)
+	response := httptest.NewRecorder()
+	ch := make(chan *logstats.LogStats, 1)
+	ch <- logStats
+	querylogzHandler(ch, nil, response, req, sqlparser.NewTestParser())
+	close(ch)
+	body, _ := io.ReadAll(response.Body)
+	if strings.Contains(string(body), "<th>Age</th>") {
+		t.Fatalf("age column should not be rendered by default: %s", body)
+	}
+
+	req, _ = http.NewRequest("GET", "/querylogz?timeout=10&limit=1&age=1", nil)
+	response = httptest.NewRecorder()
+	ch = make(chan *logstats.LogStats, 1)
+	ch <- logStats
+	querylogzHandler(ch, nil, response, req, sqlparser.NewTestParser())
+	close(ch)
+	body, _ = io.ReadAll(response.Body)
+	if !strings.Contains(string(body), "<th>Age</th>") {
+		t.Fatalf("age column header missing: %s", body)
+	}
+	checkQuerylogzHasStats(t, []string{
+		regexp.QuoteMeta(fmt.Sprintf("<td>%s</td>", logStats.EndTime.Format(time.StampMicro))),
+		`<td>2(\.\d+)?s ago</td>`,
+		`<td>0.001</td>`,
+	}, logStats, body)
+}
+
+func TestQuerylogzHandlerFormat(t *testing.T) {
+	logStats := logstats.NewLogStats(context.Background(), "Execute", "select 1", "suuid", nil, streamlog.NewQueryLogConfigForTest())
+	logStats.StartTime, _ = time.Parse("Jan 2 15:04:05", "Nov 29 13:33:09")
+	logStats.EndTime = logStats.StartTime.Add(1 * time.Millisecond)
+
+	req, _ := http.NewRequest("GET", "/querylogz?timeout=10&limit=1&format=json", nil)
+	response := httptest.NewRecorder()
+	ch := make(chan *logstats.LogStats, 1)
+	ch <- logStats
+	querylogzHandler(ch, nil, response, req, sqlparser.NewTestParser())
+	close(ch)
+	body, _ := io.ReadAll(response.Body)
+	var parsed map[string]any
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		t.Fatalf("querylogz did not return a json record: %v, body: %s", err, body)
+	}
+	if parsed["SQL"] != "select 1" || parsed["SessionUUID"] != "suuid" {
+		t.Fatalf("unexpected json record: %s", body)
+	}
+
+	// A client accepting json gets json records without asking for the
+	// format, within the same limit.
+	req, _ = http.NewRequest("GET", "/querylogz?timeout=10&limit=1", nil)
+	req.Header.Set("Accept", "text/plain;q=0.5, application/json")
+	response = httptest.NewRecorder()
+	ch = make(chan *logstats.LogStats, 2)
+	ch <- logStats
+	ch <- logStats
+	querylogzHandler(ch, nil, response, req, sqlparser.NewTestParser())
+	close(ch)
+	if got := response.Header().Get("Content-Type"); got != "application/json" {
+		t.Fatalf("unexpected content type %q", got)
+	}
+	decoder := json.NewDecoder(response.Body)
+	parsed = nil
+	if err := decoder.Decode(&parsed); err != nil {
+		t.Fatalf("querylogz did not return a json record: %v", err)
+	}
+	for _, field := range []string{"Method", "Effective Caller", "ImmediateCaller", "Start", "End", "TotalTime", "PlanTime", "ExecuteTime", "CommitTime", "StmtType", "SQL", "ShardQueries", "RowsAffected", "Error"} {
+		if _, ok := parsed[field]; !ok {
+			t.Fatalf("json record is missing %s: %v", field, parsed)
+		}
+	}
+	if decoder.More() {
+		t.Fatalf("querylogz returned more records than the limit")
+	}
+
+	req, _ = http.NewRequest("GET", "/querylogz?timeout=10&limit=1&format=nonexistent", nil)
+	response = httptest.NewRecorder()
+	querylogzHandler(ch, nil, response, req, sqlparser.NewTestParser())
+	if response.Code != http.StatusBadRequest {
+		t.Fatalf("expected bad request for unknown format, got %d", response.Code)
+	}
+}
+
+func TestQuerylogzHandlerShapes(t *testing.T) {
+	ring := newQueryLogRing(10)
+	add := func(sql string, total time.Duration) {
+		logStats := logstats.NewLogStats(context.Background(), "Execute", sql, "suuid", nil, streamlog.NewQueryLogConfigForTest())
+		logStats.EndTime = logStats.StartTime.Add(total)
+		ring.add(logStats)
+	}
+	add("select name from t where id = 1", 10*time.Millisecond)
+	add("select 1 from dual", 30*time.Millisecond)
+	add("select name from t where id = 2", 20*time.Millisecond)
+	add("select name from t where id = 3", 40*time.Millisecond)
+
+	render := func(url string) string {
+		req, _ := http.NewRequest("GET", url, nil)
+		response := httptest.NewRecorder()
+		querylogzHandler(make(chan *logstats.LogStats), ring, response, req, sqlparser.NewTestParser())
+		body, _ := io.ReadAll(response.Body)
+		return string(body)
+	}
+	bar := func(height, seconds string) string {
+		return fmt.Sprintf(`<span style="[^"]*height:%s;[^"]*" title="%ss"></span>`, regexp.QuoteMeta(height), regexp.QuoteMeta(seconds))
+	}
+
+	page := render("/querylogz?view=shapes")
+	for _, want := range []string{
+		// The most frequent shape comes first, its sparkline scaled to its
+		// slowest query.
+		"<td>select `name` from t where id = :p1</td>\\s*<td>3</td>\\s*<td>0.023333333</td>\\s*<td>" +
+			bar("0.50em", "0.01") + bar("1.00em", "0.02") + bar("2.00em", "0.04") + "</td>",
+		`<td>select :p1 from dual</td>\s*<td>1</td>\s*<td>0.03</td>\s*<td>` + bar("2.00em", "0.03") + `</td>`,
+	} {
+		if !regexp.MustCompile(want).MatchString(page) {
+			t.Fatalf("querylogz shapes do not contain %s: %s", want, page)
+		}
+	}
+
+	page = render("/querylogz?view=shapes&limit=1")
+	if strings.Contains(page, "from dual") {
+		t.Fatalf("querylogz shapes are not limited: %s", page)
+	}
+}
+
+func TestQuerylogzHandlerByCaller(t *testing.T) {
+	ring := newQueryLogRing(10)
+	add := func(caller, category string, total time.Duration) {
+		ctx := context.Background()
+		if caller != "" {
+			ctx = callerid.NewContext(ctx, callerid.NewEffectiveCallerID(caller, "", ""), nil)
+		}
+		logStats := logstats.NewLogStats(ctx, "Execute", "select 1", "suuid", nil, streamlog.NewQueryLogConfigForTest())
+		logStats.QueryCategory = category
+		logStats.EndTime = logStats.StartTime.Add(total)
+		ring.add(logStats)
+	}
+	add("app", "", 10*time.Millisecond)
+	add("batch", "OLAP", 300*time.Millisecond)
+	add("app", "", 30*time.Millisecond)
+	add("", "", 5*time.Millisecond)
+
+	render := func(url string) string {
+		req, _ := http.NewRequest("GET", url, nil)
+		response := httptest.NewRecorder()
+		querylogzHandler(make(chan *logstats.LogStats), ring, response, req, sqlparser.NewTestParser())
+		body, _ := io.ReadAll(response.Body)
+		return string(body)
+	}
+
+	// Callers are sorted by total time.
+	page := render("/querylogz?view=byCaller")
+	want := `<td>batch</td>\s*<td>1</td>\s*<td>0.3</td>\s*<td>0.3</td>` +
+		`[\s\S]*<td>app</td>\s*<td>2</td>\s*<td>0.04</td>\s*<td>0.02</td>` +
+		`[\s\S]*<td>\(none\)</td>\s*<td>1</td>\s*<td>0.005</td>\s*<td>0.005</td>`
+	if !regexp.MustCompile(want).MatchString(page) {
+		t.Fatalf("querylogz callers do not match %s: %s", want, page)
+	}
+
+	page = render("/querylogz?view=byCaller&limit=1")
+	if strings.Contains(page, "<td>app</td>") {
+		t.Fatalf("querylogz callers are not limited: %s", page)
+	}
+
+	page = render("/querylogz?view=byCaller&category=OLAP")
+	if strings.Contains(page, "<td>app</td>") || !strings.Contains(page, "<td>batch</td>") {
+		t.Fatalf("querylogz callers are not filtered: %s", page)
+	}
+}
+
+func TestQuerylogzHandlerBreakdown(t *testing.T) {
+	ring := newQueryLogRing(10)
+	add := func(sql string, plan, execute, commit, total time.Duration) {
+		logStats := logstats.NewLogStats(context.Background(), "Execute", sql, "suuid", nil, streamlog.NewQueryLogConfigForTest())
+		logStats.PlanTime = plan
+		logStats.ExecuteTime = execute
+		logStats.CommitTime = commit
+		logStats.EndTime = logStats.StartTime.Add(total)
+		logStats.QueryCategory = logstats.QueryCategoryOLTP
+		ring.add(logStats)
+	}
+	add("select 1", 10*time.Millisecond, 60*time.Millisecond, 20*time.Millisecond, 100*time.Millisecond)
+	add("select 2", 0, 40*time.Millisecond, 0, 50*time.Millisecond)
+	add("select 3", 0, time.Millisecond, 0, time.Millisecond)
+	ring.snapshot()[2].QueryCategory = logstats.QueryCategoryOLAP
+
+	render := func(url string) string {
+		req, _ := http.NewRequest("GET", url, nil)
+		response := httptest.NewRecorder()
+		querylogzHandler(make(chan *logstats.LogStats), ring, response, req, sqlparser.NewTestParser())
+		body, _ := io.ReadAll(response.Body)
+		return string(body)
+	}
+	segment := func(name, width, seconds string) string {
+		return fmt.Sprintf(`width:%s;" title="%s: %ss"`, regexp.QuoteMeta(width), name, seconds)
+	}
+
+	page := render("/querylogz?view=breakdown&category=OLTP")
+	for _, want := range []string{
+		`<td>select 1</td>\s*<td>0.1</td>\s*<td[^>]*>` +
+			`<span style="[^"]*` + segment("Plan", "10.00%", "0.01") + `></span>` +
+			`<span style="[^"]*` + segment("Execute", "60.00%", "0.06") + `></span>` +
+			`<span style="[^"]*` + segment("Commit", "20.00%", "0.02") + `></span>` +
+			`<span style="[^"]*` + segment("Other", "10.00%", "0.01") + `></span></td>`,
+		// Bars are scaled to the slowest query, and empty segments are left out.
+		`<td>select 2</td>\s*<td>0.05</td>\s*<td[^>]*>` +
+			`<span style="[^"]*` + segment("Execute", "40.00%", "0.04") + `></span>` +
+			`<span style="[^"]*` + segment("Other", "10.00%", "0.01") + `></span></td>`,
+	} {
+		if !regexp.MustCompile(want).MatchString(page) {
+			t.Fatalf("querylogz breakdown does not contain %s: %s", want, page)
+		}
+	}
+	// The breakdown only covers the records matching the filters.
+	if strings.Contains(page, "select 3") {
+		t.Fatalf("querylogz breakdown did not apply the filters: %s", page)
+	}
+}
+
+func TestQuerylogzHandlerBackfill(t *testing.T) {
+	ring := newQueryLogRing(10)
+	var buffered []*logstats.LogStats
+	for _, sql := range []string{"select 1", "select 2", "select 3"} {
+		logStats := logstats.NewLogStats(context.Background(), "Execute", sql, "suuid", nil, streamlog.NewQueryLogConfigForTest())
+		ring.add(logStats)
+		buffered = append(buffered, logStats)
+	}
+	live := logstats.NewLogStats(context.Background(), "Execute", "select 4", "suuid", nil, streamlog.NewQueryLogConfigForTest())
+
+	// The last buffered record was also received by the stream, and must
+	// only be written once.
+	ch := make(chan *logstats.LogStats, 2)
+	ch <- buffered[2]
+	ch <- live
+	req, _ := http.NewRequest("GET", "/querylogz?timeout=10&limit=1&format=json&backfill=2", nil)
+	response := httptest.NewRecorder()
+	querylogzHandler(ch, ring, response, req, sqlparser.NewTestParser())
+
+	var got []string
+	dec := json.NewDecoder(response.Body)
+	for dec.More() {
+		var parsed map[string]any
+		if err := dec.Decode(&parsed); err != nil {
+			t.Fatalf("querylogz did not return json records: %v", err)
+		}
+		got = append(got, parsed["SQL"].(string))
+	}
+	if want := []string{"select 2", "select 3", "select 4"}; !slices.Equal(got, want) {
+		t.Fatalf("got records %v, want %v", got, want)
+	}
+}
+
+func TestQuerylogzHandlerDetails(t *testing.T) {
+	logStats := logstats.NewLogStats(context.Background(), "Execute", "select 1", "suuid", nil, streamlog.NewQueryLogConfigForTest())
+	logStats.StartTime, _ = time.Parse("Jan 2 15:04:05", "Nov 29 13:33:09")
+	logStats.EndTime = logStats.StartTime.Add(10 * time.Millisecond)
+	logStats.AddConnectionSetupTime(5 * time.Millisecond)
+
+	req, _ := http.NewRequest("GET", "/querylogz?timeout=10&limit=1", nil)
+	response := httptest.NewRecorder()
+	ch := make(chan *logstats.LogStats, 1)
+	ch <- logStats
+	querylogzHandler(ch, nil, response, req, sqlparser.NewTestParser())
+	close(ch)
+	body, _ := io.ReadAll(response.Body)
+	checkQuerylogzHasStats(t, []string{
+		`<td>select 1</td>`,
+		`<td></td>`,
+		`<td>0</td>`,
+		`<td>0</td>`,
+		`<td>0</td>`,
+		`<td></td>`,
+		regexp.QuoteMeta(`<td>Conn Setup Time: 0.005<br></td>`),
+		`</tr>`,
+	}, logStats, body)
+}
+
+func TestQuerylogzHandlerPlanFingerprintFilter(t *testing.T) {
+	newStats := func(sql, fingerprint string) *logstats.LogStats {
+		logStats := logstats.NewLogStats(context.Background(), "Execute", sql, "suuid", nil, streamlog.NewQueryLogConfigForTest())
+		logStats.EndTime = logStats.StartTime.Add(1 * time.Millisecond)
+		logStats.PlanFingerprint = fingerprint
+		return logStats
+	}
+
+	req, _ := http.NewRequest("GET", "/querylogz?timeout=1&limit=1&plan_fingerprint=abcd", nil)
+	response := httptest.NewRecorder()
+	ch := make(chan *logstats.LogStats, 3)
+	ch <- newStats("select 1", "ffff")
+	ch <- newStats("select 2", "abcd")
+	ch <- newStats("select 3", "abcd")
+	querylogzHandler(ch, nil, response, req, sqlparser.NewTestParser())
+	close(ch)
+	body, _ := io.ReadAll(response.Body)
+	page := string(body)
+	if strings.Contains(page, "select 1") || strings.Contains(page, "select 3") {
+		t.Fatalf("querylogz rendered records that should have been filtered or limited: %s", page)
+	}
+	if !strings.Contains(page, "<td>select 2</td>") || !strings.Contains(page, "Plan Fingerprint: abcd") {
+		t.Fatalf("querylogz did not render the matching record: %s", page)
+	}
+}
+
+func TestQuerylogzHandlerPagination(t *testing.T) {
+	newStats := func(sql, fingerprint string) *logstats.LogStats {
+		logStats := logstats.NewLogStats(context.Background(), "Execute", sql, "suuid", nil, streamlog.NewQueryLogConfigForTest())
+		logStats.EndTime = logStats.StartTime.Add(1 * time.Millisecond)
+		logStats.PlanFingerprint = fingerprint
+		return logStats
+	}
+
+	req, _ := http.NewRequest("GET", "/querylogz?timeout=1&limit=2&offset=3&plan_fingerprint=abcd&age=1", nil)
+	response := httptest.NewRecorder()
+	ch := make(chan *logstats.LogStats, 8)
+	for i := 0; i < 7; i++ {
+		fingerprint := "abcd"
+		if i == 1 {
+			fingerprint = "ffff"
+		}
+		ch <- newStats(fmt.Sprintf("select %d", i), fingerprint)
+	}
+	querylogzHandler(ch, nil, response, req, sqlparser.NewTestParser())
+	close(ch)
+	body, _ := io.ReadAll(response.Body)
+	page := string(body)
+
+	// Records 0, 2 and 3 are the first three matches and are skipped,
+	// record 1 does not match the filter.
+	for _, sql := range []string{"select 0", "select 1", "select 2", "select 3", "select 6"} {
+		if strings.Contains(page, "<td>"+sql+"</td>") {
+			t.Fatalf("querylogz rendered %q, which is not on this page: %s", sql, page)
+		}
+	}
+	for _, sql := range []string{"select 4", "select 5"} {
+		if !strings.Contains(page, "<td>"+sql+"</td>") {
+			t.Fatalf("querylogz did not render %q: %s", sql, page)
+		}
+	}
+
+	// The pager links keep every other parameter intact.
+	wantPrev := "/querylogz?age=1&amp;limit=2&amp;offset=1&amp;plan_fingerprint=abcd&amp;timeout=1"
+	wantNext := "/querylogz?age=1&amp;limit=2&amp;offset=5&amp;plan_fingerprint=abcd&amp;timeout=1"
+	if !strings.Contains(page, `href="`+wantPrev+`"`) || !strings.Contains(page, `href="`+wantNext+`"`) {
+		t.Fatalf("querylogz pager links do not round-trip the request parameters: %s", page)
+	}
+}
+
+func TestQuerylogzHandlerHistogram(t *testing.T) {
+	ring := newQueryLogRing(10)
+	for _, d := range []time.Duration{
+		500 * time.Microsecond,
+		2 * time.Millisecond, 3 * time.Millisecond, 4 * time.Millisecond,
+		2 * time.Second,
+	} {
+		logStats := logstats.NewLogStats(context.Background(), "Execute", "select 1", "suuid", nil, streamlog.NewQueryLogConfigForTest())
+		logStats.EndTime = logStats.StartTime.Add(d)
+		logStats.PlanFingerprint = "abcd"
+		if d == 2*time.Second {
+			logStats.PlanFingerprint = "ffff"
+		}
+		ring.add(logStats)
+	}
+
+	render := func(url string) string {
+		req, _ := http.NewRequest("GET", url, nil)
+		response := httptest.NewRecorder()
+		querylogzHandler(make(chan *logstats.LogStats), ring, response, req, sqlparser.NewTestParser())
+		body, _ := io.ReadAll(response.Body)
+		return string(body)
+	}
+	bandRow := func(label string, count int, bar string) string {
+		return fmt.Sprintf(`<td>%s</td>\s*<td>%d</td>\s*<td><code>%s</code></td>`, regexp.QuoteMeta(label), count, bar)
+	}
+
+	hasBand := func(page, pattern string) {
+		t.Helper()
+		if !regexp.MustCompile(pattern).MatchString(page) {
+			t.Fatalf("histogram does not contain %s: %s", pattern, page)
+		}
+	}
+
+	page := render("/querylogz?view=histogram")
+	hasBand(page, bandRow("&lt; 1ms", 1, "#{20}"))
+	hasBand(page, bandRow("1ms - 10ms", 3, "#{60}"))
+	hasBand(page, bandRow("10ms - 100ms", 0, ""))
+	hasBand(page, bandRow("&gt;= 1s", 1, "#{20}"))
+
+	// The histogram only counts the records matching the filters.
+	page = render("/querylogz?view=histogram&plan_fingerprint=ffff")
+	hasBand(page, bandRow("1ms - 10ms", 0, ""))
+	hasBand(page, bandRow("&gt;= 1s", 1, "#{60}"))
+
+	req, _ := http.NewRequest("GET", "/querylogz?view=bogus", nil)
+	response := httptest.NewRecorder()
+	querylogzHandler(make(chan *logstats.LogStats), ring, response, req, sqlparser.NewTestParser())
+	if response.Code != http.StatusBadRequest {
+		t.Fatalf("querylogz returned %d for an unknown view, want %d", response.Code, http.StatusBadRequest)
+	}
+}
+
+func TestQuerylogzHandlerCategoryFilter(t *testing.T) {
+	newStats := func(sql, category string) *logstats.LogStats {
+		logStats := logstats.NewLogStats(context.Background(), "Execute", sql, "suuid", nil, streamlog.NewQueryLogConfigForTest())
+		logStats.EndTime = logStats.StartTime.Add(1 * time.Millisecond)
+		logStats.QueryCategory = category
+		return logStats
+	}
+
+	req, _ := http.NewRequest("GET", "/querylogz?timeout=1&limit=1&category=olap", nil)
+	response := httptest.NewRecorder()
+	ch := make(chan *logstats.LogStats, 2)
+	ch <- newStats("select 1", logstats.QueryCategoryOLTP)
+	ch <- newStats("select 2", logstats.QueryCategoryOLAP)
+	querylogzHandler(ch, nil, response, req, sqlparser.NewTestParser())
+	close(ch)
+	body, _ := io.ReadAll(response.Body)
+	page := string(body)
+	if strings.Contains(page, "<td>select 1</td>") {
+		t.Fatalf("querylogz rendered an OLTP query when filtering on OLAP: %s", page)
+	}
+	if !strings.Contains(page, "<td>OLAP</td>") || !strings.Contains(page, "<td>select 2</td>") {
+		t.Fatalf("querylogz did not render the OLAP query: %s", page)
+	}
+}
+
+func TestQuerylogzHandlerPresets(t *testing.T) {
+	newStats := func(sql, fingerprint string) *logstats.LogStats {
+		logStats := logstats.NewLogStats(context.Background(), "Execute", sql, "suuid", nil, streamlog.NewQueryLogConfigForTest())
+		logStats.EndTime = logStats.StartTime.Add(1 * time.Millisecond)
+		logStats.PlanFingerprint = fingerprint
+		return logStats
+	}
+	render := func(url string) (int, string) {
+		req, _ := http.NewRequest("GET", url, nil)
+		response := httptest.NewRecorder()
+		ch := make(chan *logstats.LogStats, 2)
+		ch <- newStats("select 1", "ffff")
+		ch <- newStats("select 2", "abcd")
+		querylogzHandler(ch, nil, response, req, sqlparser.NewTestParser())
+		body, _ := io.ReadAll(response.Body)
+		return response.Code, string(body)
+	}
+
+	// Saving a preset also applies it to the current request.
+	_, page := render("/querylogz?timeout=1&limit=1&plan_fingerprint=abcd&savePreset=mine")
+	if strings.Contains(page, "<td>select 1</td>") || !strings.Contains(page, "<td>select 2</td>") {
+		t.Fatalf("querylogz did not apply the filters of the saved preset: %s", page)
+	}
+
+	_, page = render("/querylogz?preset=mine")
+	if strings.Contains(page, "<td>select 1</td>") || !strings.Contains(page, "<td>select 2</td>") {
+		t.Fatalf("querylogz did not expand the preset: %s", page)
+	}
+	// The pager links carry the expanded parameters.
+	if !strings.Contains(page, `href="/querylogz?limit=1&amp;offset=1&amp;plan_fingerprint=abcd&amp;timeout=1"`) {
+		t.Fatalf("querylogz pager links do not carry the preset parameters: %s", page)
+	}
+
+	// Explicit parameters take precedence over the preset.
+	_, page = render("/querylogz?preset=mine&plan_fingerprint=ffff")
+	if !strings.Contains(page, "<td>select 1</td>") || strings.Contains(page, "<td>select 2</td>") {
+		t.Fatalf("querylogz did not let explicit parameters override the preset: %s", page)
+	}
+
+	code, _ := render("/querylogz?preset=unknown")
+	if code != http.StatusBadRequest {
+		t.Fatalf("querylogz returned %d for an unknown preset, want %d", code, http.StatusBadRequest)
+	}
+}
+
+func TestQuerylogzPresetStoreBounded(t *testing.T) {
+	store := &querylogzPresetStore{presets: map[string]url.Values{}}
+	for i := 0; i <= maxQuerylogzPresets; i++ {
+		store.save(fmt.Sprintf("preset%d", i), url.Values{"limit": {"1"}})
+	}
+	if _, ok := store.get("preset0"); ok {
+		t.Fatalf("the oldest preset was not evicted")
+	}
+	if _, ok := store.get(fmt.Sprintf("preset%d", maxQuerylogzPresets)); !ok {
+		t.Fatalf("the newest preset was not saved")
+	}
+	if len(store.presets) != maxQuerylogzPresets || len(store.names) != maxQuerylogzPresets {
+		t.Fatalf("store holds %d presets, want %d", len(store.presets), maxQuerylogzPresets)
+	}
+
+	// Saving an existing preset again refreshes it instead of growing the store.
+	store.save("preset1", url.Values{"limit": {"2"}})
+	params, _ := store.get("preset1")
+	if params.Get("limit") != "2" || len(store.names) != maxQuerylogzPresets {
+		t.Fatalf("overwriting a preset did not replace it in place: %v %v", params, store.names)
+	}
+}
+
+func TestQuerylogzHandlerMinTabletsFilter(t *testing.T) {
+	newStats := func(sql string, tablets uint64) *logstats.LogStats {
+		logStats := logstats.NewLogStats(context.Background(), "Execute", sql, "suuid", nil, streamlog.NewQueryLogConfigForTest())
+		logStats.EndTime = logStats.StartTime.Add(1 * time.Millisecond)
+		logStats.TabletsContacted = tablets
+		return logStats
+	}
+
+	req, _ := http.NewRequest("GET", "/querylogz?timeout=1&limit=1&min_tablets=3", nil)
+	response := httptest.NewRecorder()
+	ch := make(chan *logstats.LogStats, 2)
+	ch <- newStats("select 1", 2)
+	ch <- newStats("select 2", 5)
+	querylogzHandler(ch, nil, response, req, sqlparser.NewTestParser())
+	close(ch)
+	body, _ := io.ReadAll(response.Body)
+	page := string(body)
+	if strings.Contains(page, "<td>select 1</td>") || !strings.Contains(page, "<td>select 2</td>") {
+		t.Fatalf("querylogz did not filter on the number of tablets contacted: %s", page)
+	}
+}
+
+func TestQuerylogzHandlerTruncatedFilter(t *testing.T) {
+	newStats := func(sql string, truncated int) *logstats.LogStats {
+		logStats := logstats.NewLogStats(context.Background(), "Execute", sql, "suuid", nil, streamlog.NewQueryLogConfigForTest())
+		logStats.EndTime = logStats.StartTime.Add(1 * time.Millisecond)
+		logStats.AddRowsTruncated(truncated)
+		return logStats
+	}
+
+	req, _ := http.NewRequest("GET", "/querylogz?timeout=1&limit=1&truncated=true", nil)
+	response := httptest.NewRecorder()
+	ch := make(chan *logstats.LogStats, 2)
+	ch <- newStats("select 1", 0)
+	ch <- newStats("select 2", 7)
+	querylogzHandler(ch, nil, response, req, sqlparser.NewTestParser())
+	close(ch)
+	body, _ := io.ReadAll(response.Body)
+	page := string(body)
+	if strings.Contains(page, "<td>select 1</td>") || !strings.Contains(page, "<td>select 2</td>") {
+		t.Fatalf("querylogz did not filter on truncated queries: %s", page)
+	}
+	if !strings.Contains(page, "Rows Truncated: 7<br>") {
+		t.Fatalf("querylogz did not render the truncated rows: %s", page)
+	}
+}
+
+func TestQuerylogzHandlerExaminedRatioFilter(t *testing.T) {
+	newStats := func(sql string, examined int, returned uint64) *logstats.LogStats {
+		logStats := logstats.NewLogStats(context.Background(), "Execute", sql, "suuid", nil, streamlog.NewQueryLogConfigForTest())
+		logStats.EndTime = logStats.StartTime.Add(1 * time.Millisecond)
+		logStats.AddRowsExamined(examined)
+		logStats.RowsReturned = returned
+		return logStats
+	}
+
+	req, _ := http.NewRequest("GET", "/querylogz?timeout=1&limit=1&min_examined_ratio=10", nil)
+	response := httptest.NewRecorder()
+	ch := make(chan *logstats.LogStats, 2)
+	ch <- newStats("select 1", 20, 10)
+	ch <- newStats("select 2", 500, 4)
+	querylogzHandler(ch, nil, response, req, sqlparser.NewTestParser())
+	close(ch)
+	body, _ := io.ReadAll(response.Body)
+	page := string(body)
+	if strings.Contains(page, "<td>select 1</td>") || !strings.Contains(page, "<td>select 2</td>") {
+		t.Fatalf("querylogz did not filter on the examined ratio: %s", page)
+	}
+	if !strings.Contains(page, "Rows Examined: 500<br>Rows Returned: 4<br>Examined/Returned: 125.0<br>") {
+		t.Fatalf("querylogz did not render the examined rows: %s", page)
+	}
+}
+
+func TestQuerylogzHandlerDeadlineUsedFilter(t *testing.T) {
+	newStats := func(sql string, timeout, total time.Duration) *logstats.LogStats {
+		logStats := logstats.NewLogStats(context.Background(), "Execute", sql, "suuid", nil, streamlog.NewQueryLogConfigForTest())
+		logStats.Deadline = logStats.StartTime.Add(timeout)
+		logStats.EndTime = logStats.StartTime.Add(total)
+		return logStats
+	}
+
+	req, _ := http.NewRequest("GET", "/querylogz?timeout=1&limit=1&min_deadline_used=80", nil)
+	response := httptest.NewRecorder()
+	ch := make(chan *logstats.LogStats, 2)
+	ch <- newStats("select 1", time.Second, 100*time.Millisecond)
+	ch <- newStats("select 2", time.Second, 900*time.Millisecond)
+	querylogzHandler(ch, nil, response, req, sqlparser.NewTestParser())
+	close(ch)
+	body, _ := io.ReadAll(response.Body)
+	page := string(body)
+	if strings.Contains(page, "<td>select 1</td>") || !strings.Contains(page, "<td>select 2</td>") {
+		t.Fatalf("querylogz did not filter on the deadline used: %s", page)
+	}
+	if !strings.Contains(page, "Time To Deadline: 1<br>Deadline Used: 90%<br>") {
+		t.Fatalf("querylogz did not render the deadline: %s", page)
+	}
+}
+
+func TestQuerylogzHandlerCollapse(t *testing.T) {
+	newStats := func(sql, category string) *logstats.LogStats {
+		logStats := logstats.NewLogStats(context.Background(), "Execute", sql, "suuid", nil, streamlog.NewQueryLogConfigForTest())
+		logStats.EndTime = logStats.StartTime.Add(1 * time.Millisecond)
+		logStats.QueryCategory = category
+		return logStats
+	}
+
+	req, _ := http.NewRequest("GET", "/querylogz?timeout=1&limit=6&collapse=1&category=OLTP", nil)
+	response := httptest.NewRecorder()
+	ch := make(chan *logstats.LogStats, 7)
+	ch <- newStats("select * from t where id = 1", logstats.QueryCategoryOLTP)
+	ch <- newStats("select * from t where id = 2", logstats.QueryCategoryOLTP)
+	// Queries skipped by the filters don't break a run.
+	ch <- newStats("select count(*) from t", logstats.QueryCategoryOLAP)
+	ch <- newStats("select * from t where id = 3", logstats.QueryCategoryOLTP)
+	ch <- newStats("select * from u where id = 1", logstats.QueryCategoryOLTP)
+	ch <- newStats("select * from t where id = 4", logstats.QueryCategoryOLTP)
+	ch <- newStats("select * from t where id = 5", logstats.QueryCategoryOLTP)
+	querylogzHandler(ch, nil, response, req, sqlparser.NewTestParser())
+	close(ch)
+	body, _ := io.ReadAll(response.Body)
+	page := string(body)
+
+	var rendered []string
+	for _, match := range regexp.MustCompile(`<td>(select [^<]*)</td>`).FindAllStringSubmatch(page, -1) {
+		rendered = append(rendered, match[1])
+	}
+	want := []string{"select * from t where id = 1", "select * from u where id = 1", "select * from t where id = 4"}
+	if !slices.Equal(rendered, want) {
+		t.Fatalf("querylogz rendered %v, want %v: %s", rendered, want, page)
+	}
+	if strings.Count(page, "Collapsed: 2 more of this shape<br>") != 1 || strings.Count(page, "Collapsed: 1 more of this shape<br>") != 1 {
+		t.Fatalf("querylogz did not render the collapsed counts: %s", page)
+	}
+}
+
+func TestQuerylogzHandlerIndex(t *testing.T) {
+	newStats := func(sql string) *logstats.LogStats {
+		logStats := logstats.NewLogStats(context.Background(), "Execute", sql, "suuid", nil, streamlog.NewQueryLogConfigForTest())
+		logStats.EndTime = logStats.StartTime.Add(1 * time.Millisecond)
+		return logStats
+	}
+	serve := func(url string) string {
+		req, _ := http.NewRequest("GET", url, nil)
+		response := httptest.NewRecorder()
+		ch := make(chan *logstats.LogStats, 4)
+		ch <- newStats("select * from t where id = 1")
+		ch <- newStats("select * from t where id = 2")
+		ch <- newStats("select * from u where id = 1")
+		ch <- newStats("select * from t where id = 3")
+		querylogzHandler(ch, nil, response, req, sqlparser.NewTestParser())
+		close(ch)
+		body, _ := io.ReadAll(response.Body)
+		return string(body)
+	}
+
+	page := serve("/querylogz?timeout=1&limit=2")
+	if strings.Contains(page, "<th>#</th>") || strings.Contains(page, `id="row-`) {
+		t.Fatalf("querylogz numbered the rows by default: %s", page)
+	}
+
+	// Rows are numbered by their position among the matching queries,
+	// counting the ones skipped by the offset.
+	page = serve("/querylogz?timeout=1&limit=2&offset=1&index=1")
+	if !strings.Contains(page, "<th>#</th>") {
+		t.Fatalf("querylogz did not add the index column: %s", page)
+	}
+	for _, want := range []string{
+		`<td id="row-2"><a href="#row-2">2</a></td>`,
+		`<td id="row-3"><a href="#row-3">3</a></td>`,
+	} {
+		if !strings.Contains(page, want) {
+			t.Fatalf("querylogz did not render %s: %s", want, page)
+		}
+	}
+	if strings.Contains(page, `id="row-1"`) {
+		t.Fatalf("querylogz numbered a row skipped by the offset: %s", page)
+	}
+
+	// Collapsed rows keep the number of the first query of their run.
+	page = serve("/querylogz?timeout=1&limit=4&collapse=1&index=1")
+	for _, want := range []string{`id="row-1"`, `id="row-3"`, `id="row-4"`} {
+		if !strings.Contains(page, want) {
+			t.Fatalf("querylogz did not render %s: %s", want, page)
+		}
+	}
+	if strings.Contains(page, `id="row-2"`) {
+		t.Fatalf("querylogz numbered a collapsed query: %s", page)
+	}
+
+	page = serve("/querylogz?timeout=1&limit=1&compact=1&index=1")
+	if !strings.Contains(page, `<a id="row-1" href="#row-1">#1</a>`) {
+		t.Fatalf("querylogz did not number the card: %s", page)
+	}
+}
+
+func TestQuerylogzHandlerTopPerKeyspace(t *testing.T) {
+	ring := newQueryLogRing(10)
+	add := func(sql, keyspace string, d time.Duration) {
+		logStats := logstats.NewLogStats(context.Background(), "Execute", sql, "suuid", nil, streamlog.NewQueryLogConfigForTest())
+		logStats.EndTime = logStats.StartTime.Add(d)
+		logStats.ActiveKeyspace = keyspace
+		logStats.QueryCategory = logstats.QueryCategoryOLTP
+		ring.add(logStats)
+	}
+	add("select ks1_fast", "ks1", 1*time.Millisecond)
+	add("select ks1_slow", "ks1", 5*time.Second)
+	add("select ks1_mid", "ks1", 50*time.Millisecond)
+	add("select ks2_fast", "ks2", 2*time.Millisecond)
+	add("select noks", "", 3*time.Millisecond)
+
+	render := func(url string) string {
+		req, _ := http.NewRequest("GET", url, nil)
+		response := httptest.NewRecorder()
+		querylogzHandler(make(chan *logstats.LogStats), ring, response, req, sqlparser.NewTestParser())
+		body, _ := io.ReadAll(response.Body)
+		return string(body)
+	}
+
+	page := render("/querylogz?topPerKeyspace=2")
+	want := regexp.MustCompile(`(?s)` +
+		`Keyspace: \(none\).*select noks.*` +
+		`Keyspace: ks1.*select ks1_slow.*select ks1_mid.*` +
+		`Keyspace: ks2.*select ks2_fast`)
+	if !want.MatchString(page) {
+		t.Fatalf("querylogz did not group the slowest queries per keyspace: %s", page)
+	}
+	if strings.Contains(page, "select ks1_fast") {
+		t.Fatalf("querylogz showed more than 2 queries for ks1: %s", page)
+	}
+
+	// Filters apply before the queries are grouped.
+	page = render("/querylogz?topPerKeyspace=2&category=OLAP")
+	if strings.Contains(page, "Keyspace:") {
+		t.Fatalf("querylogz did not filter the grouped queries: %s", page)
+	}
+}
+
+func TestQuerylogzHandlerRecompiledFilter(t *testing.T) {
+	newStats := func(sql string, recompiled bool) *logstats.LogStats {
+		logStats := logstats.NewLogStats(context.Background(), "Execute", sql, "suuid", nil, streamlog.NewQueryLogConfigForTest())
+		logStats.EndTime = logStats.StartTime.Add(1 * time.Millisecond)
+		logStats.PlanRecompiled = recompiled
+		return logStats
+	}
+
+	req, _ := http.NewRequest("GET", "/querylogz?timeout=1&limit=1&recompiled=true", nil)
+	response := httptest.NewRecorder()
+	ch := make(chan *logstats.LogStats, 2)
+	ch <- newStats("select 1", false)
+	ch <- newStats("select 2", true)
+	querylogzHandler(ch, nil, response, req, sqlparser.NewTestParser())
+	close(ch)
+	body, _ := io.ReadAll(response.Body)
+	page := string(body)
+	if strings.Contains(page, "<td>select 1</td>") || !strings.Contains(page, "<td>select 2</td>") {
+		t.Fatalf("querylogz did not filter on recompiled queries: %s", page)
+	}
+	if !strings.Contains(page, "Plan Recompiled: true<br>") {
+		t.Fatalf("querylogz did not flag the recompiled query: %s", page)
+	}
+}
+
+func TestQuerylogzHandlerRepeatCount(t *testing.T) {
+	logStats := logstats.NewLogStats(context.Background(), "Execute", "select 1", "suuid", nil, streamlog.NewQueryLogConfigForTest())
+	logStats.EndTime = logStats.StartTime.Add(1 * time.Millisecond)
+	logStats.SetRepeatCount(12)
+
+	req, _ := http.NewRequest("GET", "/querylogz?timeout=1&limit=1", nil)
+	response := httptest.NewRecorder()
+	ch := make(chan *logstats.LogStats, 1)
+	ch <- logStats
+	querylogzHandler(ch, nil, response, req, sqlparser.NewTestParser())
+	close(ch)
+	body, _ := io.ReadAll(response.Body)
+	if page := string(body); !strings.Contains(page, "Repeated: 12 times<br>") {
+		t.Fatalf("querylogz did not render the repeat count: %s", page)
+	}
+}
+
+func TestQuerylogzHandlerSession(t *testing.T) {
+	logStats := logstats.NewLogStats(context.Background(), "Execute", "select 1", "suuid", nil, streamlog.NewQueryLogConfigForTest())
+	logStats.EndTime = logStats.StartTime.Add(1 * time.Millisecond)
+	logStats.SessionSettings = "workload=OLAP"
+
+	render := func(path string) string {
+		req, _ := http.NewRequest("GET", path, nil)
+		response := httptest.NewRecorder()
+		ch := make(chan *logstats.LogStats, 1)
+		ch <- logStats
+		querylogzHandler(ch, nil, response, req, sqlparser.NewTestParser())
+		close(ch)
+		body, _ := io.ReadAll(response.Body)
+		return string(body)
+	}
+
+	if page := render("/querylogz?timeout=1&limit=1"); strings.Contains(page, "Session Settings") {
+		t.Fatalf("querylogz showed the session settings without being asked to: %s", page)
+	}
+	page := render("/querylogz?timeout=1&limit=1&session=1")
+	if !strings.Contains(page, "<th>Session Settings</th>") || !strings.Contains(page, "<td>workload=OLAP</td>") {
+		t.Fatalf("querylogz did not show the session settings: %s", page)
+	}
+}
+
+func TestQuerylogzHandlerTableFilter(t *testing.T) {
+	newStats := func(sql string) *logstats.LogStats {
+		logStats := logstats.NewLogStats(context.Background(), "Execute", sql, "suuid", nil, streamlog.NewQueryLogConfigForTest())
+		logStats.EndTime = logStats.StartTime.Add(1 * time.Millisecond)
+		return logStats
+	}
+
+	render := func(url string, queries ...string) string {
+		req, _ := http.NewRequest("GET", url, nil)
+		response := httptest.NewRecorder()
+		ch := make(chan *logstats.LogStats, len(queries))
+		for _, sql := range queries {
+			ch <- newStats(sql)
+		}
+		querylogzHandler(ch, nil, response, req, sqlparser.NewTestParser())
+		close(ch)
+		body, _ := io.ReadAll(response.Body)
+		return string(body)
+	}
+
+	page := render("/querylogz?timeout=1&limit=2&table=music", "select * from t1", "select * from t1 join ks.music", "select * from music")
+	if strings.Contains(page, "<td>select * from t1</td>") {
+		t.Fatalf("querylogz did not filter on the table: %s", page)
+	}
+	if !strings.Contains(page, "<td>ks.music, t1</td>") || !strings.Contains(page, "<td>music</td>") {
+		t.Fatalf("querylogz did not render the tables of the matching queries: %s", page)
+	}
+
+	// Queries that can't be parsed have no tables and never match.
+	page = render("/querylogz?timeout=1&limit=1&table=music", "not sql music", "select 1 from music")
+	if strings.Contains(page, "not sql") {
+		t.Fatalf("querylogz matched a query that can't be parsed: %s", page)
+	}
+}
+
+func TestQuerylogzHandlerStmtTypeFilter(t *testing.T) {
+	render := func(url string, stmtTypes ...string) string {
+		req, _ := http.NewRequest("GET", url, nil)
+		response := httptest.NewRecorder()
+		ch := make(chan *logstats.LogStats, len(stmtTypes))
+		for i, stmtType := range stmtTypes {
+			logStats := logstats.NewLogStats(context.Background(), "Execute", fmt.Sprintf("query %d", i), "suuid", nil, streamlog.NewQueryLogConfigForTest())
+			logStats.StmtType = stmtType
+			logStats.EndTime = logStats.StartTime.Add(1 * time.Millisecond)
+			ch <- logStats
+		}
+		querylogzHandler(ch, nil, response, req, sqlparser.NewTestParser())
+		close(ch)
+		body, _ := io.ReadAll(response.Body)
+		return string(body)
+	}
+
+	// The limit counts the matching queries only.
+	page := render("/querylogz?timeout=1&limit=2&stmt_type=insert", "SELECT", "INSERT", "SELECT", "INSERT", "INSERT")
+	for _, sql := range []string{"query 1", "query 3"} {
+		if !strings.Contains(page, "<td>"+sql+"</td>") {
+			t.Fatalf("querylogz did not render the matching %s: %s", sql, page)
+		}
+	}
+	for _, sql := range []string{"query 0", "query 2", "query 4"} {
+		if strings.Contains(page, "<td>"+sql+"</td>") {
+			t.Fatalf("querylogz rendered %s: %s", sql, page)
+		}
+	}
+
+	// The json records are filtered too.
+	page = render("/querylogz?timeout=1&limit=1&stmt_type=Select&format=json", "INSERT", "SELECT")
+	if strings.Contains(page, "query 0") || !strings.Contains(page, "query 1") {
+		t.Fatalf("querylogz did not filter the json records on the statement type: %s", page)
+	}
+}
+
+func TestQuerylogzHandlerRewritten(t *testing.T) {
+	logStats := logstats.NewLogStats(context.Background(), "Execute", "select * from t1 where id = :id", "suuid", nil, streamlog.NewQueryLogConfigForTest())
+	logStats.EndTime = logStats.StartTime.Add(1 * time.Millisecond)
+	logStats.OriginalSQL = "select * from t1 where id = 1"
+	logStats.RewrittenSQL = "select * from t1 where id = :id limit 10"
+
+	render := func(url string) string {
+		req, _ := http.NewRequest("GET", url, nil)
+		response := httptest.NewRecorder()
+		ch := make(chan *logstats.LogStats, 1)
+		ch <- logStats
+		querylogzHandler(ch, nil, response, req, sqlparser.NewTestParser())
+		close(ch)
+		body, _ := io.ReadAll(response.Body)
+		return string(body)
+	}
+
+	page := render("/querylogz?timeout=1&limit=1")
+	if strings.Contains(page, "Rewritten SQL") || strings.Contains(page, "limit 10") {
+		t.Fatalf("querylogz showed the rewritten SQL without the toggle: %s", page)
+	}
+
+	page = render("/querylogz?timeout=1&limit=1&rewritten=1")
+	want := regexp.MustCompile(`<th>SQL</th>\s*<th>Original SQL</th>\s*<th>Rewritten SQL</th>(?s:.*)` +
+		`<td>select \* from t1 where id = :id</td>\s*` +
+		`<td>select \* from t1 where id = 1</td>\s*` +
+		`<td>select \* from t1 where id = :id limit 10</td>`)
+	if !want.MatchString(page) {
+		t.Fatalf("querylogz did not show the original and rewritten SQL: %s", page)
+	}
+
+	// The original SQL would show the values of redacted bind variables.
+	logStats.Config.RedactBindVars = regexp.MustCompile("id")
+	page = render("/querylogz?timeout=1&limit=1&rewritten=1")
+	if strings.Contains(page, "id = 1") {
+		t.Fatalf("querylogz showed the original SQL despite the bind variable redaction: %s", page)
+	}
+	if !regexp.MustCompile(`<td>\[REDACTED\]</td>\s*<td>select \* from t1 where id = :id limit 10</td>`).MatchString(page) {
+		t.Fatalf("querylogz did not redact only the original SQL: %s", page)
+	}
+
+	// They hold the literals of the query, so they are redacted with it.
+	logStats.Config.RedactBindVars = nil
+	logStats.Config.RedactDebugUIQueries = true
+	page = render("/querylogz?timeout=1&limit=1&rewritten=1")
+	if strings.Contains(page, "id = 1") || strings.Contains(page, "limit 10") {
+		t.Fatalf("querylogz showed the original and rewritten SQL despite the redaction: %s", page)
+	}
+	if !regexp.MustCompile(`<td>\[REDACTED\]</td>\s*<td>\[REDACTED\]</td>`).MatchString(page) {
+		t.Fatalf("querylogz did not redact the original and rewritten SQL: %s", page)
+	}
+}
+
+func TestQuerylogzHandlerSummary(t *testing.T) {
+	ring := newQueryLogRing(30)
+	add := func(stmtType string, d time.Duration) {
+		logStats := logstats.NewLogStats(context.Background(), "Execute", "select 1", "suuid", nil, streamlog.NewQueryLogConfigForTest())
+		logStats.EndTime = logStats.StartTime.Add(d)
+		logStats.StmtType = stmtType
+		ring.add(logStats)
+	}
+	for i := 1; i <= 20; i++ {
+		add("SELECT", time.Duration(i)*time.Millisecond)
+	}
+	add("INSERT", 100*time.Millisecond)
+	add("INSERT", 300*time.Millisecond)
+
+	render := func(url string) string {
+		req, _ := http.NewRequest("GET", url, nil)
+		response := httptest.NewRecorder()
+		querylogzHandler(make(chan *logstats.LogStats), ring, response, req, sqlparser.NewTestParser())
+		body, _ := io.ReadAll(response.Body)
+		return string(body)
+	}
+	summaryRow := func(stmtType string, count int, avg, p95 string) *regexp.Regexp {
+		return regexp.MustCompile(fmt.Sprintf(`<td>%s</td>\s*<td>%d</td>\s*<td>%s</td>\s*<td>%s</td>`, stmtType, count, avg, p95))
+	}
+
+	page := render("/querylogz?timeout=0&limit=1")
+	if strings.Contains(page, "P95 Duration") {
+		t.Fatalf("querylogz rendered the summary without the toggle: %s", page)
+	}
+
+	page = render("/querylogz?timeout=0&limit=1&summary=1")
+	for _, want := range []*regexp.Regexp{
+		summaryRow("INSERT", 2, "0.2", "0.3"),
+		summaryRow("SELECT", 20, "0.0105", "0.019"),
+	} {
+		if !want.MatchString(page) {
+			t.Fatalf("querylogz summary does not contain %s: %s", want, page)
+		}
+	}
+	if strings.Index(page, "P95 Duration") > strings.Index(page, "<th>Method</th>") {
+		t.Fatalf("querylogz summary is not rendered above the table: %s", page)
+	}
+
+	// The summary only covers the records matching the filters.
+	page = render("/querylogz?timeout=0&limit=1&summary=1&category=OLAP")
+	if strings.Contains(page, "<td>SELECT</td>") {
+		t.Fatalf("querylogz summary did not apply the filters: %s", page)
+	}
+}
+
+func TestQuerylogzHandlerApdex(t *testing.T) {
+	ring := newQueryLogRing(10)
+	add := func(d time.Duration, category string) {
+		logStats := logstats.NewLogStats(context.Background(), "Execute", "select 1", "suuid", nil, streamlog.NewQueryLogConfigForTest())
+		logStats.EndTime = logStats.StartTime.Add(d)
+		logStats.QueryCategory = category
+		ring.add(logStats)
+	}
+	add(5*time.Millisecond, logstats.QueryCategoryOLTP)
+	add(8*time.Millisecond, logstats.QueryCategoryOLTP)
+	add(20*time.Millisecond, logstats.QueryCategoryOLTP)
+	add(50*time.Millisecond, logstats.QueryCategoryOLAP)
+
+	render := func(url string) (int, string) {
+		req, _ := http.NewRequest("GET", url, nil)
+		response := httptest.NewRecorder()
+		querylogzHandler(make(chan *logstats.LogStats), ring, response, req, sqlparser.NewTestParser())
+		body, _ := io.ReadAll(response.Body)
+		return response.Code, string(body)
+	}
+
+	_, page := render("/querylogz?timeout=0&limit=1&target=10ms")
+	if !strings.Contains(page, "<h2>Apdex(10ms): 0.62</h2>") {
+		t.Fatalf("querylogz did not render the apdex score: %s", page)
+	}
+	if !strings.Contains(page, "2 satisfied, 1 tolerating, 1 frustrated out of 4 buffered queries") {
+		t.Fatalf("querylogz did not render the apdex breakdown: %s", page)
+	}
+
+	// The score only covers the records matching the filters.
+	_, page = render("/querylogz?timeout=0&limit=1&target=10ms&category=OLTP")
+	if !strings.Contains(page, "<h2>Apdex(10ms): 0.83</h2>") {
+		t.Fatalf("querylogz apdex score did not apply the filters: %s", page)
+	}
+	_, page = render("/querylogz?timeout=0&limit=1&target=10ms&category=none")
+	if !strings.Contains(page, "<h2>Apdex(10ms): n/a</h2>") {
+		t.Fatalf("querylogz apdex score without records: %s", page)
+	}
+
+	if code, _ := render("/querylogz?timeout=0&limit=1&target=fast"); code != http.StatusBadRequest {
+		t.Fatalf("expected bad request for an invalid target, got %d", code)
+	}
+}
+
+func TestQuerylogzHandlerScatterNoVindexFilter(t *testing.T) {
+	newStats := func(sql, reason string) *logstats.LogStats {
+		logStats := logstats.NewLogStats(context.Background(), "Execute", sql, "suuid", nil, streamlog.NewQueryLogConfigForTest())
+		logStats.EndTime = logStats.StartTime.Add(1 * time.Millisecond)
+		logStats.RoutingReason = reason
+		return logStats
+	}
+
+	req, _ := http.NewRequest("GET", "/querylogz?timeout=1&limit=1&scatter_no_vindex=true", nil)
+	response := httptest.NewRecorder()
+	ch := make(chan *logstats.LogStats, 2)
+	ch <- newStats("select 1", "vindex lookup on hash")
+	ch <- newStats("select 2", "vindex lookup on hash; "+engine.RoutingReasonScatterNoVindex)
+	querylogzHandler(ch, nil, response, req, sqlparser.NewTestParser())
+	close(ch)
+	body, _ := io.ReadAll(response.Body)
+	page := string(body)
+	if strings.Contains(page, "<td>select 1</td>") || !strings.Contains(page, "<td>select 2</td>") {
+		t.Fatalf("querylogz did not filter on scatters without a vindex: %s", page)
+	}
+	if !strings.Contains(page, "Routing Reason: vindex lookup on hash; scatter: no vindex<br>") {
+		t.Fatalf("querylogz did not render the routing reason: %s", page)
+	}
+}
+
+func TestQuerylogzHandlerRemoteFilter(t *testing.T) {
+	newStats := func(sql, remote string, config streamlog.QueryLogConfig) *logstats.LogStats {
+		ctx := callinfo.NewContext(context.Background(), &fakecallinfo.FakeCallInfo{Remote: remote})
+		logStats := logstats.NewLogStats(ctx, "Execute", sql, "suuid", nil, config)
+		logStats.EndTime = logStats.StartTime.Add(1 * time.Millisecond)
+		return logStats
+	}
+	render := func(url string, records ...*logstats.LogStats) string {
+		req, _ := http.NewRequest("GET", url, nil)
+		response := httptest.NewRecorder()
+		ch := make(chan *logstats.LogStats, len(records))
+		for _, stats := range records {
+			ch <- stats
+		}
+		querylogzHandler(ch, nil, response, req, sqlparser.NewTestParser())
+		close(ch)
+		body, _ := io.ReadAll(response.Body)
+		return string(body)
+	}
+
+	config := streamlog.NewQueryLogConfigForTest()
+	page := render("/querylogz?timeout=1&limit=1&remote=10.0.0.2",
+		newStats("select 1", "10.0.0.1:5000", config),
+		newStats("select 2", "10.0.0.2:5000", config))
+	if strings.Contains(page, "<td>select 1</td>") || !strings.Contains(page, "<td>select 2</td>") {
+		t.Fatalf("querylogz did not filter on the remote host: %s", page)
+	}
+	if !strings.Contains(page, "<td>10.0.0.2:5000</td>") {
+		t.Fatalf("querylogz did not render the remote address: %s", page)
+	}
+
+	page = render("/querylogz?timeout=1&limit=1&remote=10.0.0.2:5001",
+		newStats("select 1", "10.0.0.2:5000", config),
+		newStats("select 2", "10.0.0.2:5001", config))
+	if strings.Contains(page, "<td>select 1</td>") || !strings.Contains(page, "<td>select 2</td>") {
+		t.Fatalf("querylogz did not filter on the remote address: %s", page)
+	}
+
+	config.RedactDebugUIQueries = true
+	page = render("/querylogz?timeout=1&limit=1", newStats("select 1", "10.0.0.2:5000", config))
+	if strings.Contains(page, "10.0.0.2") || !strings.Contains(page, "<td>[REDACTED]</td>") {
+		t.Fatalf("querylogz did not redact the remote address: %s", page)
+	}
+}
+
+func TestQuerylogzHandlerProtocolFilter(t *testing.T) {
+	newStats := func(sql, protocol string) *logstats.LogStats {
+		ctx := callinfo.NewContext(context.Background(), &fakecallinfo.FakeCallInfo{Proto: protocol})
+		logStats := logstats.NewLogStats(ctx, "Execute", sql, "suuid", nil, streamlog.NewQueryLogConfigForTest())
+		logStats.EndTime = logStats.StartTime.Add(1 * time.Millisecond)
+		return logStats
+	}
+
+	req, _ := http.NewRequest("GET", "/querylogz?timeout=1&limit=1&protocol=gRPC", nil)
+	response := httptest.NewRecorder()
+	ch := make(chan *logstats.LogStats, 2)
+	ch <- newStats("select 1", callinfo.ProtocolMySQL)
+	ch <- newStats("select 2", callinfo.ProtocolGRPC)
+	querylogzHandler(ch, nil, response, req, sqlparser.NewTestParser())
+	close(ch)
+	body, _ := io.ReadAll(response.Body)
+	page := string(body)
+	if strings.Contains(page, "<td>select 1</td>") || !strings.Contains(page, "<td>select 2</td>") {
+		t.Fatalf("querylogz did not filter on the protocol: %s", page)
+	}
+	if !strings.Contains(page, "Protocol: grpc<br>") {
+		t.Fatalf("querylogz did not render the protocol: %s", page)
+	}
+}
+
+func TestQuerylogzHandlerPreparedFilter(t *testing.T) {
+	newStats := func(sql string, prepared bool) *logstats.LogStats {
+		logStats := logstats.NewLogStats(context.Background(), "Execute", sql, "suuid", nil, streamlog.NewQueryLogConfigForTest())
+		logStats.EndTime = logStats.StartTime.Add(1 * time.Millisecond)
+		logStats.Prepared = prepared
+		return logStats
+	}
+
+	for _, tcase := range []struct {
+		prepared        string
+		shown, filtered string
+	}{
+		{"true", "select 2", "select 1"},
+		{"false", "select 1", "select 2"},
+	} {
+		req, _ := http.NewRequest("GET", "/querylogz?timeout=1&limit=1&prepared="+tcase.prepared, nil)
+		response := httptest.NewRecorder()
+		ch := make(chan *logstats.LogStats, 2)
+		ch <- newStats("select 1", false)
+		ch <- newStats("select 2", true)
+		querylogzHandler(ch, nil, response, req, sqlparser.NewTestParser())
+		close(ch)
+		body, _ := io.ReadAll(response.Body)
+		page := string(body)
+		if strings.Contains(page, "<td>"+tcase.filtered+"</td>") || !strings.Contains(page, "<td>"+tcase.shown+"</td>") {
+			t.Fatalf("querylogz did not filter on prepared=%s: %s", tcase.prepared, page)
+		}
+		if strings.Contains(page, "Prepared: true<br>") != (tcase.prepared == "true") {
+			t.Fatalf("querylogz did not render the prepared flag: %s", page)
+		}
+	}
+}
+
+func TestQuerylogzHandlerReferenceFilter(t *testing.T) {
+	newStats := func(sql string, reference bool) *logstats.LogStats {
+		logStats := logstats.NewLogStats(context.Background(), "Execute", sql, "suuid", nil, streamlog.NewQueryLogConfigForTest())
+		logStats.EndTime = logStats.StartTime.Add(1 * time.Millisecond)
+		logStats.ReferenceTable = reference
+		return logStats
+	}
+
+	for _, tcase := range []struct {
+		reference       string
+		shown, filtered string
+	}{
+		{"true", "select 2", "select 1"},
+		{"false", "select 1", "select 2"},
+	} {
+		req, _ := http.NewRequest("GET", "/querylogz?timeout=1&limit=1&reference="+tcase.reference, nil)
+		response := httptest.NewRecorder()
+		ch := make(chan *logstats.LogStats, 2)
+		ch <- newStats("select 1", false)
+		ch <- newStats("select 2", true)
+		querylogzHandler(ch, nil, response, req, sqlparser.NewTestParser())
+		close(ch)
+		body, _ := io.ReadAll(response.Body)
+		page := string(body)
+		if strings.Contains(page, "<td>"+tcase.filtered+"</td>") || !strings.Contains(page, "<td>"+tcase.shown+"</td>") {
+			t.Fatalf("querylogz did not filter on reference=%s: %s", tcase.reference, page)
+		}
+		if strings.Contains(page, "Reference Table: true<br>") != (tcase.reference == "true") {
+			t.Fatalf("querylogz did not render the reference table flag: %s", page)
+		}
+	}
+}
+
+func TestQuerylogzHandlerMaterializedFilter(t *testing.T) {
+	newStats := func(sql string, materialized bool) *logstats.LogStats {
+		logStats := logstats.NewLogStats(context.Background(), "Execute", sql, "suuid", nil, streamlog.NewQueryLogConfigForTest())
+		logStats.EndTime = logStats.StartTime.Add(1 * time.Millisecond)
+		logStats.MaterializedWrite = materialized
+		return logStats
+	}
+
+	for _, tcase := range []struct {
+		materialized    string
+		shown, filtered string
+	}{
+		{"true", "update t set a = 2", "update t set a = 1"},
+		{"false", "update t set a = 1", "update t set a = 2"},
+	} {
+		req, _ := http.NewRequest("GET", "/querylogz?timeout=1&limit=1&materialized="+tcase.materialized, nil)
+		response := httptest.NewRecorder()
+		ch := make(chan *logstats.LogStats, 2)
+		ch <- newStats("update t set a = 1", false)
+		ch <- newStats("update t set a = 2", true)
+		querylogzHandler(ch, nil, response, req, sqlparser.NewTestParser())
+		close(ch)
+		body, _ := io.ReadAll(response.Body)
+		page := string(body)
+		if strings.Contains(page, "<td>"+tcase.filtered+"</td>") || !strings.Contains(page, "<td>"+tcase.shown+"</td>") {
+			t.Fatalf("querylogz did not filter on materialized=%s: %s", tcase.materialized, page)
+		}
+		if strings.Contains(page, "Materialized Write: true<br>") != (tcase.materialized == "true") {
+			t.Fatalf("querylogz did not render the materialized write flag: %s", page)
+		}
+	}
+}
+
+func TestQuerylogzHandlerDeniedTablesFilter(t *testing.T) {
+	newStats := func(sql string, denied bool) *logstats.LogStats {
+		logStats := logstats.NewLogStats(context.Background(), "Execute", sql, "suuid", nil, streamlog.NewQueryLogConfigForTest())
+		logStats.EndTime = logStats.StartTime.Add(1 * time.Millisecond)
+		logStats.DeniedTables = denied
+		return logStats
+	}
+
+	for _, tcase := range []struct {
+		denied          string
+		shown, filtered string
+	}{
+		{"true", "select 2", "select 1"},
+		{"false", "select 1", "select 2"},
+	} {
+		req, _ := http.NewRequest("GET", "/querylogz?timeout=1&limit=1&denied_tables="+tcase.denied, nil)
+		response := httptest.NewRecorder()
+		ch := make(chan *logstats.LogStats, 2)
+		ch <- newStats("select 1", false)
+		ch <- newStats("select 2", true)
+		querylogzHandler(ch, nil, response, req, sqlparser.NewTestParser())
+		close(ch)
+		body, _ := io.ReadAll(response.Body)
+		page := string(body)
+		if strings.Contains(page, "<td>"+tcase.filtered+"</td>") || !strings.Contains(page, "<td>"+tcase.shown+"</td>") {
+			t.Fatalf("querylogz did not filter on denied_tables=%s: %s", tcase.denied, page)
+		}
+		if strings.Contains(page, "Denied Tables: true<br>") != (tcase.denied == "true") {
+			t.Fatalf("querylogz did not render the denied tables flag: %s", page)
+		}
+	}
+}
+
+func TestQuerylogzHandlerPlannerFilter(t *testing.T) {
+	newStats := func(sql, planner string) *logstats.LogStats {
+		logStats := logstats.NewLogStats(context.Background(), "Execute", sql, "suuid", nil, streamlog.NewQueryLogConfigForTest())
+		logStats.EndTime = logStats.StartTime.Add(1 * time.Millisecond)
+		logStats.PlannerVersion = planner
+		return logStats
+	}
+
+	for _, tcase := range []struct {
+		planner         string
+		shown, filtered string
+	}{
+		{"Gen4", "select 1", "select 2"},
+		{"gen4left2right", "select 2", "select 1"},
+	} {
+		req, _ := http.NewRequest("GET", "/querylogz?timeout=1&limit=1&planner="+tcase.planner, nil)
+		response := httptest.NewRecorder()
+		ch := make(chan *logstats.LogStats, 2)
+		ch <- newStats("select 1", "Gen4")
+		ch <- newStats("select 2", "Gen4Left2Right")
+		querylogzHandler(ch, nil, response, req, sqlparser.NewTestParser())
+		close(ch)
+		body, _ := io.ReadAll(response.Body)
+		page := string(body)
+		if strings.Contains(page, "<td>"+tcase.filtered+"</td>") || !strings.Contains(page, "<td>"+tcase.shown+"</td>") {
+			t.Fatalf("querylogz did not filter on planner=%s: %s", tcase.planner, page)
+		}
+		if !strings.Contains(page, "Planner: ") {
+			t.Fatalf("querylogz did not render the planner: %s", page)
+		}
+	}
+}
+
+func TestQuerylogzHandlerRouteHintFilter(t *testing.T) {
+	newStats := func(sql, hint string) *logstats.LogStats {
+		logStats := logstats.NewLogStats(context.Background(), "Execute", sql, "suuid", nil, streamlog.NewQueryLogConfigForTest())
+		logStats.EndTime = logStats.StartTime.Add(1 * time.Millisecond)
+		logStats.RouteHint = hint
+		return logStats
+	}
+
+	req, _ := http.NewRequest("GET", "/querylogz?timeout=1&limit=1&route_hint=allow_scatter", nil)
+	response := httptest.NewRecorder()
+	ch := make(chan *logstats.LogStats, 2)
+	ch <- newStats("select 1", "")
+	ch <- newStats("select 2", "ALLOW_SCATTER")
+	querylogzHandler(ch, nil, response, req, sqlparser.NewTestParser())
+	close(ch)
+	body, _ := io.ReadAll(response.Body)
+	page := string(body)
+	if strings.Contains(page, "<td>select 1</td>") || !strings.Contains(page, "<td>select 2</td>") {
+		t.Fatalf("querylogz did not filter on route_hint: %s", page)
+	}
+	if !strings.Contains(page, "Route Hint: ALLOW_SCATTER<br>") {
+		t.Fatalf("querylogz did not render the route hint: %s", page)
+	}
+}
+
+func TestQuerylogzHandlerMinRowsBufferedFilter(t *testing.T) {
+	newStats := func(sql string, buffered uint64) *logstats.LogStats {
+		logStats := logstats.NewLogStats(context.Background(), "Execute", sql, "suuid", nil, streamlog.NewQueryLogConfigForTest())
+		logStats.EndTime = logStats.StartTime.Add(1 * time.Millisecond)
+		logStats.RowsBuffered = buffered
+		return logStats
+	}
+
+	req, _ := http.NewRequest("GET", "/querylogz?timeout=1&limit=1&min_rows_buffered=1000", nil)
+	response := httptest.NewRecorder()
+	ch := make(chan *logstats.LogStats, 2)
+	ch <- newStats("select 1", 10)
+	ch <- newStats("select 2", 5000)
+	querylogzHandler(ch, nil, response, req, sqlparser.NewTestParser())
+	close(ch)
+	body, _ := io.ReadAll(response.Body)
+	page := string(body)
+	if strings.Contains(page, "<td>select 1</td>") || !strings.Contains(page, "<td>select 2</td>") {
+		t.Fatalf("querylogz did not filter on min_rows_buffered: %s", page)
+	}
+	if !strings.Contains(page, "Rows Buffered: 5000<br>") {
+		t.Fatalf("querylogz did not render the rows buffered: %s", page)
+	}
+}
+
+func TestQuerylogzHandlerMinPostProcessingFilter(t *testing.T) {
+	newStats := func(sql string, ops uint64) *logstats.LogStats {
+		logStats := logstats.NewLogStats(context.Background(), "Execute", sql, "suuid", nil, streamlog.NewQueryLogConfigForTest())
+		logStats.EndTime = logStats.StartTime.Add(1 * time.Millisecond)
+		logStats.PostProcessingOps = ops
+		return logStats
+	}
+
+	req, _ := http.NewRequest("GET", "/querylogz?timeout=1&limit=1&min_post_processing=2", nil)
+	response := httptest.NewRecorder()
+	ch := make(chan *logstats.LogStats, 2)
+	ch <- newStats("select 1", 1)
+	ch <- newStats("select 2", 3)
+	querylogzHandler(ch, nil, response, req, sqlparser.NewTestParser())
+	close(ch)
+	body, _ := io.ReadAll(response.Body)
+	page := string(body)
+	if strings.Contains(page, "<td>select 1</td>") || !strings.Contains(page, "<td>select 2</td>") {
+		t.Fatalf("querylogz did not filter on min_post_processing: %s", page)
+	}
+	if !strings.Contains(page, "Post-Processing Ops: 3<br>") {
+		t.Fatalf("querylogz did not render the post-processing ops: %s", page)
+	}
+}
+
+func TestQuerylogzHandlerBackendConnections(t *testing.T) {
+	logStats := logstats.NewLogStats(context.Background(), "Execute", "select 1", "suuid", nil, streamlog.NewQueryLogConfigForTest())
+	logStats.EndTime = logStats.StartTime.Add(1 * time.Millisecond)
+	logStats.AddBackendConnectionID("ks/80-", 7)
+	logStats.AddBackendConnectionID("ks/-80", 12)
+
+	req, _ := http.NewRequest("GET", "/querylogz?timeout=1&limit=1", nil)
+	response := httptest.NewRecorder()
+	ch := make(chan *logstats.LogStats, 1)
+	ch <- logStats
+	querylogzHandler(ch, nil, response, req, sqlparser.NewTestParser())
+	close(ch)
+	body, _ := io.ReadAll(response.Body)
+	page := string(body)
+	if !strings.Contains(page, "Backend Connections: ks/-80: 12, ks/80-: 7<br>") {
+		t.Fatalf("querylogz did not render the backend connections: %s", page)
+	}
+}
+
+func TestQuerylogzHandlerCollationFilter(t *testing.T) {
+	newStats := func(sql, collation string) *logstats.LogStats {
+		logStats := logstats.NewLogStats(context.Background(), "Execute", sql, "suuid", nil, streamlog.NewQueryLogConfigForTest())
+		logStats.EndTime = logStats.StartTime.Add(1 * time.Millisecond)
+		logStats.Collation = collation
+		return logStats
+	}
+
+	req, _ := http.NewRequest("GET", "/querylogz?timeout=1&limit=1&collation=UTF8MB4_BIN", nil)
+	response := httptest.NewRecorder()
+	ch := make(chan *logstats.LogStats, 2)
+	ch <- newStats("select 1", "utf8mb4_0900_ai_ci")
+	ch <- newStats("select 2", "utf8mb4_bin")
+	querylogzHandler(ch, nil, response, req, sqlparser.NewTestParser())
+	close(ch)
+	body, _ := io.ReadAll(response.Body)
+	page := string(body)
+	if strings.Contains(page, "<td>select 1</td>") || !strings.Contains(page, "<td>select 2</td>") {
+		t.Fatalf("querylogz did not filter on collation: %s", page)
+	}
+	if !strings.Contains(page, "Collation: utf8mb4_bin<br>") {
+		t.Fatalf("querylogz did not render the collation: %s", page)
+	}
+}
+
+func TestQuerylogzHandlerFastPathFilter(t *testing.T) {
+	newStats := func(sql string, fastPath bool) *logstats.LogStats {
+		logStats := logstats.NewLogStats(context.Background(), "Execute", sql, "suuid", nil, streamlog.NewQueryLogConfigForTest())
+		logStats.EndTime = logStats.StartTime.Add(1 * time.Millisecond)
+		logStats.FastPath = fastPath
+		return logStats
+	}
+
+	for _, tcase := range []struct {
+		fastPath        string
+		shown, filtered string
+	}{
+		{"true", "select 2", "select 1"},
+		{"false", "select 1", "select 2"},
+	} {
+		req, _ := http.NewRequest("GET", "/querylogz?timeout=1&limit=1&fast_path="+tcase.fastPath, nil)
+		response := httptest.NewRecorder()
+		ch := make(chan *logstats.LogStats, 2)
+		ch <- newStats("select 1", false)
+		ch <- newStats("select 2", true)
+		querylogzHandler(ch, nil, response, req, sqlparser.NewTestParser())
+		close(ch)
+		body, _ := io.ReadAll(response.Body)
+		page := string(body)
+		if strings.Contains(page, "<td>"+tcase.filtered+"</td>") || !strings.Contains(page, "<td>"+tcase.shown+"</td>") {
+			t.Fatalf("querylogz did not filter on fast_path=%s: %s", tcase.fastPath, page)
+		}
+		if strings.Contains(page, "Fast Path: true<br>") != (tcase.fastPath == "true") {
+			t.Fatalf("querylogz did not render the fast path flag: %s", page)
+		}
+	}
+}
+
+func TestQuerylogzHandlerMinKeyspacesFilter(t *testing.T) {
+	newStats := func(sql string, keyspaces uint64) *logstats.LogStats {
+		logStats := logstats.NewLogStats(context.Background(), "Execute", sql, "suuid", nil, streamlog.NewQueryLogConfigForTest())
+		logStats.EndTime = logStats.StartTime.Add(1 * time.Millisecond)
+		logStats.KeyspacesTouched = keyspaces
+		return logStats
+	}
+
+	req, _ := http.NewRequest("GET", "/querylogz?timeout=1&limit=1&min_keyspaces=2", nil)
+	response := httptest.NewRecorder()
+	ch := make(chan *logstats.LogStats, 2)
+	ch <- newStats("select 1", 1)
+	ch <- newStats("select 2", 2)
+	querylogzHandler(ch, nil, response, req, sqlparser.NewTestParser())
+	close(ch)
+	body, _ := io.ReadAll(response.Body)
+	page := string(body)
+	if strings.Contains(page, "<td>select 1</td>") || !strings.Contains(page, "<td>select 2</td>") {
+		t.Fatalf("querylogz did not filter on min_keyspaces: %s", page)
+	}
+	if !strings.Contains(page, "Keyspaces Touched: 2<br>") {
+		t.Fatalf("querylogz did not render the keyspaces touched: %s", page)
+	}
+}
+
+func TestQuerylogzFormatDuration(t *testing.T) {
+	for _, tcase := range []struct {
+		d                 time.Duration
+		seconds, humanize string
+	}{
+		{0, "0", "0s"},
+		{750 * time.Nanosecond, "7.5e-07", "750ns"},
+		{340 * time.Microsecond, "0.00034", "340µs"},
+		{1234 * time.Microsecond, "0.001234", "1.2ms"},
+		{2149 * time.Millisecond, "2.149", "2.1s"},
+		{90 * time.Second, "90", "1m30s"},
+	} {
+		if got := querylogzFormatDuration(tcase.d, false); got != tcase.seconds {
+			t.Errorf("querylogzFormatDuration(%v, false) = %q, want %q", tcase.d, got, tcase.seconds)
+		}
+		if got := querylogzFormatDuration(tcase.d, true); got != tcase.humanize {
+			t.Errorf("querylogzFormatDuration(%v, true) = %q, want %q", tcase.d, got, tcase.humanize)
+		}
+	}
+}
+
+func TestQuerylogzHandlerHumanize(t *testing.T) {
+	logStats := logstats.NewLogStats(context.Background(), "Execute", "select 1", "suuid", nil, streamlog.NewQueryLogConfigForTest())
+	logStats.EndTime = logStats.StartTime.Add(2100 * time.Millisecond)
+	logStats.PlanTime = 340 * time.Microsecond
+	logStats.ExecuteTime = 1200 * time.Microsecond
+	logStats.ParseTime = 15 * time.Microsecond
+
+	for _, tcase := range []struct {
+		url  string
+		want []string
+	}{
+		{"/querylogz?timeout=1&limit=1", []string{"<td>2.1</td>", "<td>0.00034</td>", "<td>0.0012</td>", "<td>0</td>", "Parse Time: 1.5e-05<br>"}},
+		{"/querylogz?timeout=1&limit=1&humanize=1", []string{"<td>2.1s</td>", "<td>340µs</td>", "<td>1.2ms</td>", "<td>0s</td>", "Parse Time: 15µs<br>"}},
+		{"/querylogz?timeout=1&limit=1&humanize=1&compact=1", []string{"<b>Execute</b> 2.1s</div>"}},
+	} {
+		req, _ := http.NewRequest("GET", tcase.url, nil)
+		response := httptest.NewRecorder()
+		ch := make(chan *logstats.LogStats, 1)
+		ch <- logStats
+		querylogzHandler(ch, nil, response, req, sqlparser.NewTestParser())
+		close(ch)
+		body, _ := io.ReadAll(response.Body)
+		for _, want := range tcase.want {
+			if !strings.Contains(string(body), want) {
+				t.Fatalf("querylogz at %s does not contain %s: %s", tcase.url, want, body)
+			}
+		}
+	}
+}
+
+func TestQuerylogzHandlerOnlineDDLFilter(t *testing.T) {
+	newStats := func(sql string, uuids ...string) *logstats.LogStats {
+		logStats := logstats.NewLogStats(context.Background(), "Execute", sql, "suuid", nil, streamlog.NewQueryLogConfigForTest())
+		logStats.EndTime = logStats.StartTime.Add(1 * time.Millisecond)
+		logStats.OnlineDDL = len(uuids) > 0
+		logStats.MigrationUUIDs = uuids
+		return logStats
+	}
+
+	for _, tcase := range []struct {
+		params          string
+		shown, filtered string
+	}{
+		{"online_ddl=true", "alter table t2 add column c int", "alter table t1 add column c int"},
+		{"online_ddl=false", "alter table t1 add column c int", "alter table t2 add column c int"},
+		{"migration=bbb", "alter table t2 add column c int", "alter table t1 add column c int"},
+	} {
+		req, _ := http.NewRequest("GET", "/querylogz?timeout=1&limit=1&"+tcase.params, nil)
+		response := httptest.NewRecorder()
+		ch := make(chan *logstats.LogStats, 2)
+		ch <- newStats("alter table t1 add column c int")
+		ch <- newStats("alter table t2 add column c int", "aaa", "bbb")
+		querylogzHandler(ch, nil, response, req, sqlparser.NewTestParser())
+		close(ch)
+		body, _ := io.ReadAll(response.Body)
+		page := string(body)
+		if strings.Contains(page, "<td>"+tcase.filtered+"</td>") || !strings.Contains(page, "<td>"+tcase.shown+"</td>") {
+			t.Fatalf("querylogz did not filter on %s: %s", tcase.params, page)
+		}
+		if strings.Contains(page, "Online DDL: aaa, bbb<br>") != (tcase.params != "online_ddl=false") {
+			t.Fatalf("querylogz did not render the online DDL migrations: %s", page)
+		}
+	}
+}
+
+func TestQuerylogzHandlerAnnotationFilter(t *testing.T) {
+	newStats := func(sql string, annotations ...string) *logstats.LogStats {
+		ctx := context.Background()
+		for _, requestID := range annotations {
+			ctx = logstats.WithAnnotation(ctx, "request_id", requestID)
+		}
+		logStats := logstats.NewLogStats(ctx, "Execute", sql, "suuid", nil, streamlog.NewQueryLogConfigForTest())
+		logStats.EndTime = logStats.StartTime.Add(1 * time.Millisecond)
+		return logStats
+	}
+	render := func(url string) string {
+		req, _ := http.NewRequest("GET", url, nil)
+		response := httptest.NewRecorder()
+		ch := make(chan *logstats.LogStats, 3)
+		ch <- newStats("select 1")
+		ch <- newStats("select 2", "r-1")
+		ch <- newStats("select 3", "r-2")
+		querylogzHandler(ch, nil, response, req, sqlparser.NewTestParser())
+		close(ch)
+		body, _ := io.ReadAll(response.Body)
+		return string(body)
+	}
+
+	page := render("/querylogz?timeout=1&limit=1&annotation=request_id:r-2")
+	if strings.Contains(page, "<td>select 2</td>") || !strings.Contains(page, "<td>select 3</td>") {
+		t.Fatalf("querylogz did not filter on the annotation value: %s", page)
+	}
+	if !strings.Contains(page, "request_id: r-2<br>") {
+		t.Fatalf("querylogz did not render the annotations: %s", page)
+	}
+
+	page = render("/querylogz?timeout=1&limit=2&annotation=request_id")
+	if strings.Contains(page, "<td>select 1</td>") || !strings.Contains(page, "<td>select 2</td>") || !strings.Contains(page, "<td>select 3</td>") {
+		t.Fatalf("querylogz did not filter on the annotation key: %s", page)
+	}
+}
+
+func TestQuerylogzHandlerLookupFilter(t *testing.T) {
+	newStats := func(sql string, lookups int) *logstats.LogStats {
+		logStats := logstats.NewLogStats(context.Background(), "Execute", sql, "suuid", nil, streamlog.NewQueryLogConfigForTest())
+		logStats.EndTime = logStats.StartTime.Add(1 * time.Millisecond)
+		for range lookups {
+			logStats.AddLookupRoundTrip(250 * time.Microsecond)
+		}
+		return logStats
+	}
+
+	req, _ := http.NewRequest("GET", "/querylogz?timeout=1&limit=1&min_lookups=2", nil)
+	response := httptest.NewRecorder()
+	ch := make(chan *logstats.LogStats, 2)
+	ch <- newStats("select 1", 1)
+	ch <- newStats("select 2", 2)
+	querylogzHandler(ch, nil, response, req, sqlparser.NewTestParser())
+	close(ch)
+	body, _ := io.ReadAll(response.Body)
+	page := string(body)
+	if strings.Contains(page, "<td>select 1</td>") || !strings.Contains(page, "<td>select 2</td>") {
+		t.Fatalf("querylogz did not filter on the lookup round trips: %s", page)
+	}
+	if !strings.Contains(page, "Lookup Round Trips: 2<br>") || !strings.Contains(page, "Lookup Time: 0.0005<br>") {
+		t.Fatalf("querylogz did not render the lookup round trips: %s", page)
+	}
+}
+
+func TestQuerylogzHandlerParseTimeFilter(t *testing.T) {
+	newStats := func(sql string, parseTime time.Duration) *logstats.LogStats {
+		logStats := logstats.NewLogStats(context.Background(), "Execute", sql, "suuid", nil, streamlog.NewQueryLogConfigForTest())
+		logStats.PlanTime = 3 * time.Millisecond
+		logStats.ParseTime = parseTime
+		logStats.EndTime = logStats.StartTime.Add(5 * time.Millisecond)
+		return logStats
+	}
+
+	req, _ := http.NewRequest("GET", "/querylogz?timeout=1&limit=1&min_parse_time=1ms", nil)
+	response := httptest.NewRecorder()
+	ch := make(chan *logstats.LogStats, 2)
+	ch <- newStats("select 1", 100*time.Microsecond)
+	ch <- newStats("select 2", 2*time.Millisecond)
+	querylogzHandler(ch, nil, response, req, sqlparser.NewTestParser())
+	close(ch)
+	body, _ := io.ReadAll(response.Body)
+	page := string(body)
+	if strings.Contains(page, "<td>select 1</td>") || !strings.Contains(page, "<td>select 2</td>") {
+		t.Fatalf("querylogz did not filter on the parse time: %s", page)
+	}
+	if !strings.Contains(page, "Parse Time: 0.002<br>") {
+		t.Fatalf("querylogz did not render the parse time: %s", page)
+	}
+}
+
+func TestQuerylogzHandlerBufferedFilter(t *testing.T) {
+	newStats := func(sql string, buffered time.Duration) *logstats.LogStats {
+		logStats := logstats.NewLogStats(context.Background(), "Execute", sql, "suuid", nil, streamlog.NewQueryLogConfigForTest())
+		logStats.EndTime = logStats.StartTime.Add(2 * time.Second)
+		if buffered > 0 {
+			logStats.AddBufferTime(buffered)
+		}
+		return logStats
+	}
+
+	render := func(path string) string {
+		req, _ := http.NewRequest("GET", path, nil)
+		response := httptest.NewRecorder()
+		ch := make(chan *logstats.LogStats, 2)
+		ch <- newStats("select 1", 0)
+		ch <- newStats("select 2", 1500*time.Millisecond)
+		querylogzHandler(ch, nil, response, req, sqlparser.NewTestParser())
+		close(ch)
+		body, _ := io.ReadAll(response.Body)
+		return string(body)
+	}
+
+	page := render("/querylogz?timeout=1&limit=1&buffered=true")
+	if strings.Contains(page, "<td>select 1</td>") || !strings.Contains(page, "<td>select 2</td>") {
+		t.Fatalf("querylogz did not filter on buffering: %s", page)
+	}
+	if !strings.Contains(page, "Buffer Time: 1.5<br>") {
+		t.Fatalf("querylogz did not render the buffer time: %s", page)
+	}
+
+	page = render("/querylogz?timeout=1&limit=1&buffered=false")
+	if !strings.Contains(page, "<td>select 1</td>") || strings.Contains(page, "<td>select 2</td>") {
+		t.Fatalf("querylogz did not filter on buffering: %s", page)
+	}
+}
+
+func TestQuerylogzHandlerErrnoFilter(t *testing.T) {
+	newStats := func(sql string, err error) *logstats.LogStats {
+		logStats := logstats.NewLogStats(context.Background(), "Execute", sql, "suuid", nil, streamlog.NewQueryLogConfigForTest())
+		logStats.EndTime = logStats.StartTime.Add(1 * time.Millisecond)
+		logStats.Error = err
+		return logStats
+	}
+
+	req, _ := http.NewRequest("GET", "/querylogz?timeout=1&limit=1&errno=1062", nil)
+	response := httptest.NewRecorder()
+	ch := make(chan *logstats.LogStats, 3)
+	ch <- newStats("select 1", nil)
+	ch <- newStats("select 2", sqlerror.NewSQLError(sqlerror.ERLockWaitTimeout, sqlerror.SSUnknownSQLState, "Lock wait timeout exceeded"))
+	ch <- newStats("select 3", sqlerror.NewSQLError(sqlerror.ERDupEntry, sqlerror.SSConstraintViolation, "Duplicate entry"))
+	querylogzHandler(ch, nil, response, req, sqlparser.NewTestParser())
+	close(ch)
+	body, _ := io.ReadAll(response.Body)
+	page := string(body)
+	if strings.Contains(page, "<td>select 1</td>") || strings.Contains(page, "<td>select 2</td>") || !strings.Contains(page, "<td>select 3</td>") {
+		t.Fatalf("querylogz did not filter on the error number: %s", page)
+	}
+	if !strings.Contains(page, "Error Number: 1062<br>") {
+		t.Fatalf("querylogz did not render the error number: %s", page)
+	}
+}
+
+func TestQuerylogzHandlerStatusFilter(t *testing.T) {
+	newStats := func(sql, category string, err error) *logstats.LogStats {
+		logStats := logstats.NewLogStats(context.Background(), "Execute", sql, "suuid", nil, streamlog.NewQueryLogConfigForTest())
+		logStats.EndTime = logStats.StartTime.Add(1 * time.Millisecond)
+		logStats.QueryCategory = category
+		logStats.Error = err
+		return logStats
+	}
+
+	for _, tcase := range []struct {
+		params          string
+		shown, filtered []string
+	}{
+		{"status=ok", []string{"select 1", "select 3"}, []string{"select 2", "select 4"}},
+		{"status=error", []string{"select 2", "select 4"}, []string{"select 1", "select 3"}},
+		// Other values don't filter.
+		{"status=", []string{"select 1", "select 2", "select 3", "select 4"}, nil},
+		// The status composes with the other filters.
+		{"status=error&category=OLAP", []string{"select 4"}, []string{"select 1", "select 2", "select 3"}},
+	} {
+		req, _ := http.NewRequest("GET", "/querylogz?timeout=1&limit=4&"+tcase.params, nil)
+		response := httptest.NewRecorder()
+		ch := make(chan *logstats.LogStats, 4)
+		ch <- newStats("select 1", logstats.QueryCategoryOLTP, nil)
+		ch <- newStats("select 2", logstats.QueryCategoryOLTP, errors.New("failed"))
+		ch <- newStats("select 3", logstats.QueryCategoryOLAP, nil)
+		ch <- newStats("select 4", logstats.QueryCategoryOLAP, errors.New("failed"))
+		querylogzHandler(ch, nil, response, req, sqlparser.NewTestParser())
+		close(ch)
+		body, _ := io.ReadAll(response.Body)
+		page := string(body)
+		for _, sql := range tcase.shown {
+			if !strings.Contains(page, "<td>"+sql+"</td>") {
+				t.Fatalf("querylogz with %s did not show %s: %s", tcase.params, sql, page)
+			}
+		}
+		for _, sql := range tcase.filtered {
+			if strings.Contains(page, "<td>"+sql+"</td>") {
+				t.Fatalf("querylogz with %s did not filter %s: %s", tcase.params, sql, page)
+			}
+		}
+	}
+}
+
+func TestQuerylogzHandlerCopyButton(t *testing.T) {
+	render := func(path string, config streamlog.QueryLogConfig) string {
+		sql := "select name from t where note = 'a \"quoted\" <b>note</b>'"
+		logStats := logstats.NewLogStats(context.Background(), "Execute", sql, "suuid", nil, config)
+		logStats.EndTime = logStats.StartTime.Add(1 * time.Millisecond)
+		req, _ := http.NewRequest("GET", path, nil)
+		response := httptest.NewRecorder()
+		ch := make(chan *logstats.LogStats, 1)
+		ch <- logStats
+		querylogzHandler(ch, nil, response, req, sqlparser.NewTestParser())
+		close(ch)
+		body, _ := io.ReadAll(response.Body)
+		return string(body)
+	}
+
+	config := streamlog.NewQueryLogConfigForTest()
+	page := render("/querylogz?timeout=1&limit=1&copy=1", config)
+	want := `<button class="copy-sql" data-sql="select name from t where note = &#39;a &#34;quoted&#34; &lt;b&gt;note&lt;/b&gt;&#39;" hidden>Copy</button>`
+	if !strings.Contains(page, want) {
+		t.Fatalf("querylogz did not render the copy button %s: %s", want, page)
+	}
+	if !strings.Contains(page, "navigator.clipboard.writeText") {
+		t.Fatalf("querylogz did not render the copy script: %s", page)
+	}
+
+	page = render("/querylogz?timeout=1&limit=1", config)
+	if strings.Contains(page, "copy-sql") {
+		t.Fatalf("querylogz rendered the copy button without copy=1: %s", page)
+	}
+
+	config.RedactDebugUIQueries = true
+	page = render("/querylogz?timeout=1&limit=1&copy=1", config)
+	if strings.Contains(page, "<button") {
+		t.Fatalf("querylogz rendered the copy button of a redacted query: %s", page)
+	}
+}
+
+func TestQuerylogzHandlerAppFilter(t *testing.T) {
+	newStats := func(sql, app string) *logstats.LogStats {
+		ctx := callinfo.NewContext(context.Background(), &fakecallinfo.FakeCallInfo{Proto: callinfo.ProtocolMySQL, App: app})
+		logStats := logstats.NewLogStats(ctx, "Execute", sql, "suuid", nil, streamlog.NewQueryLogConfigForTest())
+		logStats.EndTime = logStats.StartTime.Add(1 * time.Millisecond)
+		return logStats
+	}
+
+	req, _ := http.NewRequest("GET", "/querylogz?timeout=1&limit=1&app=billing", nil)
+	response := httptest.NewRecorder()
+	ch := make(chan *logstats.LogStats, 2)
+	ch <- newStats("select 1", "reports")
+	ch <- newStats("select 2", "billing")
+	querylogzHandler(ch, nil, response, req, sqlparser.NewTestParser())
+	close(ch)
+	body, _ := io.ReadAll(response.Body)
+	page := string(body)
+	if strings.Contains(page, "<td>select 1</td>") || !strings.Contains(page, "<td>select 2</td>") {
+		t.Fatalf("querylogz did not filter on the application name: %s", page)
+	}
+	if !strings.Contains(page, "Application: billing<br>") {
+		t.Fatalf("querylogz did not render the application name: %s", page)
+	}
+}
+
+func TestQuerylogzHandlerCompressionFilter(t *testing.T) {
+	newStats := func(sql, compression string) *logstats.LogStats {
+		ctx := callinfo.NewContext(context.Background(), &fakecallinfo.FakeCallInfo{Proto: callinfo.ProtocolGRPC, Comp: compression})
+		logStats := logstats.NewLogStats(ctx, "Execute", sql, "suuid", nil, streamlog.NewQueryLogConfigForTest())
+		logStats.EndTime = logStats.StartTime.Add(1 * time.Millisecond)
+		return logStats
+	}
+
+	for _, tcase := range []struct {
+		compression     string
+		shown, filtered string
+	}{
+		{"snappy", "select 2", "select 1"},
+		{"none", "select 1", "select 2"},
+	} {
+		req, _ := http.NewRequest("GET", "/querylogz?timeout=1&limit=1&compression="+tcase.compression, nil)
+		response := httptest.NewRecorder()
+		ch := make(chan *logstats.LogStats, 2)
+		ch <- newStats("select 1", "")
+		ch <- newStats("select 2", "snappy")
+		querylogzHandler(ch, nil, response, req, sqlparser.NewTestParser())
+		close(ch)
+		body, _ := io.ReadAll(response.Body)
+		page := string(body)
+		if strings.Contains(page, "<td>"+tcase.filtered+"</td>") || !strings.Contains(page, "<td>"+tcase.shown+"</td>") {
+			t.Fatalf("querylogz did not filter on compression=%s: %s", tcase.compression, page)
+		}
+		if strings.Contains(page, "Compression: snappy<br>") != (tcase.compression == "snappy") {
+			t.Fatalf("querylogz did not render the compression: %s", page)
+		}
+	}
+}
+
+func TestQuerylogzHandlerSticky(t *testing.T) {
+	logStats := logstats.NewLogStats(context.Background(), "Execute", "select 1", "suuid", nil, streamlog.NewQueryLogConfigForTest())
+	logStats.EndTime = logStats.StartTime.Add(1 * time.Millisecond)
+
+	for _, tcase := range []struct {
+		url    string
+		sticky bool
+	}{
+		{"/querylogz?timeout=1&limit=1", false},
+		{"/querylogz?timeout=1&limit=1&sticky=1", true},
+		{"/querylogz?timeout=1&limit=1&sticky=1&compact=1", false},
+	} {
+		req, _ := http.NewRequest("GET", tcase.url, nil)
+		response := httptest.NewRecorder()
+		ch := make(chan *logstats.LogStats, 1)
+		ch <- logStats
+		querylogzHandler(ch, nil, response, req, sqlparser.NewTestParser())
+		close(ch)
+		body, _ := io.ReadAll(response.Body)
+		if got := strings.Contains(string(body), `<div class="sticky">`); got != tcase.sticky {
+			t.Fatalf("querylogz at %s has a sticky header: %v, want %v: %s", tcase.url, got, tcase.sticky, body)
+		}
+		if tcase.sticky && !strings.Contains(string(body), "position: sticky;") {
+			t.Fatalf("querylogz at %s does not pin the header row: %s", tcase.url, body)
+		}
+		if !strings.Contains(string(body), "select 1") {
+			t.Fatalf("querylogz at %s does not contain the query: %s", tcase.url, body)
+		}
+	}
+}
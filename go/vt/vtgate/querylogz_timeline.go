@@ -0,0 +1,90 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vtgate
+
+import (
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/google/safehtml"
+
+	"vitess.io/vitess/go/vt/vtgate/logstats"
+)
+
+// querylogzTimelineWidth is the width, in pixels, that the whole query spans
+// in the shard timeline.
+const querylogzTimelineWidth = 200
+
+// querylogzTimelineSpan is a line of the shard timeline: the query sent to
+// Shard, starting Offset after the query and lasting Duration. Spacer pushes
+// Bar to the start of the shard query, and Bar spans its duration.
+type querylogzTimelineSpan struct {
+	Shard    string
+	Offset   string
+	Duration string
+	Spacer   safehtml.Style
+	Bar      safehtml.Style
+}
+
+// querylogzShardTimeline lays out the queries stats sent to the shards
+// against the whole query, in the order they started, so that a shard
+// lagging behind the others or shards queried one after another stand out.
+func querylogzShardTimeline(stats *logstats.LogStats, humanize bool) []querylogzTimelineSpan {
+	if len(stats.ShardTimings) == 0 {
+		return nil
+	}
+	timings := slices.Clone(stats.ShardTimings)
+	slices.SortStableFunc(timings, func(a, b logstats.ShardTiming) int {
+		return a.Start.Compare(b.Start)
+	})
+
+	// Shard queries may outlast the query when they were streaming and the
+	// client hung up, so the scale covers both.
+	total := stats.TotalTime()
+	for _, timing := range timings {
+		total = max(total, timing.End.Sub(stats.StartTime))
+	}
+	scale := func(d time.Duration) float64 {
+		if total <= 0 {
+			return 0
+		}
+		return querylogzTimelineWidth * float64(max(d, 0)) / float64(total)
+	}
+
+	spans := make([]querylogzTimelineSpan, 0, len(timings))
+	for _, timing := range timings {
+		offset := timing.Start.Sub(stats.StartTime)
+		duration := timing.End.Sub(timing.Start)
+		spans = append(spans, querylogzTimelineSpan{
+			Shard:    timing.Shard,
+			Offset:   querylogzFormatDuration(offset, humanize),
+			Duration: querylogzFormatDuration(duration, humanize),
+			Spacer: safehtml.StyleFromProperties(safehtml.StyleProperties{
+				Display: "inline-block",
+				Width:   strconv.FormatFloat(scale(offset), 'f', 2, 64) + "px",
+			}),
+			Bar: safehtml.StyleFromProperties(safehtml.StyleProperties{
+				Display:         "inline-block",
+				Width:           strconv.FormatFloat(max(scale(duration), 1), 'f', 2, 64) + "px",
+				Height:          "0.8em",
+				BackgroundColor: "#4e79a7",
+			}),
+		})
+	}
+	return spans
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vtgate
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"vitess.io/vitess/go/streamlog"
+	"vitess.io/vitess/go/vt/sqlparser"
+	"vitess.io/vitess/go/vt/vtgate/logstats"
+)
+
+func TestQuerylogzShardTimeline(t *testing.T) {
+	logStats := logstats.NewLogStats(context.Background(), "Execute", "select 1", "suuid", nil, streamlog.NewQueryLogConfigForTest())
+	start := logStats.StartTime
+	logStats.EndTime = start.Add(10 * time.Millisecond)
+	logStats.AddShardTiming("ks/80-", start.Add(5*time.Millisecond), start.Add(10*time.Millisecond))
+	logStats.AddShardTiming("ks/-80", start.Add(time.Millisecond), start.Add(4*time.Millisecond))
+
+	spans := querylogzShardTimeline(logStats, true)
+	if assert.Len(t, spans, 2) {
+		// The spans are in the order the shard queries started.
+		assert.Equal(t, "ks/-80", spans[0].Shard)
+		assert.Equal(t, "1ms", spans[0].Offset)
+		assert.Equal(t, "3ms", spans[0].Duration)
+		assert.Contains(t, spans[0].Spacer.String(), "width:20.00px;")
+		assert.Contains(t, spans[0].Bar.String(), "width:60.00px;")
+		assert.Equal(t, "ks/80-", spans[1].Shard)
+		assert.Contains(t, spans[1].Spacer.String(), "width:100.00px;")
+		assert.Contains(t, spans[1].Bar.String(), "width:100.00px;")
+	}
+	assert.Empty(t, querylogzShardTimeline(logstats.NewLogStats(context.Background(), "Execute", "select 1", "suuid", nil, streamlog.NewQueryLogConfigForTest()), true))
+
+	render := func(url string) string {
+		req, _ := http.NewRequest("GET", url, nil)
+		response := httptest.NewRecorder()
+		ch := make(chan *logstats.LogStats, 1)
+		ch <- logStats
+		querylogzHandler(ch, nil, response, req, sqlparser.NewTestParser())
+		close(ch)
+		body, _ := io.ReadAll(response.Body)
+		return string(body)
+	}
+	assert.Contains(t, render("/querylogz?timeout=1&limit=1&timeline=1&humanize=1"), "+1ms, 3ms<br>")
+	assert.NotContains(t, render("/querylogz?timeout=1&limit=1&humanize=1"), "+1ms, 3ms")
+}
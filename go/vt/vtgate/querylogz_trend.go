@@ -0,0 +1,143 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vtgate
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/safehtml"
+	"github.com/google/safehtml/template"
+
+	"vitess.io/vitess/go/vt/log"
+	"vitess.io/vitess/go/vt/vtgate/logstats"
+)
+
+const (
+	// querylogzTrendBuckets is the number of time buckets the buffered
+	// window is split into to draw the latency trend.
+	querylogzTrendBuckets = 30
+	// querylogzTrendSmoothing is the number of consecutive buckets the
+	// average latency of the trend is computed over.
+	querylogzTrendSmoothing = 3
+)
+
+var querylogzTrendTmpl = template.Must(template.New("trend").Parse(`
+<p>{{if .Bars}}Latency trend over the last {{.Window}}: {{range .Bars}}<span style="{{.Style}}" title="{{.Duration}}"></span>{{end}} {{.First}} to {{.Last}}, {{.Direction}}{{else}}Latency trend: n/a{{end}}</p>
+`))
+
+// querylogzLatencyTrend is the latency trend rendered by querylogzTrend.
+type querylogzLatencyTrend struct {
+	// Window is the time span of the matching records, or zero when there
+	// aren't enough of them to draw a trend.
+	Window time.Duration
+	// Bars hold the moving average of the total time of the queries, one
+	// per time bucket holding at least one query, oldest first.
+	Bars []querylogzSparklineBar
+	// First and Last are the oldest and the most recent moving averages,
+	// and Direction tells whether latency is rising, falling or steady
+	// between them.
+	First, Last time.Duration
+	Direction   string
+}
+
+// querylogzComputeTrend returns the latency trend of the records matching
+// filter. Their time span is split into querylogzTrendBuckets buckets by
+// start time, and the average total time of each bucket is smoothed over
+// the querylogzTrendSmoothing buckets up to it. Empty buckets are skipped.
+func querylogzComputeTrend(records []*logstats.LogStats, filter querylogzFilter) querylogzLatencyTrend {
+	var trend querylogzLatencyTrend
+	var matching []*logstats.LogStats
+	var start, end time.Time
+	for _, stats := range records {
+		if !filter.matches(stats) {
+			continue
+		}
+		if len(matching) == 0 || stats.StartTime.Before(start) {
+			start = stats.StartTime
+		}
+		if stats.StartTime.After(end) {
+			end = stats.StartTime
+		}
+		matching = append(matching, stats)
+	}
+	window := end.Sub(start)
+	if len(matching) < 2 || window <= 0 {
+		return trend
+	}
+
+	var totals [querylogzTrendBuckets]time.Duration
+	var counts [querylogzTrendBuckets]int
+	for _, stats := range matching {
+		i := min(int(float64(stats.StartTime.Sub(start))/float64(window)*querylogzTrendBuckets), querylogzTrendBuckets-1)
+		totals[i] += stats.TotalTime()
+		counts[i]++
+	}
+	var averages []time.Duration
+	for i, count := range counts {
+		if count > 0 {
+			averages = append(averages, totals[i]/time.Duration(count))
+		}
+	}
+	moving := make([]time.Duration, len(averages))
+	var highest time.Duration
+	for i := range averages {
+		var sum time.Duration
+		smoothed := averages[max(i-querylogzTrendSmoothing+1, 0) : i+1]
+		for _, avg := range smoothed {
+			sum += avg
+		}
+		moving[i] = sum / time.Duration(len(smoothed))
+		highest = max(highest, moving[i])
+	}
+
+	trend.Window = window.Round(time.Millisecond)
+	for _, d := range moving {
+		// Scale the bars to the highest average, keeping every bar visible.
+		height := 0.1
+		if highest > 0 {
+			height = max(height, 2*float64(d)/float64(highest))
+		}
+		trend.Bars = append(trend.Bars, querylogzSparklineBar{d, safehtml.StyleFromProperties(safehtml.StyleProperties{
+			Display:         "inline-block",
+			Width:           "3px",
+			Height:          strconv.FormatFloat(height, 'f', 2, 64) + "em",
+			BackgroundColor: "#4e79a7",
+		})})
+	}
+	trend.First, trend.Last = moving[0], moving[len(moving)-1]
+	// Changes within 10% are noise rather than a trend.
+	switch {
+	case trend.Last > trend.First+trend.First/10:
+		trend.Direction = "rising"
+	case trend.Last < trend.First-trend.First/10:
+		trend.Direction = "falling"
+	default:
+		trend.Direction = "steady"
+	}
+	return trend
+}
+
+// querylogzTrend renders the moving average of the latency of the buffered
+// queries matching filter as a line of bars, to show at a glance whether
+// latency is rising or falling.
+func querylogzTrend(w http.ResponseWriter, records []*logstats.LogStats, filter querylogzFilter) {
+	if err := querylogzTrendTmpl.Execute(w, querylogzComputeTrend(records, filter)); err != nil {
+		log.Errorf("querylogz: couldn't execute trend template: %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2025 The Vitess Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vtgate
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"vitess.io/vitess/go/streamlog"
+	"vitess.io/vitess/go/vt/sqlparser"
+	"vitess.io/vitess/go/vt/vtgate/logstats"
+)
+
+func TestQuerylogzTrend(t *testing.T) {
+	ring := newQueryLogRing(10)
+	start := time.Now()
+	add := func(category string, at, d time.Duration) {
+		logStats := logstats.NewLogStats(context.Background(), "Execute", "select 1", "suuid", nil, streamlog.NewQueryLogConfigForTest())
+		logStats.QueryCategory = category
+		logStats.StartTime = start.Add(at)
+		logStats.EndTime = logStats.StartTime.Add(d)
+		ring.add(logStats)
+	}
+	// One query per bucket, getting slower.
+	add("", 0, 10*time.Millisecond)
+	add("", time.Second, 10*time.Millisecond)
+	add("OLAP", 2*time.Second, 40*time.Millisecond)
+	add("", 3*time.Second, 40*time.Millisecond)
+	add("OLAP", 4*time.Second, 10*time.Millisecond)
+	add("", 5*time.Second, 40*time.Millisecond)
+
+	trend := querylogzComputeTrend(ring.snapshot(), querylogzFilter{})
+	assert.Equal(t, 5*time.Second, trend.Window)
+	var moving []time.Duration
+	for _, bar := range trend.Bars {
+		moving = append(moving, bar.Duration)
+	}
+	assert.Equal(t, []time.Duration{10 * time.Millisecond, 10 * time.Millisecond, 20 * time.Millisecond, 30 * time.Millisecond, 30 * time.Millisecond, 30 * time.Millisecond}, moving)
+	assert.Equal(t, 10*time.Millisecond, trend.First)
+	assert.Equal(t, 30*time.Millisecond, trend.Last)
+	assert.Equal(t, "rising", trend.Direction)
+
+	// The trend reflects the filtered queries only.
+	trend = querylogzComputeTrend(ring.snapshot(), querylogzFilter{category: "OLAP"})
+	assert.Equal(t, 2*time.Second, trend.Window)
+	assert.Equal(t, 40*time.Millisecond, trend.First)
+	assert.Equal(t, 25*time.Millisecond, trend.Last)
+	assert.Equal(t, "falling", trend.Direction)
+
+	render := func(ring *queryLogRing, url string) string {
+		req, _ := http.NewRequest("GET", url, nil)
+		response := httptest.NewRecorder()
+		querylogzHandler(make(chan *logstats.LogStats), ring, response, req, sqlparser.NewTestParser())
+		body, _ := io.ReadAll(response.Body)
+		return string(body)
+	}
+	page := render(ring, "/querylogz?timeout=0&trend=1")
+	assert.Contains(t, page, "Latency trend over the last 5s: ")
+	assert.Contains(t, page, `title="20ms"`)
+	assert.Contains(t, page, " 10ms to 30ms, rising</p>")
+	assert.Contains(t, render(newQueryLogRing(10), "/querylogz?timeout=0&trend=1"), "Latency trend: n/a")
+	assert.NotContains(t, render(ring, "/querylogz?timeout=0"), "Latency trend")
+}
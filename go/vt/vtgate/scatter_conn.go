@@ -77,6 +77,53 @@ type (
 
 func (nullResultsObserver) Observe(*sqltypes.Result) {}
 
+// observeRowsExamined reports n rows received from a tablet to the stats
+// sink attached to ctx, if any.
+func observeRowsExamined(ctx context.Context, n int) {
+	if stats, ok := engine.StatsSinkFromContext(ctx); ok {
+		stats.AddRowsExamined(n)
+	}
+}
+
+// observeBackendConnection reports the MySQL connection that produced qr on
+// target to the stats sink attached to ctx, if any. Results of tablets that
+// don't report their connection are skipped.
+func observeBackendConnection(ctx context.Context, target *querypb.Target, qr *sqltypes.Result) {
+	if qr.ConnectionID == 0 || target == nil {
+		return
+	}
+	if stats, ok := engine.StatsSinkFromContext(ctx); ok {
+		stats.AddBackendConnectionID(target.Keyspace+"/"+target.Shard, qr.ConnectionID)
+	}
+}
+
+// observeShardTiming reports a query to target that started at start and
+// ends now to the stats sink attached to ctx, if any.
+func observeShardTiming(ctx context.Context, target *querypb.Target, start time.Time) {
+	if target == nil {
+		return
+	}
+	if stats, ok := engine.StatsSinkFromContext(ctx); ok {
+		stats.AddShardTiming(target.Keyspace+"/"+target.Shard, start, time.Now())
+	}
+}
+
+// withConnectionIDRequested returns opts, or a copy of it, asking the tablet
+// for the id of the MySQL connection that runs the query when a stats sink
+// is attached to ctx, which is only the case while the query log has
+// subscribers. Otherwise opts is returned unchanged.
+func withConnectionIDRequested(ctx context.Context, opts *querypb.ExecuteOptions) *querypb.ExecuteOptions {
+	if _, ok := engine.StatsSinkFromContext(ctx); !ok || opts.GetIncludeConnectionId() {
+		return opts
+	}
+	if opts == nil {
+		return &querypb.ExecuteOptions{IncludeConnectionId: true}
+	}
+	opts = opts.CloneVT()
+	opts.IncludeConnectionId = true
+	return opts
+}
+
 // NewScatterConn creates a new ScatterConn.
 func NewScatterConn(statsName string, txConn *TxConn, gw *TabletGateway) *ScatterConn {
 	// this only works with TabletGateway
@@ -185,6 +232,7 @@ func (stc *ScatterConn) ExecuteMultiShard(
 				alias   *topodatapb.TabletAlias
 				qs      queryservice.QueryService
 			)
+			defer observeShardTiming(ctx, rs.Target, time.Now())
 			transactionID := info.transactionID
 			reservedID := info.reservedID
 
@@ -195,6 +243,7 @@ func (stc *ScatterConn) ExecuteMultiShard(
 			if opts == nil && fetchLastInsertID {
 				opts = &querypb.ExecuteOptions{FetchLastInsertId: fetchLastInsertID}
 			}
+			opts = withConnectionIDRequested(ctx, opts)
 
 			if autocommit {
 				// As this is auto-commit, the transactionID is supposed to be zero.
@@ -279,6 +328,8 @@ func (stc *ScatterConn) ExecuteMultiShard(
 
 			if innerqr != nil {
 				resultsObserver.Observe(innerqr)
+				observeRowsExamined(ctx, len(innerqr.Rows))
+				observeBackendConnection(ctx, rs.Target, innerqr)
 			}
 
 			// Don't append more rows if row count is exceeded.
@@ -395,6 +446,7 @@ func (stc *ScatterConn) StreamExecuteMulti(
 	observedCallback := func(reply *sqltypes.Result) error {
 		if reply != nil {
 			resultsObserver.Observe(reply)
+			observeRowsExamined(ctx, len(reply.Rows))
 		}
 		return callback(reply)
 	}
@@ -416,8 +468,15 @@ func (stc *ScatterConn) StreamExecuteMulti(
 				alias *topodatapb.TabletAlias
 				qs    queryservice.QueryService
 			)
+			defer observeShardTiming(ctx, rs.Target, time.Now())
 			transactionID := info.transactionID
 			reservedID := info.reservedID
+			shardCallback := func(reply *sqltypes.Result) error {
+				if reply != nil {
+					observeBackendConnection(ctx, rs.Target, reply)
+				}
+				return observedCallback(reply)
+			}
 
 			if session != nil && session.Session != nil {
 				opts = session.Session.Options
@@ -426,6 +485,7 @@ func (stc *ScatterConn) StreamExecuteMulti(
 			if opts == nil && fetchLastInsertID {
 				opts = &querypb.ExecuteOptions{FetchLastInsertId: fetchLastInsertID}
 			}
+			opts = withConnectionIDRequested(ctx, opts)
 
 			if autocommit {
 				// As this is auto-commit, the transactionID is supposed to be zero.
@@ -455,20 +515,20 @@ func (stc *ScatterConn) StreamExecuteMulti(
 
 			switch info.actionNeeded {
 			case nothing:
-				err = qs.StreamExecute(ctx, rs.Target, query, bindVars[i], transactionID, reservedID, opts, observedCallback)
+				err = qs.StreamExecute(ctx, rs.Target, query, bindVars[i], transactionID, reservedID, opts, shardCallback)
 				if err != nil {
 					retryRequest(func() {
 						// we seem to have lost our connection. it was a reserved connection, let's try to recreate it
 						info.actionNeeded = reserve
 						var state queryservice.ReservedState
-						state, err = qs.ReserveStreamExecute(ctx, rs.Target, session.SetPreQueries(), query, bindVars[i], 0 /*transactionId*/, opts, observedCallback)
+						state, err = qs.ReserveStreamExecute(ctx, rs.Target, session.SetPreQueries(), query, bindVars[i], 0 /*transactionId*/, opts, shardCallback)
 						reservedID = state.ReservedID
 						alias = state.TabletAlias
 					})
 				}
 			case begin:
 				var state queryservice.TransactionState
-				state, err = qs.BeginStreamExecute(ctx, rs.Target, session.SavePoints(), query, bindVars[i], reservedID, opts, observedCallback)
+				state, err = qs.BeginStreamExecute(ctx, rs.Target, session.SavePoints(), query, bindVars[i], reservedID, opts, shardCallback)
 				transactionID = state.TransactionID
 				alias = state.TabletAlias
 				if err != nil {
@@ -476,7 +536,7 @@ func (stc *ScatterConn) StreamExecuteMulti(
 						// we seem to have lost our connection. it was a reserved connection, let's try to recreate it
 						info.actionNeeded = reserveBegin
 						var state queryservice.ReservedTransactionState
-						state, err = qs.ReserveBeginStreamExecute(ctx, rs.Target, session.SetPreQueries(), session.SavePoints(), query, bindVars[i], opts, observedCallback)
+						state, err = qs.ReserveBeginStreamExecute(ctx, rs.Target, session.SetPreQueries(), session.SavePoints(), query, bindVars[i], opts, shardCallback)
 						transactionID = state.TransactionID
 						reservedID = state.ReservedID
 						alias = state.TabletAlias
@@ -484,12 +544,12 @@ func (stc *ScatterConn) StreamExecuteMulti(
 				}
 			case reserve:
 				var state queryservice.ReservedState
-				state, err = qs.ReserveStreamExecute(ctx, rs.Target, session.SetPreQueries(), query, bindVars[i], transactionID, opts, observedCallback)
+				state, err = qs.ReserveStreamExecute(ctx, rs.Target, session.SetPreQueries(), query, bindVars[i], transactionID, opts, shardCallback)
 				reservedID = state.ReservedID
 				alias = state.TabletAlias
 			case reserveBegin:
 				var state queryservice.ReservedTransactionState
-				state, err = qs.ReserveBeginStreamExecute(ctx, rs.Target, session.SetPreQueries(), session.SavePoints(), query, bindVars[i], opts, observedCallback)
+				state, err = qs.ReserveBeginStreamExecute(ctx, rs.Target, session.SetPreQueries(), session.SavePoints(), query, bindVars[i], opts, shardCallback)
 				transactionID = state.TransactionID
 				reservedID = state.ReservedID
 				alias = state.TabletAlias
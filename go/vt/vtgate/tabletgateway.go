@@ -41,6 +41,7 @@ import (
 	"vitess.io/vitess/go/vt/vterrors"
 	"vitess.io/vitess/go/vt/vtgate/balancer"
 	"vitess.io/vitess/go/vt/vtgate/buffer"
+	"vitess.io/vitess/go/vt/vtgate/engine"
 	"vitess.io/vitess/go/vt/vttablet/queryservice"
 
 	querypb "vitess.io/vitess/go/vt/proto/query"
@@ -310,10 +311,12 @@ func (gw *TabletGateway) withRetry(ctx context.Context, target *querypb.Target,
 		// b) no transaction was created yet.
 		if gw.buffer != nil && !bufferedOnce && !inTransaction && target.TabletType == topodatapb.TabletType_PRIMARY {
 			// The next call blocks if we should buffer during a failover.
+			bufferStart := time.Now()
 			retryDone, bufferErr := gw.buffer.WaitForFailoverEnd(ctx, target.Keyspace, target.Shard, gw.kev, err)
 
 			// Request may have been buffered.
 			if retryDone != nil {
+				observeBuffering(ctx, time.Since(bufferStart))
 				// We're going to retry this request as part of a buffer drain.
 				// Notify the buffer after we retried.
 				defer retryDone()
@@ -405,6 +408,7 @@ func (gw *TabletGateway) withRetry(ctx context.Context, target *querypb.Target,
 		}
 
 		gw.updateDefaultConnCollation(tabletLastUsed)
+		observeTablet(ctx, tabletLastUsed.Alias)
 
 		startTime := time.Now()
 		var canRetry bool
@@ -504,3 +508,19 @@ func NewShardError(in error, target *querypb.Target) error {
 	}
 	return in
 }
+
+// observeTablet reports the tablet a query is sent to to the stats sink
+// attached to ctx, if any.
+func observeTablet(ctx context.Context, alias *topodatapb.TabletAlias) {
+	if stats, ok := engine.StatsSinkFromContext(ctx); ok {
+		stats.AddTablet(topoproto.TabletAliasString(alias))
+	}
+}
+
+// observeBuffering reports that a request was buffered for d to the stats
+// sink attached to ctx, if any.
+func observeBuffering(ctx context.Context, d time.Duration) {
+	if stats, ok := engine.StatsSinkFromContext(ctx); ok {
+		stats.AddBufferTime(d)
+	}
+}
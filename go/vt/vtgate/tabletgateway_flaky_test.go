@@ -26,9 +26,12 @@ import (
 
 	"vitess.io/vitess/go/mysql/collations"
 	"vitess.io/vitess/go/sqltypes"
+	"vitess.io/vitess/go/streamlog"
 	"vitess.io/vitess/go/test/utils"
 	"vitess.io/vitess/go/vt/discovery"
 	"vitess.io/vitess/go/vt/vtgate/buffer"
+	"vitess.io/vitess/go/vt/vtgate/engine"
+	"vitess.io/vitess/go/vt/vtgate/logstats"
 
 	querypb "vitess.io/vitess/go/vt/proto/query"
 	topodatapb "vitess.io/vitess/go/vt/proto/topodata"
@@ -222,9 +225,11 @@ func TestGatewayBufferingWhileReparenting(t *testing.T) {
 	sbcReplica.SetResults([]*sqltypes.Result{sqlResult1})
 
 	// execute the query in a go routine since it should be buffered, and check that it eventually succeed
+	logStats := logstats.NewLogStats(ctx, "Execute", "query", "", nil, streamlog.QueryLogConfig{})
+	bufferCtx := engine.WithStatsSink(ctx, logStats)
 	queryChan := make(chan struct{})
 	go func() {
-		res, err = tg.Execute(ctx, target, "query", nil, 0, 0, nil)
+		res, err = tg.Execute(bufferCtx, target, "query", nil, 0, 0, nil)
 		queryChan <- struct{}{}
 	}()
 
@@ -259,6 +264,8 @@ outer:
 	case <-queryChan:
 		require.NoError(t, err)
 		require.Equal(t, sqlResult1, res)
+		// The query was buffered until the new primary was serving.
+		require.GreaterOrEqual(t, logStats.BufferTime, time.Second)
 	case <-time.After(15 * time.Second):
 		t.Fatalf("timed out waiting for query to execute")
 	}
@@ -155,8 +155,16 @@ var (
 
 	// queryLogToFile controls whether query logs are sent to a file
 	queryLogToFile string
+	// queryLogToConsole controls whether query logs are sent to stdout or stderr
+	queryLogToConsole string
+	// queryLogToConsoleMinDuration only sends queries at least this slow to the console
+	queryLogToConsoleMinDuration time.Duration
 	// queryLogBufferSize controls how many query logs will be buffered before dropping them if logging is not fast enough
 	queryLogBufferSize = 10
+	// queryLogRingSize controls how many recent query logs are kept in memory for the aggregate querylogz views, which are off by default
+	queryLogRingSize int
+	// queryLogDedupWindow is the longest a run of identical consecutive queries is held before being logged as one entry
+	queryLogDedupWindow time.Duration
 
 	messageStreamGracePeriod = 30 * time.Second
 
@@ -195,7 +203,12 @@ func registerFlags(fs *pflag.FlagSet) {
 	fs.BoolVar(&enableSchemaChangeSignal, "schema_change_signal", enableSchemaChangeSignal, "Enable the schema tracker; requires queryserver-config-schema-change-signal to be enabled on the underlying vttablets for this to work")
 	fs.IntVar(&queryTimeout, "query-timeout", queryTimeout, "Sets the default query timeout (in ms). Can be overridden by session variable (query_timeout) or comment directive (QUERY_TIMEOUT_MS)")
 	fs.StringVar(&queryLogToFile, "log_queries_to_file", queryLogToFile, "Enable query logging to the specified file")
+	fs.StringVar(&queryLogToConsole, "log-queries-to-console", queryLogToConsole, "Enable query logging to the console, either \"stdout\" or \"stderr\"")
+	fs.DurationVar(&queryLogToConsoleMinDuration, "log-queries-to-console-min-duration", queryLogToConsoleMinDuration, "Only log queries to the console that take at least this long")
 	fs.IntVar(&queryLogBufferSize, "querylog-buffer-size", queryLogBufferSize, "Maximum number of buffered query logs before throttling log output")
+	fs.IntVar(&queryLogRingSize, "querylog-ring-size", queryLogRingSize, "Number of recent query logs kept in memory for the aggregate querylogz views; 0, the default, disables them")
+	fs.Var(querylogzInternalQueries, "querylogz-internal-queries", "regular expression matching the internal queries, such as health checks, that querylogz hides with excludeInternal=1; can be repeated, and replaces the default health check and monitoring patterns")
+	fs.DurationVar(&queryLogDedupWindow, "querylog-dedup-window", queryLogDedupWindow, "Collapse identical consecutive queries into a single query log entry with a repeat count, holding each run for at most this long; 0 disables deduplication")
 	fs.DurationVar(&messageStreamGracePeriod, "message_stream_grace_period", messageStreamGracePeriod, "the amount of time to give for a vttablet to resume if it ends a message stream, usually because of a reparent.")
 	fs.BoolVar(&enableViews, "enable-views", enableViews, "Enable views support in vtgate.")
 	fs.BoolVar(&enableUdfs, "track-udfs", enableUdfs, "Track UDFs in vtgate.")
@@ -374,11 +387,13 @@ func Init(
 	plans := DefaultPlanCache()
 
 	eConfig := ExecutorConfig{
-		Normalize:           normalizeQueries,
-		StreamSize:          streamBufferSize,
-		AllowScatter:        !noScatter,
-		WarmingReadsPercent: warmingReadsPercent,
-		QueryLogToFile:      queryLogToFile,
+		Normalize:                    normalizeQueries,
+		StreamSize:                   streamBufferSize,
+		AllowScatter:                 !noScatter,
+		WarmingReadsPercent:          warmingReadsPercent,
+		QueryLogToFile:               queryLogToFile,
+		QueryLogToConsole:            queryLogToConsole,
+		QueryLogToConsoleMinDuration: queryLogToConsoleMinDuration,
 	}
 
 	executor := NewExecutor(ctx, env, serv, cell, resolver, eConfig, warnShardedOnly, plans, si, pv, dynamicConfig)
@@ -19,6 +19,7 @@ package tabletconn
 import (
 	"context"
 	"sync"
+	"time"
 
 	"github.com/spf13/pflag"
 
@@ -94,3 +95,21 @@ func GetDialer() TabletDialer {
 	}
 	return td
 }
+
+type dialObserverKey struct{}
+
+// WithDialObserver returns a context that reports, through observe, the
+// time spent establishing any new tablet connection while it is in use.
+// This lets per-query accounting attribute connection setup cost to the
+// query that paid it.
+func WithDialObserver(ctx context.Context, observe func(time.Duration)) context.Context {
+	return context.WithValue(ctx, dialObserverKey{}, observe)
+}
+
+// ObserveDial reports the time spent establishing a new tablet connection
+// to the observer attached to ctx, if any.
+func ObserveDial(ctx context.Context, d time.Duration) {
+	if observe, ok := ctx.Value(dialObserverKey{}).(func(time.Duration)); ok {
+		observe(d)
+	}
+}
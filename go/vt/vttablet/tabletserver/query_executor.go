@@ -497,6 +497,11 @@ func (qre *QueryExecutor) checkPermissions() error {
 	default:
 		// no rules against this query. Good to proceed
 	}
+	// Time the table ACL checks apart from the query rules above, whose
+	// buffering can take arbitrarily long.
+	defer func(start time.Time) {
+		qre.logStats.ACLCheckTime += time.Since(start)
+	}(time.Now())
 	// Skip ACL check for queries against the dummy dual table
 	if qre.plan.TableName().String() == "dual" {
 		return nil
@@ -626,9 +631,13 @@ func (qre *QueryExecutor) execDDL(conn *StatefulConnection) (result *sqltypes.Re
 			// Instead of synchronously recalculating table size stats
 			// after every DDL, let them be outdated until the periodic
 			// schema reload fixes it.
+			start := time.Now()
 			if err := qre.tsv.se.ReloadAtEx(qre.ctx, replication.Position{}, false); err != nil {
 				log.Errorf("failed to reload schema %v", err)
+			} else {
+				qre.logStats.SchemaReloaded = true
 			}
+			qre.logStats.SchemaReloadTime += time.Since(start)
 		}()
 	}
 	sql := qre.query
@@ -737,6 +746,7 @@ func (qre *QueryExecutor) execSelect() (*sqltypes.Result, error) {
 	if qre.shouldConsolidate() {
 		q, original := qre.tsv.qe.consolidator.Create(sqlWithoutComments)
 		if original {
+			qre.logStats.Consolidation = tabletenv.ConsolidationLeader
 			defer q.Broadcast()
 			conn, err := qre.getConn()
 
@@ -752,6 +762,7 @@ func (qre *QueryExecutor) execSelect() (*sqltypes.Result, error) {
 			waiterCap := qre.tsv.config.ConsolidatorQueryWaiterCap
 			if waiterCap == 0 || *q.AddWaiterCounter(0) <= waiterCap {
 				qre.logStats.QuerySources |= tabletenv.QuerySourceConsolidator
+				qre.logStats.Consolidation = tabletenv.ConsolidationFollower
 				startTime := time.Now()
 				q.Wait()
 				qre.tsv.stats.WaitTimings.Record("Consolidations", startTime)
@@ -817,6 +828,7 @@ func (qre *QueryExecutor) getConn() (*connpool.PooledConn, error) {
 	span, ctx := trace.NewSpan(qre.ctx, "QueryExecutor.getConn")
 	defer span.Finish()
 
+	qre.logStats.RecordConnWaitQueueDepth(qre.tsv.qe.conns.Waiting())
 	defer func(start time.Time) {
 		qre.logStats.WaitingForConnection += time.Since(start)
 	}(time.Now())
@@ -827,6 +839,7 @@ func (qre *QueryExecutor) getStreamConn() (*connpool.PooledConn, error) {
 	span, ctx := trace.NewSpan(qre.ctx, "QueryExecutor.getStreamConn")
 	defer span.Finish()
 
+	qre.logStats.RecordConnWaitQueueDepth(qre.tsv.qe.streamConns.Waiting())
 	defer func(start time.Time) {
 		qre.logStats.WaitingForConnection += time.Since(start)
 	}(time.Now())
@@ -1169,6 +1182,9 @@ func (qre *QueryExecutor) execDBConn(conn *connpool.Conn, sql string, wantfields
 	if err := qre.fetchLastInsertID(ctx, conn, exec); err != nil {
 		return nil, err
 	}
+	if qre.options.GetIncludeConnectionId() {
+		exec.ConnectionID = uint64(conn.ID())
+	}
 
 	return exec, nil
 }
@@ -1197,6 +1213,9 @@ func (qre *QueryExecutor) execStatefulConn(conn *StatefulConnection, sql string,
 	if err := qre.fetchLastInsertID(ctx, conn.UnderlyingDBConn().Conn, exec); err != nil {
 		return nil, err
 	}
+	if qre.options.GetIncludeConnectionId() {
+		exec.ConnectionID = uint64(conn.UnderlyingDBConn().Conn.ID())
+	}
 
 	return exec, nil
 }
@@ -1262,9 +1281,15 @@ func (qre *QueryExecutor) execStreamSQL(conn *connpool.PooledConn, isTransaction
 	}
 
 	lastInsertIDSet := false
+	includeConnectionID := qre.options.GetIncludeConnectionId()
 	cb := func(result *sqltypes.Result) error {
-		if result != nil && result.InsertIDUpdated() {
-			lastInsertIDSet = true
+		if result != nil {
+			if result.InsertIDUpdated() {
+				lastInsertIDSet = true
+			}
+			if includeConnectionID {
+				result.ConnectionID = uint64(conn.Conn.ID())
+			}
 		}
 		return callback(result)
 	}
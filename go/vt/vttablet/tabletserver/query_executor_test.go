@@ -18,6 +18,7 @@ package tabletserver
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"math/rand/v2"
@@ -698,6 +699,54 @@ func TestQueryExecutorPlanPassSelectWithLockOutsideATransaction(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestQueryExecutorConnectionID(t *testing.T) {
+	db := setUpQueryExecutorTest(t)
+	defer db.Close()
+	query := "select * from test_table"
+	db.AddQuery("select * from test_table limit 10001", &sqltypes.Result{
+		Fields: getTestTableFields(),
+	})
+	db.AddQuery(query, &sqltypes.Result{
+		Fields: getTestTableFields(),
+	})
+	ctx := context.Background()
+	tsv := newTestTabletServer(ctx, noFlags, db)
+	defer tsv.StopService()
+	options := &querypb.ExecuteOptions{IncludeConnectionId: true}
+	newExecutor := func(txID int64) *QueryExecutor {
+		qre := newTestQueryExecutor(ctx, tsv, query, txID)
+		qre.options = options
+		return qre
+	}
+
+	// The id is only reported on request.
+	got, err := newTestQueryExecutor(ctx, tsv, query, 0).Execute()
+	require.NoError(t, err)
+	assert.Zero(t, got.ConnectionID)
+	got, err = newExecutor(0).Execute()
+	require.NoError(t, err)
+	assert.NotZero(t, got.ConnectionID)
+
+	// The queries of a transaction all run on its connection.
+	txid := newTransaction(tsv, nil)
+	first, err := newExecutor(txid).Execute()
+	require.NoError(t, err)
+	second, err := newExecutor(txid).Execute()
+	require.NoError(t, err)
+	assert.NotZero(t, first.ConnectionID)
+	assert.Equal(t, first.ConnectionID, second.ConnectionID)
+	_, err = tsv.Commit(ctx, tsv.sm.Target(), txid)
+	require.NoError(t, err)
+
+	qre := newTestQueryExecutorStreaming(ctx, tsv, query, 0)
+	qre.options = options
+	err = qre.Stream(func(result *sqltypes.Result) error {
+		assert.NotZero(t, result.ConnectionID)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
 func TestQueryExecutorPlanNextval(t *testing.T) {
 	db := setUpQueryExecutorTest(t)
 	defer db.Close()
@@ -927,6 +976,7 @@ func TestQueryExecutorTableAcl(t *testing.T) {
 	if !got.Equal(want) {
 		t.Fatalf("qre.Execute() = %v, want: %v", got, want)
 	}
+	assert.NotZero(t, qre.logStats.ACLCheckTime)
 }
 
 func TestQueryExecutorTableAclNoPermission(t *testing.T) {
@@ -1447,12 +1497,15 @@ func TestQueryExecutorShouldConsolidate(t *testing.T) {
 				if tcase.consolidatorHasIdenticalQuery {
 					require.Equal(t, 0, fakePendingResult.BroadcastCalls)
 					require.Equal(t, 1, fakePendingResult.WaitCalls)
+					require.Equal(t, tabletenv.ConsolidationFollower, qre.logStats.ConsolidationRole())
 				} else {
 					require.Equal(t, 1, fakePendingResult.BroadcastCalls)
 					require.Equal(t, 0, fakePendingResult.WaitCalls)
+					require.Equal(t, tabletenv.ConsolidationLeader, qre.logStats.ConsolidationRole())
 				}
 			} else {
 				require.Len(t, fakeConsolidator.CreateCalls, 0)
+				require.Equal(t, tabletenv.ConsolidationStandalone, qre.logStats.ConsolidationRole())
 			}
 
 			if tcase.expectExec {
@@ -1867,6 +1920,23 @@ func TestQueryExecSchemaReloadCount(t *testing.T) {
 	}
 }
 
+func TestQueryExecSchemaReloadFailure(t *testing.T) {
+	db := setUpQueryExecutorTest(t)
+	defer db.Close()
+	tsv := newTestTabletServer(context.Background(), noFlags, db)
+	tsv.config.DB.DBName = "ks"
+	defer tsv.StopService()
+
+	db.AddQueryPattern("create.*", &sqltypes.Result{})
+	// The schema engine reads the MySQL time first thing in a reload.
+	db.AddRejectedQuery("SELECT UNIX_TIMESTAMP()", errors.New("reload failed"))
+
+	qre := newTestQueryExecutor(context.Background(), tsv, "create table t(a int, b varchar(64))", 0)
+	_, err := qre.Execute()
+	require.NoError(t, err)
+	assert.False(t, qre.logStats.SchemaReloaded)
+}
+
 type mockTxThrottler struct {
 	throttle bool
 }
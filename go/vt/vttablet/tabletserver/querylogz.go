@@ -44,10 +44,14 @@ var (
 				<th>Duration</th>
 				<th>MySQL time</th>
 				<th>Conn wait</th>
+				<th>ACL time</th>
+				<th>Schema reload</th>
+				<th>Conn queue</th>
 				<th>Plan</th>
 				<th>SQL</th>
 				<th>Queries</th>
 				<th>Sources</th>
+				<th>Consolidation</th>
 				<th>RowsAffected</th>
 				<th>Response Size</th>
 				<th>Transaction ID</th>
@@ -72,10 +76,14 @@ var (
 			<td>{{.TotalTime.Seconds}}</td>
 			<td>{{.MysqlResponseTime.Seconds}}</td>
 			<td>{{.WaitingForConnection.Seconds}}</td>
+			<td>{{.ACLCheckTime.Seconds}}</td>
+			<td>{{if .SchemaReloaded}}{{.SchemaReloadTime.Seconds}}{{end}}</td>
+			<td>{{.ConnWaitQueueDepth}}</td>
 			<td>{{.PlanType}}</td>
 			<td>{{.OriginalSQL | .Parser.TruncateForUI | unquote | cssWrappable}}</td>
 			<td>{{.NumberOfQueries}}</td>
 			<td>{{.FmtQuerySources}}</td>
+			<td>{{.ConsolidationRole}}</td>
 			<td>{{.RowsAffected}}</td>
 			<td>{{.SizeOfResponse}}</td>
 			<td>{{.TransactionID}}</td>
@@ -85,21 +93,61 @@ var (
 	`))
 )
 
+// querylogzFilter selects the records rendered by querylogz, based on the
+// request's query parameters. Records that don't match are skipped before
+// they count against the limit.
+type querylogzFilter struct {
+	// minQueueDepth, from the min_queue_depth parameter, matches the queries
+	// that waited behind at least that many other clients for a connection.
+	minQueueDepth int
+	// minACLTime, from the min_acl_time parameter, a duration such as "5ms",
+	// matches the queries whose table ACL checks took at least that long.
+	minACLTime time.Duration
+	// schemaReload, set by schema_reload=1, matches the queries that
+	// triggered a schema reload.
+	schemaReload bool
+	// consolidation, from the consolidation parameter, one of leader,
+	// follower or standalone, matches the queries of that role in query
+	// consolidation.
+	consolidation string
+}
+
+func parseQuerylogzFilter(r *http.Request) querylogzFilter {
+	var filter querylogzFilter
+	filter.minQueueDepth, _ = strconv.Atoi(r.URL.Query().Get("min_queue_depth"))
+	filter.minACLTime, _ = time.ParseDuration(r.URL.Query().Get("min_acl_time"))
+	filter.schemaReload = r.URL.Query().Get("schema_reload") == "1"
+	filter.consolidation = r.URL.Query().Get("consolidation")
+	return filter
+}
+
+func (f querylogzFilter) matches(stats *tabletenv.LogStats) bool {
+	if stats.ConnWaitQueueDepth < f.minQueueDepth || stats.ACLCheckTime < f.minACLTime {
+		return false
+	}
+	if f.consolidation != "" && stats.ConsolidationRole() != f.consolidation {
+		return false
+	}
+	return !f.schemaReload || stats.SchemaReloaded
+}
+
 // querylogzHandler serves a human readable snapshot of the
-// current query log.
+// current query log, restricted to the records matching the
+// querylogzFilter of the request.
 func querylogzHandler(ch chan *tabletenv.LogStats, w http.ResponseWriter, r *http.Request, parser *sqlparser.Parser) {
 	if err := acl.CheckAccessHTTP(r, acl.DEBUGGING); err != nil {
 		acl.SendError(w, err)
 		return
 	}
 	timeout, limit := parseTimeoutLimitParams(r)
+	filter := parseQuerylogzFilter(r)
 	logz.StartHTMLTable(w)
 	defer logz.EndHTMLTable(w)
 	w.Write(querylogzHeader)
 
 	tmr := time.NewTimer(timeout)
 	defer tmr.Stop()
-	for i := 0; i < limit; i++ {
+	for i := 0; i < limit; {
 		select {
 		case stats := <-ch:
 			select {
@@ -107,6 +155,10 @@ func querylogzHandler(ch chan *tabletenv.LogStats, w http.ResponseWriter, r *htt
 				return
 			default:
 			}
+			if !filter.matches(stats) {
+				continue
+			}
+			i++
 			var level string
 			if stats.TotalTime().Seconds() < 0.01 {
 				level = "low"
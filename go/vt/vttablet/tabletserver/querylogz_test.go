@@ -63,10 +63,14 @@ func TestQuerylogzHandler(t *testing.T) {
 		`<td>0.001</td>`,
 		`<td>0.001</td>`,
 		`<td>1e-08</td>`,
+		`<td>0</td>`,
+		`<td></td>`,
+		`<td>0</td>`,
 		`<td>Select</td>`,
 		regexp.QuoteMeta("<td>select name,\u200b &#39;inject &lt;script&gt;alert()\u200b;&lt;/script&gt;&#39; from test_table limit 1000</td>"),
 		`<td>1</td>`,
 		`<td>none</td>`,
+		`<td>standalone</td>`,
 		`<td>1000</td>`,
 		`<td>0</td>`,
 		`<td>131</td>`,
@@ -94,10 +98,14 @@ func TestQuerylogzHandler(t *testing.T) {
 		`<td>0.02</td>`,
 		`<td>0.001</td>`,
 		`<td>1e-08</td>`,
+		`<td>0</td>`,
+		`<td></td>`,
+		`<td>0</td>`,
 		`<td>Select</td>`,
 		regexp.QuoteMeta("<td>select name,\u200b &#39;inject &lt;script&gt;alert()\u200b;&lt;/script&gt;&#39; from test_table limit 1000</td>"),
 		`<td>1</td>`,
 		`<td>none</td>`,
+		`<td>standalone</td>`,
 		`<td>1000</td>`,
 		`<td>0</td>`,
 		`<td>131</td>`,
@@ -125,10 +133,14 @@ func TestQuerylogzHandler(t *testing.T) {
 		`<td>0.5</td>`,
 		`<td>0.001</td>`,
 		`<td>1e-08</td>`,
+		`<td>0</td>`,
+		`<td></td>`,
+		`<td>0</td>`,
 		`<td>Select</td>`,
 		regexp.QuoteMeta("<td>select name,\u200b &#39;inject &lt;script&gt;alert()\u200b;&lt;/script&gt;&#39; from test_table limit 1000</td>"),
 		`<td>1</td>`,
 		`<td>none</td>`,
+		`<td>standalone</td>`,
 		`<td>1000</td>`,
 		`<td>0</td>`,
 		`<td>131</td>`,
@@ -153,6 +165,123 @@ func TestQuerylogzHandler(t *testing.T) {
 	checkQuerylogzHasStats(t, slowQueryPattern, logStats, body)
 }
 
+func TestQuerylogzHandlerMinQueueDepth(t *testing.T) {
+	newStats := func(sql string, depth int) *tabletenv.LogStats {
+		logStats := tabletenv.NewLogStats(context.Background(), "Execute", streamlog.NewQueryLogConfigForTest())
+		logStats.OriginalSQL = sql
+		logStats.EndTime = logStats.StartTime.Add(1 * time.Millisecond)
+		logStats.RecordConnWaitQueueDepth(depth)
+		return logStats
+	}
+
+	req, _ := http.NewRequest("GET", "/querylogz?timeout=1&limit=1&min_queue_depth=5", nil)
+	response := httptest.NewRecorder()
+	ch := make(chan *tabletenv.LogStats, 2)
+	ch <- newStats("select 1", 2)
+	ch <- newStats("select 2", 8)
+	querylogzHandler(ch, response, req, sqlparser.NewTestParser())
+	close(ch)
+	body, _ := io.ReadAll(response.Body)
+	page := string(body)
+	if strings.Contains(page, "<td>select 1</td>") || !strings.Contains(page, "<td>select 2</td>") {
+		t.Fatalf("querylogz did not filter on the connection queue depth: %s", page)
+	}
+	if !strings.Contains(page, "<td>8</td>") {
+		t.Fatalf("querylogz did not show the connection queue depth: %s", page)
+	}
+}
+
+func TestQuerylogzHandlerMinACLTime(t *testing.T) {
+	newStats := func(sql string, aclTime time.Duration) *tabletenv.LogStats {
+		logStats := tabletenv.NewLogStats(context.Background(), "Execute", streamlog.NewQueryLogConfigForTest())
+		logStats.OriginalSQL = sql
+		logStats.EndTime = logStats.StartTime.Add(10 * time.Millisecond)
+		logStats.ACLCheckTime = aclTime
+		return logStats
+	}
+
+	req, _ := http.NewRequest("GET", "/querylogz?timeout=1&limit=1&min_acl_time=5ms", nil)
+	response := httptest.NewRecorder()
+	ch := make(chan *tabletenv.LogStats, 2)
+	ch <- newStats("select 1", time.Millisecond)
+	ch <- newStats("select 2", 8*time.Millisecond)
+	querylogzHandler(ch, response, req, sqlparser.NewTestParser())
+	close(ch)
+	body, _ := io.ReadAll(response.Body)
+	page := string(body)
+	if strings.Contains(page, "<td>select 1</td>") || !strings.Contains(page, "<td>select 2</td>") {
+		t.Fatalf("querylogz did not filter on the ACL check time: %s", page)
+	}
+	if !strings.Contains(page, "<td>0.008</td>") {
+		t.Fatalf("querylogz did not show the ACL check time: %s", page)
+	}
+}
+
+func TestQuerylogzHandlerSchemaReload(t *testing.T) {
+	newStats := func(sql string, reloaded bool) *tabletenv.LogStats {
+		logStats := tabletenv.NewLogStats(context.Background(), "Execute", streamlog.NewQueryLogConfigForTest())
+		logStats.OriginalSQL = sql
+		logStats.EndTime = logStats.StartTime.Add(10 * time.Millisecond)
+		if reloaded {
+			logStats.SchemaReloaded = true
+			logStats.SchemaReloadTime = 3 * time.Millisecond
+		}
+		return logStats
+	}
+
+	req, _ := http.NewRequest("GET", "/querylogz?timeout=1&limit=1&schema_reload=1", nil)
+	response := httptest.NewRecorder()
+	ch := make(chan *tabletenv.LogStats, 2)
+	ch <- newStats("select 1", false)
+	ch <- newStats("alter table t add column c int", true)
+	querylogzHandler(ch, response, req, sqlparser.NewTestParser())
+	close(ch)
+	body, _ := io.ReadAll(response.Body)
+	page := string(body)
+	if strings.Contains(page, "<td>select 1</td>") || !strings.Contains(page, "alter table") {
+		t.Fatalf("querylogz did not filter on schema reloads: %s", page)
+	}
+	if !strings.Contains(page, "<td>0.003</td>") {
+		t.Fatalf("querylogz did not show the schema reload time: %s", page)
+	}
+}
+
+func TestQuerylogzHandlerConsolidation(t *testing.T) {
+	newStats := func(sql, consolidation string) *tabletenv.LogStats {
+		logStats := tabletenv.NewLogStats(context.Background(), "Execute", streamlog.NewQueryLogConfigForTest())
+		logStats.OriginalSQL = sql
+		logStats.EndTime = logStats.StartTime.Add(10 * time.Millisecond)
+		logStats.Consolidation = consolidation
+		return logStats
+	}
+	render := func(consolidation string) string {
+		req, _ := http.NewRequest("GET", "/querylogz?timeout=1&limit=1&consolidation="+consolidation, nil)
+		response := httptest.NewRecorder()
+		ch := make(chan *tabletenv.LogStats, 3)
+		ch <- newStats("select 1", "")
+		ch <- newStats("select 2", tabletenv.ConsolidationLeader)
+		ch <- newStats("select 3", tabletenv.ConsolidationFollower)
+		querylogzHandler(ch, response, req, sqlparser.NewTestParser())
+		close(ch)
+		body, _ := io.ReadAll(response.Body)
+		return string(body)
+	}
+
+	for sql, consolidation := range map[string]string{
+		"select 1": "standalone",
+		"select 2": "leader",
+		"select 3": "follower",
+	} {
+		page := render(consolidation)
+		if !strings.Contains(page, "<td>"+sql+"</td>") || strings.Count(page, "<td>select ") != 1 {
+			t.Fatalf("querylogz did not filter on consolidation=%s: %s", consolidation, page)
+		}
+		if !strings.Contains(page, "<td>"+consolidation+"</td>") {
+			t.Fatalf("querylogz did not show the consolidation role %s: %s", consolidation, page)
+		}
+	}
+}
+
 func checkQuerylogzHasStats(t *testing.T, pattern []string, logStats *tabletenv.LogStats, page []byte) {
 	t.Helper()
 	matcher := regexp.MustCompile(strings.Join(pattern, `\s*`))
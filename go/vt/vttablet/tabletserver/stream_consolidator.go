@@ -110,6 +110,7 @@ func (sc *StreamConsolidator) Consolidate(waitTimings *servenv.TimingsWrapper, l
 		}()
 
 		logStats.QuerySources |= tabletenv.QuerySourceConsolidator
+		logStats.Consolidation = tabletenv.ConsolidationFollower
 
 		// first, catch up our client by sending all the Results to the streaming query
 		// that the leader has already sent
@@ -134,6 +135,7 @@ func (sc *StreamConsolidator) Consolidate(waitTimings *servenv.TimingsWrapper, l
 
 	// we don't have a followChan so we're the leaders for this query. we must run it in the
 	// upstream MySQL and fan out all the Results to any followers that show up
+	logStats.Consolidation = tabletenv.ConsolidationLeader
 
 	defer func() {
 		sc.mu.Lock()
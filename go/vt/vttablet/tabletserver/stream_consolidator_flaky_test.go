@@ -40,6 +40,8 @@ type consolidationResult struct {
 	items    []*sqltypes.Result
 	duration time.Duration
 	count    int64
+	// consolidation is the role of the worker's query, as logged.
+	consolidation string
 }
 
 func nocleanup(_ *sqltypes.Result) error {
@@ -140,6 +142,7 @@ func (ct *consolidationTest) run(workers int, generateCallback func(int) (string
 			cr := ct.results[worker]
 			cr.err = err
 			cr.duration = time.Since(start)
+			cr.consolidation = logStats.ConsolidationRole()
 		}(i)
 	}
 
@@ -166,10 +169,13 @@ func TestConsolidatorSimple(t *testing.T) {
 
 	require.Equal(t, uint64(1), ct.leaderCalls)
 
+	roles := map[string]int{}
 	for _, results := range ct.results {
 		require.Len(t, results.items, ct.streamItemCount)
 		require.NoError(t, results.err)
+		roles[results.consolidation]++
 	}
+	require.Equal(t, map[string]int{tabletenv.ConsolidationLeader: 1, tabletenv.ConsolidationFollower: 9}, roles)
 }
 
 func TestConsolidatorErrorPropagation(t *testing.T) {
@@ -41,6 +41,18 @@ const (
 	QuerySourceMySQL
 )
 
+// Roles of a query in query consolidation, see LogStats.ConsolidationRole.
+const (
+	// ConsolidationLeader means the query was sent to MySQL on behalf of
+	// itself and of the identical queries merged into it, if any.
+	ConsolidationLeader = "leader"
+	// ConsolidationFollower means the query was merged into an identical
+	// query already running, and served its result.
+	ConsolidationFollower = "follower"
+	// ConsolidationStandalone means the query wasn't consolidated.
+	ConsolidationStandalone = "standalone"
+)
+
 // LogStats records the stats for a single query
 type LogStats struct {
 	Config streamlog.QueryLogConfig
@@ -64,6 +76,22 @@ type LogStats struct {
 	ReservedID           int64
 	Error                error
 	CachedPlan           bool
+
+	// ConnWaitQueueDepth is the number of clients that were already waiting
+	// for a connection from the pool when the query asked for one. A deep
+	// queue together with a long WaitingForConnection means the pool is
+	// saturated.
+	ConnWaitQueueDepth int
+	// ACLCheckTime is the time spent checking the table ACLs of the query.
+	ACLCheckTime time.Duration
+	// SchemaReloaded is set when the query triggered a successful schema
+	// reload, as DDLs do. SchemaReloadTime is the time spent reloading.
+	SchemaReloaded   bool
+	SchemaReloadTime time.Duration
+	// Consolidation is ConsolidationLeader or ConsolidationFollower when
+	// the query went through the consolidator, and empty otherwise. The
+	// near zero MySQL time of followers is explained by it.
+	Consolidation string
 }
 
 // NewLogStats constructs a new LogStats with supplied Method and ctx
@@ -106,6 +134,13 @@ func (stats *LogStats) AddRewrittenSQL(sql string, start time.Time) {
 	stats.MysqlResponseTime += time.Since(start)
 }
 
+// RecordConnWaitQueueDepth records the depth of the wait queue of the
+// connection pool the query is about to get a connection from. Queries that
+// get several connections keep the deepest queue they saw.
+func (stats *LogStats) RecordConnWaitQueueDepth(depth int) {
+	stats.ConnWaitQueueDepth = max(stats.ConnWaitQueueDepth, depth)
+}
+
 // TotalTime returns how long this query has been running
 func (stats *LogStats) TotalTime() time.Duration {
 	return stats.EndTime.Sub(stats.StartTime)
@@ -153,6 +188,15 @@ func (stats *LogStats) FmtQuerySources() string {
 	return strings.Join(sources[:n], ",")
 }
 
+// ConsolidationRole returns the role of the query in query consolidation,
+// ConsolidationStandalone when it wasn't consolidated.
+func (stats *LogStats) ConsolidationRole() string {
+	if stats.Consolidation == "" {
+		return ConsolidationStandalone
+	}
+	return stats.Consolidation
+}
+
 // ContextHTML returns the HTML version of the context that was used, or "".
 // This is a method on LogStats instead of a field so that it doesn't need
 // to be passed by value everywhere.
@@ -214,7 +258,7 @@ func (stats *LogStats) Logf(w io.Writer, params url.Values) error {
 	if stats.Config.RedactDebugUIQueries {
 		log.Redacted()
 	} else {
-		log.BindVariables(stats.BindVariables, fullBindParams)
+		log.RedactedBindVariables(stats.BindVariables, fullBindParams, stats.Config.RedactBindVars)
 	}
 	log.Key("Queries")
 	log.Int(int64(stats.NumberOfQueries))
@@ -238,6 +282,16 @@ func (stats *LogStats) Logf(w io.Writer, params url.Values) error {
 	log.Int(int64(stats.SizeOfResponse()))
 	log.Key("Error")
 	log.String(stats.ErrorStr())
+	log.Key("ConnWaitQueueDepth")
+	log.Int(int64(stats.ConnWaitQueueDepth))
+	log.Key("ACLCheckTime")
+	log.Duration(stats.ACLCheckTime)
+	log.Key("SchemaReloaded")
+	log.Bool(stats.SchemaReloaded)
+	log.Key("SchemaReloadTime")
+	log.Duration(stats.SchemaReloadTime)
+	log.Key("Consolidation")
+	log.StringUnquoted(stats.ConsolidationRole())
 
 	// logstats from the vttablet are always tab-terminated; keep this for backwards
 	// compatibility for existing parsers